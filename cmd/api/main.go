@@ -7,10 +7,11 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"recipe-generator/internal/api"
 	"recipe-generator/internal/core/ai/cache"
+	"recipe-generator/internal/core/ai/queue"
+	"recipe-generator/internal/core/jobs"
 	"recipe-generator/internal/infrastructure/config"
 	"recipe-generator/internal/pkg/common"
 
@@ -44,16 +45,21 @@ func main() {
 		zap.String("openrouter_model", cfg.OpenRouter.Model),
 	)
 
-	// 初始化快取
-	cacheManager := cache.NewManager(cfg)
-	// 只在快取開啟但初始化失敗時才 Fatal
-	if cfg.Cache.Enabled && cacheManager == nil {
-		common.LogFatal("Failed to initialize cache manager")
+	// 初始化快取（依設定使用記憶體或 Redis，關閉時為 nil）
+	cacheManager, err := cache.New(cfg)
+	if err != nil {
+		common.LogFatal("Failed to initialize cache", zap.Error(err))
+	}
+
+	// 初始化非同步任務管理器
+	jobStore, err := jobs.NewStore(cfg.Jobs)
+	if err != nil {
+		common.LogFatal("Failed to initialize job store", zap.Error(err))
 	}
-	defer cacheManager.Close()
+	jobManager := jobs.NewManager(cfg, queue.NewManager(cfg), jobStore)
 
 	// 設置路由
-	router, err := api.SetupRouter(cfg, cacheManager)
+	router, err := api.SetupRouter(cfg, cacheManager, jobManager)
 	if err != nil {
 		common.LogError("Failed to setup router", zap.Error(err))
 		os.Exit(1)
@@ -91,16 +97,37 @@ func main() {
 
 	common.LogInfo("Shutting down server...")
 
-	// 設置關閉超時
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// 設置關閉超時，需涵蓋執行中的非同步任務
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
+	// HTTP 服務未能正常關閉時仍需關閉任務與快取，最後才以非零狀態結束
+	exitCode := 0
 	if err := srv.Shutdown(ctx); err != nil {
 		common.LogError("Server forced to shutdown",
 			zap.Error(err),
 		)
-		os.Exit(1)
+		exitCode = 1
+	}
+
+	// 等待執行中的非同步任務完成，逾時未完成的任務會在下次啟動時恢復
+	if err := jobManager.Close(ctx); err != nil {
+		common.LogError("Job manager forced to shutdown",
+			zap.Error(err),
+		)
+	}
+
+	if cacheManager != nil {
+		if err := cacheManager.Close(); err != nil {
+			common.LogError("Failed to close cache",
+				zap.Error(err),
+			)
+		}
 	}
 
 	common.LogInfo("Server exited")
+	if exitCode != 0 {
+		common.Sync()
+		os.Exit(exitCode)
+	}
 }
@@ -0,0 +1,122 @@
+// eval 離線評估：以黃金資料集執行食譜生成、推薦與圖片辨識，輸出評分報告，
+// 指定基準報告時評分下降即以非零狀態結束，用於把關提示詞修改
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"recipe-generator/internal/core/ai/service"
+	"recipe-generator/internal/core/prompt"
+	"recipe-generator/internal/eval"
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+)
+
+func main() {
+	dataset := flag.String("dataset", "", "黃金資料集（JSONL）路徑")
+	out := flag.String("out", "eval-report.json", "報告輸出路徑")
+	providerName := flag.String("provider", "", "覆寫 AI_PROVIDER，例如 fake；指定時不使用備援")
+	promptsDir := flag.String("prompts", "", "覆寫 PROMPTS_DIR，評估修改中的模板")
+	concurrency := flag.Int("concurrency", 4, "同時執行的案例數")
+	baseline := flag.String("baseline", "", "基準報告路徑，評分下降時以狀態碼 1 結束")
+	tolerance := flag.Float64("tolerance", 0, "與基準比較時允許的比率下降")
+	flag.Parse()
+
+	if *dataset == "" {
+		fmt.Fprintln(os.Stderr, "-dataset is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// 載入設定
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	if *providerName != "" {
+		cfg.AI.Provider = *providerName
+		cfg.AI.Fallbacks = nil
+	}
+	if *promptsDir != "" {
+		cfg.Prompts.Dir = *promptsDir
+	}
+
+	if err := common.InitLogger(cfg.LogLevel); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer common.Sync()
+
+	cases, err := eval.LoadDataset(*dataset)
+	if err != nil {
+		fatal("Failed to load dataset", err)
+	}
+
+	p, err := service.NewProvider(cfg)
+	if err != nil {
+		fatal("Failed to create AI provider", err)
+	}
+	defer p.Close()
+
+	prompts, err := prompt.NewRegistry(cfg.Prompts.Dir)
+	if err != nil {
+		fatal("Failed to load prompt templates", err)
+	}
+
+	// 中斷時取消進行中的呼叫，已完成的案例仍會寫入報告
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	runner := eval.NewRunner(cfg, p, prompts, *concurrency)
+	report := eval.NewReport(cfg.AI.Provider, p.GetModel(), runner.Run(ctx, cases))
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fatal("Failed to create report", err)
+	}
+	if err := report.Write(f); err != nil {
+		f.Close()
+		fatal("Failed to write report", err)
+	}
+	if err := f.Close(); err != nil {
+		fatal("Failed to write report", err)
+	}
+
+	report.PrintSummary(os.Stderr)
+	fmt.Fprintf(os.Stderr, "report written to %s\n", *out)
+
+	if *baseline == "" {
+		return
+	}
+	base, err := eval.LoadReport(*baseline)
+	if err != nil {
+		fatal("Failed to load baseline", err)
+	}
+	regressions, newlyFailing := eval.Compare(base, report, *tolerance)
+	if len(newlyFailing) > 0 {
+		fmt.Fprintf(os.Stderr, "newly failing cases: %s\n", strings.Join(newlyFailing, ", "))
+	}
+	if len(regressions) > 0 {
+		fmt.Fprintln(os.Stderr, "regressions against baseline:")
+		for _, r := range regressions {
+			fmt.Fprintf(os.Stderr, "  %s\n", r)
+		}
+		common.Sync()
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "no regressions against baseline")
+}
+
+// fatal 輸出錯誤並以狀態碼 1 結束
+func fatal(msg string, err error) {
+	fmt.Fprintf(os.Stderr, "%s: %v\n", msg, err)
+	common.Sync()
+	os.Exit(1)
+}
@@ -0,0 +1,190 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"recipe-generator/internal/core/ai/cache"
+	"recipe-generator/internal/core/ai/service"
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// defaultKeyLimit 列出快取鍵的預設數量上限
+const defaultKeyLimit = 100
+
+// DeleteEntryRequest 刪除快取條目的請求：key 為 /cache/keys 列出的快取鍵，可刪除食譜指紋、
+// 圖片感知雜湊等無法由 prompt 重新計算的條目；未指定 key 時依 prompt 與圖片計算，
+// prompt 由模板產生時需填入模板 ID 與版本，例如 recipe.generate@v1
+type DeleteEntryRequest struct {
+	Key      string `json:"key,omitempty"`
+	Prompt   string `json:"prompt,omitempty"`
+	Template string `json:"template,omitempty"`
+	Image    string `json:"image,omitempty"`
+}
+
+// CacheHandler 快取管理處理程序
+type CacheHandler struct {
+	cache     cache.Cache
+	aiService *service.Service
+	indexes   []cache.Index
+}
+
+// NewCacheHandler 創建快取管理處理程序；快取關閉時 c 為 nil。
+// indexes 為建立在快取條目上的本地索引，刪除或清除條目後同步移除索引中的記錄
+func NewCacheHandler(c cache.Cache, aiService *service.Service, indexes ...cache.Index) *CacheHandler {
+	return &CacheHandler{cache: c, aiService: aiService, indexes: indexes}
+}
+
+// prune 移除本地索引中已刪除條目的記錄；失敗只記錄日誌，索引查找時也會略過已失效的條目
+func (h *CacheHandler) prune(c *gin.Context) {
+	for _, idx := range h.indexes {
+		n, err := idx.Prune(c.Request.Context())
+		if err != nil {
+			common.LogWarn("快取索引同步失敗", zap.Error(err))
+			continue
+		}
+		if n > 0 {
+			common.LogInfo("已移除快取索引中的失效記錄", zap.Int("removed", n))
+		}
+	}
+}
+
+// available 快取關閉時回傳 503
+func (h *CacheHandler) available(c *gin.Context) bool {
+	if h.cache == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cache disabled"})
+		return false
+	}
+	return true
+}
+
+// filter 由查詢參數組成篩選條件
+func filter(c *gin.Context) cache.Filter {
+	return cache.Filter{
+		Prefix:   c.Query("prefix"),
+		Endpoint: c.Query("endpoint"),
+	}
+}
+
+// Stats 快取統計與進行中請求合併統計
+func (h *CacheHandler) Stats(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"cache":    h.cache.GetStats(),
+		"coalesce": h.aiService.CoalesceStats(),
+	})
+}
+
+// Keys 列出快取條目的中繼資料，可依 prefix、endpoint 篩選，limit 預設 100
+func (h *CacheHandler) Keys(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+
+	limit := defaultKeyLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.cache.Entries(c.Request.Context(), filter(c), limit)
+	if err != nil {
+		common.LogError("列出快取條目失敗", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list cache entries"})
+		return
+	}
+	if entries == nil {
+		entries = []cache.EntryInfo{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"count":   len(entries),
+		"entries": entries,
+	})
+}
+
+// Purge 清除快取；未指定 prefix、endpoint 時清空全部
+func (h *CacheHandler) Purge(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+
+	f := filter(c)
+	count, err := h.cache.Purge(c.Request.Context(), f)
+	if err != nil {
+		common.LogError("清除快取失敗", zap.Error(err), zap.Int("purged", count))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge cache", "purged": count})
+		return
+	}
+	h.prune(c)
+
+	common.LogInfo("管理員清除快取",
+		zap.String("prefix", f.Prefix),
+		zap.String("endpoint", f.Endpoint),
+		zap.Int("purged", count),
+		zap.String("client_ip", c.ClientIP()),
+	)
+	c.JSON(http.StatusOK, gin.H{"purged": count})
+}
+
+// DeleteEntry 依快取鍵，或依 prompt 與圖片重新計算快取鍵並刪除該條目
+func (h *CacheHandler) DeleteEntry(c *gin.Context) {
+	if !h.available(c) {
+		return
+	}
+
+	var req DeleteEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Key == "") == (req.Prompt == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: specify either key or prompt"})
+		return
+	}
+
+	if req.Key != "" {
+		h.deleteKey(c, req.Key)
+		return
+	}
+
+	key, err := h.aiService.InvalidateCache(c.Request.Context(), req.Prompt, req.Template, req.Image)
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image format"})
+		return
+	}
+	if err != nil {
+		common.LogError("刪除快取條目失敗", zap.String("key", key), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete cache entry", "key": key})
+		return
+	}
+
+	common.LogInfo("管理員刪除快取條目",
+		zap.String("key", key),
+		zap.String("client_ip", c.ClientIP()),
+	)
+	c.JSON(http.StatusOK, gin.H{"key": key, "deleted": true})
+}
+
+// deleteKey 依快取鍵刪除條目；只刪除鍵完全相同的條目，依前綴批次清除請改用 DELETE /admin/cache，
+// tiered 模式同樣會通知所有實例
+func (h *CacheHandler) deleteKey(c *gin.Context, key string) {
+	count, err := h.cache.Purge(c.Request.Context(), cache.Filter{Key: key})
+	if err != nil {
+		common.LogError("刪除快取條目失敗", zap.String("key", key), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete cache entry", "key": key})
+		return
+	}
+	h.prune(c)
+
+	common.LogInfo("管理員刪除快取條目",
+		zap.String("key", key),
+		zap.Int("deleted", count),
+		zap.String("client_ip", c.ClientIP()),
+	)
+	c.JSON(http.StatusOK, gin.H{"key": key, "deleted": count > 0})
+}
@@ -0,0 +1,195 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"recipe-generator/internal/core/ai/cache"
+	"recipe-generator/internal/core/ai/fake"
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/core/ai/service"
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	common.Logger = zap.NewNop()
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// countingIndex 記錄被要求同步的次數
+type countingIndex struct {
+	prunes int
+}
+
+func (idx *countingIndex) Prune(ctx context.Context) (int, error) {
+	idx.prunes++
+	return 0, nil
+}
+
+// newTestRouter 以記憶體快取建立管理介面的快取路由，並寫入兩個端點的條目
+func newTestRouter(t *testing.T) (*gin.Engine, *cache.CacheManager, *countingIndex) {
+	t.Helper()
+	cfg := &config.Config{Cache: config.CacheConfig{
+		Enabled:         true,
+		MaxBytes:        1 << 20,
+		Shards:          4,
+		TTL:             time.Hour,
+		CleanupInterval: time.Hour,
+	}}
+	store := cache.NewManager(cfg)
+	t.Cleanup(func() { store.Close() })
+
+	p, err := fake.New(provider.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := &countingIndex{}
+	h := NewCacheHandler(store, service.NewServiceWithProvider(cfg, store, p), idx)
+
+	generate := cache.WithEndpoint(context.Background(), "/api/v1/recipe/generate")
+	food := cache.WithEndpoint(context.Background(), "/api/v1/recipe/food")
+	for prompt, ctx := range map[string]context.Context{
+		"template=recipe.generate@v1|番茄炒蛋": generate,
+		"template=recipe.generate@v1|青椒牛肉": generate,
+		"辨識食物": food,
+	} {
+		if err := store.Set(ctx, prompt, "", "{}"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	router := gin.New()
+	router.GET("/cache/stats", h.Stats)
+	router.GET("/cache/keys", h.Keys)
+	router.DELETE("/cache", h.Purge)
+	router.POST("/cache/delete", h.DeleteEntry)
+	return router, store, idx
+}
+
+// do 送出請求並解析 JSON 回應
+func do(t *testing.T, router *gin.Engine, method, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s: invalid JSON %q", method, path, w.Body.String())
+	}
+	return w.Code, resp
+}
+
+// remaining 快取中剩餘的條目數
+func remaining(t *testing.T, store cache.Cache) int {
+	t.Helper()
+	entries, err := store.Entries(context.Background(), cache.Filter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(entries)
+}
+
+func TestCacheKeys(t *testing.T) {
+	router, _, _ := newTestRouter(t)
+
+	tests := []struct {
+		path  string
+		count float64
+	}{
+		{"/cache/keys", 3},
+		{"/cache/keys?limit=1", 1},
+		{"/cache/keys?endpoint=/api/v1/recipe/generate", 2},
+		{"/cache/keys?prefix=multimodal:", 0},
+	}
+	for _, tt := range tests {
+		code, resp := do(t, router, http.MethodGet, tt.path, "")
+		if code != http.StatusOK || resp["count"] != tt.count {
+			t.Fatalf("GET %s: %d %v", tt.path, code, resp)
+		}
+	}
+	if code, _ := do(t, router, http.MethodGet, "/cache/keys?limit=-1", ""); code != http.StatusBadRequest {
+		t.Fatalf("invalid limit: %d", code)
+	}
+	if code, resp := do(t, router, http.MethodGet, "/cache/stats", ""); code != http.StatusOK || resp["cache"] == nil || resp["coalesce"] == nil {
+		t.Fatalf("stats: %d %v", code, resp)
+	}
+}
+
+func TestCachePurge(t *testing.T) {
+	router, store, idx := newTestRouter(t)
+
+	code, resp := do(t, router, http.MethodDelete, "/cache?endpoint=/api/v1/recipe/generate", "")
+	if code != http.StatusOK || resp["purged"] != float64(2) || remaining(t, store) != 1 {
+		t.Fatalf("purge by endpoint: %d %v", code, resp)
+	}
+	if idx.prunes != 1 {
+		t.Fatalf("index pruned %d times", idx.prunes)
+	}
+	code, resp = do(t, router, http.MethodDelete, "/cache", "")
+	if code != http.StatusOK || resp["purged"] != float64(1) || remaining(t, store) != 0 {
+		t.Fatalf("purge all: %d %v", code, resp)
+	}
+}
+
+func TestCacheDeleteByKey(t *testing.T) {
+	router, store, idx := newTestRouter(t)
+	key := cache.Key("template=recipe.generate@v1|番茄炒蛋", "")
+
+	// 鍵的前綴不是完整的鍵，不可清除整個命名空間
+	for _, partial := range []string{"text:", key[:len(key)-1]} {
+		code, resp := do(t, router, http.MethodPost, "/cache/delete", `{"key":"`+partial+`"}`)
+		if code != http.StatusOK || resp["deleted"] != false || remaining(t, store) != 3 {
+			t.Fatalf("delete %q: %d %v, %d entries left", partial, code, resp, remaining(t, store))
+		}
+	}
+
+	code, resp := do(t, router, http.MethodPost, "/cache/delete", `{"key":"`+key+`"}`)
+	if code != http.StatusOK || resp["deleted"] != true || resp["key"] != key || remaining(t, store) != 2 {
+		t.Fatalf("delete by key: %d %v", code, resp)
+	}
+	if idx.prunes != 3 {
+		t.Fatalf("index pruned %d times", idx.prunes)
+	}
+
+	// 依模板與 prompt 重新計算快取鍵
+	code, resp = do(t, router, http.MethodPost, "/cache/delete", `{"prompt":"青椒 牛肉","template":"recipe.generate@v1"}`)
+	if code != http.StatusOK || resp["key"] != cache.Key("template=recipe.generate@v1|青椒牛肉", "") || remaining(t, store) != 1 {
+		t.Fatalf("delete by prompt: %d %v", code, resp)
+	}
+
+	for _, body := range []string{`{}`, `{"key":"text:x","prompt":"p"}`, `not json`} {
+		if code, _ := do(t, router, http.MethodPost, "/cache/delete", body); code != http.StatusBadRequest {
+			t.Fatalf("delete %s: %d", body, code)
+		}
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	h := NewCacheHandler(nil, nil)
+	router := gin.New()
+	router.GET("/cache/keys", h.Keys)
+	router.DELETE("/cache", h.Purge)
+	router.POST("/cache/delete", h.DeleteEntry)
+
+	for _, r := range []struct{ method, path string }{
+		{http.MethodGet, "/cache/keys"},
+		{http.MethodDelete, "/cache"},
+		{http.MethodPost, "/cache/delete"},
+	} {
+		if code, _ := do(t, router, r.method, r.path, `{"key":"text:x"}`); code != http.StatusServiceUnavailable {
+			t.Fatalf("%s %s with cache disabled: %d", r.method, r.path, code)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package admin
+
+import (
+	"net/http"
+
+	"recipe-generator/internal/core/experiment"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExperimentsHandler 提示詞實驗管理處理程序
+type ExperimentsHandler struct {
+	manager *experiment.Manager
+}
+
+// NewExperimentsHandler 創建提示詞實驗管理處理程序
+func NewExperimentsHandler(manager *experiment.Manager) *ExperimentsHandler {
+	return &ExperimentsHandler{manager: manager}
+}
+
+// List 列出進行中的實驗，以及各變體的解析成功率、AR 回退率、延遲、token 用量與評分
+func (h *ExperimentsHandler) List(c *gin.Context) {
+	reports := h.manager.Reports()
+	c.JSON(http.StatusOK, gin.H{
+		"count":       len(reports),
+		"experiments": reports,
+	})
+}
@@ -0,0 +1,42 @@
+package admin
+
+import (
+	"net/http"
+
+	"recipe-generator/internal/core/prompt"
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PromptsHandler 提示詞模板管理處理程序
+type PromptsHandler struct {
+	registry *prompt.Registry
+}
+
+// NewPromptsHandler 創建提示詞模板管理處理程序
+func NewPromptsHandler(registry *prompt.Registry) *PromptsHandler {
+	return &PromptsHandler{registry: registry}
+}
+
+// List 列出所有已載入的模板與版本
+func (h *PromptsHandler) List(c *gin.Context) {
+	templates := h.registry.List()
+	c.JSON(http.StatusOK, gin.H{
+		"count":     len(templates),
+		"templates": templates,
+	})
+}
+
+// Reload 重新讀取覆寫目錄；任一模板無效時保留原本的模板
+func (h *PromptsHandler) Reload(c *gin.Context) {
+	if err := h.registry.Reload(); err != nil {
+		common.LogError("重新載入提示詞模板失敗", zap.Error(err))
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to reload prompts", "details": err.Error()})
+		return
+	}
+
+	common.LogInfo("管理員重新載入提示詞模板", zap.String("client_ip", c.ClientIP()))
+	h.List(c)
+}
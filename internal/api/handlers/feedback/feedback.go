@@ -0,0 +1,53 @@
+package feedback
+
+import (
+	"errors"
+	"net/http"
+
+	"recipe-generator/internal/core/experiment"
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RatingRequest 使用者對單次回應的評分
+type RatingRequest struct {
+	RequestID string `json:"request_id" binding:"required"` // 原請求的 X-Request-ID 響應標頭
+	Rating    int    `json:"rating" binding:"required,min=1,max=5"`
+}
+
+// Handler 使用者回饋處理程序
+type Handler struct {
+	experiments *experiment.Manager
+}
+
+// NewHandler 創建使用者回饋處理程序
+func NewHandler(experiments *experiment.Manager) *Handler {
+	return &Handler{experiments: experiments}
+}
+
+// Rate 記錄使用者評分，計入該請求分配到的實驗變體
+func (h *Handler) Rate(c *gin.Context) {
+	var req RatingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	if err := h.experiments.Rate(req.RequestID, req.Rating); err != nil {
+		if errors.Is(err, experiment.ErrUnknownRequest) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Request not found or already rated"})
+			return
+		}
+		common.LogError("記錄評分失敗", zap.String("request_id", req.RequestID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record rating"})
+		return
+	}
+
+	common.LogInfo("已記錄使用者評分",
+		zap.String("request_id", req.RequestID),
+		zap.Int("rating", req.Rating),
+	)
+	c.Status(http.StatusNoContent)
+}
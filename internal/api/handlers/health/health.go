@@ -5,6 +5,9 @@ import (
 	"runtime"
 	"time"
 
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/core/ai/service"
+	"recipe-generator/internal/core/jobs"
 	"recipe-generator/internal/infrastructure/config"
 	"recipe-generator/internal/pkg/common"
 
@@ -14,11 +17,13 @@ import (
 
 // HealthResponse 健康檢查響應
 type HealthResponse struct {
-	Status    string                 `json:"status"`
-	Timestamp time.Time              `json:"timestamp"`
-	Version   string                 `json:"version"`
-	Runtime   map[string]interface{} `json:"runtime"`
-	Queue     *QueueStatus           `json:"queue,omitempty"`
+	Status    string                  `json:"status"`
+	Timestamp time.Time               `json:"timestamp"`
+	Version   string                  `json:"version"`
+	Runtime   map[string]interface{}  `json:"runtime"`
+	Queue     *QueueStatus            `json:"queue,omitempty"`
+	Providers []provider.MemberStatus `json:"providers,omitempty"`
+	Coalesce  *service.CoalesceStats  `json:"coalesce,omitempty"`
 }
 
 // QueueStatus 隊列狀態
@@ -27,6 +32,7 @@ type QueueStatus struct {
 	ProcessedCount int `json:"processed_count"`
 	MaxQueueSize   int `json:"max_queue_size"`
 	Workers        int `json:"workers"`
+	ActiveWorkers  int `json:"active_workers"`
 }
 
 // HealthCheck 健康檢查處理器
@@ -58,7 +64,6 @@ func HealthCheck(c *gin.Context) {
 		})
 		return
 	}
-	_ = aiSvc // 若未使用，直接忽略
 
 	// 獲取運行時信息
 	var m runtime.MemStats
@@ -80,7 +85,26 @@ func HealthCheck(c *gin.Context) {
 		},
 	}
 
-	// 如果 AI 服務可用，這裡可擴充隊列狀態（暫不實作）
+	// 回報非同步任務隊列狀態
+	if jm, ok := c.Get("job_manager"); ok {
+		if manager, ok := jm.(*jobs.Manager); ok && manager != nil {
+			status := manager.QueueStatus()
+			response.Queue = &QueueStatus{
+				QueueLength:    status.QueueLength,
+				ProcessedCount: status.ProcessedCount,
+				MaxQueueSize:   status.MaxQueueSize,
+				Workers:        status.Workers,
+				ActiveWorkers:  status.ActiveWorkers,
+			}
+		}
+	}
+
+	// 如果 AI 服務可用，回報失敗轉移鏈的提供者狀態與請求合併統計
+	if svc, ok := aiSvc.(*service.Service); ok && svc != nil {
+		response.Providers = svc.ProviderStatus()
+		stats := svc.CoalesceStats()
+		response.Coalesce = &stats
+	}
 
 	// 記錄請求
 	common.LogInfo("Health check request",
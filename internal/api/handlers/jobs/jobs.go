@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"recipe-generator/internal/core/ai/queue"
+	jobService "recipe-generator/internal/core/jobs"
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SubmitResponse 任務建立響應
+type SubmitResponse struct {
+	JobID     string            `json:"job_id"`
+	Type      jobService.Type   `json:"type"`
+	Status    jobService.Status `json:"status"`
+	StatusURL string            `json:"status_url"`
+}
+
+// Handler 非同步任務處理程序
+type Handler struct {
+	manager *jobService.Manager
+}
+
+// NewHandler 創建非同步任務處理程序
+func NewHandler(manager *jobService.Manager) *Handler {
+	return &Handler{manager: manager}
+}
+
+// Submit 建立指定類型的任務，請求體與對應的同步端點相同
+func (h *Handler) Submit(t jobService.Type) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil || !json.Valid(body) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+
+		job, err := h.manager.Submit(t, body)
+		if err != nil {
+			switch {
+			case errors.Is(err, queue.ErrQueueFull):
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Job queue is full, please retry later"})
+			case errors.Is(err, queue.ErrQueueClosed):
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Server is shutting down"})
+			case errors.Is(err, jobService.ErrUnknownType):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Unknown job type"})
+			default:
+				common.LogWarn("任務建立失敗",
+					zap.String("type", string(t)),
+					zap.Error(err),
+				)
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		statusURL := "/api/v1/jobs/" + job.ID
+		c.Header("Location", statusURL)
+		c.JSON(http.StatusAccepted, SubmitResponse{
+			JobID:     job.ID,
+			Type:      job.Type,
+			Status:    job.Status,
+			StatusURL: statusURL,
+		})
+	}
+}
+
+// Get 查詢任務狀態與結果
+func (h *Handler) Get(c *gin.Context) {
+	job, err := h.manager.Get(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, jobService.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		common.LogError("任務查詢失敗", zap.String("job_id", c.Param("id")), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load job"})
+		return
+	}
+
+	// 不回傳原始請求內容，避免重複傳輸大型圖片；持有實例屬內部資訊
+	job.Payload = nil
+	job.Owner = ""
+	c.JSON(http.StatusOK, job)
+}
+
+// Cancel 取消排隊中或執行中的任務
+func (h *Handler) Cancel(c *gin.Context) {
+	job, err := h.manager.Cancel(c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, jobService.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		case errors.Is(err, jobService.ErrJobFinished):
+			c.JSON(http.StatusConflict, gin.H{"error": "Job already finished", "status": job.Status})
+		default:
+			common.LogError("任務取消失敗", zap.String("job_id", c.Param("id")), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel job"})
+		}
+		return
+	}
+
+	job.Payload = nil
+	job.Owner = ""
+	c.JSON(http.StatusOK, job)
+}
@@ -1,9 +1,11 @@
 package recipe
 
 import (
+	"errors"
 	"net/http"
 
 	"recipe-generator/internal/core/ai/image"
+	imagecore "recipe-generator/internal/core/image"
 	recipeService "recipe-generator/internal/core/recipe"
 	"recipe-generator/internal/pkg/common"
 
@@ -21,16 +23,19 @@ type FoodRecognitionRequest struct {
 }
 
 // FoodRecognitionResponse 圖片辨識食物回應
-// recognized_foods: [{name, description, possible_ingredients, possible_equipment}]
+// recognized_foods: [{name, description, image, possible_ingredients, possible_equipment}]
 type FoodRecognitionResponse struct {
 	RecognizedFoods []RecognizedFood `json:"recognized_foods"` // 辨識出的食物列表
 }
 
 type RecognizedFood struct {
-	Name                string               `json:"name"`                 // 食物名稱
-	Description         string               `json:"description"`          // 此食物的特徵與可能料理方式說明
-	PossibleIngredients []PossibleIngredient `json:"possible_ingredients"` // 可能的食材
-	PossibleEquipment   []PossibleEquipment  `json:"possible_equipment"`   // 可能的設備
+	Name                string               `json:"name"`                   // 食物名稱
+	Description         string               `json:"description"`            // 此食物的特徵與可能料理方式說明
+	Confidence          *float64             `json:"confidence,omitempty"`   // 辨識的信心分數（0~1）
+	BoundingBox         *common.BoundingBox  `json:"bounding_box,omitempty"` // 食物在圖片中的位置（正規化座標）
+	Image               int                  `json:"image"`                  // 來源圖片序號，從 0 開始依上傳順序
+	PossibleIngredients []PossibleIngredient `json:"possible_ingredients"`   // 可能的食材
+	PossibleEquipment   []PossibleEquipment  `json:"possible_equipment"`     // 可能的設備
 }
 
 type PossibleIngredient struct {
@@ -73,95 +78,116 @@ func HandleFoodRecognition(foodService *recipeService.FoodService, imageService
 			zap.String("client_ip", c.ClientIP()),
 		)
 
+		// multipart 上傳可包含多張圖片，JSON 請求則為單張
+		var images []string
 		var req FoodRecognitionRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			common.LogError("請求格式無效",
-				zap.Error(err),
-				zap.String("request_id", requestID),
-			)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
-			return
-		}
-
-		// 處理圖片
-		processedImage, err := imageService.FormatImageData(req.Image)
-		if err != nil {
-			common.LogError("圖片處理失敗",
-				zap.Error(err),
-				zap.String("request_id", requestID),
-				zap.String("image_type", getImageType(req.Image)),
-				zap.Int("image_length", len(req.Image)),
-				zap.String("description_hint", req.DescriptionHint),
-			)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image format"})
-			return
-		}
-
-		// 識別食物
-		foods, err := foodService.IdentifyFood(c.Request.Context(), processedImage, req.DescriptionHint)
-		if err != nil {
-			// 圖片格式錯誤，回傳 400
-			errStr := err.Error()
-			if errStr == "no choices in OpenRouter response" {
-				common.LogError("AI 無回應 (no choices)",
+		if isMultipart(c.Request) {
+			upload, err := readImageUpload(c.Request, imageService)
+			if err != nil {
+				common.LogError("圖片上傳處理失敗",
+					zap.Error(err),
 					zap.String("request_id", requestID),
-					zap.String("image_type", getImageType(processedImage)),
-					zap.Int("image_length", len(processedImage)),
-					zap.String("description_hint", req.DescriptionHint),
+				)
+				c.JSON(uploadErrorStatus(err), gin.H{"error": uploadErrorMessage(err)})
+				return
+			}
+			images = upload.Images
+			req.DescriptionHint = upload.Fields["description_hint"]
+		} else {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				common.LogError("請求格式無效",
 					zap.Error(err),
+					zap.String("request_id", requestID),
 				)
-				c.JSON(http.StatusBadGateway, gin.H{"error": "AI did not return a valid response. Please check if the model supports image input or try a different image."})
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 				return
 			}
-			if len(errStr) > 0 && (containsIgnoreCase(errStr, "invalid image data format") || containsIgnoreCase(errStr, "image format")) {
-				common.LogError("圖片格式錯誤 (AI service)",
+
+			// 處理圖片
+			processedImage, err := imageService.FormatImageData(c.Request.Context(), req.Image)
+			if err != nil {
+				common.LogError("圖片處理失敗",
 					zap.Error(err),
 					zap.String("request_id", requestID),
-					zap.String("image_type", getImageType(processedImage)),
-					zap.Int("image_length", len(processedImage)),
+					zap.String("image_type", getImageType(req.Image)),
+					zap.Int("image_length", len(req.Image)),
 					zap.String("description_hint", req.DescriptionHint),
 				)
 				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image format"})
 				return
 			}
-			// 其他錯誤
-			common.LogError("食物辨識失敗",
-				zap.Error(err),
-				zap.String("request_id", requestID),
-				zap.String("image_type", getImageType(processedImage)),
-				zap.Int("image_length", len(processedImage)),
-				zap.String("description_hint", req.DescriptionHint),
-			)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Food recognition failed"})
-			return
+			images = []string{processedImage}
 		}
 
+		// 識別食物，多張圖片依上傳順序合併結果，每個食物標記來源圖片序號
 		response := FoodRecognitionResponse{
-			RecognizedFoods: make([]RecognizedFood, len(foods.RecognizedFoods)),
+			RecognizedFoods: []RecognizedFood{},
 		}
-
-		for i, food := range foods.RecognizedFoods {
-			possibleIngredients := make([]PossibleIngredient, len(food.PossibleIngredients))
-			for j, ing := range food.PossibleIngredients {
-				possibleIngredients[j] = convertToPossibleIngredient(ing)
-			}
-
-			possibleEquipment := make([]PossibleEquipment, len(food.PossibleEquipment))
-			for j, eq := range food.PossibleEquipment {
-				possibleEquipment[j] = convertToPossibleEquipment(eq)
+		for index, processedImage := range images {
+			result, err := foodService.IdentifyFood(c.Request.Context(), processedImage, req.DescriptionHint)
+			if err != nil {
+				// 圖片格式錯誤，回傳 400
+				errStr := err.Error()
+				if containsIgnoreCase(errStr, "no choices in OpenRouter response") {
+					common.LogError("AI 無回應 (no choices)",
+						zap.String("request_id", requestID),
+						zap.String("image_type", getImageType(processedImage)),
+						zap.Int("image_length", len(processedImage)),
+						zap.String("description_hint", req.DescriptionHint),
+						zap.Error(err),
+					)
+					c.JSON(http.StatusBadGateway, gin.H{"error": "AI did not return a valid response. Please check if the model supports image input or try a different image."})
+					return
+				}
+				if errors.Is(err, imagecore.ErrURLNotAllowed) || (len(errStr) > 0 && (containsIgnoreCase(errStr, "invalid image data format") || containsIgnoreCase(errStr, "image format"))) {
+					common.LogError("圖片格式錯誤 (AI service)",
+						zap.Error(err),
+						zap.String("request_id", requestID),
+						zap.String("image_type", getImageType(processedImage)),
+						zap.Int("image_length", len(processedImage)),
+						zap.String("description_hint", req.DescriptionHint),
+					)
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image format"})
+					return
+				}
+				// 其他錯誤
+				common.LogError("食物辨識失敗",
+					zap.Error(err),
+					zap.String("request_id", requestID),
+					zap.String("image_type", getImageType(processedImage)),
+					zap.Int("image_length", len(processedImage)),
+					zap.String("description_hint", req.DescriptionHint),
+				)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Food recognition failed"})
+				return
 			}
 
-			response.RecognizedFoods[i] = RecognizedFood{
-				Name:                food.Name,
-				Description:         food.Description,
-				PossibleIngredients: possibleIngredients,
-				PossibleEquipment:   possibleEquipment,
+			for _, food := range result.RecognizedFoods {
+				possibleIngredients := make([]PossibleIngredient, len(food.PossibleIngredients))
+				for j, ing := range food.PossibleIngredients {
+					possibleIngredients[j] = convertToPossibleIngredient(ing)
+				}
+
+				possibleEquipment := make([]PossibleEquipment, len(food.PossibleEquipment))
+				for j, eq := range food.PossibleEquipment {
+					possibleEquipment[j] = convertToPossibleEquipment(eq)
+				}
+
+				response.RecognizedFoods = append(response.RecognizedFoods, RecognizedFood{
+					Name:                food.Name,
+					Description:         food.Description,
+					Confidence:          food.Confidence,
+					BoundingBox:         food.BoundingBox,
+					Image:               index,
+					PossibleIngredients: possibleIngredients,
+					PossibleEquipment:   possibleEquipment,
+				})
 			}
 		}
 
 		common.LogInfo("食物辨識成功",
 			zap.String("request_id", requestID),
-			zap.Int("foods_count", len(foods.RecognizedFoods)),
+			zap.Int("foods_count", len(response.RecognizedFoods)),
 		)
 
 		c.JSON(http.StatusOK, response)
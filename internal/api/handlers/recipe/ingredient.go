@@ -2,10 +2,12 @@ package recipe
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
 	"recipe-generator/internal/core/ai/image"
+	imagecore "recipe-generator/internal/core/image"
 	"recipe-generator/internal/core/recipe"
 	"recipe-generator/internal/pkg/common"
 
@@ -35,84 +37,109 @@ func HandleIngredientRecognition(ingredientService *recipe.IngredientService, im
 			w.Header().Set("X-Request-ID", requestID)
 		}
 
-		// 解析請求
-		var req IngredientRecognitionRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			common.LogError("Invalid request format",
-				zap.Error(err),
-				zap.String("request_id", requestID))
-			common.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request format")
-			return
-		}
-
-		// 驗證圖片格式（加強）
-		if req.Image == "" || !strings.HasPrefix(req.Image, "data:image/") {
-			common.LogError("Invalid image format (handler)",
-				zap.String("request_id", requestID),
-				zap.String("image_type", getImageType(req.Image)),
-				zap.Int("image_length", len(req.Image)),
-			)
-			common.WriteErrorResponse(w, http.StatusBadRequest, "Invalid image format")
-			return
-		}
-
-		// 處理圖片
-		processedImage, err := imageService.FormatImageData(req.Image)
-		if err != nil {
-			common.LogError("Image processing failed",
-				zap.Error(err),
-				zap.String("request_id", requestID))
-			common.WriteErrorResponse(w, http.StatusInternalServerError, "Image processing failed")
-			return
-		}
+		// multipart 上傳可包含多張圖片，JSON 請求則為單張
+		var images []string
+		if isMultipart(r) {
+			upload, err := readImageUpload(r, imageService)
+			if err != nil {
+				common.LogError("Image upload failed",
+					zap.Error(err),
+					zap.String("request_id", requestID))
+				common.WriteErrorResponse(w, uploadErrorStatus(err), uploadErrorMessage(err))
+				return
+			}
+			images = upload.Images
+		} else {
+			// 解析請求
+			var req IngredientRecognitionRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				common.LogError("Invalid request format",
+					zap.Error(err),
+					zap.String("request_id", requestID))
+				common.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request format")
+				return
+			}
 
-		// 識別食材
-		result, err := ingredientService.IdentifyIngredient(r.Context(), processedImage)
-		if err != nil {
-			// 根據錯誤訊息內容判斷是否屬於用戶端錯誤
-			if strings.Contains(err.Error(), "image format") || strings.Contains(err.Error(), "base64") {
-				common.LogError("Invalid image format (service)",
+			// 處理圖片：與 /food 相同，接受 data URI、base64 與圖片網址
+			processedImage, err := imageService.FormatImageData(r.Context(), req.Image)
+			if errors.Is(err, imagecore.ErrURLNotAllowed) {
+				common.LogWarn("Image URL rejected",
 					zap.Error(err),
 					zap.String("request_id", requestID))
+				common.WriteErrorResponse(w, http.StatusBadRequest, "Image URL not allowed")
+				return
+			}
+			if err != nil {
+				common.LogError("Invalid image format (handler)",
+					zap.Error(err),
+					zap.String("request_id", requestID),
+					zap.String("image_type", getImageType(req.Image)),
+					zap.Int("image_length", len(req.Image)),
+				)
 				common.WriteErrorResponse(w, http.StatusBadRequest, "Invalid image format")
 				return
 			}
-			// 其他錯誤回傳 500
-			common.LogError("Failed to identify ingredients",
-				zap.Error(err),
-				zap.String("request_id", requestID))
-			common.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to identify ingredients")
-			return
+			images = []string{processedImage}
 		}
 
-		// 構建響應
+		// 識別食材，多張圖片依上傳順序合併結果，每個項目標記來源圖片序號
 		response := IngredientRecognitionResponse{
-			Ingredients: make([]Ingredient, len(result.Ingredients)),
-			Equipment:   make([]Equipment, len(result.Equipment)),
-			Summary:     result.Summary,
+			Ingredients: []Ingredient{},
+			Equipment:   []Equipment{},
 		}
+		var summaries []string
+		for index, processedImage := range images {
+			result, err := ingredientService.IdentifyIngredient(r.Context(), processedImage)
+			if err != nil {
+				// 根據錯誤訊息內容判斷是否屬於用戶端錯誤
+				if errors.Is(err, imagecore.ErrURLNotAllowed) || strings.Contains(err.Error(), "image format") || strings.Contains(err.Error(), "base64") {
+					common.LogError("Invalid image format (service)",
+						zap.Error(err),
+						zap.String("request_id", requestID))
+					common.WriteErrorResponse(w, http.StatusBadRequest, "Invalid image format")
+					return
+				}
+				// 其他錯誤回傳 500
+				common.LogError("Failed to identify ingredients",
+					zap.Error(err),
+					zap.String("request_id", requestID))
+				common.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to identify ingredients")
+				return
+			}
 
-		// 轉換食材信息
-		for i, ing := range result.Ingredients {
-			response.Ingredients[i] = Ingredient{
-				Name:        ing.Name,
-				Type:        ing.Type,
-				Amount:      ing.Amount,
-				Unit:        ing.Unit,
-				Preparation: ing.Preparation,
+			// 轉換食材信息
+			for _, ing := range result.Ingredients {
+				response.Ingredients = append(response.Ingredients, Ingredient{
+					Name:        ing.Name,
+					Type:        ing.Type,
+					Amount:      ing.Amount,
+					Unit:        ing.Unit,
+					Preparation: ing.Preparation,
+					Confidence:  ing.Confidence,
+					BoundingBox: ing.BoundingBox,
+					Image:       imageIndex(index),
+				})
 			}
-		}
 
-		// 轉換設備信息
-		for i, equip := range result.Equipment {
-			response.Equipment[i] = Equipment{
-				Name:        equip.Name,
-				Type:        equip.Type,
-				Size:        equip.Size,
-				Material:    equip.Material,
-				PowerSource: equip.PowerSource,
+			// 轉換設備信息
+			for _, equip := range result.Equipment {
+				response.Equipment = append(response.Equipment, Equipment{
+					Name:        equip.Name,
+					Type:        equip.Type,
+					Size:        equip.Size,
+					Material:    equip.Material,
+					PowerSource: equip.PowerSource,
+					Confidence:  equip.Confidence,
+					BoundingBox: equip.BoundingBox,
+					Image:       imageIndex(index),
+				})
+			}
+
+			if result.Summary != "" {
+				summaries = append(summaries, result.Summary)
 			}
 		}
+		response.Summary = strings.Join(summaries, "\n")
 
 		// 返回響應
 		w.Header().Set("Content-Type", "application/json")
@@ -127,7 +154,12 @@ func HandleIngredientRecognition(ingredientService *recipe.IngredientService, im
 		// 記錄成功
 		common.LogInfo("Successfully identified ingredients",
 			zap.String("request_id", requestID),
-			zap.Int("ingredients_count", len(result.Ingredients)),
-			zap.Int("equipment_count", len(result.Equipment)))
+			zap.Int("ingredients_count", len(response.Ingredients)),
+			zap.Int("equipment_count", len(response.Equipment)))
 	}
 }
+
+// imageIndex 回傳圖片序號的指標，供辨識結果標記來源圖片
+func imageIndex(index int) *int {
+	return &index
+}
@@ -0,0 +1,106 @@
+package recipe
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"recipe-generator/internal/core/ai/image"
+	imagecore "recipe-generator/internal/core/image"
+	recipeService "recipe-generator/internal/core/recipe"
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// InventoryRecognitionRequest 多張圖片食材盤點請求
+// images: base64 或 URL，依序對應回應中的圖片序號
+type InventoryRecognitionRequest struct {
+	Images []string `json:"images" binding:"required,min=1"`
+}
+
+// HandleInventoryRecognition 處理 /recipe/inventory 多張圖片食材盤點 API；
+// 回應的 available_ingredients 與 available_equipment 可直接放進 /recipe/suggest 的請求
+func HandleInventoryRecognition(inventoryService *recipeService.InventoryService, imageService *image.Processor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if requestID == "" {
+			requestID = uuid.New().String()
+			c.Header("X-Request-ID", requestID)
+		}
+
+		var images []string
+		if isMultipart(c.Request) {
+			upload, err := readImageUpload(c.Request, imageService)
+			if err != nil {
+				common.LogError("圖片上傳處理失敗",
+					zap.Error(err),
+					zap.String("request_id", requestID),
+				)
+				c.JSON(uploadErrorStatus(err), gin.H{"error": uploadErrorMessage(err)})
+				return
+			}
+			images = upload.Images
+		} else {
+			var req InventoryRecognitionRequest
+			if err := c.ShouldBindJSON(&req); err != nil {
+				common.LogError("請求格式無效",
+					zap.Error(err),
+					zap.String("request_id", requestID),
+				)
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+				return
+			}
+			if len(req.Images) > maxUploadImages {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Too many images (max %d)", maxUploadImages)})
+				return
+			}
+
+			images = make([]string, len(req.Images))
+			for i, raw := range req.Images {
+				processedImage, err := imageService.FormatImageData(c.Request.Context(), raw)
+				if err != nil {
+					common.LogError("圖片處理失敗",
+						zap.Error(err),
+						zap.String("request_id", requestID),
+						zap.Int("image", i),
+						zap.String("image_type", getImageType(raw)),
+						zap.Int("image_length", len(raw)),
+					)
+					c.JSON(http.StatusBadRequest, gin.H{"error": uploadErrorMessage(err), "image": i})
+					return
+				}
+				images[i] = processedImage
+			}
+		}
+
+		common.LogInfo("開始處理食材盤點請求",
+			zap.String("request_id", requestID),
+			zap.Int("images", len(images)),
+		)
+
+		inventory, err := inventoryService.IdentifyInventory(c.Request.Context(), images)
+		if err != nil {
+			common.LogError("食材盤點失敗",
+				zap.Error(err),
+				zap.String("request_id", requestID),
+			)
+			if errors.Is(err, imagecore.ErrURLNotAllowed) || containsIgnoreCase(err.Error(), "image format") {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image format"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to identify ingredients"})
+			return
+		}
+
+		common.LogInfo("食材盤點成功",
+			zap.String("request_id", requestID),
+			zap.Int("ingredients_count", len(inventory.AvailableIngredients)),
+			zap.Int("equipment_count", len(inventory.AvailableEquipment)),
+		)
+
+		c.JSON(http.StatusOK, inventory)
+	}
+}
@@ -0,0 +1,117 @@
+package recipe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"recipe-generator/internal/core/ai/cache"
+	"recipe-generator/internal/core/ai/image"
+	"recipe-generator/internal/core/jobs"
+	recipeService "recipe-generator/internal/core/recipe"
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// decodeJobPayload 解析任務 payload 並依 binding 標籤驗證，與同步端點的 ShouldBindJSON 行為一致
+func decodeJobPayload(payload json.RawMessage, out interface{}) error {
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("invalid request format: %w", err)
+	}
+	if err := binding.Validator.ValidateStruct(out); err != nil {
+		return fmt.Errorf("invalid request format: %w", err)
+	}
+	return nil
+}
+
+// validateJob 產生只做格式驗證的 Validate 函式
+func validateJob[T any]() func(json.RawMessage) error {
+	return func(payload json.RawMessage) error {
+		var req T
+		return decodeJobPayload(payload, &req)
+	}
+}
+
+// register 註冊任務執行器，並將任務寫入的快取條目標記為對應的任務端點
+func register(m *jobs.Manager, t jobs.Type, r jobs.Runner) {
+	run := r.Run
+	endpoint := "/api/v1/jobs/" + string(t)
+	r.Run = func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return run(cache.WithEndpoint(ctx, endpoint), payload)
+	}
+	m.Register(t, r)
+}
+
+// RegisterJobRunners 註冊食物、食材、食譜生成與推薦的非同步任務執行器
+func RegisterJobRunners(m *jobs.Manager, foodService *recipeService.FoodService, ingredientService *recipeService.IngredientService, recipeSvc *recipeService.RecipeService, suggestionService *recipeService.SuggestionService, imageService *image.Processor) {
+	register(m, jobs.TypeFood, jobs.Runner{
+		Validate: validateJob[FoodRecognitionRequest](),
+		Run: func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+			var req FoodRecognitionRequest
+			if err := decodeJobPayload(payload, &req); err != nil {
+				return nil, err
+			}
+			processedImage, err := imageService.FormatImageData(ctx, req.Image)
+			if err != nil {
+				return nil, fmt.Errorf("invalid image format: %w", err)
+			}
+			return foodService.IdentifyFood(ctx, processedImage, req.DescriptionHint)
+		},
+	})
+
+	register(m, jobs.TypeIngredient, jobs.Runner{
+		Validate: func(payload json.RawMessage) error {
+			var req IngredientRecognitionRequest
+			if err := decodeJobPayload(payload, &req); err != nil {
+				return err
+			}
+			if !strings.HasPrefix(req.Image, "data:image/") {
+				return fmt.Errorf("invalid image format")
+			}
+			return nil
+		},
+		Run: func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+			var req IngredientRecognitionRequest
+			if err := decodeJobPayload(payload, &req); err != nil {
+				return nil, err
+			}
+			processedImage, err := imageService.FormatImageData(ctx, req.Image)
+			if err != nil {
+				return nil, fmt.Errorf("image processing failed: %w", err)
+			}
+			return ingredientService.IdentifyIngredient(ctx, processedImage)
+		},
+	})
+
+	register(m, jobs.TypeGenerate, jobs.Runner{
+		Validate: validateJob[RecipeByNameRequest](),
+		Run: func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+			var req RecipeByNameRequest
+			if err := decodeJobPayload(payload, &req); err != nil {
+				return nil, err
+			}
+			ingredients, preferences := recipeByNameInput(&req)
+			recipe, err := recipeSvc.GenerateRecipe(ctx, req.DishName, ingredients, preferences)
+			if err != nil {
+				return nil, err
+			}
+			recipe.DishName = req.DishName
+			return recipe, nil
+		},
+	})
+
+	register(m, jobs.TypeSuggest, jobs.Runner{
+		Validate: validateJob[RecipeByIngredientsRequest](),
+		Run: func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+			var req RecipeByIngredientsRequest
+			if err := decodeJobPayload(payload, &req); err != nil {
+				return nil, err
+			}
+			return suggestionService.SuggestRecipes(ctx, suggestionInput(&req))
+		},
+	})
+
+	common.LogInfo("Recipe job runners registered")
+}
@@ -1,12 +1,16 @@
 package recipe
 
 import (
+	"context"
     "fmt"
 	"net/http"
 	"strings"
+	"recipe-generator/internal/core/ai/provider"
 	recipeAI "recipe-generator/internal/core/ai/service"
+	promptpkg "recipe-generator/internal/core/prompt"
 	recipeService "recipe-generator/internal/core/recipe"
 	"recipe-generator/internal/pkg/common"
+	"recipe-generator/internal/pkg/jsonschema"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -24,6 +28,7 @@ type RecipeByNameRequest struct {
 		Doneness      string `json:"doneness"`               // 希望的熟度（如：全熟、三分熟）
 		ServingSize   string `json:"serving_size,omitempty"` // 份量（例如：2人份，可省略）
 	} `json:"preference" binding:"required"`
+	Stream bool `json:"stream,omitempty"` // 是否以 SSE 串流回傳
 }
 
 // RecipeByNameResponse 詳細新手友善食譜
@@ -68,7 +73,13 @@ type CookQARequest struct {
 type CookQAResponse struct {
 	Answer     string    `json:"answer"`
 	KeyPoints  []string  `json:"key_points,omitempty"`
-	Confidence *float64  `json:"confidence,omitempty"`
+	Confidence *float64  `json:"confidence,omitempty" jsonschema:"minimum=0,maximum=1"`
+}
+
+// cookQAFormat 料理問答要求 AI 回傳的 JSON 結構
+var cookQAFormat = &provider.ResponseFormat{
+	Name:   "cook_qa",
+	Schema: jsonschema.For(CookQAResponse{}),
 }
 
 // RecipeByIngredientsRequest 使用食材與設備資訊推薦食譜
@@ -80,6 +91,7 @@ type RecipeByIngredientsRequest struct {
 		DietaryRestrictions []string `json:"dietary_restrictions,omitempty"` // 過敏原或禁忌
 		ServingSize         string   `json:"serving_size,omitempty"`         // 份量（可省略）
 	} `json:"preference" binding:"required"`
+	Stream bool `json:"stream,omitempty"` // 是否以 SSE 串流回傳
 }
 
 // Handler 食譜處理程序
@@ -87,14 +99,16 @@ type Handler struct {
 	recipeService     *recipeService.RecipeService
 	suggestionService *recipeService.SuggestionService
 	aiService         *recipeAI.Service
+	prompts           *promptpkg.Registry
 }
 
 // NewHandler 創建新的食譜處理程序
-func NewHandler(recipeService *recipeService.RecipeService, suggestionService *recipeService.SuggestionService, aiService *recipeAI.Service) *Handler {
+func NewHandler(recipeService *recipeService.RecipeService, suggestionService *recipeService.SuggestionService, aiService *recipeAI.Service, prompts *promptpkg.Registry) *Handler {
 	return &Handler{
 		recipeService:     recipeService,
 		suggestionService: suggestionService,
 		aiService:         aiService,
+		prompts:           prompts,
 	}
 }
 
@@ -121,22 +135,18 @@ func (h *Handler) HandleRecipeByName(c *gin.Context) {
 		return
 	}
 
-	preferences := common.RecipePreferences{
-		CookingMethod:       req.Preference.CookingMethod,
-		DietaryRestrictions: []string{req.Preference.Doneness},
-		ServingSize:         req.Preference.ServingSize,
-	}
-	if len(req.PreferredEquipment) > 0 {
-		equipmentNote := fmt.Sprintf("可用設備：%s", strings.Join(req.PreferredEquipment, "、"))
-		preferences.DietaryRestrictions = append(preferences.DietaryRestrictions, equipmentNote)
-	}
+	ingredients, preferences := recipeByNameInput(&req)
 
-	// 將 preferred_ingredients 轉換為 Ingredient 結構
-	var ingredients []common.Ingredient
-	for _, name := range req.PreferredIngredients {
-		ingredients = append(ingredients, common.Ingredient{
-			Name: name,
+	if wantsStream(c, req.Stream) {
+		streamRecipe(c, requestID, func(ctx context.Context, onDelta provider.DeltaFunc) (*common.Recipe, error) {
+			recipe, err := h.recipeService.GenerateRecipeStream(ctx, req.DishName, ingredients, preferences, onDelta)
+			if err != nil {
+				return nil, err
+			}
+			recipe.DishName = req.DishName
+			return recipe, nil
 		})
+		return
 	}
 
 	recipe, err := h.recipeService.GenerateRecipe(c.Request.Context(), req.DishName, ingredients, preferences)
@@ -246,35 +256,16 @@ func (h *Handler) HandleRecipeByIngredients(c *gin.Context) {
 	}
 	common.LogDebug("用戶輸入 (原始 req)", zap.String("request_id", requestID), zap.Any("req", req))
 
-	serviceReq := &common.RecipeByIngredientsRequest{
-		AvailableIngredients: make([]common.Ingredient, len(req.AvailableIngredients)),
-		AvailableEquipment:   make([]common.Equipment, len(req.AvailableEquipment)),
-		Preference: common.RecipePreferences{
-			CookingMethod:       req.Preference.CookingMethod,
-			DietaryRestrictions: req.Preference.DietaryRestrictions,
-			ServingSize:         req.Preference.ServingSize,
-		},
-	}
-	for i, ing := range req.AvailableIngredients {
-		serviceReq.AvailableIngredients[i] = common.Ingredient{
-			Name:        ing.Name,
-			Type:        ing.Type,
-			Amount:      ing.Amount,
-			Unit:        ing.Unit,
-			Preparation: ing.Preparation,
-		}
-	}
-	for i, equip := range req.AvailableEquipment {
-		serviceReq.AvailableEquipment[i] = common.Equipment{
-			Name:        equip.Name,
-			Type:        equip.Type,
-			Size:        equip.Size,
-			Material:    equip.Material,
-			PowerSource: equip.PowerSource,
-		}
-	}
+	serviceReq := suggestionInput(&req)
 	common.LogDebug("轉換後的 serviceReq", zap.String("request_id", requestID), zap.Any("serviceReq", serviceReq))
 
+	if wantsStream(c, req.Stream) {
+		streamRecipe(c, requestID, func(ctx context.Context, onDelta provider.DeltaFunc) (*common.Recipe, error) {
+			return h.suggestionService.SuggestRecipesStream(ctx, serviceReq, onDelta)
+		})
+		return
+	}
+
 	result, err := h.suggestionService.SuggestRecipes(c.Request.Context(), serviceReq)
 	if err != nil {
 		common.LogError("食譜推薦失敗",
@@ -402,9 +393,21 @@ func (h *Handler) HandleCookQA(c *gin.Context) {
 		return
 	}
 
-	prompt := buildCookQAPrompt(req.Question, req.CurrentStepDescription, recipeJSON)
+	prompt, err := h.prompts.Render(c.Request.Context(), promptpkg.CookQA, promptpkg.Vars{
+		"Question":    req.Question,
+		"CurrentStep": strings.TrimSpace(req.CurrentStepDescription),
+		"RecipeJSON":  recipeJSON,
+	})
+	if err != nil {
+		common.LogError("Cook QA 提示詞渲染失敗",
+			zap.Error(err),
+			zap.String("request_id", requestID),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build prompt"})
+		return
+	}
 
-	resp, err := h.aiService.ProcessRequest(c.Request.Context(), prompt, req.Image)
+	resp, err := h.aiService.ProcessStructured(c.Request.Context(), prompt, req.Image, cookQAFormat)
 	if err != nil {
 		common.LogError("Cook QA AI 服務失敗",
 			zap.Error(err),
@@ -448,41 +451,9 @@ func (h *Handler) HandleCookQA(c *gin.Context) {
 }
 
 
-func buildCookQAPrompt(question, currentStep, recipeJSON string) string {
-	var sb strings.Builder
-	sb.WriteString("你是一位專業的中式料理助理，請針對使用者的問題提供具體建議。\n")
-	sb.WriteString("請務必閱讀以下資訊並回應。\n")
-	sb.WriteString(fmt.Sprintf("使用者問題：%s\n", question))
-	if strings.TrimSpace(currentStep) != "" {
-		sb.WriteString(fmt.Sprintf("目前步驟狀態：%s\n", currentStep))
-	}
-	sb.WriteString("以下是完整的食譜 JSON：\n")
-	sb.WriteString(recipeJSON)
-	sb.WriteString("\n請僅回傳 JSON，格式如下：\n")
-	sb.WriteString("{\n")
-	sb.WriteString("  \"answer\": \"必填，提供明確建議\",\n")
-	sb.WriteString("  \"key_points\": [\"若需要，可補充重點\"],\n")
-	sb.WriteString("  \"confidence\": 0.0\n")
-	sb.WriteString("}\n")
-	sb.WriteString("說明：\n")
-	sb.WriteString("- 僅輸出單一 JSON 物件，不要包含其他文字或程式碼區塊標記。\n")
-	sb.WriteString("- answer 必須使用繁體中文，內容要可直接執行。\n")
-	sb.WriteString("- key_points 可省略或為空陣列。\n")
-	sb.WriteString("- confidence (0~1) 若不確定可傳 0.0。\n")
-	return sb.String()
-}
-
 func parseCookQAResponse(content string) (*CookQAResponse, error) {
-	text := strings.TrimSpace(content)
-	text = strings.TrimPrefix(text, "```json")
-	text = strings.TrimPrefix(text, "```")
-	text = strings.TrimSuffix(text, "```")
-	text = strings.TrimSpace(text)
-	if start, end := strings.Index(text, "{"), strings.LastIndex(text, "}"); start != -1 && end != -1 && end > start {
-		text = text[start : end+1]
-	}
 	var result CookQAResponse
-	if err := common.ParseJSON(text, &result); err != nil {
+	if err := common.ParseJSON(common.ExtractJSON(content), &result); err != nil {
 		return nil, err
 	}
 	return &result, nil
@@ -490,18 +461,79 @@ func parseCookQAResponse(content string) (*CookQAResponse, error) {
 
 // Ingredient 食材結構
 type Ingredient struct {
-	Name        string `json:"name"`
-	Type        string `json:"type,omitempty"`
-	Amount      string `json:"amount,omitempty"`
-	Unit        string `json:"unit,omitempty"`
-	Preparation string `json:"preparation,omitempty"`
+	Name        string              `json:"name"`
+	Type        string              `json:"type,omitempty"`
+	Amount      string              `json:"amount,omitempty"`
+	Unit        string              `json:"unit,omitempty"`
+	Preparation string              `json:"preparation,omitempty"`
+	Confidence  *float64            `json:"confidence,omitempty"`   // 圖片辨識的信心分數（0~1）
+	BoundingBox *common.BoundingBox `json:"bounding_box,omitempty"` // 圖片辨識的位置（正規化座標）
+	Image       *int                `json:"image,omitempty"`        // 圖片辨識的來源圖片序號，從 0 開始依上傳順序
 }
 
 // Equipment 設備結構
 type Equipment struct {
-	Name        string `json:"name"`
-	Type        string `json:"type"`
-	Size        string `json:"size,omitempty"`
-	Material    string `json:"material,omitempty"`
-	PowerSource string `json:"power_source,omitempty"`
+	Name        string              `json:"name"`
+	Type        string              `json:"type"`
+	Size        string              `json:"size,omitempty"`
+	Material    string              `json:"material,omitempty"`
+	PowerSource string              `json:"power_source,omitempty"`
+	Confidence  *float64            `json:"confidence,omitempty"`   // 圖片辨識的信心分數（0~1）
+	BoundingBox *common.BoundingBox `json:"bounding_box,omitempty"` // 圖片辨識的位置（正規化座標）
+	Image       *int                `json:"image,omitempty"`        // 圖片辨識的來源圖片序號，從 0 開始依上傳順序
+}
+
+// recipeByNameInput 將食譜生成請求轉換為食譜服務參數
+func recipeByNameInput(req *RecipeByNameRequest) ([]common.Ingredient, common.RecipePreferences) {
+	preferences := common.RecipePreferences{
+		CookingMethod:       req.Preference.CookingMethod,
+		DietaryRestrictions: []string{req.Preference.Doneness},
+		ServingSize:         req.Preference.ServingSize,
+	}
+	if len(req.PreferredEquipment) > 0 {
+		equipmentNote := fmt.Sprintf("可用設備：%s", strings.Join(req.PreferredEquipment, "、"))
+		preferences.DietaryRestrictions = append(preferences.DietaryRestrictions, equipmentNote)
+	}
+
+	// 將 preferred_ingredients 轉換為 Ingredient 結構
+	var ingredients []common.Ingredient
+	for _, name := range req.PreferredIngredients {
+		ingredients = append(ingredients, common.Ingredient{
+			Name: name,
+		})
+	}
+
+	return ingredients, preferences
+}
+
+// suggestionInput 將食譜推薦請求轉換為推薦服務參數
+func suggestionInput(req *RecipeByIngredientsRequest) *common.RecipeByIngredientsRequest {
+	serviceReq := &common.RecipeByIngredientsRequest{
+		AvailableIngredients: make([]common.Ingredient, len(req.AvailableIngredients)),
+		AvailableEquipment:   make([]common.Equipment, len(req.AvailableEquipment)),
+		Preference: common.RecipePreferences{
+			CookingMethod:       req.Preference.CookingMethod,
+			DietaryRestrictions: req.Preference.DietaryRestrictions,
+			ServingSize:         req.Preference.ServingSize,
+		},
+	}
+	for i, ing := range req.AvailableIngredients {
+		serviceReq.AvailableIngredients[i] = common.Ingredient{
+			Name:        ing.Name,
+			Type:        ing.Type,
+			Amount:      ing.Amount,
+			Unit:        ing.Unit,
+			Preparation: ing.Preparation,
+		}
+	}
+	for i, equip := range req.AvailableEquipment {
+		serviceReq.AvailableEquipment[i] = common.Equipment{
+			Name:        equip.Name,
+			Type:        equip.Type,
+			Size:        equip.Size,
+			Material:    equip.Material,
+			PowerSource: equip.PowerSource,
+		}
+	}
+	return serviceReq
 }
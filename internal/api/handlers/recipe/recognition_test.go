@@ -0,0 +1,272 @@
+package recipe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	aiimage "recipe-generator/internal/core/ai/image"
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/core/ai/service"
+	imagecore "recipe-generator/internal/core/image"
+	"recipe-generator/internal/core/prompt"
+	recipeService "recipe-generator/internal/core/recipe"
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	common.Logger = zap.NewNop()
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// scriptedProvider 依呼叫順序回傳預先準備的回應，並記錄每次請求附帶的圖片
+type scriptedProvider struct {
+	mu        sync.Mutex
+	responses []string
+	images    []string
+}
+
+func (p *scriptedProvider) Generate(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, msg := range req.Messages {
+		p.images = append(p.images, msg.Images...)
+	}
+	content := p.responses[0]
+	p.responses = p.responses[1:]
+	return &provider.Response{Content: content}, nil
+}
+
+func (p *scriptedProvider) GetModel() string          { return "scripted" }
+func (p *scriptedProvider) GetTimeout() time.Duration { return time.Minute }
+func (p *scriptedProvider) Close() error              { return nil }
+
+// newRecognitionServices 以腳本提供者建立食物與食材辨識服務，關閉快取、請求合併與限流
+func newRecognitionServices(t *testing.T, p provider.Provider) (*recipeService.FoodService, *recipeService.IngredientService, *aiimage.Processor) {
+	t.Helper()
+	cfg := &config.Config{
+		Image: config.ImageConfig{
+			MaxSizeBytes:   10 * 1024 * 1024,
+			MaxDimension:   1200,
+			TargetSize:     200 * 1024,
+			InitialQuality: 85,
+			MinQuality:     40,
+			FinalScale:     0.8,
+			FetchSchemes:   []string{"https", "http"},
+		},
+	}
+	prompts, err := prompt.NewRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ai := service.NewServiceWithProvider(cfg, nil, p)
+	images := aiimage.NewProcessor(imagecore.NewService(cfg.Image))
+	imageCache := recipeService.NewImageCache(cfg.Cache.Perceptual, nil, nil)
+	foods := recipeService.NewFoodService(ai, nil, imageCache, cfg.Recognition, prompts)
+	ingredients := recipeService.NewIngredientService(ai, nil, images, imageCache, cfg.Recognition, prompts)
+	return foods, ingredients, images
+}
+
+// twoImageUpload 建立含兩張不同顏色 JPEG 的 multipart 請求
+func twoImageUpload(t *testing.T, path string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, c := range []color.RGBA{{200, 30, 30, 255}, {30, 30, 200, 255}} {
+		img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+		for y := 0; y < 48; y++ {
+			for x := 0; x < 64; x++ {
+				img.Set(x, y, c)
+			}
+		}
+		part, err := w.CreateFormFile("images", "photo.jpg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := jpeg.Encode(part, img, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestIngredientRecognitionTagsImageIndex(t *testing.T) {
+	p := &scriptedProvider{responses: []string{
+		`{"ingredients":[{"name":"番茄","type":"蔬菜","amount":"2","unit":"顆","preparation":"","confidence":0.9,"bounding_box":{"x":0.1,"y":0.1,"width":0.2,"height":0.2}}],"equipment":[],"summary":"冰箱"}`,
+		`{"ingredients":[{"name":"雞蛋","type":"蛋類","amount":"6","unit":"顆","preparation":"","bounding_box":{"x":0.5,"y":0.5,"width":0.3,"height":0.3}}],"equipment":[{"name":"平底鍋","type":"鍋具","size":"","material":"","power_source":""}],"summary":"流理台"}`,
+	}}
+	_, ingredients, images := newRecognitionServices(t, p)
+
+	rec := httptest.NewRecorder()
+	HandleIngredientRecognition(ingredients, images)(rec, twoImageUpload(t, "/api/v1/recipe/ingredient"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(p.images) != 2 || p.images[0] == p.images[1] {
+		t.Fatalf("provider saw %d images", len(p.images))
+	}
+
+	var resp IngredientRecognitionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Ingredients) != 2 || len(resp.Equipment) != 1 {
+		t.Fatalf("response: %+v", resp)
+	}
+	for i, want := range []struct {
+		name  string
+		image int
+	}{{"番茄", 0}, {"雞蛋", 1}} {
+		ing := resp.Ingredients[i]
+		if ing.Name != want.name || ing.Image == nil || *ing.Image != want.image || ing.BoundingBox == nil {
+			t.Fatalf("ingredient %d: %+v", i, ing)
+		}
+	}
+	if equip := resp.Equipment[0]; equip.Image == nil || *equip.Image != 1 {
+		t.Fatalf("equipment: %+v", equip)
+	}
+	if resp.Summary != "冰箱\n流理台" {
+		t.Fatalf("summary: %q", resp.Summary)
+	}
+}
+
+func TestFoodRecognitionTagsImageIndex(t *testing.T) {
+	p := &scriptedProvider{responses: []string{
+		`{"recognized_foods":[{"name":"炒飯","description":"","bounding_box":{"x":0.1,"y":0.1,"width":0.5,"height":0.5},"possible_ingredients":[],"possible_equipment":[]},{"name":"湯","description":"","possible_ingredients":[],"possible_equipment":[]}]}`,
+		`{"recognized_foods":[{"name":"沙拉","description":"","bounding_box":{"x":0.2,"y":0.2,"width":0.4,"height":0.4},"possible_ingredients":[],"possible_equipment":[]}]}`,
+	}}
+	foods, _, images := newRecognitionServices(t, p)
+
+	router := gin.New()
+	router.POST("/api/v1/recipe/food", HandleFoodRecognition(foods, images))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, twoImageUpload(t, "/api/v1/recipe/food"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp FoodRecognitionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name  string
+		image int
+	}{{"炒飯", 0}, {"湯", 0}, {"沙拉", 1}}
+	if len(resp.RecognizedFoods) != len(want) {
+		t.Fatalf("response: %+v", resp)
+	}
+	for i, w := range want {
+		if food := resp.RecognizedFoods[i]; food.Name != w.name || food.Image != w.image {
+			t.Fatalf("food %d: %+v, want %s in image %d", i, food, w.name, w.image)
+		}
+	}
+}
+
+func TestIngredientRecognitionImageValidation(t *testing.T) {
+	_, ingredients, images := newRecognitionServices(t, &scriptedProvider{})
+	tests := []struct {
+		name  string
+		image string
+		want  string
+	}{
+		{"private url", "http://169.254.169.254/latest/meta-data/", "Image URL not allowed"},
+		{"loopback url", "http://[::ffff:127.0.0.1]/a.jpg", "Image URL not allowed"},
+		{"unsupported scheme", "ftp://example.com/a.jpg", "Invalid image format"},
+		{"not base64", "not an image", "Invalid image format"},
+		{"base64 text", "aGVsbG8gd29ybGQ=", "Invalid image format"},
+		{"empty", "", "Invalid image format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(IngredientRecognitionRequest{Image: tt.image})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/ingredient", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			HandleIngredientRecognition(ingredients, images)(rec, req)
+
+			var resp map[string]string
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			if rec.Code != http.StatusBadRequest || resp["error"] != tt.want {
+				t.Fatalf("status %d: %s, want 400 %q", rec.Code, rec.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadRejectsHEIC(t *testing.T) {
+	p := &scriptedProvider{}
+	foods, _, images := newRecognitionServices(t, p)
+	router := gin.New()
+	router.POST("/api/v1/recipe/food", HandleFoodRecognition(foods, images))
+
+	// ftyp box 開頭的 HEIC 檔案內容
+	heic := append([]byte{0, 0, 0, 24}, []byte("ftypheic\x00\x00\x00\x00mif1heic")...)
+	tests := []struct {
+		name        string
+		filename    string
+		contentType string
+		field       string // 以文字欄位傳送的 data URI，非空時不上傳檔案
+		data        []byte
+	}{
+		{"content type", "photo", "image/heic", "", []byte("not decoded")},
+		{"extension", "IMG_0001.HEIC", "application/octet-stream", "", []byte("not decoded")},
+		{"sniffed", "photo", "application/octet-stream", "", heic},
+		{"data uri", "", "", "data:image/heif;base64,AAAA", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			w := multipart.NewWriter(&body)
+			if tt.field != "" {
+				w.WriteField("image", tt.field)
+			} else {
+				h := make(textproto.MIMEHeader)
+				h.Set("Content-Disposition", `form-data; name="images"; filename="`+tt.filename+`"`)
+				h.Set("Content-Type", tt.contentType)
+				part, err := w.CreatePart(h)
+				if err != nil {
+					t.Fatal(err)
+				}
+				part.Write(tt.data)
+			}
+			w.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/food", &body)
+			req.Header.Set("Content-Type", w.FormDataContentType())
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			var resp map[string]string
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			if rec.Code != http.StatusUnsupportedMediaType || !strings.Contains(resp["error"], "HEIC") {
+				t.Fatalf("status %d: %s, want 415", rec.Code, rec.Body.String())
+			}
+		})
+	}
+	if len(p.images) != 0 {
+		t.Fatalf("provider called with %d images", len(p.images))
+	}
+}
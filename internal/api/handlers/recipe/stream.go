@@ -0,0 +1,81 @@
+package recipe
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SSE 事件名稱
+const (
+	eventDelta  = "delta"  // AI 增量輸出
+	eventRecipe = "recipe" // 後處理完成的食譜
+	eventError  = "error"  // 生成失敗
+)
+
+// wantsStream 判斷請求是否要求 SSE 串流：?stream=true、請求體 stream 欄位或 Accept: text/event-stream
+func wantsStream(c *gin.Context, bodyFlag bool) bool {
+	if v := c.Query("stream"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	if bodyFlag {
+		return true
+	}
+	return strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+}
+
+// streamRecipe 以 SSE 回傳食譜生成過程：先逐段轉發 AI 輸出，最後送出完整食譜
+func streamRecipe(c *gin.Context, requestID string, generate func(ctx context.Context, onDelta provider.DeltaFunc) (*common.Recipe, error)) {
+	// 串流時間可能超過伺服器寫入超時，改由請求 context 控制
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		common.LogDebug("無法取消寫入超時", zap.String("request_id", requestID), zap.Error(err))
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	deltas := 0
+	onDelta := func(delta string) error {
+		if err := c.Request.Context().Err(); err != nil {
+			return err
+		}
+		deltas++
+		c.SSEvent(eventDelta, gin.H{"content": delta})
+		c.Writer.Flush()
+		return nil
+	}
+
+	recipe, err := generate(c.Request.Context(), onDelta)
+	if err != nil {
+		common.LogError("串流食譜生成失敗",
+			zap.Error(err),
+			zap.String("request_id", requestID),
+			zap.Int("deltas", deltas),
+		)
+		c.SSEvent(eventError, gin.H{"error": "Recipe generation failed"})
+		c.Writer.Flush()
+		return
+	}
+
+	common.LogInfo("串流食譜生成成功",
+		zap.String("request_id", requestID),
+		zap.String("dish_name", recipe.DishName),
+		zap.Int("deltas", deltas),
+	)
+	c.SSEvent(eventRecipe, recipe)
+	c.Writer.Flush()
+}
@@ -0,0 +1,178 @@
+package recipe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"recipe-generator/internal/core/ai/image"
+	imagecore "recipe-generator/internal/core/image"
+)
+
+const (
+	// maxUploadImages 單一 multipart 請求可上傳的圖片數
+	maxUploadImages = 8
+	// maxUploadFieldBytes 一般文字欄位（例如 description_hint）的大小上限
+	maxUploadFieldBytes = 4 << 10
+	// uploadOverheadBytes multipart 邊界、標頭與文字欄位的餘裕
+	uploadOverheadBytes = 1 << 20
+)
+
+// UploadBodyLimit multipart 上傳路由的請求體上限：每張圖片以文字欄位傳送時的最大長度，加上其他部分的餘裕。
+// 各部分讀取時另有各自的上限，整體上限只限制單一請求的總傳輸量
+func UploadBodyLimit(maxImageBytes int64) int64 {
+	return maxUploadImages*imagecore.MaxEncodedSize(maxImageBytes) + uploadOverheadBytes
+}
+
+var (
+	// errNoUploadImage multipart 請求中沒有圖片
+	errNoUploadImage = errors.New("no image in upload")
+	// errTooManyUploadImages multipart 請求的圖片超過上限
+	errTooManyUploadImages = fmt.Errorf("too many images in upload (max %d)", maxUploadImages)
+	// errInvalidUpload multipart 格式錯誤或文字欄位過長
+	errInvalidUpload = errors.New("invalid multipart request")
+	// errUnsupportedImageType 無法解碼的圖片格式，例如 iPhone 預設的 HEIC
+	errUnsupportedImageType = errors.New("unsupported image type")
+)
+
+// heifBrands HEIC/HEIF 檔案 ftyp box 中的主要品牌
+var heifBrands = map[string]bool{
+	"heic": true, "heix": true, "hevc": true, "hevx": true,
+	"heim": true, "heis": true, "mif1": true, "msf1": true,
+}
+
+// isHEIF 依部分標頭的 Content-Type、副檔名或檔案開頭的 ftyp box 判斷是否為 HEIC/HEIF；
+// 部分瀏覽器上傳時只標示 application/octet-stream，因此也檢查檔案內容
+func isHEIF(part *multipart.Part, head []byte) bool {
+	mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+	if strings.HasPrefix(mediaType, "image/heic") || strings.HasPrefix(mediaType, "image/heif") {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(part.FileName())) {
+	case ".heic", ".heif":
+		return true
+	}
+	return len(head) >= 12 && bytes.Equal(head[4:8], []byte("ftyp")) && heifBrands[string(head[8:12])]
+}
+
+// imageUpload multipart 請求中的圖片與文字欄位
+type imageUpload struct {
+	Images []string          // 已前處理的 JPEG data URI，依上傳順序排列
+	Fields map[string]string // 其他文字欄位
+}
+
+// isMultipart 判斷請求是否為 multipart/form-data
+func isMultipart(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// readImageUpload 逐一讀取 multipart 的各個部分：有檔名的部分視為圖片檔案，直接串流交給圖片前處理；
+// 名為 image 或 images 的文字欄位可填 URL 或 data URI；其他欄位當作文字。
+// 不使用 ParseMultipartForm，整個請求不會先被讀進記憶體或暫存檔
+func readImageUpload(r *http.Request, imageService *image.Processor) (*imageUpload, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidUpload, err)
+	}
+
+	upload := &imageUpload{Fields: make(map[string]string)}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidUpload, err)
+		}
+
+		name := part.FormName()
+		switch {
+		case part.FileName() != "" || name == "image" || name == "images":
+			if len(upload.Images) >= maxUploadImages {
+				part.Close()
+				return nil, errTooManyUploadImages
+			}
+			processed, err := readUploadImage(r.Context(), part, imageService)
+			part.Close()
+			if err != nil {
+				return nil, fmt.Errorf("image %d: %w", len(upload.Images)+1, err)
+			}
+			upload.Images = append(upload.Images, processed)
+		default:
+			value, err := io.ReadAll(io.LimitReader(part, maxUploadFieldBytes+1))
+			part.Close()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", errInvalidUpload, err)
+			}
+			if len(value) > maxUploadFieldBytes {
+				return nil, fmt.Errorf("%w: field %q exceeds %d bytes", errInvalidUpload, name, maxUploadFieldBytes)
+			}
+			upload.Fields[name] = strings.TrimSpace(string(value))
+		}
+	}
+
+	if len(upload.Images) == 0 {
+		return nil, errNoUploadImage
+	}
+	return upload, nil
+}
+
+// readUploadImage 處理單一圖片部分；檔案直接串流前處理，文字欄位則視為 URL 或 data URI。
+// HEIC/HEIF 在讀取前即拒絕，不必等到解碼失敗
+func readUploadImage(ctx context.Context, part *multipart.Part, imageService *image.Processor) (string, error) {
+	if part.FileName() != "" {
+		r := bufio.NewReader(part)
+		head, _ := r.Peek(12)
+		if isHEIF(part, head) {
+			return "", errUnsupportedImageType
+		}
+		return imageService.FormatImageReader(r)
+	}
+	limit := imageService.MaxEncodedSize()
+	value, err := io.ReadAll(io.LimitReader(part, limit+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read image field: %w", err)
+	}
+	if int64(len(value)) > limit {
+		return "", fmt.Errorf("image field exceeds maximum length of %d bytes", limit)
+	}
+	if strings.HasPrefix(string(value), "data:image/heic") || strings.HasPrefix(string(value), "data:image/heif") {
+		return "", errUnsupportedImageType
+	}
+	return imageService.FormatImageData(ctx, string(value))
+}
+
+// uploadErrorStatus 上傳錯誤的 HTTP 狀態碼：不支援的圖片格式為 415，其他為 400
+func uploadErrorStatus(err error) int {
+	if errors.Is(err, errUnsupportedImageType) {
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusBadRequest
+}
+
+// uploadErrorMessage 將上傳錯誤轉成回應給客戶端的訊息
+func uploadErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, errNoUploadImage):
+		return "No image provided"
+	case errors.Is(err, errTooManyUploadImages):
+		return fmt.Sprintf("Too many images (max %d)", maxUploadImages)
+	case errors.Is(err, errInvalidUpload):
+		return "Invalid request format"
+	case errors.Is(err, errUnsupportedImageType):
+		return "Unsupported image type: HEIC/HEIF is not supported, upload JPEG, PNG, GIF or WebP"
+	case errors.Is(err, imagecore.ErrURLNotAllowed):
+		return "Image URL not allowed"
+	default:
+		return "Invalid image format"
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"recipe-generator/internal/pkg/common"
+)
+
+// AdminAuth 管理介面驗證中間件，要求 Authorization: Bearer <token>
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth := c.GetHeader("Authorization")
+		supplied, ok := strings.CutPrefix(auth, "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(supplied), []byte(token)) != 1 {
+			common.LogWarn("Admin authentication failed",
+				zap.String("client_ip", c.ClientIP()),
+				zap.String("path", c.Request.URL.Path),
+			)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Unauthorized",
+				"code":  "UNAUTHORIZED",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestAdminAuth(t *testing.T) {
+	common.Logger = zap.NewNop()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(AdminAuth("secret-token"))
+	router.GET("/admin/cache/keys", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer other-token", http.StatusUnauthorized},
+		{"token prefix", "Bearer secret", http.StatusUnauthorized},
+		{"no bearer scheme", "secret-token", http.StatusUnauthorized},
+		{"lowercase scheme", "bearer secret-token", http.StatusUnauthorized},
+		{"valid", "Bearer secret-token", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/admin/cache/keys", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Fatalf("%s: status %d, want %d", tt.name, w.Code, tt.want)
+		}
+		if tt.want == http.StatusUnauthorized && w.Body.String() != `{"code":"UNAUTHORIZED","error":"Unauthorized"}` {
+			t.Fatalf("%s: body %s", tt.name, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+
+	"recipe-generator/internal/core/ai/provider"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// HeaderAIProvider 實際回應的 AI 提供者
+	HeaderAIProvider = "X-AI-Provider"
+	// HeaderAIModel 實際使用的 AI 模型
+	HeaderAIModel = "X-AI-Model"
+	// HeaderPromptTemplate 產生回應的提示詞模板 ID 與版本
+	HeaderPromptTemplate = "X-Prompt-Template"
+	// HeaderExperiment 分配到的提示詞實驗與變體，例如 recipe-v2/treatment
+	HeaderExperiment = "X-Experiment"
+)
+
+// aiMetadataWriter 在寫出響應前補上 AI 提供者、提示詞模板與實驗變體標頭
+type aiMetadataWriter struct {
+	gin.ResponseWriter
+	metadata *provider.Metadata
+}
+
+// setHeaders 尚未寫出時設定標頭
+func (w *aiMetadataWriter) setHeaders() {
+	if w.Written() {
+		return
+	}
+	name, model := w.metadata.Get()
+	if name != "" {
+		w.Header().Set(HeaderAIProvider, name)
+	}
+	if model != "" {
+		w.Header().Set(HeaderAIModel, model)
+	}
+	if template := w.metadata.Template(); template != "" {
+		w.Header().Set(HeaderPromptTemplate, template)
+	}
+	if variant := w.metadata.Variant(); variant != "" {
+		w.Header().Set(HeaderExperiment, variant)
+	}
+}
+
+// WriteHeaderNow 實現 gin.ResponseWriter 介面
+func (w *aiMetadataWriter) WriteHeaderNow() {
+	w.setHeaders()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Write 實現 http.ResponseWriter 介面
+func (w *aiMetadataWriter) Write(data []byte) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString 實現 gin.ResponseWriter 介面
+func (w *aiMetadataWriter) WriteString(s string) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Flush 實現 http.Flusher 介面
+func (w *aiMetadataWriter) Flush() {
+	w.setHeaders()
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap 供 http.ResponseController 取得底層 ResponseWriter
+func (w *aiMetadataWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// AIMetadata 記錄請求實際使用的 AI 提供者、提示詞模板與實驗變體，並以響應標頭回傳
+func AIMetadata() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, md := provider.WithMetadata(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		c.Writer = &aiMetadataWriter{ResponseWriter: c.Writer, metadata: md}
+		c.Next()
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"mime"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -9,27 +10,39 @@ import (
 	"recipe-generator/internal/pkg/common"
 )
 
-// BodySizeLimit 限制請求體大小的中間件
-func BodySizeLimit(maxSize int64) gin.HandlerFunc {
+// BodySizeLimit 限制請求體大小的中間件；uploads 以路由路徑列出可上傳多張圖片的路由，
+// 這些路由收到 multipart 請求時改用各自的上限，其他請求仍使用 maxSize
+func BodySizeLimit(maxSize int64, uploads map[string]int64) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		limit := maxSize
+		if n, ok := uploads[c.FullPath()]; ok && isMultipart(c.GetHeader("Content-Type")) {
+			limit = n
+		}
+
 		// 檢查 Content-Length
-		if c.Request.ContentLength > maxSize {
+		if c.Request.ContentLength > limit {
 			common.LogError("Request body too large",
 				zap.Int64("content_length", c.Request.ContentLength),
-				zap.Int64("max_size", maxSize),
+				zap.Int64("max_size", limit),
 				zap.String("client_ip", c.ClientIP()),
 				zap.String("path", c.Request.URL.Path),
 			)
 			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
 				"error":    "Request body too large",
-				"max_size": maxSize,
+				"max_size": limit,
 			})
 			return
 		}
 
 		// 設置請求體大小限制
-		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
 
 		c.Next()
 	}
 }
+
+// isMultipart 判斷 Content-Type 是否為 multipart/form-data
+func isMultipart(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "multipart/form-data"
+}
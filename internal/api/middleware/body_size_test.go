@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestBodySizeLimitUploadRoutes(t *testing.T) {
+	common.Logger = zap.NewNop()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(BodySizeLimit(10, map[string]int64{"/upload": 100}))
+	read := func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+	router.POST("/upload", read)
+	router.POST("/other", read)
+
+	tests := []struct {
+		path        string
+		contentType string
+		size        int
+		want        int
+	}{
+		{"/upload", "multipart/form-data; boundary=x", 50, http.StatusOK},
+		{"/upload", "multipart/form-data; boundary=x", 101, http.StatusRequestEntityTooLarge},
+		// 上傳路由的 JSON 請求仍使用一般上限
+		{"/upload", "application/json", 50, http.StatusRequestEntityTooLarge},
+		{"/other", "multipart/form-data; boundary=x", 50, http.StatusRequestEntityTooLarge},
+		{"/other", "application/json", 10, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(strings.Repeat("x", tt.size)))
+		req.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s %s %d bytes: status %d, want %d", tt.path, tt.contentType, tt.size, w.Code, tt.want)
+		}
+	}
+}
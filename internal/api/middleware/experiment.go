@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
+
+	"recipe-generator/internal/core/experiment"
+
+	"github.com/gin-contrib/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderClientID 用戶端 ID，提供時同一用戶端固定分配到相同的實驗變體
+const HeaderClientID = "X-Client-ID"
+
+// Experiments 決定請求的實驗分配單位：優先使用 X-Client-ID，否則以請求路徑與 JSON 內容的雜湊，
+// 相同的請求會分配到相同的變體；其他內容（例如 multipart 上傳）以路徑與用戶端 IP 分配，不讀取請求內容。
+// 沒有進行中的實驗時不讀取請求內容
+func Experiments(m *experiment.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.Enabled() {
+			c.Next()
+			return
+		}
+
+		unit := c.GetHeader(HeaderClientID)
+		if unit == "" {
+			unit = requestHash(c)
+		}
+		ctx := m.Begin(c.Request.Context(), unit, requestid.Get(c))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		m.End(ctx)
+	}
+}
+
+// requestHash 計算請求路徑與 JSON 內容的雜湊，讀取後放回請求內容供處理程序照常讀取；
+// 讀取失敗（例如超過大小限制）時處理程序會收到相同的錯誤。
+// 非 JSON 內容不讀取：multipart 上傳需維持串流處理，且每次請求的 boundary 都不同，無法穩定分配
+func requestHash(c *gin.Context) string {
+	h := sha256.New()
+	h.Write([]byte(c.Request.URL.Path))
+	if !isJSON(c.GetHeader("Content-Type")) {
+		h.Write([]byte{0})
+		h.Write([]byte(c.ClientIP()))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+	if c.Request.Body != nil {
+		data, err := io.ReadAll(c.Request.Body)
+		h.Write(data)
+		rest := io.Reader(bytes.NewReader(data))
+		if err != nil {
+			rest = io.MultiReader(rest, errReader{err})
+		}
+		c.Request.Body = readCloser{Reader: rest, Closer: c.Request.Body}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isJSON 判斷 Content-Type 是否為 JSON
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// errReader 每次讀取都回傳相同錯誤
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+// readCloser 以新的 Reader 取代請求內容，關閉時仍關閉原本的請求內容
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
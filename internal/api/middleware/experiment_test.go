@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"recipe-generator/internal/core/experiment"
+	"recipe-generator/internal/core/prompt"
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/gin-contrib/requestid"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// countingReader 記錄是否被讀取
+type countingReader struct {
+	io.Reader
+	reads int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.reads++
+	return r.Reader.Read(p)
+}
+
+// hashOf 以指定內容建立請求並計算實驗分配用的雜湊
+func hashOf(t *testing.T, path, contentType string, body io.Reader) (string, *gin.Context) {
+	t.Helper()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, path, body)
+	c.Request.Header.Set("Content-Type", contentType)
+	return requestHash(c), c
+}
+
+func TestRequestHashJSON(t *testing.T) {
+	const body = `{"dish_name":"番茄炒蛋"}`
+	a, c := hashOf(t, "/api/v1/recipe/generate", "application/json; charset=utf-8", strings.NewReader(body))
+
+	// 雜湊後請求內容須原樣放回
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil || string(data) != body {
+		t.Fatalf("body after hashing: %q, %v", data, err)
+	}
+
+	if b, _ := hashOf(t, "/api/v1/recipe/generate", "application/json", strings.NewReader(body)); b != a {
+		t.Fatal("same request hashed differently")
+	}
+	if b, _ := hashOf(t, "/api/v1/recipe/generate", "application/json", strings.NewReader(`{"dish_name":"蛋花湯"}`)); b == a {
+		t.Fatal("different body hashed the same")
+	}
+	if b, _ := hashOf(t, "/api/v1/recipe/suggest", "application/json", strings.NewReader(body)); b == a {
+		t.Fatal("different path hashed the same")
+	}
+}
+
+func TestRequestHashSkipsMultipart(t *testing.T) {
+	body := &countingReader{Reader: strings.NewReader("--x\r\nContent-Disposition: form-data; name=\"images\"\r\n\r\n...")}
+	a, c := hashOf(t, "/api/v1/recipe/ingredient", "multipart/form-data; boundary=x", body)
+	if body.reads != 0 {
+		t.Fatal("multipart body read while hashing")
+	}
+	if c.Request.Body == nil {
+		t.Fatal("multipart body replaced")
+	}
+
+	// 不同 boundary 與內容的上傳，同一用戶端仍分配到相同單位
+	other := strings.NewReader("--y\r\n...")
+	if b, _ := hashOf(t, "/api/v1/recipe/ingredient", "multipart/form-data; boundary=y", other); b != a {
+		t.Fatal("multipart upload hashed by content")
+	}
+}
+
+func TestExperimentsMiddleware(t *testing.T) {
+	common.Logger = zap.NewNop()
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "exp.tmpl"), []byte("id: exp\nversion: 1\n---\n第一版"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	experiments := filepath.Join(dir, "experiments.json")
+	if err := os.WriteFile(experiments, []byte(`{"experiments":[{"id":"e","template":"exp","variants":[{"name":"a","weight":1},{"name":"b","weight":1}]}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	prompts, err := prompt.NewRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := experiment.NewManager(experiments, prompts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(requestid.New(), Experiments(m))
+	router.POST("/generate", func(c *gin.Context) {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		sel := m.Select(c.Request.Context(), "exp")
+		if sel == nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, sel.Variant+"|"+string(data))
+	})
+
+	send := func(id, clientID, body string) string {
+		req := httptest.NewRequest(http.MethodPost, "/generate", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Request-ID", id)
+		if clientID != "" {
+			req.Header.Set(HeaderClientID, clientID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d", w.Code)
+		}
+		return w.Body.String()
+	}
+
+	first := send("req-1", "", `{"n":1}`)
+	if !strings.HasSuffix(first, `|{"n":1}`) {
+		t.Fatalf("handler response: %q", first)
+	}
+	if again := send("req-2", "", `{"n":1}`); again != first {
+		t.Fatalf("same request assigned %q then %q", first, again)
+	}
+	// 用戶端 ID 優先於請求內容
+	byClient := send("req-3", "client-1", `{"n":2}`)
+	for i := 0; i < 5; i++ {
+		if got := send("req-x", "client-1", `{"n":3}`); strings.Split(got, "|")[0] != strings.Split(byClient, "|")[0] {
+			t.Fatal("client ID not sticky across bodies")
+		}
+	}
+
+	// 請求結束後可依請求 ID 評分
+	if err := m.Rate("req-1", 5); err != nil {
+		t.Fatalf("Rate: %v", err)
+	}
+	if err := m.Rate("req-1", 5); err == nil {
+		t.Fatal("request rated twice")
+	}
+}
+
+func TestExperimentsMiddlewareDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m, _ := experiment.NewManager("", nil)
+
+	body := &countingReader{Reader: strings.NewReader(`{}`)}
+	router := gin.New()
+	router.Use(Experiments(m))
+	router.POST("/generate", func(c *gin.Context) {
+		if m.Select(c.Request.Context(), "exp") != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/generate", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || body.reads != 0 {
+		t.Fatalf("status %d, reads %d", w.Code, body.reads)
+	}
+}
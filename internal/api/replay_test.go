@@ -0,0 +1,202 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"recipe-generator/internal/core/ai/cache"
+	"recipe-generator/internal/core/ai/queue"
+	"recipe-generator/internal/core/jobs"
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 端到端測試以 testdata/fixtures 重播 OpenRouter 回應，不連線外部服務。
+// 重新錄製：
+//
+//	AI_FIXTURES_MODE=record APP_OPENROUTER_API_KEY=... go test ./internal/api -run TestEndpointsReplay
+//
+// 錄製前先刪除舊的 fixture，提示詞或請求內容變更後舊檔不會再被使用
+func newReplayRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	fixtures, err := filepath.Abs(filepath.Join("testdata", "fixtures"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// LoadConfig 需要工作目錄下的 .env，日誌也寫到工作目錄，改在暫存目錄執行
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if os.Getenv("AI_FIXTURES_MODE") != "record" {
+		t.Setenv("AI_FIXTURES_MODE", "replay")
+		t.Setenv("APP_OPENROUTER_API_KEY", "test-key")
+	}
+	t.Setenv("AI_FIXTURES_DIR", fixtures)
+	t.Setenv("AI_PROVIDER", "openrouter")
+	t.Setenv("AI_FALLBACKS", "")
+	t.Setenv("APP_OPENROUTER_MODEL", "google/gemini-2.0-flash-001")
+	t.Setenv("APP_OPENROUTER_MAX_RETRIES", "0")
+	t.Setenv("CACHE_ENABLED", "false")
+	t.Setenv("RATE_LIMIT_ENABLED", "false")
+	t.Setenv("JOBS_STORE", "memory")
+	t.Setenv("PROMPTS_DIR", "")
+	t.Setenv("EXPERIMENTS_FILE", "")
+	t.Setenv("LOG_LEVEL", "error")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if err := common.InitLogger(cfg.LogLevel); err != nil {
+		t.Fatalf("init logger: %v", err)
+	}
+
+	cacheManager, err := cache.New(cfg)
+	if err != nil {
+		t.Fatalf("init cache: %v", err)
+	}
+	jobStore, err := jobs.NewStore(cfg.Jobs)
+	if err != nil {
+		t.Fatalf("init job store: %v", err)
+	}
+	jobManager := jobs.NewManager(cfg, queue.NewManager(cfg), jobStore)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		jobManager.Close(ctx)
+	})
+
+	gin.SetMode(gin.TestMode)
+	router, err := SetupRouter(cfg, cacheManager, jobManager)
+	if err != nil {
+		t.Fatalf("setup router: %v", err)
+	}
+	return router
+}
+
+// testImage 測試圖片的 data URI；尺寸與大小都在前處理上限內，會原樣送出，fixture 鍵不受 JPEG 編碼器影響
+func testImage(t *testing.T) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "dish.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// postJSON 送出 JSON 請求並解析響應
+func postJSON(t *testing.T, router http.Handler, path string, body interface{}) map[string]interface{} {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST %s: status %d, body %s", path, rec.Code, rec.Body.String())
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("POST %s: invalid JSON response: %v", path, err)
+	}
+	return out
+}
+
+// requireNonEmpty 檢查響應欄位存在且不為空
+func requireNonEmpty(t *testing.T, resp map[string]interface{}, field string) {
+	t.Helper()
+	switch v := resp[field].(type) {
+	case string:
+		if v != "" {
+			return
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			return
+		}
+	}
+	t.Fatalf("response field %q is missing or empty: %v", field, resp)
+}
+
+func TestEndpointsReplay(t *testing.T) {
+	image := testImage(t)
+	router := newReplayRouter(t)
+
+	recipe := map[string]interface{}{
+		"dish_name":             "番茄炒蛋",
+		"preferred_ingredients": []string{},
+		"excluded_ingredients":  []string{},
+		"preferred_equipment":   []string{},
+		"preference": map[string]interface{}{
+			"cooking_method":       "炒",
+			"dietary_restrictions": []string{},
+			"serving_size":         "2人份",
+		},
+	}
+
+	t.Run("food", func(t *testing.T) {
+		resp := postJSON(t, router, "/api/v1/recipe/food", map[string]interface{}{"image": image})
+		requireNonEmpty(t, resp, "recognized_foods")
+	})
+
+	t.Run("ingredient", func(t *testing.T) {
+		resp := postJSON(t, router, "/api/v1/recipe/ingredient", map[string]interface{}{"image": image})
+		requireNonEmpty(t, resp, "ingredients")
+		requireNonEmpty(t, resp, "equipment")
+	})
+
+	t.Run("generate", func(t *testing.T) {
+		resp := postJSON(t, router, "/api/v1/recipe/generate", recipe)
+		requireNonEmpty(t, resp, "dish_name")
+		requireNonEmpty(t, resp, "recipe")
+	})
+
+	t.Run("suggest", func(t *testing.T) {
+		resp := postJSON(t, router, "/api/v1/recipe/suggest", map[string]interface{}{
+			"available_ingredients": []map[string]string{
+				{"name": "番茄", "type": "蔬菜", "amount": "2", "unit": "顆"},
+				{"name": "雞蛋", "type": "蛋類", "amount": "3", "unit": "顆"},
+			},
+			"available_equipment": []map[string]string{
+				{"name": "平底鍋", "type": "鍋具"},
+			},
+			"preference": map[string]string{"cooking_method": "炒"},
+		})
+		requireNonEmpty(t, resp, "dish_name")
+		requireNonEmpty(t, resp, "recipe")
+	})
+
+	t.Run("cook_qa", func(t *testing.T) {
+		resp := postJSON(t, router, "/api/v1/cook/qa", map[string]interface{}{
+			"question": "番茄出水怎麼辦",
+			"recipe":   map[string]interface{}{"dish_name": "番茄炒蛋", "ingredients": []string{}, "equipment": []string{}, "recipe": []string{}},
+		})
+		requireNonEmpty(t, resp, "answer")
+	})
+}
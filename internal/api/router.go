@@ -4,12 +4,19 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	adminHandler "recipe-generator/internal/api/handlers/admin"
+	feedbackHandler "recipe-generator/internal/api/handlers/feedback"
 	"recipe-generator/internal/api/handlers/health"
+	jobHandler "recipe-generator/internal/api/handlers/jobs"
 	recipeHandler "recipe-generator/internal/api/handlers/recipe"
 	"recipe-generator/internal/api/middleware"
 	"recipe-generator/internal/core/ai/cache"
 	"recipe-generator/internal/core/ai/image"
 	"recipe-generator/internal/core/ai/service"
+	"recipe-generator/internal/core/experiment"
+	imagecore "recipe-generator/internal/core/image"
+	"recipe-generator/internal/core/jobs"
+	"recipe-generator/internal/core/prompt"
 	recipeService "recipe-generator/internal/core/recipe"
 	"recipe-generator/internal/infrastructure/config"
 	"recipe-generator/internal/pkg/common"
@@ -29,7 +36,7 @@ const (
 )
 
 // SetupRouter 設置路由
-func SetupRouter(cfg *config.Config, cacheManager *cache.CacheManager) (*gin.Engine, error) {
+func SetupRouter(cfg *config.Config, cacheManager cache.Cache, jobManager *jobs.Manager) (*gin.Engine, error) {
 	common.LogInfo("Starting router setup",
 		zap.Bool("debug_mode", cfg.App.Debug),
 		zap.String("version", cfg.App.Version),
@@ -53,18 +60,27 @@ func SetupRouter(cfg *config.Config, cacheManager *cache.CacheManager) (*gin.Eng
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID"},
-		ExposeHeaders:    []string{"Content-Length", "X-Request-ID"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID", middleware.HeaderClientID},
+		ExposeHeaders:    []string{"Content-Length", "X-Request-ID", middleware.HeaderAIProvider, middleware.HeaderAIModel, middleware.HeaderPromptTemplate, middleware.HeaderExperiment},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
-	// 請求體大小限制
-	router.Use(middleware.BodySizeLimit(maxBodySize))
+	// 請求體大小限制；圖片上傳路由的 multipart 請求依可上傳的圖片數放寬
+	uploadLimit := recipeHandler.UploadBodyLimit(cfg.Image.MaxSizeBytes)
+	router.Use(middleware.BodySizeLimit(maxBodySize, map[string]int64{
+		"/api/v1/recipe/food":       uploadLimit,
+		"/api/v1/recipe/ingredient": uploadLimit,
+		"/api/v1/recipe/inventory":  uploadLimit,
+	}))
+
+	// 記錄實際使用的 AI 提供者與提示詞模板
+	router.Use(middleware.AIMetadata())
 
 	common.LogInfo("Initializing services",
 		zap.Bool("cache_enabled", cfg.Cache.Enabled),
 		zap.Int("queue_workers", cfg.Queue.Workers),
+		zap.String("provider", cfg.AI.Provider),
 		zap.String("model", cfg.OpenRouter.Model),
 		zap.Duration("timeout", timeoutDuration),
 	)
@@ -76,24 +92,47 @@ func SetupRouter(cfg *config.Config, cacheManager *cache.CacheManager) (*gin.Eng
 		return nil, fmt.Errorf("failed to initialize AI service: %w", err)
 	}
 
-	// 初始化圖片服務
-	imageService := image.NewProcessor(1200) // 最大尺寸 1200px
+	// 載入提示詞模板，覆寫目錄中的模板無效時拒絕啟動
+	prompts, err := prompt.NewRegistry(cfg.Prompts.Dir)
+	if err != nil {
+		common.LogError("Failed to load prompt templates", zap.Error(err), zap.String("dir", cfg.Prompts.Dir))
+		return nil, fmt.Errorf("failed to load prompt templates: %w", err)
+	}
+
+	// 載入提示詞實驗，請求依用戶端 ID 或請求雜湊固定分配變體
+	experiments, err := experiment.NewManager(cfg.Experiments.File, prompts)
+	if err != nil {
+		common.LogError("Failed to load experiments", zap.Error(err), zap.String("file", cfg.Experiments.File))
+		return nil, fmt.Errorf("failed to load experiments: %w", err)
+	}
+	prompts.SetSelector(experiments)
+	router.Use(middleware.Experiments(experiments))
+
+	// 初始化圖片服務，前處理參數由設定決定
+	images := imagecore.NewService(cfg.Image)
+	imageService := image.NewProcessor(images)
 	if imageService == nil {
 		common.LogError("Failed to initialize image service")
 		return nil, fmt.Errorf("failed to initialize image service")
 	}
 
+	// 以感知雜湊快取圖片辨識結果，食物與食材辨識共用
+	imageCache := recipeService.NewImageCache(cfg.Cache.Perceptual, cacheManager, images)
+
 	// 初始化食材識別服務
-	ingredientSvc := recipeService.NewIngredientService(aiService, cacheManager, imageService)
+	ingredientSvc := recipeService.NewIngredientService(aiService, cacheManager, imageService, imageCache, cfg.Recognition, prompts)
 	if ingredientSvc == nil {
 		common.LogError("Failed to initialize ingredient service")
 		return nil, fmt.Errorf("failed to initialize ingredient service")
 	}
 
+	// 多張圖片食材盤點，逐張辨識沿用食材識別服務
+	inventorySvc := recipeService.NewInventoryService(ingredientSvc, cfg.Inventory)
+
 	// 初始化食譜服務
-	foodSvc := recipeService.NewFoodService(aiService, cacheManager)
-	recipeSvc := recipeService.NewRecipeService(aiService, cacheManager)
-	suggestionSvc := recipeService.NewSuggestionService(aiService, cacheManager)
+	foodSvc := recipeService.NewFoodService(aiService, cacheManager, imageCache, cfg.Recognition, prompts)
+	recipeSvc := recipeService.NewRecipeService(aiService, cacheManager, cfg.Cache.Semantic, prompts)
+	suggestionSvc := recipeService.NewSuggestionService(aiService, cacheManager, prompts)
 
 	if foodSvc == nil || recipeSvc == nil || suggestionSvc == nil {
 		common.LogError("Failed to initialize recipe services: service returned nil",
@@ -110,14 +149,18 @@ func SetupRouter(cfg *config.Config, cacheManager *cache.CacheManager) (*gin.Eng
 		zap.String("environment", cfg.App.Env),
 	)
 
+	// 註冊非同步任務執行器並啟動工作者池
+	recipeHandler.RegisterJobRunners(jobManager, foodSvc, ingredientSvc, recipeSvc, suggestionSvc, imageService)
+	jobManager.Start()
+
 	// 全局中間件：設置超時和服務
 	router.Use(func(c *gin.Context) {
 		// 設置請求超時
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeoutDuration)
 		defer cancel()
 
-		// 創建新的請求上下文
-		req := c.Request.WithContext(ctx)
+		// 創建新的請求上下文，並標記寫入快取的端點
+		req := c.Request.WithContext(cache.WithEndpoint(ctx, c.FullPath()))
 		c.Request = req
 
 		// 設置配置
@@ -139,6 +182,7 @@ func SetupRouter(cfg *config.Config, cacheManager *cache.CacheManager) (*gin.Eng
 		c.Set("ingredient_service", ingredientSvc)
 		c.Set("recipe_service", recipeSvc)
 		c.Set("suggestion_service", suggestionSvc)
+		c.Set("job_manager", jobManager)
 		common.LogDebug("Recipe services injected into context",
 			zap.String("path", c.Request.URL.Path),
 			zap.String("request_id", c.GetHeader("X-Request-ID")),
@@ -149,20 +193,7 @@ func SetupRouter(cfg *config.Config, cacheManager *cache.CacheManager) (*gin.Eng
 
 		// 檢查是否超時
 		if ctx.Err() == context.DeadlineExceeded {
-			common.LogError("Request timeout",
-				zap.String("path", c.Request.URL.Path),
-				zap.String("request_id", c.GetHeader("X-Request-ID")),
-				zap.Duration("timeout", timeoutDuration),
-			)
-			c.JSON(http.StatusGatewayTimeout, gin.H{
-				"error": "Request timeout",
-				"code":  "REQUEST_TIMEOUT",
-				"details": gin.H{
-					"timeout": timeoutDuration.String(),
-				},
-			})
-			c.Abort()
-			return
+			respondTimeout(c, timeoutDuration)
 		}
 	})
 
@@ -174,7 +205,7 @@ func SetupRouter(cfg *config.Config, cacheManager *cache.CacheManager) (*gin.Eng
 	// API 路由組
 	api := router.Group("/api/v1")
 	{
-		recipeHandlerInstance := recipeHandler.NewHandler(recipeSvc, suggestionSvc, aiService)
+		recipeHandlerInstance := recipeHandler.NewHandler(recipeSvc, suggestionSvc, aiService, prompts)
 
 		// 註冊食譜相關路由
 		recipeGroup := api.Group("/recipe")
@@ -187,6 +218,9 @@ func SetupRouter(cfg *config.Config, cacheManager *cache.CacheManager) (*gin.Eng
 				recipeHandler.HandleIngredientRecognition(ingredientSvc, imageService)(c.Writer, c.Request)
 			})
 
+			// 多張圖片食材盤點，合併後的清單可直接用於推薦食譜
+			recipeGroup.POST("/inventory", recipeHandler.HandleInventoryRecognition(inventorySvc, imageService))
+
 			// 使用食材名稱生成食譜
 			recipeGroup.POST("/generate", recipeHandlerInstance.HandleRecipeByName)
 
@@ -198,6 +232,46 @@ func SetupRouter(cfg *config.Config, cacheManager *cache.CacheManager) (*gin.Eng
 		{
 			cookGroup.POST("/qa", recipeHandlerInstance.HandleCookQA)
 		}
+
+		// 使用者評分，計入請求分配到的實驗變體
+		api.POST("/feedback", feedbackHandler.NewHandler(experiments).Rate)
+
+		// 非同步任務：立即回傳任務 ID，稍後查詢結果
+		jobHandlerInstance := jobHandler.NewHandler(jobManager)
+		jobGroup := api.Group("/jobs")
+		{
+			jobGroup.POST("/food", jobHandlerInstance.Submit(jobs.TypeFood))
+			jobGroup.POST("/ingredient", jobHandlerInstance.Submit(jobs.TypeIngredient))
+			jobGroup.POST("/generate", jobHandlerInstance.Submit(jobs.TypeGenerate))
+			jobGroup.POST("/suggest", jobHandlerInstance.Submit(jobs.TypeSuggest))
+			jobGroup.GET("/:id", jobHandlerInstance.Get)
+			jobGroup.DELETE("/:id", jobHandlerInstance.Cancel)
+		}
+
+		// 管理介面：未設定 ADMIN_TOKEN 時不註冊
+		if cfg.Admin.Token != "" {
+			adminGroup := api.Group("/admin", middleware.AdminAuth(cfg.Admin.Token))
+			cacheAdmin := adminHandler.NewCacheHandler(cacheManager, aiService, recipeSvc, imageCache)
+			{
+				adminGroup.GET("/cache/stats", cacheAdmin.Stats)
+				adminGroup.GET("/cache/keys", cacheAdmin.Keys)
+				adminGroup.DELETE("/cache", cacheAdmin.Purge)
+				adminGroup.POST("/cache/delete", cacheAdmin.DeleteEntry)
+			}
+
+			promptsAdmin := adminHandler.NewPromptsHandler(prompts)
+			{
+				adminGroup.GET("/prompts", promptsAdmin.List)
+				adminGroup.POST("/prompts/reload", promptsAdmin.Reload)
+			}
+
+			experimentsAdmin := adminHandler.NewExperimentsHandler(experiments)
+			{
+				adminGroup.GET("/experiments", experimentsAdmin.List)
+			}
+		} else {
+			common.LogWarn("ADMIN_TOKEN not set, admin API disabled")
+		}
 	}
 
 	common.LogInfo("Router setup completed successfully",
@@ -209,7 +283,30 @@ func SetupRouter(cfg *config.Config, cacheManager *cache.CacheManager) (*gin.Eng
 		zap.Bool("cache_manager_initialized", cacheManager != nil),
 		zap.Duration("timeout", timeoutDuration),
 		zap.Int64("max_body_size", maxBodySize),
+		zap.Int64("max_upload_size", uploadLimit),
 	)
 
 	return router, nil
 }
+
+// respondTimeout 記錄請求超時並回傳 504；處理器已寫出回應（例如 SSE 串流已送出標頭與事件）時
+// 狀態碼無法再改寫，附加 JSON 只會破壞串流內容，此時只記錄日誌，錯誤由處理器在串流中回報
+func respondTimeout(c *gin.Context, timeout time.Duration) {
+	common.LogError("Request timeout",
+		zap.String("path", c.Request.URL.Path),
+		zap.String("request_id", c.GetHeader("X-Request-ID")),
+		zap.Duration("timeout", timeout),
+		zap.Bool("response_written", c.Writer.Written()),
+	)
+	c.Abort()
+	if c.Writer.Written() {
+		return
+	}
+	c.JSON(http.StatusGatewayTimeout, gin.H{
+		"error": "Request timeout",
+		"code":  "REQUEST_TIMEOUT",
+		"details": gin.H{
+			"timeout": timeout.String(),
+		},
+	})
+}
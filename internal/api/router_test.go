@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestRespondTimeout(t *testing.T) {
+	common.Logger = zap.NewNop()
+	gin.SetMode(gin.TestMode)
+
+	const timeout = 50 * time.Millisecond
+	router := gin.New()
+	// 與 SetupRouter 的全局中間件相同的超時處理
+	router.Use(func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		if ctx.Err() == context.DeadlineExceeded {
+			respondTimeout(c, timeout)
+		}
+	})
+	router.POST("/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+	})
+	router.POST("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.SSEvent("delta", "番茄")
+		c.Writer.Flush()
+		<-c.Request.Context().Done()
+		c.SSEvent("error", "timeout")
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/slow", nil))
+	if rec.Code != http.StatusGatewayTimeout || !strings.Contains(rec.Body.String(), "REQUEST_TIMEOUT") {
+		t.Fatalf("slow handler: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// 串流已送出時不附加 504 JSON
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/stream", nil))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || strings.Contains(body, "REQUEST_TIMEOUT") {
+		t.Fatalf("stream: %d %s", rec.Code, body)
+	}
+	if !strings.Contains(body, "event:delta") || !strings.HasSuffix(body, "event:error\ndata:timeout\n\n") {
+		t.Fatalf("stream events: %q", body)
+	}
+}
@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+)
+
+// 快取後端名稱
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+	BackendTiered = "tiered" // 本地 LRU 在前、共用 Redis 在後
+)
+
+// 衍生條目的命名空間：服務以正規化內容（食譜指紋、圖片感知雜湊）寫入的條目，
+// prompt 以「命名空間:」開頭時快取鍵保留命名空間而非 text:，管理介面可依前綴列出與清除
+const (
+	NamespaceRecipeFingerprint = "recipe-fingerprint"
+	NamespaceImagePHash        = "image-phash"
+)
+
+// Cache AI 回應快取；鍵由 prompt 與圖片經 Key 雜湊產生，不保存原始內容
+type Cache interface {
+	// Get 取得快取值，未命中或過期時回傳錯誤
+	Get(ctx context.Context, prompt, imageData string) (string, error)
+	// Set 寫入快取值，有效時間由設定的 TTL 決定
+	Set(ctx context.Context, prompt, imageData, value string) error
+	// Delete 刪除指定請求的快取值
+	Delete(ctx context.Context, prompt, imageData string) error
+	// Entries 列出符合條件的條目（不含值），依最近使用排序，limit <= 0 表示不限
+	Entries(ctx context.Context, filter Filter, limit int) ([]EntryInfo, error)
+	// Purge 刪除符合條件的條目，空條件清空全部，回傳刪除的數量
+	Purge(ctx context.Context, filter Filter) (int, error)
+	// GetStats 回傳快取統計
+	GetStats() map[string]interface{}
+	// Close 釋放資源
+	Close() error
+}
+
+// Index 建立在快取條目上的本地索引，例如食譜相似度與圖片感知雜湊索引
+type Index interface {
+	// Prune 移除指向已不存在條目的記錄，回傳移除的數量；管理介面刪除條目後呼叫
+	Prune(ctx context.Context) (int, error)
+}
+
+// Filter 條目篩選條件，空欄位表示不限
+type Filter struct {
+	Prefix   string // 快取鍵前綴，例如 text:、multimodal:、recipe-fingerprint:
+	Endpoint string // 寫入條目的 API 端點
+	Key      string // 完整的快取鍵，只符合鍵完全相同的條目
+}
+
+// Match 判斷條目是否符合條件
+func (f Filter) Match(key, endpoint string) bool {
+	if f.Key != "" && key != f.Key {
+		return false
+	}
+	if f.Prefix != "" && !strings.HasPrefix(key, f.Prefix) {
+		return false
+	}
+	return f.Endpoint == "" || f.Endpoint == endpoint
+}
+
+// EntryInfo 條目中繼資料
+type EntryInfo struct {
+	Key         string    `json:"key"`
+	Type        string    `json:"type"` // text、multimodal 或衍生條目的命名空間
+	Endpoint    string    `json:"endpoint,omitempty"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+	Age         string    `json:"age"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	AccessCount int       `json:"access_count"`
+}
+
+// newEntryInfo 由鍵與時間資訊組成中繼資料
+func newEntryInfo(key, endpoint string, size int64, createdAt, expiresAt time.Time, accessCount int) EntryInfo {
+	typ, _, _ := strings.Cut(key, ":")
+	return EntryInfo{
+		Key:         key,
+		Type:        typ,
+		Endpoint:    endpoint,
+		Size:        size,
+		CreatedAt:   createdAt,
+		Age:         time.Since(createdAt).Truncate(time.Second).String(),
+		ExpiresAt:   expiresAt,
+		AccessCount: accessCount,
+	}
+}
+
+// endpointKey 快取條目來源端點的 context 鍵
+type endpointKey struct{}
+
+// WithEndpoint 標記之後寫入的快取條目來自哪個 API 端點，供管理介面依端點清除
+func WithEndpoint(ctx context.Context, endpoint string) context.Context {
+	return context.WithValue(ctx, endpointKey{}, endpoint)
+}
+
+// EndpointFromContext 取得 WithEndpoint 設定的端點
+func EndpointFromContext(ctx context.Context) string {
+	endpoint, _ := ctx.Value(endpointKey{}).(string)
+	return endpoint
+}
+
+// New 依設定建立快取；快取關閉時回傳 nil
+func New(cfg *config.Config) (Cache, error) {
+	if !cfg.Cache.Enabled {
+		common.LogInfo("Cache disabled")
+		return nil, nil
+	}
+
+	switch cfg.Cache.Backend {
+	case BackendRedis:
+		return NewRedisCache(cfg)
+	case BackendTiered:
+		return NewTieredCache(cfg)
+	case BackendMemory, "":
+		return NewManager(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", cfg.Cache.Backend)
+	}
+}
@@ -1,11 +1,16 @@
 package cache
 
 import (
+	"container/list"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"recipe-generator/internal/infrastructure/config"
@@ -14,19 +19,42 @@ import (
 	"go.uber.org/zap"
 )
 
-// CacheManager 緩存管理器
+// entryOverhead 每個條目在字串內容之外的估計記憶體開銷（map、鏈結串列節點與時間欄位）
+const entryOverhead = 128
+
+var _ Cache = (*CacheManager)(nil)
+
+// CacheManager 記憶體緩存管理器
+// 依鍵雜湊分片，每個分片各自持有鎖與 LRU 鏈結串列，查找、更新與淘汰皆為 O(1)；
+// 容量以位元組計算，多模態回應大小差異很大，條目數量沒有意義。
+// 容量為所有分片共用的總預算，分片大小不固定，單一條目只要不超過總預算即可寫入
 type CacheManager struct {
-	config *config.Config
-	mu     sync.RWMutex
-	store  map[string]cacheEntry
-	stats  cacheStats
+	config   *config.Config
+	shards   []*shard
+	maxBytes int64
+	used     atomic.Int64 // 所有分片的位元組總數
+	stop     chan struct{}
+	once     sync.Once
+	stats    cacheStats
+}
+
+// shard 緩存分片
+type shard struct {
+	mu    sync.Mutex
+	items map[string]*list.Element
+	lru   *list.List // 前端為最近使用
+	bytes int64
+	used  *atomic.Int64 // 指向 CacheManager.used
 }
 
 // cacheEntry 緩存條目
 type cacheEntry struct {
+	key         string
 	value       string
-	expiresAt   time.Time
 	imageHash   string
+	endpoint    string
+	size        int64
+	expiresAt   time.Time
 	createdAt   time.Time
 	lastAccess  time.Time
 	accessCount int
@@ -34,10 +62,11 @@ type cacheEntry struct {
 
 // cacheStats 緩存統計
 type cacheStats struct {
-	hits      int64
-	misses    int64
-	evictions int64
-	errors    int64
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+	expired   atomic.Int64
+	errors    atomic.Int64
 }
 
 // NewManager 創建新的緩存管理器
@@ -47,17 +76,38 @@ func NewManager(cfg *config.Config) *CacheManager {
 		return nil
 	}
 
+	n := cfg.Cache.Shards
+	if n <= 0 {
+		n = 1
+	}
 	m := &CacheManager{
-		config: cfg,
-		store:  make(map[string]cacheEntry),
-		stats:  cacheStats{},
+		config:   cfg,
+		shards:   make([]*shard, n),
+		maxBytes: cfg.Cache.MaxBytes,
+		stop:     make(chan struct{}),
+	}
+	for i := range m.shards {
+		m.shards[i] = &shard{
+			items: make(map[string]*list.Element),
+			lru:   list.New(),
+			used:  &m.used,
+		}
+	}
+
+	// 從上次關閉前的快照預熱
+	if cfg.Cache.SnapshotPath != "" {
+		m.loadSnapshot()
 	}
 
 	// 啟動清理過期緩存的協程
 	go m.startCleanup()
+	if cfg.Cache.SnapshotPath != "" && cfg.Cache.SnapshotInterval > 0 {
+		go m.snapshotLoop()
+	}
 
 	common.LogInfo("快取管理員已初始化",
-		zap.Int("最大容量", cfg.Cache.MaxSize),
+		zap.Int64("最大容量(bytes)", cfg.Cache.MaxBytes),
+		zap.Int("分片數", n),
 		zap.Duration("存活時間", cfg.Cache.TTL),
 		zap.Duration("清理間隔", cfg.Cache.CleanupInterval),
 	)
@@ -65,6 +115,13 @@ func NewManager(cfg *config.Config) *CacheManager {
 	return m
 }
 
+// shardFor 依鍵選擇分片
+func (m *CacheManager) shardFor(key string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return m.shards[h.Sum32()%uint32(len(m.shards))]
+}
+
 // Get 獲取緩存值
 func (m *CacheManager) Get(ctx context.Context, prompt, imageData string) (string, error) {
 	if !m.config.Cache.Enabled {
@@ -72,54 +129,47 @@ func (m *CacheManager) Get(ctx context.Context, prompt, imageData string) (strin
 		return "", common.ErrCacheDisabled
 	}
 
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
 	// 生成緩存鍵
 	key := m.generateKey(prompt, imageData)
+	s := m.shardFor(key)
+
+	s.mu.Lock()
+	el, exists := s.items[key]
+	if !exists {
+		s.mu.Unlock()
+		m.stats.misses.Add(1)
+		common.LogInfo("快取未命中",
+			zap.String("鍵", key),
+		)
+		return "", common.ErrCacheDisabled
+	}
 
-	// 檢查緩存
-	if entry, exists := m.store[key]; exists {
-		// 檢查是否過期
-		if time.Now().After(entry.expiresAt) {
-			m.mu.RUnlock()
-			m.mu.Lock()
-			delete(m.store, key)
-			m.stats.evictions++
-			m.mu.Unlock()
-			m.mu.RLock()
-			common.LogInfo("快取已過期",
-				zap.String("鍵", key),
-			)
-			return "", common.ErrCacheDisabled
-		}
-
-		// 檢查圖片哈希是否匹配
-		if imageData != "" && entry.imageHash != m.hashImage(imageData) {
-			m.stats.misses++
-			common.LogInfo("快取因圖片變更未命中",
-				zap.String("鍵", key),
-			)
-			return "", fmt.Errorf("image changed")
-		}
-
-		// 更新訪問統計
-		entry.lastAccess = time.Now()
-		entry.accessCount++
-		m.store[key] = entry
-		m.stats.hits++
-
-		common.LogInfo("快取命中",
+	entry := el.Value.(*cacheEntry)
+	now := time.Now()
+	// 檢查是否過期
+	if now.After(entry.expiresAt) {
+		s.remove(el)
+		s.mu.Unlock()
+		m.stats.expired.Add(1)
+		m.stats.misses.Add(1)
+		common.LogInfo("快取已過期",
 			zap.String("鍵", key),
 		)
-		return entry.value, nil
+		return "", common.ErrCacheDisabled
 	}
 
-	m.stats.misses++
-	common.LogInfo("快取未命中",
+	// 更新訪問統計並移到最近使用
+	entry.lastAccess = now
+	entry.accessCount++
+	s.lru.MoveToFront(el)
+	value := entry.value
+	s.mu.Unlock()
+
+	m.stats.hits.Add(1)
+	common.LogInfo("快取命中",
 		zap.String("鍵", key),
 	)
-	return "", common.ErrCacheDisabled
+	return value, nil
 }
 
 // Set 設置緩存值
@@ -129,70 +179,139 @@ func (m *CacheManager) Set(ctx context.Context, prompt, imageData, value string)
 		return nil
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	// 生成緩存鍵
+	key := m.generateKey(prompt, imageData)
+	now := time.Now()
+	entry := &cacheEntry{
+		key:        key,
+		value:      value,
+		imageHash:  m.hashImage(imageData),
+		endpoint:   EndpointFromContext(ctx),
+		size:       int64(len(key)+len(value)) + entryOverhead,
+		expiresAt:  now.Add(m.config.Cache.TTL),
+		createdAt:  now,
+		lastAccess: now,
+	}
 
-	// 檢查緩存大小
-	if len(m.store) >= m.config.Cache.MaxSize {
-		// 清理過期項目
-		evicted := m.cleanup()
-		common.LogInfo("快取清理執行",
-			zap.Int("清理數量", evicted),
+	evicted, err := m.add(entry)
+	if err != nil {
+		m.stats.errors.Add(1)
+		common.LogWarn("快取條目超過快取容量",
+			zap.String("鍵", key),
+			zap.Int64("大小", entry.size),
 		)
+		return err
+	}
+	if evicted > 0 {
+		m.stats.evictions.Add(int64(evicted))
+		common.LogInfo("快取已淘汰(LRU)",
+			zap.Int("淘汰數量", evicted),
+		)
+	}
+
+	common.LogInfo("快取已儲存",
+		zap.String("鍵", key),
+	)
+
+	return nil
+}
+
+// add 加入或取代條目，超過總容量時淘汰最久未使用的條目，回傳淘汰數量；
+// 只有大於總容量的條目會被拒絕
+func (m *CacheManager) add(entry *cacheEntry) (int, error) {
+	if entry.size > m.maxBytes {
+		return 0, common.ErrCacheFull
+	}
+
+	s := m.shardFor(entry.key)
+	s.mu.Lock()
+	if el, ok := s.items[entry.key]; ok {
+		s.remove(el)
+	}
+	s.items[entry.key] = s.lru.PushFront(entry)
+	s.bytes += entry.size
+	s.used.Add(entry.size)
+	s.mu.Unlock()
+
+	if m.used.Load() <= m.maxBytes {
+		return 0, nil
+	}
+	return m.evict(s, entry), nil
+}
 
-		// 如果仍然超過大小限制，執行 LRU 清理
-		if len(m.store) >= m.config.Cache.MaxSize {
-			m.evictLRU()
+// evict 超過總容量時淘汰條目，先淘汰寫入分片的最久未使用端，不足時依序淘汰其他分片；
+// 每次只鎖定一個分片，剛寫入的條目不會被淘汰
+func (m *CacheManager) evict(from *shard, keep *cacheEntry) int {
+	start := 0
+	for i, s := range m.shards {
+		if s == from {
+			start = i
+			break
 		}
+	}
 
-		// 如果仍然超過大小限制，返回錯誤
-		if len(m.store) >= m.config.Cache.MaxSize {
-			m.stats.errors++
-			common.LogWarn("快取已滿",
-				zap.Int("目前容量", len(m.store)),
-			)
-			return common.ErrCacheFull
+	evicted := 0
+	for i := 0; i < len(m.shards) && m.used.Load() > m.maxBytes; i++ {
+		s := m.shards[(start+i)%len(m.shards)]
+		s.mu.Lock()
+		for m.used.Load() > m.maxBytes {
+			oldest := s.lru.Back()
+			if oldest == nil || oldest.Value.(*cacheEntry) == keep {
+				break
+			}
+			s.remove(oldest)
+			evicted++
 		}
+		s.mu.Unlock()
 	}
+	return evicted
+}
 
-	// 生成緩存鍵
-	key := m.generateKey(prompt, imageData)
+// remove 移除條目，呼叫端需持有鎖
+func (s *shard) remove(el *list.Element) {
+	entry := s.lru.Remove(el).(*cacheEntry)
+	delete(s.items, entry.key)
+	s.bytes -= entry.size
+	s.used.Add(-entry.size)
+}
 
-	// 設置緩存
-	now := time.Now()
-	m.store[key] = cacheEntry{
-		value:       value,
-		expiresAt:   now.Add(m.config.Cache.TTL),
-		imageHash:   m.hashImage(imageData),
-		createdAt:   now,
-		lastAccess:  now,
-		accessCount: 0,
+// Key 依 prompt 與圖片計算請求鍵，快取與進行中請求合併共用同一組鍵
+func Key(prompt, imageData string) string {
+	if imageData == "" {
+		if ns := namespace(prompt); ns != "" {
+			return fmt.Sprintf("%s:%s", ns, hashString(prompt))
+		}
+		return fmt.Sprintf("text:%s", hashString(prompt))
 	}
+	return fmt.Sprintf("multimodal:%s:%s", hashString(prompt), hashString(imageData))
+}
 
-	common.LogInfo("快取已儲存",
-		zap.String("鍵", key),
-	)
-
-	return nil
+// namespace 衍生條目 prompt 的命名空間，一般 prompt 回傳空字串
+func namespace(prompt string) string {
+	ns, _, ok := strings.Cut(prompt, ":")
+	if !ok || (ns != NamespaceRecipeFingerprint && ns != NamespaceImagePHash) {
+		return ""
+	}
+	return ns
 }
 
 // generateKey 生成緩存鍵
 func (m *CacheManager) generateKey(prompt, imageData string) string {
-	if imageData == "" {
-		return fmt.Sprintf("text:%s", m.hashString(prompt))
-	}
-	return fmt.Sprintf("multimodal:%s:%s", m.hashString(prompt), m.hashImage(imageData))
+	return Key(prompt, imageData)
 }
 
 // hashString 計算字符串的 SHA-256 哈希值
-func (m *CacheManager) hashString(s string) string {
+func hashString(s string) string {
 	hash := sha256.Sum256([]byte(s))
 	return hex.EncodeToString(hash[:])
 }
 
-// hashImage 計算圖片數據的哈希值
+// hashImage 計算圖片數據的哈希值，無圖片時為空
 func (m *CacheManager) hashImage(imageData string) string {
-	return m.hashString(imageData)
+	if imageData == "" {
+		return ""
+	}
+	return hashString(imageData)
 }
 
 // startCleanup 啟動清理過期緩存的協程
@@ -200,89 +319,195 @@ func (m *CacheManager) startCleanup() {
 	ticker := time.NewTicker(m.config.Cache.CleanupInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		m.cleanup()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.cleanup()
+		}
 	}
 }
 
-// cleanup 清理過期的緩存
+// cleanup 逐一分片清理過期的緩存，每次只鎖定一個分片
 func (m *CacheManager) cleanup() int {
 	now := time.Now()
 	count := 0
 
-	for key, entry := range m.store {
-		if now.After(entry.expiresAt) {
-			delete(m.store, key)
-			count++
-			m.stats.evictions++
+	for _, s := range m.shards {
+		s.mu.Lock()
+		for el := s.lru.Back(); el != nil; {
+			prev := el.Prev()
+			if now.After(el.Value.(*cacheEntry).expiresAt) {
+				s.remove(el)
+				count++
+			}
+			el = prev
 		}
+		s.mu.Unlock()
 	}
 
 	if count > 0 {
+		m.stats.expired.Add(int64(count))
 		common.LogInfo("Cleaned up expired cache entries",
 			zap.Int("count", count),
-			zap.Int64("total_evictions", m.stats.evictions),
-			zap.Int("remaining_size", len(m.store)),
-			zap.Float64("eviction_ratio", float64(m.stats.evictions)/float64(m.stats.hits+m.stats.misses)),
+			zap.Int64("total_expired", m.stats.expired.Load()),
+			zap.Int64("remaining_bytes", m.usedBytes()),
 		)
 	}
 
 	return count
 }
 
-// evictLRU 執行 LRU 清理
-func (m *CacheManager) evictLRU() {
-	var oldestKey string
-	var oldestAccess time.Time
-	var lowestAccessCount int
-
-	// 找到最少訪問的項目
-	for key, entry := range m.store {
-		if oldestKey == "" ||
-			entry.accessCount < lowestAccessCount ||
-			(entry.accessCount == lowestAccessCount && entry.lastAccess.Before(oldestAccess)) {
-			oldestKey = key
-			oldestAccess = entry.lastAccess
-			lowestAccessCount = entry.accessCount
+// Delete 刪除指定請求的緩存
+func (m *CacheManager) Delete(ctx context.Context, prompt, imageData string) error {
+	m.deleteKey(m.generateKey(prompt, imageData))
+	return nil
+}
+
+// Clear 清空緩存，回傳刪除的數量
+func (m *CacheManager) Clear(ctx context.Context) (int, error) {
+	count := 0
+	for _, s := range m.shards {
+		s.mu.Lock()
+		count += len(s.items)
+		s.items = make(map[string]*list.Element)
+		s.lru.Init()
+		s.used.Add(-s.bytes)
+		s.bytes = 0
+		s.mu.Unlock()
+	}
+	common.LogInfo("快取已清空", zap.Int("清除數量", count))
+	return count, nil
+}
+
+// Purge 刪除符合條件的緩存，空條件清空全部
+func (m *CacheManager) Purge(ctx context.Context, filter Filter) (int, error) {
+	if filter == (Filter{}) {
+		return m.Clear(ctx)
+	}
+
+	count := 0
+	for _, s := range m.shards {
+		s.mu.Lock()
+		for el := s.lru.Front(); el != nil; {
+			next := el.Next()
+			e := el.Value.(*cacheEntry)
+			if filter.Match(e.key, e.endpoint) {
+				s.remove(el)
+				count++
+			}
+			el = next
 		}
+		s.mu.Unlock()
 	}
+	common.LogInfo("快取已依條件清除",
+		zap.String("prefix", filter.Prefix),
+		zap.String("endpoint", filter.Endpoint),
+		zap.String("key", filter.Key),
+		zap.Int("清除數量", count),
+	)
+	return count, nil
+}
 
-	if oldestKey != "" {
-		delete(m.store, oldestKey)
-		m.stats.evictions++
-		common.LogInfo("快取已淘汰(LRU)",
-			zap.String("鍵", oldestKey),
-		)
+// Entries 列出符合條件且未過期的條目，依最近使用排序
+func (m *CacheManager) Entries(ctx context.Context, filter Filter, limit int) ([]EntryInfo, error) {
+	type item struct {
+		info       EntryInfo
+		lastAccess time.Time
+	}
+	now := time.Now()
+	var items []item
+	for _, s := range m.shards {
+		s.mu.Lock()
+		for el := s.lru.Front(); el != nil; el = el.Next() {
+			e := el.Value.(*cacheEntry)
+			if now.After(e.expiresAt) || !filter.Match(e.key, e.endpoint) {
+				continue
+			}
+			items = append(items, item{
+				info:       newEntryInfo(e.key, e.endpoint, e.size, e.createdAt, e.expiresAt, e.accessCount),
+				lastAccess: e.lastAccess,
+			})
+		}
+		s.mu.Unlock()
 	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].lastAccess.After(items[j].lastAccess) })
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	out := make([]EntryInfo, len(items))
+	for i, it := range items {
+		out[i] = it.info
+	}
+	return out, nil
+}
+
+// deleteKey 依緩存鍵刪除
+func (m *CacheManager) deleteKey(key string) bool {
+	s := m.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[key]
+	if !ok {
+		return false
+	}
+	s.remove(el)
+	return true
+}
+
+// usedBytes 目前使用的位元組數
+func (m *CacheManager) usedBytes() int64 {
+	return m.used.Load()
 }
 
 // GetStats 獲取緩存統計信息
 func (m *CacheManager) GetStats() map[string]interface{} {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	size := 0
+	var bytes int64
+	for _, s := range m.shards {
+		s.mu.Lock()
+		size += len(s.items)
+		bytes += s.bytes
+		s.mu.Unlock()
+	}
+
+	hits, misses := m.stats.hits.Load(), m.stats.misses.Load()
+	ratio := 0.0
+	if hits+misses > 0 {
+		ratio = float64(hits) / float64(hits+misses)
+	}
 
 	return map[string]interface{}{
-		"size":      len(m.store),
-		"max_size":  m.config.Cache.MaxSize,
-		"hits":      m.stats.hits,
-		"misses":    m.stats.misses,
-		"evictions": m.stats.evictions,
-		"errors":    m.stats.errors,
-		"hit_ratio": float64(m.stats.hits) / float64(m.stats.hits+m.stats.misses),
+		"backend":   BackendMemory,
+		"size":      size,
+		"bytes":     bytes,
+		"max_bytes": m.maxBytes,
+		"shards":    len(m.shards),
+		"hits":      hits,
+		"misses":    misses,
+		"evictions": m.stats.evictions.Load(),
+		"expired":   m.stats.expired.Load(),
+		"errors":    m.stats.errors.Load(),
+		"hit_ratio": ratio,
 	}
 }
 
 // Close 關閉緩存管理器
 func (m *CacheManager) Close() error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	m.once.Do(func() { close(m.stop) })
 
-	// 清空緩存
-	m.store = make(map[string]cacheEntry)
+	// 保存快照供下次啟動預熱，再清空緩存
+	if m.config.Cache.SnapshotPath != "" {
+		m.saveSnapshot()
+	}
+	m.Clear(context.Background())
 	common.LogInfo("快取管理員已關閉",
-		zap.Int64("命中次數", m.stats.hits),
-		zap.Int64("未命中次數", m.stats.misses),
-		zap.Int64("淘汰次數", m.stats.evictions),
+		zap.Int64("命中次數", m.stats.hits.Load()),
+		zap.Int64("未命中次數", m.stats.misses.Load()),
+		zap.Int64("淘汰次數", m.stats.evictions.Load()),
 	)
 	return nil
 }
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	common.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// newTestManager 建立不保存快照的記憶體快取
+func newTestManager(tb testing.TB, maxBytes int64, shards int) *CacheManager {
+	tb.Helper()
+	m := NewManager(&config.Config{Cache: config.CacheConfig{
+		Enabled:         true,
+		MaxBytes:        maxBytes,
+		Shards:          shards,
+		TTL:             time.Hour,
+		CleanupInterval: time.Hour,
+	}})
+	tb.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestSetEntryLargerThanShardShare(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(t, 64<<10, 16)
+
+	// 大於總容量的 1/16，但小於總容量
+	value := strings.Repeat("x", 16<<10)
+	if err := m.Set(ctx, "large", "", value); err != nil {
+		t.Fatalf("Set large entry: %v", err)
+	}
+	got, err := m.Get(ctx, "large", "")
+	if err != nil || got != value {
+		t.Fatalf("Get large entry: err=%v, len=%d", err, len(got))
+	}
+
+	tooLarge := strings.Repeat("x", 64<<10)
+	if err := m.Set(ctx, "too-large", "", tooLarge); !errors.Is(err, common.ErrCacheFull) {
+		t.Fatalf("Set entry larger than budget: err=%v, want ErrCacheFull", err)
+	}
+}
+
+func TestEvictionKeepsTotalWithinBudget(t *testing.T) {
+	ctx := context.Background()
+	const budget = 32 << 10
+	m := newTestManager(t, budget, 4)
+
+	value := strings.Repeat("x", 3<<10)
+	for i := 0; i < 50; i++ {
+		if err := m.Set(ctx, fmt.Sprintf("prompt-%d", i), "", value); err != nil {
+			t.Fatalf("Set %d: %v", i, err)
+		}
+		if used := m.usedBytes(); used > budget {
+			t.Fatalf("after Set %d: used %d bytes, budget %d", i, used, budget)
+		}
+	}
+
+	// 最後寫入的條目必須留在快取中
+	if _, err := m.Get(ctx, "prompt-49", ""); err != nil {
+		t.Fatalf("latest entry evicted: %v", err)
+	}
+	if evictions := m.GetStats()["evictions"].(int64); evictions == 0 {
+		t.Fatal("expected evictions")
+	}
+
+	if _, err := m.Clear(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if used := m.usedBytes(); used != 0 {
+		t.Fatalf("after Clear: used %d bytes", used)
+	}
+}
+
+// benchShards 比較單一分片與分片的併發吞吐量
+var benchShards = []int{1, 16}
+
+// benchKeys 預先寫入的提示詞數量
+const benchKeys = 4096
+
+func prefill(b *testing.B, m *CacheManager, value string) []string {
+	b.Helper()
+	ctx := context.Background()
+	prompts := make([]string, benchKeys)
+	for i := range prompts {
+		prompts[i] = fmt.Sprintf("請提供番茄炒蛋的食譜 #%d", i)
+		if err := m.Set(ctx, prompts[i], "", value); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return prompts
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	value := strings.Repeat("x", 2<<10)
+	for _, shards := range benchShards {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			m := newTestManager(b, 256<<20, shards)
+			prompts := prefill(b, m, value)
+			ctx := context.Background()
+			var seq atomic.Int64
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := int(seq.Add(1)) * 7919
+				for pb.Next() {
+					if _, err := m.Get(ctx, prompts[i%benchKeys], ""); err != nil {
+						b.Fatal(err)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkSetParallel(b *testing.B) {
+	value := strings.Repeat("x", 2<<10)
+	for _, shards := range benchShards {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			// 容量只容納部分條目，同時量測淘汰
+			m := newTestManager(b, 4<<20, shards)
+			ctx := context.Background()
+			var seq atomic.Int64
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					n := seq.Add(1)
+					if err := m.Set(ctx, fmt.Sprintf("prompt-%d", n), "", value); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkMixedParallel 九成讀取、一成寫入，接近實際的命中情境
+func BenchmarkMixedParallel(b *testing.B) {
+	value := strings.Repeat("x", 2<<10)
+	for _, shards := range benchShards {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			m := newTestManager(b, 256<<20, shards)
+			prompts := prefill(b, m, value)
+			ctx := context.Background()
+			var seq atomic.Int64
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := int(seq.Add(1)) * 7919
+				for pb.Next() {
+					prompt := prompts[i%benchKeys]
+					if i%10 == 0 {
+						if err := m.Set(ctx, prompt, "", value); err != nil {
+							b.Fatal(err)
+						}
+					} else {
+						m.Get(ctx, prompt, "")
+					}
+					i++
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// redisTimeout 單次 Redis 操作的超時，避免快取拖慢請求
+const redisTimeout = 2 * time.Second
+
+// RedisCache 以 Redis 保存快取，多個實例共用同一份快取
+type RedisCache struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+
+	hits   atomic.Int64
+	misses atomic.Int64
+	errors atomic.Int64
+}
+
+// NewRedisCache 連接 Redis 快取
+func NewRedisCache(cfg *config.Config) (*RedisCache, error) {
+	rc := cfg.Cache.Redis
+	client := redis.NewClient(&redis.Options{
+		Addr:     rc.Addr,
+		Password: rc.Password,
+		DB:       rc.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis %s: %w", rc.Addr, err)
+	}
+
+	common.LogInfo("Redis 快取已初始化",
+		zap.String("addr", rc.Addr),
+		zap.Int("db", rc.DB),
+		zap.String("prefix", rc.Prefix),
+		zap.Duration("ttl", cfg.Cache.TTL),
+	)
+
+	return &RedisCache{
+		client: client,
+		prefix: rc.Prefix,
+		ttl:    cfg.Cache.TTL,
+	}, nil
+}
+
+// 條目以 Redis hash 保存，欄位如下
+const (
+	fieldValue     = "v"
+	fieldEndpoint  = "endpoint"
+	fieldImageHash = "image_hash"
+	fieldCreatedAt = "created_at" // Unix 毫秒
+	fieldHits      = "hits"
+)
+
+// getScript 讀取值並在命中時累加存取次數，未命中時不會建立空的 hash
+var getScript = redis.NewScript(`
+local v = redis.call('HGET', KEYS[1], ARGV[1])
+if v then redis.call('HINCRBY', KEYS[1], ARGV[2], 1) end
+return v
+`)
+
+// redisKey 加上前綴的快取鍵
+func (c *RedisCache) redisKey(prompt, imageData string) string {
+	return c.keyPrefix() + Key(prompt, imageData)
+}
+
+// keyPrefix 快取條目在 Redis 中的前綴
+func (c *RedisCache) keyPrefix() string {
+	return c.prefix + "cache:"
+}
+
+// invalidationChannel 快取失效通知的 pub/sub 頻道
+func (c *RedisCache) invalidationChannel() string {
+	return c.prefix + "cache:invalidate"
+}
+
+// Get 實作 Cache
+func (c *RedisCache) Get(ctx context.Context, prompt, imageData string) (string, error) {
+	key := c.redisKey(prompt, imageData)
+
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	val, err := getScript.Run(ctx, c.client, []string{key}, fieldValue, fieldHits).Text()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			c.misses.Add(1)
+			common.LogInfo("快取未命中", zap.String("鍵", key))
+			return "", common.ErrCacheDisabled
+		}
+		c.errors.Add(1)
+		common.LogWarn("Redis 快取讀取失敗", zap.String("鍵", key), zap.Error(err))
+		return "", fmt.Errorf("failed to get cache: %w", err)
+	}
+
+	c.hits.Add(1)
+	common.LogInfo("快取命中", zap.String("鍵", key))
+	return val, nil
+}
+
+// Set 實作 Cache
+func (c *RedisCache) Set(ctx context.Context, prompt, imageData, value string) error {
+	key := c.redisKey(prompt, imageData)
+	imageHash := ""
+	if imageData != "" {
+		imageHash = hashString(imageData)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key,
+			fieldValue, value,
+			fieldEndpoint, EndpointFromContext(ctx),
+			fieldImageHash, imageHash,
+			fieldCreatedAt, time.Now().UnixMilli(),
+			fieldHits, 0,
+		)
+		pipe.PExpire(ctx, key, c.ttl)
+		return nil
+	})
+	if err != nil {
+		c.errors.Add(1)
+		common.LogWarn("Redis 快取寫入失敗", zap.String("鍵", key), zap.Error(err))
+		return fmt.Errorf("failed to set cache: %w", err)
+	}
+
+	common.LogInfo("快取已儲存", zap.String("鍵", key))
+	return nil
+}
+
+// Delete 實作 Cache
+func (c *RedisCache) Delete(ctx context.Context, prompt, imageData string) error {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	if err := c.client.Del(ctx, c.redisKey(prompt, imageData)).Err(); err != nil {
+		c.errors.Add(1)
+		return fmt.Errorf("failed to delete cache: %w", err)
+	}
+	return nil
+}
+
+// redisEntry 掃描時讀取的條目中繼資料
+type redisEntry struct {
+	key  string // 不含前綴的快取鍵
+	info EntryInfo
+}
+
+// scan 以 SCAN 逐批讀取符合條件的條目中繼資料，fn 回傳 false 時停止
+// 只掃描前綴下的鍵，不影響同一 Redis 中的其他資料；指定完整的鍵時只比對該鍵
+func (c *RedisCache) scan(ctx context.Context, filter Filter, fn func(batch []redisEntry) (bool, error)) error {
+	prefix := c.keyPrefix()
+	pattern := prefix + filter.Prefix + "*"
+	if filter.Key != "" {
+		pattern = prefix + filter.Key
+	}
+	iter := c.client.Scan(ctx, 0, pattern, 500).Iterator()
+
+	keys := make([]string, 0, 500)
+	flush := func() (bool, error) {
+		if len(keys) == 0 {
+			return true, nil
+		}
+		defer func() { keys = keys[:0] }()
+
+		pipe := c.client.Pipeline()
+		meta := make([]*redis.SliceCmd, len(keys))
+		sizes := make([]*redis.Cmd, len(keys))
+		ttls := make([]*redis.DurationCmd, len(keys))
+		for i, k := range keys {
+			meta[i] = pipe.HMGet(ctx, k, fieldEndpoint, fieldCreatedAt, fieldHits)
+			sizes[i] = pipe.Do(ctx, "HSTRLEN", k, fieldValue)
+			ttls[i] = pipe.PTTL(ctx, k)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+			return false, err
+		}
+
+		batch := make([]redisEntry, 0, len(keys))
+		for i, k := range keys {
+			vals, err := meta[i].Result()
+			if err != nil || len(vals) != 3 {
+				continue
+			}
+			endpoint, _ := vals[0].(string)
+			key := strings.TrimPrefix(k, prefix)
+			if !filter.Match(key, endpoint) {
+				continue
+			}
+			createdMs, _ := strconv.ParseInt(fmt.Sprint(vals[1]), 10, 64)
+			hits, _ := strconv.Atoi(fmt.Sprint(vals[2]))
+			createdAt := time.UnixMilli(createdMs)
+			var expiresAt time.Time
+			if ttl := ttls[i].Val(); ttl > 0 {
+				expiresAt = time.Now().Add(ttl)
+			}
+			valueSize, _ := sizes[i].Int64()
+			size := valueSize + int64(len(key)) + entryOverhead
+			batch = append(batch, redisEntry{
+				key:  k,
+				info: newEntryInfo(key, endpoint, size, createdAt, expiresAt, hits),
+			})
+		}
+		return fn(batch)
+	}
+
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == cap(keys) {
+			if more, err := flush(); err != nil || !more {
+				return err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	_, err := flush()
+	return err
+}
+
+// Entries 實作 Cache；Redis 不記錄最後存取時間，依建立時間由新到舊排序
+func (c *RedisCache) Entries(ctx context.Context, filter Filter, limit int) ([]EntryInfo, error) {
+	var out []EntryInfo
+	err := c.scan(ctx, filter, func(batch []redisEntry) (bool, error) {
+		for _, e := range batch {
+			out = append(out, e.info)
+		}
+		return true, nil
+	})
+	if err != nil {
+		c.errors.Add(1)
+		return nil, fmt.Errorf("failed to list cache: %w", err)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// Purge 實作 Cache
+func (c *RedisCache) Purge(ctx context.Context, filter Filter) (int, error) {
+	count := 0
+	err := c.scan(ctx, filter, func(batch []redisEntry) (bool, error) {
+		if len(batch) == 0 {
+			return true, nil
+		}
+		keys := make([]string, len(batch))
+		for i, e := range batch {
+			keys[i] = e.key
+		}
+		n, err := c.client.Del(ctx, keys...).Result()
+		count += int(n)
+		return err == nil, err
+	})
+	if err != nil {
+		c.errors.Add(1)
+		return count, fmt.Errorf("failed to purge cache: %w", err)
+	}
+
+	common.LogInfo("Redis 快取已依條件清除",
+		zap.String("prefix", filter.Prefix),
+		zap.String("endpoint", filter.Endpoint),
+		zap.String("key", filter.Key),
+		zap.Int("清除數量", count),
+	)
+	return count, nil
+}
+
+// GetStats 實作 Cache；統計只涵蓋本實例的存取
+func (c *RedisCache) GetStats() map[string]interface{} {
+	hits, misses := c.hits.Load(), c.misses.Load()
+	ratio := 0.0
+	if hits+misses > 0 {
+		ratio = float64(hits) / float64(hits+misses)
+	}
+	return map[string]interface{}{
+		"backend":   BackendRedis,
+		"hits":      hits,
+		"misses":    misses,
+		"errors":    c.errors.Load(),
+		"hit_ratio": ratio,
+	}
+}
+
+// Close 實作 Cache
+func (c *RedisCache) Close() error {
+	common.LogInfo("Redis 快取已關閉",
+		zap.Int64("命中次數", c.hits.Load()),
+		zap.Int64("未命中次數", c.misses.Load()),
+	)
+	return c.client.Close()
+}
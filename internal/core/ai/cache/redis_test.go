@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestRedisCache 以 miniredis 建立 Redis 快取
+func newTestRedisCache(t *testing.T) (*RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	c, err := NewRedisCache(&config.Config{Cache: config.CacheConfig{
+		TTL: time.Hour,
+		Redis: config.RedisConfig{
+			Addr:   mr.Addr(),
+			Prefix: "test:",
+		},
+	}})
+	if err != nil {
+		t.Fatalf("NewRedisCache: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, mr
+}
+
+func TestRedisCacheGetSet(t *testing.T) {
+	c, mr := newTestRedisCache(t)
+	ctx := WithEndpoint(context.Background(), "/api/v1/recipe/generate")
+
+	if _, err := c.Get(ctx, "prompt", ""); !errors.Is(err, common.ErrCacheDisabled) {
+		t.Fatalf("Get before Set: err=%v, want miss", err)
+	}
+	if err := c.Set(ctx, "prompt", "", "value"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got, err := c.Get(ctx, "prompt", ""); err != nil || got != "value" {
+		t.Fatalf("Get: %q, %v", got, err)
+	}
+
+	// 同一 prompt 加上圖片為不同的條目
+	if err := c.Set(ctx, "prompt", "data:image/jpeg;base64,AAAA", "with-image"); err != nil {
+		t.Fatalf("Set multimodal: %v", err)
+	}
+	if got, _ := c.Get(ctx, "prompt", "data:image/jpeg;base64,AAAA"); got != "with-image" {
+		t.Fatalf("Get multimodal: %q", got)
+	}
+	if got, _ := c.Get(ctx, "prompt", ""); got != "value" {
+		t.Fatalf("text entry overwritten: %q", got)
+	}
+
+	key := c.redisKey("prompt", "")
+	if !mr.Exists(key) {
+		t.Fatalf("key %s not stored with prefix", key)
+	}
+	if endpoint := mr.HGet(key, fieldEndpoint); endpoint != "/api/v1/recipe/generate" {
+		t.Fatalf("endpoint field: %q", endpoint)
+	}
+
+	stats := c.GetStats()
+	if stats["hits"].(int64) != 3 || stats["misses"].(int64) != 1 {
+		t.Fatalf("stats: %v", stats)
+	}
+}
+
+func TestRedisCacheGetScript(t *testing.T) {
+	c, mr := newTestRedisCache(t)
+	ctx := context.Background()
+
+	// 未命中時不會建立空的 hash
+	c.Get(ctx, "missing", "")
+	if mr.Exists(c.redisKey("missing", "")) {
+		t.Fatal("miss created a hash")
+	}
+
+	if err := c.Set(ctx, "prompt", "", "value"); err != nil {
+		t.Fatal(err)
+	}
+	key := c.redisKey("prompt", "")
+	for i := 0; i < 3; i++ {
+		if _, err := c.Get(ctx, "prompt", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if hits := mr.HGet(key, fieldHits); hits != "3" {
+		t.Fatalf("hits field: %q, want 3", hits)
+	}
+
+	// 重新寫入會重設存取次數
+	if err := c.Set(ctx, "prompt", "", "value2"); err != nil {
+		t.Fatal(err)
+	}
+	if hits := mr.HGet(key, fieldHits); hits != "0" {
+		t.Fatalf("hits after Set: %q, want 0", hits)
+	}
+
+	entries, err := c.Entries(ctx, Filter{}, 0)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Entries: %v, %v", entries, err)
+	}
+	if entries[0].Size != int64(len("value2")+len(Key("prompt", "")))+entryOverhead {
+		t.Fatalf("entry size: %d", entries[0].Size)
+	}
+}
+
+func TestRedisCacheTTL(t *testing.T) {
+	c, mr := newTestRedisCache(t)
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "prompt", "", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL(c.redisKey("prompt", "")); ttl != time.Hour {
+		t.Fatalf("TTL: %v, want 1h", ttl)
+	}
+
+	mr.FastForward(59 * time.Minute)
+	if _, err := c.Get(ctx, "prompt", ""); err != nil {
+		t.Fatalf("Get before expiry: %v", err)
+	}
+	mr.FastForward(2 * time.Minute)
+	if _, err := c.Get(ctx, "prompt", ""); !errors.Is(err, common.ErrCacheDisabled) {
+		t.Fatalf("Get after expiry: err=%v, want miss", err)
+	}
+}
+
+func TestRedisCacheDelete(t *testing.T) {
+	c, _ := newTestRedisCache(t)
+	ctx := context.Background()
+
+	c.Set(ctx, "a", "", "1")
+	c.Set(ctx, "b", "", "2")
+	if err := c.Delete(ctx, "a", ""); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := c.Get(ctx, "a", ""); err == nil {
+		t.Fatal("deleted entry still served")
+	}
+	if got, _ := c.Get(ctx, "b", ""); got != "2" {
+		t.Fatalf("other entry: %q", got)
+	}
+	// 刪除不存在的條目不是錯誤
+	if err := c.Delete(ctx, "missing", ""); err != nil {
+		t.Fatalf("Delete missing: %v", err)
+	}
+}
+
+func TestRedisCachePurge(t *testing.T) {
+	c, mr := newTestRedisCache(t)
+	generate := WithEndpoint(context.Background(), "/api/v1/recipe/generate")
+	food := WithEndpoint(context.Background(), "/api/v1/recipe/food")
+
+	c.Set(generate, "g1", "", "1")
+	c.Set(generate, "g2", "", "2")
+	c.Set(food, "f1", "data:image/jpeg;base64,AAAA", "3")
+	// 前綴以外的鍵不受影響
+	mr.Set("other:key", "keep")
+
+	// 完整的鍵只符合該條目，鍵的前綴不會清除整個命名空間
+	for _, key := range []string{"text:", Key("g1", "")[:len(Key("g1", ""))-1]} {
+		if n, err := c.Purge(context.Background(), Filter{Key: key}); err != nil || n != 0 {
+			t.Fatalf("Purge by partial key %q: %d, %v", key, n, err)
+		}
+	}
+	n, err := c.Purge(context.Background(), Filter{Key: Key("g1", "")})
+	if err != nil || n != 1 {
+		t.Fatalf("Purge by key: %d, %v", n, err)
+	}
+	c.Set(generate, "g1", "", "1")
+
+	n, err = c.Purge(context.Background(), Filter{Prefix: "multimodal:"})
+	if err != nil || n != 1 {
+		t.Fatalf("Purge by prefix: %d, %v", n, err)
+	}
+
+	n, err = c.Purge(context.Background(), Filter{Endpoint: "/api/v1/recipe/generate"})
+	if err != nil || n != 2 {
+		t.Fatalf("Purge by endpoint: %d, %v", n, err)
+	}
+
+	c.Set(generate, "g3", "", "4")
+	n, err = c.Purge(context.Background(), Filter{})
+	if err != nil || n != 1 {
+		t.Fatalf("Purge all: %d, %v", n, err)
+	}
+	if !mr.Exists("other:key") {
+		t.Fatal("purge removed a key outside the prefix")
+	}
+	if entries, _ := c.Entries(context.Background(), Filter{}, 0); len(entries) != 0 {
+		t.Fatalf("entries after purge: %v", entries)
+	}
+}
@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+// 快照檔格式：magic(8) | version(uint32) | payload 長度(uint64) | payload SHA-256(32) | payload
+// payload 為 gzip 壓縮的 JSON 條目陣列，依最近使用到最久未使用排列
+const (
+	snapshotMagic   = "RGCACHE\x00"
+	snapshotVersion = 1
+	snapshotHeader  = len(snapshotMagic) + 4 + 8 + sha256.Size
+)
+
+var (
+	// errSnapshotCorrupt 快照檔損毀或校驗失敗
+	errSnapshotCorrupt = errors.New("cache snapshot corrupt")
+	// errSnapshotVersion 快照檔版本不相容
+	errSnapshotVersion = errors.New("cache snapshot version incompatible")
+)
+
+// snapshotEntry 快照中的單一條目
+type snapshotEntry struct {
+	Key         string    `json:"key"`
+	Value       string    `json:"value"`
+	ImageHash   string    `json:"image_hash,omitempty"`
+	Endpoint    string    `json:"endpoint,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastAccess  time.Time `json:"last_access"`
+	AccessCount int       `json:"access_count"`
+}
+
+// SaveSnapshot 將未過期的條目寫入快照檔；先寫暫存檔再改名，避免中途失敗留下半個檔案
+func (m *CacheManager) SaveSnapshot(path string) (int, error) {
+	entries := m.snapshotEntries()
+
+	var payload bytes.Buffer
+	zw := gzip.NewWriter(&payload)
+	if err := json.NewEncoder(zw).Encode(entries); err != nil {
+		return 0, fmt.Errorf("failed to encode cache snapshot: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return 0, fmt.Errorf("failed to compress cache snapshot: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	sum := sha256.Sum256(payload.Bytes())
+	w := bufio.NewWriter(tmp)
+	w.WriteString(snapshotMagic)
+	binary.Write(w, binary.BigEndian, uint32(snapshotVersion))
+	binary.Write(w, binary.BigEndian, uint64(payload.Len()))
+	w.Write(sum[:])
+	w.Write(payload.Bytes())
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("failed to write cache snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("failed to sync cache snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, fmt.Errorf("failed to close cache snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, fmt.Errorf("failed to replace cache snapshot: %w", err)
+	}
+	return len(entries), nil
+}
+
+// snapshotEntries 依最近使用順序收集未過期的條目，每次只鎖定一個分片
+func (m *CacheManager) snapshotEntries() []snapshotEntry {
+	now := time.Now()
+	var entries []snapshotEntry
+	for _, s := range m.shards {
+		s.mu.Lock()
+		for el := s.lru.Front(); el != nil; el = el.Next() {
+			e := el.Value.(*cacheEntry)
+			if now.After(e.expiresAt) {
+				continue
+			}
+			entries = append(entries, snapshotEntry{
+				Key:         e.key,
+				Value:       e.value,
+				ImageHash:   e.imageHash,
+				Endpoint:    e.endpoint,
+				ExpiresAt:   e.expiresAt,
+				CreatedAt:   e.createdAt,
+				LastAccess:  e.lastAccess,
+				AccessCount: e.accessCount,
+			})
+		}
+		s.mu.Unlock()
+	}
+	return entries
+}
+
+// LoadSnapshot 從快照檔載入未過期的條目，回傳載入數量；檔案不存在時不視為錯誤
+func (m *CacheManager) LoadSnapshot(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read cache snapshot: %w", err)
+	}
+
+	entries, err := decodeSnapshot(data)
+	if err != nil {
+		return 0, err
+	}
+
+	// 從最久未使用的條目開始放入，讓 LRU 順序與保存時一致；容量不足時保留最近使用的條目
+	now := time.Now()
+	loaded := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Key == "" || now.After(e.ExpiresAt) {
+			continue
+		}
+		entry := &cacheEntry{
+			key:         e.Key,
+			value:       e.Value,
+			imageHash:   e.ImageHash,
+			endpoint:    e.Endpoint,
+			size:        int64(len(e.Key)+len(e.Value)) + entryOverhead,
+			expiresAt:   e.ExpiresAt,
+			createdAt:   e.CreatedAt,
+			lastAccess:  e.LastAccess,
+			accessCount: e.AccessCount,
+		}
+		if _, err := m.add(entry); err != nil {
+			continue
+		}
+		loaded++
+	}
+	return loaded, nil
+}
+
+// decodeSnapshot 驗證檔頭、版本與校驗碼後解析條目
+func decodeSnapshot(data []byte) ([]snapshotEntry, error) {
+	if len(data) < snapshotHeader || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, errSnapshotCorrupt
+	}
+	r := bytes.NewReader(data[len(snapshotMagic):])
+
+	var version uint32
+	var length uint64
+	binary.Read(r, binary.BigEndian, &version)
+	binary.Read(r, binary.BigEndian, &length)
+	if version != snapshotVersion {
+		return nil, fmt.Errorf("%w: got %d, want %d", errSnapshotVersion, version, snapshotVersion)
+	}
+
+	var sum [sha256.Size]byte
+	io.ReadFull(r, sum[:])
+	payload := data[snapshotHeader:]
+	if uint64(len(payload)) != length || sha256.Sum256(payload) != sum {
+		return nil, errSnapshotCorrupt
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errSnapshotCorrupt, err)
+	}
+	defer zr.Close()
+
+	var entries []snapshotEntry
+	if err := json.NewDecoder(zr).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("%w: %v", errSnapshotCorrupt, err)
+	}
+	return entries, nil
+}
+
+// saveSnapshot 寫入設定的快照檔並記錄日誌
+func (m *CacheManager) saveSnapshot() {
+	path := m.config.Cache.SnapshotPath
+	start := time.Now()
+	n, err := m.SaveSnapshot(path)
+	if err != nil {
+		common.LogWarn("快取快照寫入失敗", zap.String("path", path), zap.Error(err))
+		return
+	}
+	common.LogInfo("快取快照已寫入",
+		zap.String("path", path),
+		zap.Int("條目數", n),
+		zap.Duration("耗時", time.Since(start)),
+	)
+}
+
+// loadSnapshot 啟動時載入快照；損毀或版本不相容時只記錄警告，不阻擋啟動
+func (m *CacheManager) loadSnapshot() {
+	path := m.config.Cache.SnapshotPath
+	n, err := m.LoadSnapshot(path)
+	if err != nil {
+		common.LogWarn("忽略無法使用的快取快照", zap.String("path", path), zap.Error(err))
+		return
+	}
+	if n > 0 {
+		common.LogInfo("已從快照預熱快取", zap.String("path", path), zap.Int("條目數", n))
+	}
+}
+
+// snapshotLoop 定期寫入快照
+func (m *CacheManager) snapshotLoop() {
+	ticker := time.NewTicker(m.config.Cache.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.saveSnapshot()
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"recipe-generator/internal/infrastructure/config"
+)
+
+// newSnapshotManager 建立以 path 為快照檔的記憶體快取，建立時即從快照預熱
+func newSnapshotManager(t *testing.T, path string) *CacheManager {
+	t.Helper()
+	m := NewManager(&config.Config{Cache: config.CacheConfig{
+		Enabled:         true,
+		MaxBytes:        1 << 20,
+		Shards:          1,
+		TTL:             time.Hour,
+		CleanupInterval: time.Hour,
+		SnapshotPath:    path,
+	}})
+	t.Cleanup(func() {
+		m.config.Cache.SnapshotPath = ""
+		m.Close()
+	})
+	return m
+}
+
+// encodeTestSnapshot 依快照檔格式組出檔案內容，version 可指定為不相容的版本
+func encodeTestSnapshot(t *testing.T, version uint32, entries []snapshotEntry) []byte {
+	t.Helper()
+	var payload bytes.Buffer
+	zw := gzip.NewWriter(&payload)
+	if err := json.NewEncoder(zw).Encode(entries); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+
+	var buf bytes.Buffer
+	sum := sha256.Sum256(payload.Bytes())
+	buf.WriteString(snapshotMagic)
+	binary.Write(&buf, binary.BigEndian, version)
+	binary.Write(&buf, binary.BigEndian, uint64(payload.Len()))
+	buf.Write(sum[:])
+	buf.Write(payload.Bytes())
+	return buf.Bytes()
+}
+
+func keysOf(entries []snapshotEntry) []string {
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+	}
+	return keys
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	ctx := context.Background()
+
+	a := newSnapshotManager(t, path)
+	a.Set(WithEndpoint(ctx, "/recipe/generate"), "番茄炒蛋", "", `{"dish_name":"番茄炒蛋"}`)
+	a.Set(WithEndpoint(ctx, "/recipe/food"), "辨識", "data:image/jpeg;base64,AAAA", `{"recognized_foods":[]}`)
+	a.Set(ctx, "recipe-fingerprint:v1|番茄炒蛋", "", "番茄炒蛋")
+	a.Get(ctx, "番茄炒蛋", "") // 移到最近使用
+
+	saved, err := a.SaveSnapshot(path)
+	if err != nil || saved != 3 {
+		t.Fatalf("SaveSnapshot = %d, %v", saved, err)
+	}
+
+	b := newSnapshotManager(t, path)
+	if got, want := keysOf(b.snapshotEntries()), keysOf(a.snapshotEntries()); !slices.Equal(got, want) {
+		t.Fatalf("LRU order after load = %v, want %v", got, want)
+	}
+	if v, err := b.Get(ctx, "辨識", "data:image/jpeg;base64,AAAA"); err != nil || v != `{"recognized_foods":[]}` {
+		t.Fatalf("multimodal entry: %q, %v", v, err)
+	}
+
+	entries, _ := b.Entries(ctx, Filter{Endpoint: "/recipe/generate"}, 0)
+	if len(entries) != 1 || entries[0].AccessCount != 1 {
+		t.Fatalf("endpoint or access count lost: %+v", entries)
+	}
+	if n, _ := b.Purge(ctx, Filter{Prefix: NamespaceRecipeFingerprint + ":"}); n != 1 {
+		t.Fatalf("derived namespace lost: purged %d", n)
+	}
+}
+
+func TestSnapshotSavedOnCloseAndLoadedOnStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "cache.snap")
+	ctx := context.Background()
+
+	a := NewManager(&config.Config{Cache: config.CacheConfig{
+		Enabled: true, MaxBytes: 1 << 20, Shards: 4, TTL: time.Hour, CleanupInterval: time.Hour, SnapshotPath: path,
+	}})
+	a.Set(ctx, "prompt", "", "value")
+	a.Close()
+
+	b := newSnapshotManager(t, path)
+	if v, err := b.Get(ctx, "prompt", ""); err != nil || v != "value" {
+		t.Fatalf("entry not restored: %q, %v", v, err)
+	}
+}
+
+func TestLoadSnapshotSkipsExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	now := time.Now()
+	entries := []snapshotEntry{
+		{Key: Key("fresh", ""), Value: "a", ExpiresAt: now.Add(time.Hour), CreatedAt: now, LastAccess: now},
+		{Key: Key("expired", ""), Value: "b", ExpiresAt: now.Add(-time.Second), CreatedAt: now.Add(-time.Hour), LastAccess: now},
+		{Key: "", Value: "c", ExpiresAt: now.Add(time.Hour)},
+	}
+	if err := os.WriteFile(path, encodeTestSnapshot(t, snapshotVersion, entries), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newSnapshotManager(t, "")
+	n, err := m.LoadSnapshot(path)
+	if err != nil || n != 1 {
+		t.Fatalf("LoadSnapshot = %d, %v; want 1", n, err)
+	}
+	ctx := context.Background()
+	if _, err := m.Get(ctx, "expired", ""); err == nil {
+		t.Fatal("expired entry loaded")
+	}
+	if v, err := m.Get(ctx, "fresh", ""); err != nil || v != "a" {
+		t.Fatalf("fresh entry: %q, %v", v, err)
+	}
+}
+
+func TestLoadSnapshotRejectsBadFiles(t *testing.T) {
+	now := time.Now()
+	valid := encodeTestSnapshot(t, snapshotVersion, []snapshotEntry{
+		{Key: Key("prompt", ""), Value: "value", ExpiresAt: now.Add(time.Hour), CreatedAt: now, LastAccess: now},
+	})
+	flipped := append([]byte(nil), valid...)
+	flipped[len(flipped)-5] ^= 0xff
+	badMagic := append([]byte("NOTCACHE"), valid[len(snapshotMagic):]...)
+
+	// 校驗碼正確但內容不是 gzip
+	garbage := []byte("not gzip")
+	sum := sha256.Sum256(garbage)
+	var notGzip bytes.Buffer
+	notGzip.WriteString(snapshotMagic)
+	binary.Write(&notGzip, binary.BigEndian, uint32(snapshotVersion))
+	binary.Write(&notGzip, binary.BigEndian, uint64(len(garbage)))
+	notGzip.Write(sum[:])
+	notGzip.Write(garbage)
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", nil, errSnapshotCorrupt},
+		{"magic only", valid[:len(snapshotMagic)], errSnapshotCorrupt},
+		{"truncated header", valid[:snapshotHeader-1], errSnapshotCorrupt},
+		{"header only", valid[:snapshotHeader], errSnapshotCorrupt},
+		{"truncated payload", valid[:len(valid)-3], errSnapshotCorrupt},
+		{"trailing bytes", append(append([]byte(nil), valid...), 0), errSnapshotCorrupt},
+		{"checksum mismatch", flipped, errSnapshotCorrupt},
+		{"bad magic", badMagic, errSnapshotCorrupt},
+		{"not gzip", notGzip.Bytes(), errSnapshotCorrupt},
+		{"newer version", encodeTestSnapshot(t, snapshotVersion+1, nil), errSnapshotVersion},
+		{"older version", encodeTestSnapshot(t, 0, nil), errSnapshotVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cache.snap")
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			m := newSnapshotManager(t, "")
+			if n, err := m.LoadSnapshot(path); !errors.Is(err, tt.want) || n != 0 {
+				t.Fatalf("LoadSnapshot = %d, %v; want %v", n, err, tt.want)
+			}
+
+			// 啟動時遇到無法使用的快照只記錄警告，快取照常運作
+			started := newSnapshotManager(t, path)
+			if entries := started.snapshotEntries(); len(entries) != 0 {
+				t.Fatalf("loaded %d entries from a bad snapshot", len(entries))
+			}
+			if err := started.Set(context.Background(), "prompt", "", "value"); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestLoadSnapshotMissingFile(t *testing.T) {
+	m := newSnapshotManager(t, "")
+	if n, err := m.LoadSnapshot(filepath.Join(t.TempDir(), "missing.snap")); n != 0 || err != nil {
+		t.Fatalf("LoadSnapshot = %d, %v", n, err)
+	}
+}
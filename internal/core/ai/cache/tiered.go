@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// TieredCache 二級快取：本地 LRU 服務熱門項目，未命中時回退到共用的 Redis，
+// 並透過 Redis pub/sub 廣播刪除與清空，讓所有實例的本地快取同步失效
+type TieredCache struct {
+	local    *CacheManager
+	remote   *RedisCache
+	instance string
+
+	pubsub *redis.PubSub
+	stop   context.CancelFunc
+	done   chan struct{}
+
+	localHits     atomic.Int64
+	remoteHits    atomic.Int64
+	misses        atomic.Int64
+	invalidations atomic.Int64
+}
+
+var _ Cache = (*TieredCache)(nil)
+
+// NewTieredCache 建立二級快取；本地層使用 CACHE_LOCAL_TTL 限制與共用層不一致的時間
+func NewTieredCache(cfg *config.Config) (*TieredCache, error) {
+	remote, err := NewRedisCache(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// 共用層本身即可持久化，本地層不寫快照
+	localCfg := *cfg
+	localCfg.Cache.SnapshotPath = ""
+	localCfg.Cache.TTL = cfg.Cache.LocalTTL
+	if localCfg.Cache.TTL <= 0 || localCfg.Cache.TTL > cfg.Cache.TTL {
+		localCfg.Cache.TTL = cfg.Cache.TTL
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	c := &TieredCache{
+		local:    NewManager(&localCfg),
+		remote:   remote,
+		instance: common.GenerateUUID(),
+		stop:     stop,
+		done:     make(chan struct{}),
+	}
+
+	c.pubsub = remote.client.Subscribe(ctx, remote.invalidationChannel())
+	// 等待訂閱確認，確保建立後的失效通知不會遺漏
+	if _, err := c.pubsub.Receive(ctx); err != nil {
+		stop()
+		c.pubsub.Close()
+		remote.Close()
+		return nil, err
+	}
+	go c.listen(ctx)
+
+	common.LogInfo("二級快取已初始化",
+		zap.Duration("local_ttl", localCfg.Cache.TTL),
+		zap.Int64("local_max_bytes", localCfg.Cache.MaxBytes),
+		zap.String("instance", c.instance),
+	)
+	return c, nil
+}
+
+// Get 實作 Cache：先查本地，再查 Redis 並回填本地
+func (c *TieredCache) Get(ctx context.Context, prompt, imageData string) (string, error) {
+	if val, err := c.local.Get(ctx, prompt, imageData); err == nil && val != "" {
+		c.localHits.Add(1)
+		return val, nil
+	}
+
+	val, err := c.remote.Get(ctx, prompt, imageData)
+	if err != nil {
+		c.misses.Add(1)
+		return "", err
+	}
+	c.remoteHits.Add(1)
+	_ = c.local.Set(ctx, prompt, imageData, val)
+	return val, nil
+}
+
+// Set 實作 Cache：寫入 Redis 與本地
+func (c *TieredCache) Set(ctx context.Context, prompt, imageData, value string) error {
+	if err := c.remote.Set(ctx, prompt, imageData, value); err != nil {
+		return err
+	}
+	return c.local.Set(ctx, prompt, imageData, value)
+}
+
+// Delete 實作 Cache：刪除兩層並通知其他實例
+func (c *TieredCache) Delete(ctx context.Context, prompt, imageData string) error {
+	key := Key(prompt, imageData)
+	err := c.remote.Delete(ctx, prompt, imageData)
+	c.local.deleteKey(key)
+	c.publish(ctx, Filter{Key: key})
+	return err
+}
+
+// Entries 實作 Cache，以共用層為準
+func (c *TieredCache) Entries(ctx context.Context, filter Filter, limit int) ([]EntryInfo, error) {
+	return c.remote.Entries(ctx, filter, limit)
+}
+
+// Purge 實作 Cache：清除兩層並通知其他實例，回傳共用層刪除的數量
+func (c *TieredCache) Purge(ctx context.Context, filter Filter) (int, error) {
+	count, err := c.remote.Purge(ctx, filter)
+	_, _ = c.local.Purge(ctx, filter)
+	c.publish(ctx, filter)
+	return count, err
+}
+
+// publish 廣播失效通知，失敗只記錄日誌（其他實例的本地快取最遲於本地 TTL 後過期）
+// 訊息格式：<來源實例>|<鍵前綴>|<端點>|<完整的鍵>，對應一次 Purge
+func (c *TieredCache) publish(ctx context.Context, filter Filter) {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	msg := c.instance + "|" + filter.Prefix + "|" + filter.Endpoint + "|" + filter.Key
+	if err := c.remote.client.Publish(ctx, c.remote.invalidationChannel(), msg).Err(); err != nil {
+		common.LogWarn("快取失效通知發送失敗",
+			zap.String("prefix", filter.Prefix),
+			zap.String("endpoint", filter.Endpoint),
+			zap.String("key", filter.Key),
+			zap.Error(err),
+		)
+	}
+}
+
+// listen 接收其他實例的失效通知並清除本地快取
+func (c *TieredCache) listen(ctx context.Context) {
+	defer close(c.done)
+
+	ch := c.pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			c.apply(ctx, msg.Payload)
+		}
+	}
+}
+
+// apply 套用一則失效通知；自己發出的通知已在本地處理過
+// 滾動更新期間舊版實例的通知沒有完整的鍵欄位，仍依前綴與端點清除
+func (c *TieredCache) apply(ctx context.Context, payload string) {
+	parts := strings.SplitN(payload, "|", 4)
+	if len(parts) < 3 {
+		common.LogWarn("無法識別的快取失效通知", zap.String("payload", payload))
+		return
+	}
+	if parts[0] == c.instance {
+		return
+	}
+
+	filter := Filter{Prefix: parts[1], Endpoint: parts[2]}
+	if len(parts) == 4 {
+		filter.Key = parts[3]
+	}
+	n, _ := c.local.Purge(ctx, filter)
+	c.invalidations.Add(int64(n))
+}
+
+// GetStats 實作 Cache，依層級分別統計
+func (c *TieredCache) GetStats() map[string]interface{} {
+	localHits, remoteHits, misses := c.localHits.Load(), c.remoteHits.Load(), c.misses.Load()
+	total := localHits + remoteHits + misses
+	ratio := 0.0
+	if total > 0 {
+		ratio = float64(localHits+remoteHits) / float64(total)
+	}
+	return map[string]interface{}{
+		"backend":       BackendTiered,
+		"hits":          localHits + remoteHits,
+		"misses":        misses,
+		"hit_ratio":     ratio,
+		"invalidations": c.invalidations.Load(),
+		"local":         c.local.GetStats(),
+		"remote":        c.remote.GetStats(),
+	}
+}
+
+// Close 實作 Cache
+func (c *TieredCache) Close() error {
+	c.stop()
+	err := c.pubsub.Close()
+	<-c.done
+	c.local.Close()
+	if rerr := c.remote.Close(); err == nil {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"recipe-generator/internal/infrastructure/config"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestTieredCache 建立連到同一個 miniredis 的二級快取，模擬多個實例
+func newTestTieredCache(t *testing.T, mr *miniredis.Miniredis) *TieredCache {
+	t.Helper()
+	c, err := NewTieredCache(&config.Config{Cache: config.CacheConfig{
+		Enabled:         true,
+		MaxBytes:        1 << 20,
+		Shards:          1,
+		TTL:             time.Hour,
+		LocalTTL:        time.Minute,
+		CleanupInterval: time.Hour,
+		Redis: config.RedisConfig{
+			Addr:   mr.Addr(),
+			Prefix: "test:",
+		},
+	}})
+	if err != nil {
+		t.Fatalf("NewTieredCache: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// waitInvalidations 等待實例收到指定數量的失效通知
+func waitInvalidations(t *testing.T, c *TieredCache, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for c.invalidations.Load() < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("invalidations = %d, want %d", c.invalidations.Load(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// localValue 只查本地層
+func localValue(c *TieredCache, prompt string) string {
+	val, _ := c.local.Get(context.Background(), prompt, "")
+	return val
+}
+
+func TestTieredCacheStatsPerTier(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a, b := newTestTieredCache(t, mr), newTestTieredCache(t, mr)
+	ctx := context.Background()
+
+	if err := a.Set(ctx, "prompt", "", "value"); err != nil {
+		t.Fatal(err)
+	}
+	// b 第一次從共用層取得並回填本地，第二次由本地命中
+	for i := 0; i < 2; i++ {
+		if got, err := b.Get(ctx, "prompt", ""); err != nil || got != "value" {
+			t.Fatalf("Get %d: %q, %v", i, got, err)
+		}
+	}
+	if _, err := b.Get(ctx, "missing", ""); err == nil {
+		t.Fatal("Get missing: want miss")
+	}
+
+	stats := b.GetStats()
+	if stats["hits"].(int64) != 2 || stats["misses"].(int64) != 1 {
+		t.Fatalf("stats: %v", stats)
+	}
+	local := stats["local"].(map[string]interface{})
+	remote := stats["remote"].(map[string]interface{})
+	if local["hits"].(int64) != 1 || local["size"].(int) != 1 {
+		t.Fatalf("local stats: %v", local)
+	}
+	if remote["hits"].(int64) != 1 || remote["misses"].(int64) != 1 {
+		t.Fatalf("remote stats: %v", remote)
+	}
+}
+
+func TestTieredCacheInvalidatesOtherInstances(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a, b := newTestTieredCache(t, mr), newTestTieredCache(t, mr)
+	ctx := WithEndpoint(context.Background(), "/api/v1/recipe/generate")
+
+	for _, p := range []string{"one", "two", "three"} {
+		if err := a.Set(ctx, p, "", p); err != nil {
+			t.Fatal(err)
+		}
+		b.Get(ctx, p, "")
+	}
+	if localValue(b, "one") != "one" {
+		t.Fatal("entry not backfilled into b's local tier")
+	}
+
+	// a 刪除後 b 的本地副本也須失效，不可繼續回傳舊值
+	if err := a.Delete(ctx, "one", ""); err != nil {
+		t.Fatal(err)
+	}
+	waitInvalidations(t, b, 1)
+	if localValue(b, "one") != "" {
+		t.Fatal("deleted entry still in b's local tier")
+	}
+	if got, err := b.Get(ctx, "one", ""); err == nil {
+		t.Fatalf("Get deleted entry: %q", got)
+	}
+	if localValue(b, "two") != "two" {
+		t.Fatal("delete invalidated unrelated entries")
+	}
+
+	// 依端點清除同樣廣播到其他實例
+	if _, err := a.Purge(ctx, Filter{Endpoint: "/api/v1/recipe/generate"}); err != nil {
+		t.Fatal(err)
+	}
+	waitInvalidations(t, b, 3)
+	if localValue(b, "two") != "" || localValue(b, "three") != "" {
+		t.Fatal("purged entries still in b's local tier")
+	}
+
+	// a 自己發出的通知已在本地處理，不計入失效數量
+	if n := a.invalidations.Load(); n != 0 {
+		t.Fatalf("instance applied its own invalidations: %d", n)
+	}
+}
+
+func TestTieredCacheIgnoresOwnMessages(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a := newTestTieredCache(t, mr)
+	ctx := context.Background()
+
+	if err := a.Set(ctx, "prompt", "", "value"); err != nil {
+		t.Fatal(err)
+	}
+	key := Key("prompt", "")
+	a.apply(ctx, a.instance+"|"+key+"|")
+	if localValue(a, "prompt") != "value" || a.invalidations.Load() != 0 {
+		t.Fatal("own invalidation message purged the local tier")
+	}
+
+	// 其他實例的通知與格式錯誤的通知
+	a.apply(ctx, "garbage")
+	if localValue(a, "prompt") != "value" {
+		t.Fatal("malformed message purged the local tier")
+	}
+	a.apply(ctx, "other|"+key+"|")
+	if localValue(a, "prompt") != "" || a.invalidations.Load() != 1 {
+		t.Fatal("message from another instance not applied")
+	}
+
+	// 刪除單一條目的通知只比對完整的鍵
+	a.Set(ctx, "prompt", "", "value")
+	a.apply(ctx, "other|||text:")
+	if localValue(a, "prompt") != "value" {
+		t.Fatal("partial key in an exact-key message purged the local tier")
+	}
+	a.apply(ctx, "other|||"+key)
+	if localValue(a, "prompt") != "" || a.invalidations.Load() != 2 {
+		t.Fatal("exact-key message not applied")
+	}
+}
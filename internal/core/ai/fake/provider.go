@@ -0,0 +1,152 @@
+package fake
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"recipe-generator/internal/core/ai/provider"
+)
+
+// ProviderName 假提供者註冊名稱
+const ProviderName = "fake"
+
+func init() {
+	provider.Register(ProviderName, func(cfg provider.Config) (provider.Provider, error) {
+		return New(cfg)
+	})
+}
+
+// 依 prompt 內容回傳的固定回應，涵蓋所有端點的 JSON 結構
+const (
+	foodResponse = `{"recognized_foods":[{"name":"番茄炒蛋","description":"以番茄與雞蛋拌炒的家常菜","confidence":0.92,"bounding_box":{"x":0.18,"y":0.22,"width":0.6,"height":0.5},"possible_ingredients":[{"name":"番茄","type":"蔬菜"},{"name":"雞蛋","type":"蛋類"}],"possible_equipment":[{"name":"炒鍋","type":"鍋具"}]}]}`
+
+	ingredientResponse = `{"ingredients":[{"name":"番茄","type":"蔬菜","amount":"2","unit":"顆","preparation":"洗淨","confidence":0.94,"bounding_box":{"x":0.1,"y":0.3,"width":0.25,"height":0.3}},{"name":"雞蛋","type":"蛋類","amount":"3","unit":"顆","preparation":"無特殊處理","confidence":0.62,"bounding_box":{"x":0.42,"y":0.35,"width":0.2,"height":0.22}}],"equipment":[{"name":"平底鍋","type":"鍋具","size":"中型","material":"不沾","power_source":"瓦斯","confidence":0.88,"bounding_box":{"x":0.55,"y":0.5,"width":0.4,"height":0.35}}],"summary":"番茄兩顆、雞蛋三顆與一個平底鍋"}`
+
+	recipeResponse = `{"dish_name":"番茄炒蛋","dish_description":"酸甜開胃的經典家常菜","ingredients":[{"name":"番茄","type":"蔬菜","amount":"2","unit":"顆","preparation":"切塊"},{"name":"雞蛋","type":"蛋類","amount":"3","unit":"顆","preparation":"打散"}],"equipment":[{"name":"平底鍋","type":"鍋具","size":"中型","material":"不沾","power_source":"瓦斯"}],"recipe":[{"step_number":1,"ARtype":"beatEgg","ar_parameters":{"type":"beatEgg","container":"bowl","ingredient":null,"color":null,"time":null,"temperature":null,"flameLevel":null},"title":"打蛋","description":"將雞蛋打入碗中攪散","actions":[{"action":"打蛋","tool_required":"筷子","material_required":["雞蛋"],"time_minutes":60,"instruction_detail":"打至蛋白蛋黃均勻混合"}],"estimated_total_time":"1分鐘","temperature":"常溫","warnings":"無","notes":"無備註"},{"step_number":2,"ARtype":"cut","ar_parameters":{"type":"cut","container":"","ingredient":"tomato","color":null,"time":null,"temperature":null,"flameLevel":null},"title":"切番茄","description":"將番茄切成小塊","actions":[{"action":"切塊","tool_required":"菜刀","material_required":["番茄"],"time_minutes":120,"instruction_detail":"切成約兩公分大小"}],"estimated_total_time":"2分鐘","temperature":"常溫","warnings":"注意刀具安全","notes":"無備註"},{"step_number":3,"ARtype":"stir","ar_parameters":{"type":"stir","container":"pan","ingredient":"tomato,egg","color":null,"time":null,"temperature":null,"flameLevel":null},"title":"拌炒","description":"於平底鍋中拌炒番茄與蛋液","actions":[{"action":"拌炒","tool_required":"鍋鏟","material_required":["番茄","雞蛋"],"time_minutes":180,"instruction_detail":"中火拌炒至蛋液凝固"}],"estimated_total_time":"3分鐘","temperature":"中火","warnings":"避免油溫過高","notes":"無備註"}]}`
+
+	qaResponse = `{"answer":"先將番茄去籽並以大火快炒，縮短加熱時間即可減少出水。","key_points":["番茄去籽","大火快炒"],"confidence":0.8}`
+)
+
+// Provider 回傳固定內容的假 AI 提供者，供離線開發與測試使用；
+// 設定腳本時依腳本回應並注入模型錯誤，用於混沌測試
+type Provider struct {
+	config   provider.Config
+	scenario *Scenario
+	retry    provider.RetryPolicy
+}
+
+// New 創建假提供者，cfg.Scenario 不為空時載入腳本
+func New(cfg provider.Config) (*Provider, error) {
+	if cfg.Model == "" {
+		cfg.Model = "fake-model"
+	}
+	p := &Provider{config: cfg}
+	if cfg.Scenario != "" {
+		s, err := LoadScenario(cfg.Scenario)
+		if err != nil {
+			return nil, err
+		}
+		p.scenario = s
+		p.retry = provider.DefaultRetryPolicy(s.MaxRetries)
+	}
+	return p, nil
+}
+
+// Generate 實作 provider.Provider，依 prompt 判斷端點並回傳對應的固定 JSON
+func (p *Provider) Generate(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var prompt strings.Builder
+	for _, m := range req.Messages {
+		prompt.WriteString(m.Content)
+	}
+	text := prompt.String()
+
+	content, err := p.respond(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &provider.Response{Content: content, Model: provider.ModelFor(p, req)}
+	resp.Usage.PromptTokens = estimateTokens(text)
+	resp.Usage.CompletionTokens = estimateTokens(resp.Content)
+	resp.Usage.TotalTokens = resp.Usage.PromptTokens + resp.Usage.CompletionTokens
+	return resp, nil
+}
+
+// respond 沒有腳本時回傳固定內容，有腳本時依腳本回應，注入的 429 與 5xx 與實際提供者相同會重試
+func (p *Provider) respond(ctx context.Context, prompt string) (string, error) {
+	if p.scenario == nil {
+		return Respond(prompt), nil
+	}
+	var content string
+	err := p.retry.Do(ctx, "Fake Generate", func(attempt int) error {
+		var err error
+		content, err = p.scenario.respond(ctx, prompt)
+		return err
+	})
+	return content, err
+}
+
+// streamChunkRunes 串流時每段內容的字元數
+const streamChunkRunes = 32
+
+// GenerateStream 實作 provider.StreamProvider，將固定回應切段送出
+func (p *Provider) GenerateStream(ctx context.Context, req *provider.Request, onDelta provider.DeltaFunc) (*provider.Response, error) {
+	resp, err := p.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	runes := []rune(resp.Content)
+	for start := 0; start < len(runes); start += streamChunkRunes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := min(start+streamChunkRunes, len(runes))
+		if err := onDelta(string(runes[start:end])); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// Respond 依 prompt 中的欄位關鍵字選擇固定回應
+func Respond(prompt string) string {
+	switch {
+	case strings.Contains(prompt, "recognized_foods"):
+		return foodResponse
+	case strings.Contains(prompt, `"answer"`):
+		return qaResponse
+	case strings.Contains(prompt, "dish_name"):
+		return recipeResponse
+	case strings.Contains(prompt, "ingredients") && strings.Contains(prompt, "summary"):
+		return ingredientResponse
+	default:
+		return `{}`
+	}
+}
+
+// GetModel 實作 provider.Provider
+func (p *Provider) GetModel() string {
+	return p.config.Model
+}
+
+// GetTimeout 實作 provider.Provider
+func (p *Provider) GetTimeout() time.Duration {
+	return p.config.Timeout
+}
+
+// Close 實作 provider.Provider
+func (p *Provider) Close() error {
+	return nil
+}
+
+// estimateTokens 以字元數粗估 token 數量
+func estimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 3) / 4
+}
@@ -0,0 +1,435 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+// 錯誤注入的預設值
+const (
+	defaultSlowDelay  = 5 * time.Second
+	defaultRetryAfter = time.Second
+	invalidARType     = "boil" // 不在 ARtype 白名單中的類型
+)
+
+// Duration 以字串表示的時間長度，例如 "1.5s"
+type Duration time.Duration
+
+// UnmarshalJSON 解析 time.ParseDuration 格式的字串
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"2s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if v < 0 {
+		return fmt.Errorf("duration must not be negative: %s", s)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Faults 各種模型錯誤的注入機率（0~1），每次回應獨立抽樣
+type Faults struct {
+	RateLimit    float64  `json:"rate_limit,omitempty"`    // 回傳 429 與 Retry-After
+	ServerError  float64  `json:"server_error,omitempty"`  // 回傳 503
+	Slow         float64  `json:"slow,omitempty"`          // 延遲 slow_delay 後才回應
+	SlowDelay    Duration `json:"slow_delay,omitempty"`    // 預設 5s
+	WrongARType  float64  `json:"wrong_ar_type,omitempty"` // 把一個步驟的 ar_parameters.type 換成不存在的類型
+	ExtraActions float64  `json:"extra_actions,omitempty"` // 在一個步驟加入多餘的 action
+	UnquotedKeys float64  `json:"unquoted_keys,omitempty"` // 移除鍵的雙引號
+	Fence        float64  `json:"fence,omitempty"`         // 加上說明文字並以 Markdown 程式碼區塊包住
+	Truncate     float64  `json:"truncate,omitempty"`      // 從中間截斷回應
+}
+
+func (f *Faults) validate() error {
+	for _, fault := range []struct {
+		name string
+		p    float64
+	}{
+		{"rate_limit", f.RateLimit},
+		{"server_error", f.ServerError},
+		{"slow", f.Slow},
+		{"wrong_ar_type", f.WrongARType},
+		{"extra_actions", f.ExtraActions},
+		{"unquoted_keys", f.UnquotedKeys},
+		{"fence", f.Fence},
+		{"truncate", f.Truncate},
+	} {
+		if fault.p < 0 || fault.p > 1 {
+			return fmt.Errorf("fault %s must be between 0 and 1", fault.name)
+		}
+	}
+	return nil
+}
+
+// Rule 腳本規則，依序比對，第一個符合的規則生效
+type Rule struct {
+	Name         string          `json:"name"`
+	Template     string          `json:"template,omitempty"`      // 提示詞模板 ID，例如 recipe.generate
+	Match        string          `json:"match,omitempty"`         // 比對提示詞內容的正規表示式
+	Times        int             `json:"times,omitempty"`         // 只套用於前幾次符合的呼叫，0 表示不限
+	Response     json.RawMessage `json:"response,omitempty"`      // 回應內容：字串原樣回傳（可為不合法的 JSON），物件或陣列序列化後回傳
+	ResponseFile string          `json:"response_file,omitempty"` // 回應內容檔，相對於腳本檔所在目錄
+	Delay        Duration        `json:"delay,omitempty"`         // 回應前的延遲
+	Status       int             `json:"status,omitempty"`        // 以此 HTTP 狀態碼失敗，例如 429、503
+	RetryAfter   Duration        `json:"retry_after,omitempty"`   // 失敗時的 Retry-After
+	Faults       *Faults         `json:"faults,omitempty"`        // 覆寫全域的錯誤注入機率
+
+	pattern  *regexp.Regexp
+	content  string
+	hasReply bool
+	hits     int
+}
+
+// Scenario 假提供者的腳本：依模板與提示詞比對規則，回傳固定內容、延遲或錯誤狀態碼，
+// 並依機率注入截斷、Markdown、未加引號的鍵、錯誤的 AR 類型、多餘的 action、429 與慢速回應
+type Scenario struct {
+	Seed       int64   `json:"seed"`        // 亂數種子，0 表示每次啟動不同
+	MaxRetries int     `json:"max_retries"` // 暫時性錯誤（429、5xx）的重試次數，與實際提供者相同依 Retry-After 等待
+	Faults     Faults  `json:"faults"`      // 沒有規則符合或規則未指定時的錯誤注入機率
+	Rules      []*Rule `json:"rules"`
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// LoadScenario 讀取並檢查腳本檔
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fake scenario: %w", err)
+	}
+	var s Scenario
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse fake scenario: %w", err)
+	}
+	if s.MaxRetries < 0 {
+		return nil, errors.New("fake scenario: max_retries must not be negative")
+	}
+	if err := s.Faults.validate(); err != nil {
+		return nil, fmt.Errorf("fake scenario: %w", err)
+	}
+	for i, r := range s.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if err := r.prepare(filepath.Dir(path)); err != nil {
+			return nil, fmt.Errorf("fake scenario rule %q: %w", r.Name, err)
+		}
+	}
+
+	seed := s.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	s.rng = rand.New(rand.NewSource(seed))
+
+	common.LogInfo("假提供者腳本已載入",
+		zap.String("file", path),
+		zap.Int("rules", len(s.Rules)),
+		zap.Int64("seed", seed),
+	)
+	return &s, nil
+}
+
+// prepare 編譯比對規則並載入回應內容
+func (r *Rule) prepare(dir string) error {
+	if r.Match != "" {
+		re, err := regexp.Compile(r.Match)
+		if err != nil {
+			return fmt.Errorf("invalid match: %w", err)
+		}
+		r.pattern = re
+	}
+	if r.Times < 0 {
+		return errors.New("times must not be negative")
+	}
+	if r.Status != 0 && (r.Status < 400 || r.Status > 599) {
+		return fmt.Errorf("invalid status %d", r.Status)
+	}
+	if r.Faults != nil {
+		if err := r.Faults.validate(); err != nil {
+			return err
+		}
+	}
+
+	if len(r.Response) > 0 && r.ResponseFile != "" {
+		return errors.New("response and response_file are mutually exclusive")
+	}
+	switch {
+	case r.ResponseFile != "":
+		path := r.ResponseFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read response file: %w", err)
+		}
+		r.content, r.hasReply = string(data), true
+	case len(r.Response) > 0:
+		if r.Response[0] == '"' {
+			if err := json.Unmarshal(r.Response, &r.content); err != nil {
+				return fmt.Errorf("invalid response: %w", err)
+			}
+		} else {
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, r.Response); err != nil {
+				return fmt.Errorf("invalid response: %w", err)
+			}
+			r.content = buf.String()
+		}
+		r.hasReply = true
+	}
+	return nil
+}
+
+// matches 判斷規則是否符合模板 ID 與提示詞
+func (r *Rule) matches(templateID, prompt string) bool {
+	if r.Template != "" && r.Template != templateID {
+		return false
+	}
+	if r.pattern != nil && !r.pattern.MatchString(prompt) {
+		return false
+	}
+	return r.Times == 0 || r.hits < r.Times
+}
+
+// match 找出第一個符合的規則並計數
+func (s *Scenario) match(templateID, prompt string) *Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.Rules {
+		if r.matches(templateID, prompt) {
+			r.hits++
+			return r
+		}
+	}
+	return nil
+}
+
+// chance 以機率 p 回傳 true
+func (s *Scenario) chance(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rng.Float64() < p
+}
+
+// intn 回傳 [0, n) 的亂數
+func (s *Scenario) intn(n int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rng.Intn(n)
+}
+
+// respond 依腳本產生單次呼叫的回應內容或錯誤
+func (s *Scenario) respond(ctx context.Context, prompt string) (string, error) {
+	templateID, _, _ := strings.Cut(provider.MetadataFromContext(ctx).Template(), "@")
+	rule := s.match(templateID, prompt)
+	faults := s.Faults
+	name := ""
+	if rule != nil {
+		name = rule.Name
+		if rule.Faults != nil {
+			faults = *rule.Faults
+		}
+	}
+
+	var injected []string
+	defer func() {
+		if rule != nil || len(injected) > 0 {
+			common.LogInfo("假提供者套用腳本",
+				zap.String("rule", name),
+				zap.String("template", templateID),
+				zap.Strings("faults", injected),
+			)
+		}
+	}()
+
+	if rule != nil {
+		if err := sleep(ctx, time.Duration(rule.Delay)); err != nil {
+			return "", err
+		}
+		if rule.Status != 0 {
+			injected = append(injected, fmt.Sprintf("status_%d", rule.Status))
+			return "", statusError(rule.Status, time.Duration(rule.RetryAfter))
+		}
+	}
+	if s.chance(faults.RateLimit) {
+		injected = append(injected, "rate_limit")
+		return "", statusError(http.StatusTooManyRequests, defaultRetryAfter)
+	}
+	if s.chance(faults.ServerError) {
+		injected = append(injected, "server_error")
+		return "", statusError(http.StatusServiceUnavailable, 0)
+	}
+	if s.chance(faults.Slow) {
+		injected = append(injected, "slow")
+		delay := time.Duration(faults.SlowDelay)
+		if delay == 0 {
+			delay = defaultSlowDelay
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return "", err
+		}
+	}
+
+	content := Respond(prompt)
+	if rule != nil && rule.hasReply {
+		content = rule.content
+	}
+
+	// 先修改 JSON 內容，再破壞格式
+	if s.chance(faults.WrongARType) {
+		if mutated, ok := s.mutateRecipe(content, s.wrongARType); ok {
+			content = mutated
+			injected = append(injected, "wrong_ar_type")
+		}
+	}
+	if s.chance(faults.ExtraActions) {
+		if mutated, ok := s.mutateRecipe(content, s.extraActions); ok {
+			content = mutated
+			injected = append(injected, "extra_actions")
+		}
+	}
+	if s.chance(faults.UnquotedKeys) {
+		content = unquoteKeys(content)
+		injected = append(injected, "unquoted_keys")
+	}
+	if s.chance(faults.Fence) {
+		content = "以下是您要求的結果：\n```json\n" + content + "\n```\n希望對您有幫助！"
+		injected = append(injected, "fence")
+	}
+	if s.chance(faults.Truncate) {
+		content = s.truncate(content)
+		injected = append(injected, "truncate")
+	}
+	return content, nil
+}
+
+// mutateRecipe 解析食譜 JSON 並修改其中一個步驟，內容不是有步驟的食譜時不修改
+func (s *Scenario) mutateRecipe(content string, mutate func(step map[string]interface{})) (string, bool) {
+	var recipe map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &recipe); err != nil {
+		return content, false
+	}
+	steps, _ := recipe["recipe"].([]interface{})
+	if len(steps) == 0 {
+		return content, false
+	}
+	step, ok := steps[s.intn(len(steps))].(map[string]interface{})
+	if !ok {
+		return content, false
+	}
+	mutate(step)
+
+	data, err := json.Marshal(recipe)
+	if err != nil {
+		return content, false
+	}
+	return string(data), true
+}
+
+// wrongARType 把步驟的 ar_parameters.type（沒有參數時為 ARtype）換成不存在的類型
+func (s *Scenario) wrongARType(step map[string]interface{}) {
+	if params, ok := step["ar_parameters"].(map[string]interface{}); ok {
+		params["type"] = invalidARType
+		return
+	}
+	step["ARtype"] = invalidARType
+}
+
+// extraActions 複製步驟的 action，違反每個步驟只有一個 action 的要求
+func (s *Scenario) extraActions(step map[string]interface{}) {
+	actions, _ := step["actions"].([]interface{})
+	extra := map[string]interface{}{
+		"action":             "額外動作",
+		"tool_required":      nil,
+		"material_required":  []interface{}{},
+		"time_minutes":       30,
+		"instruction_detail": "模型多拆出的子動作",
+	}
+	if len(actions) > 0 {
+		if first, ok := actions[0].(map[string]interface{}); ok {
+			for k, v := range first {
+				if _, set := extra[k]; !set {
+					extra[k] = v
+				}
+			}
+		}
+	}
+	count := 1 + s.intn(2)
+	for i := 0; i < count; i++ {
+		actions = append(actions, extra)
+	}
+	step["actions"] = actions
+}
+
+// truncate 在 30%～90% 之間的位置截斷（以字元計算，不切斷 UTF-8）
+func (s *Scenario) truncate(content string) string {
+	runes := []rune(content)
+	if len(runes) < 2 {
+		return content
+	}
+	cut := len(runes) * (30 + s.intn(61)) / 100
+	if cut < 1 {
+		cut = 1
+	}
+	return string(runes[:cut])
+}
+
+// quotedKeyPattern JSON 中加上雙引號的鍵
+var quotedKeyPattern = regexp.MustCompile(`"([A-Za-z_][A-Za-z0-9_]*)"\s*:`)
+
+// unquoteKeys 移除鍵的雙引號，模擬輸出 JavaScript 物件寫法的模型
+func unquoteKeys(content string) string {
+	return quotedKeyPattern.ReplaceAllString(content, "$1:")
+}
+
+// statusError 模擬上游回傳的錯誤狀態
+func statusError(status int, retryAfter time.Duration) error {
+	return &provider.StatusError{
+		Provider:   "Fake",
+		StatusCode: status,
+		Body:       fmt.Sprintf(`{"error":{"message":"injected by fake scenario","code":%d}}`, status),
+		RetryAfter: retryAfter,
+	}
+}
+
+// sleep 等待 d，context 結束時提前返回
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,265 @@
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	common.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// writeScenario 在暫存目錄寫入腳本與其他檔案，回傳腳本路徑
+func writeScenario(t *testing.T, scenario string, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "scenario.json")
+	if err := os.WriteFile(path, []byte(scenario), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// seeded 建立使用固定亂數種子的腳本
+func seeded(seed int64) *Scenario {
+	return &Scenario{rng: rand.New(rand.NewSource(seed))}
+}
+
+// withTemplate 建立記錄了提示詞模板的 context，與服務層呼叫提供者前相同
+func withTemplate(template string) context.Context {
+	ctx, md := provider.WithMetadata(context.Background())
+	md.RecordTemplate(template)
+	return ctx
+}
+
+func TestScenarioRules(t *testing.T) {
+	path := writeScenario(t, `{
+		"seed": 1,
+		"rules": [
+			{"name": "qa-once", "template": "cook.qa", "times": 1, "response": {"answer": "腳本回答", "key_points": []}},
+			{"name": "busy", "template": "recipe.suggest", "status": 503, "retry_after": "2s"},
+			{"name": "tomato", "match": "番茄\\d+", "response": "not json {"},
+			{"template": "food.recognize", "response_file": "food.json"}
+		]
+	}`, map[string]string{"food.json": `{"recognized_foods":[{"name":"腳本食物"}]}`})
+	s, err := LoadScenario(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Rules[3].Name != "rule-4" {
+		t.Fatalf("unnamed rule: %q", s.Rules[3].Name)
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		prompt   string
+		want     string
+	}{
+		// 物件回應序列化後回傳；times 用完後改回固定回應
+		{"rule object", "cook.qa@v1", `"answer"`, `{"answer":"腳本回答","key_points":[]}`},
+		{"times exhausted", "cook.qa@v1", `"answer"`, qaResponse},
+		// 字串回應原樣回傳，可用來模擬不合法的 JSON
+		{"match", "recipe.generate@v1", "番茄2顆 dish_name", "not json {"},
+		{"match needs pattern", "recipe.generate@v1", "番茄 dish_name", recipeResponse},
+		{"response file", "food.recognize@v2", "recognized_foods", `{"recognized_foods":[{"name":"腳本食物"}]}`},
+		{"no rule", "ingredient.recognize@v1", "ingredients summary", ingredientResponse},
+	}
+	for _, tt := range tests {
+		got, err := s.respond(withTemplate(tt.template), tt.prompt)
+		if err != nil || got != tt.want {
+			t.Fatalf("%s: respond = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	_, err = s.respond(withTemplate("recipe.suggest@v1"), "dish_name")
+	var se *provider.StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusServiceUnavailable || se.RetryAfter.Seconds() != 2 {
+		t.Fatalf("status rule: %v", err)
+	}
+}
+
+func TestLoadScenarioValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		scenario string
+		want     string
+	}{
+		{"invalid json", `{"rules":`, "failed to parse"},
+		{"negative retries", `{"max_retries":-1}`, "max_retries"},
+		{"fault probability", `{"faults":{"truncate":1.5}}`, "truncate"},
+		{"rule fault probability", `{"rules":[{"faults":{"fence":-0.1}}]}`, "fence"},
+		{"invalid match", `{"rules":[{"match":"("}]}`, "invalid match"},
+		{"negative times", `{"rules":[{"times":-1}]}`, "times"},
+		{"invalid status", `{"rules":[{"status":200}]}`, "invalid status"},
+		{"response and file", `{"rules":[{"response":"x","response_file":"food.json"}]}`, "mutually exclusive"},
+		{"missing file", `{"rules":[{"response_file":"missing.json"}]}`, "response file"},
+		{"duration not string", `{"rules":[{"delay":5}]}`, "duration must be a string"},
+		{"negative duration", `{"rules":[{"delay":"-1s"}]}`, "negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadScenario(writeScenario(t, tt.scenario, nil))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("LoadScenario: %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateKeepsUTF8(t *testing.T) {
+	s := seeded(42)
+	for i := 0; i < 50; i++ {
+		got := s.truncate(recipeResponse)
+		total, kept := utf8.RuneCountInString(recipeResponse), utf8.RuneCountInString(got)
+		if !utf8.ValidString(got) || !strings.HasPrefix(recipeResponse, got) {
+			t.Fatalf("truncate produced invalid prefix: %q", got)
+		}
+		if kept < total*30/100 || kept > total*90/100 {
+			t.Fatalf("truncate kept %d of %d runes", kept, total)
+		}
+	}
+	if got := s.truncate("蛋"); got != "蛋" {
+		t.Fatalf("single rune truncated to %q", got)
+	}
+
+	// 相同種子得到相同結果
+	if seeded(7).truncate(recipeResponse) != seeded(7).truncate(recipeResponse) {
+		t.Fatal("truncate not deterministic for a fixed seed")
+	}
+}
+
+func TestUnquoteKeys(t *testing.T) {
+	got := unquoteKeys(`{"answer":"時間: 3分鐘","key_points":["a"],"nested":{"x_1": 2}}`)
+	want := `{answer:"時間: 3分鐘",key_points:["a"],nested:{x_1: 2}}`
+	if got != want {
+		t.Fatalf("unquoteKeys = %s, want %s", got, want)
+	}
+	if json.Valid([]byte(unquoteKeys(recipeResponse))) {
+		t.Fatal("unquoted recipe still valid JSON")
+	}
+	// 服務層的修正可還原完整的食譜
+	if fixed := common.ExtractJSON(unquoteKeys(recipeResponse)); !json.Valid([]byte(fixed)) {
+		t.Fatalf("QuoteJSONKeys could not restore: %s", fixed)
+	}
+}
+
+// recipeSteps 解析食譜步驟
+func recipeSteps(t *testing.T, content string) []map[string]interface{} {
+	t.Helper()
+	var recipe struct {
+		Recipe []map[string]interface{} `json:"recipe"`
+	}
+	if err := json.Unmarshal([]byte(content), &recipe); err != nil {
+		t.Fatal(err)
+	}
+	return recipe.Recipe
+}
+
+func TestWrongARType(t *testing.T) {
+	s := seeded(3)
+	mutated, ok := s.mutateRecipe(recipeResponse, s.wrongARType)
+	if !ok {
+		t.Fatal("recipe not mutated")
+	}
+	wrong := 0
+	for _, step := range recipeSteps(t, mutated) {
+		params := step["ar_parameters"].(map[string]interface{})
+		if params["type"] == invalidARType {
+			wrong++
+			if step["ARtype"] == invalidARType {
+				t.Fatal("ARtype changed together with ar_parameters.type")
+			}
+		}
+	}
+	if wrong != 1 {
+		t.Fatalf("%d steps with wrong AR type", wrong)
+	}
+
+	// 沒有 ar_parameters 時改 ARtype
+	step := map[string]interface{}{"ARtype": "stir"}
+	s.wrongARType(step)
+	if step["ARtype"] != invalidARType {
+		t.Fatalf("step: %v", step)
+	}
+
+	// 不是食譜的內容不修改
+	for _, content := range []string{qaResponse, "not json", `{"recipe":[]}`} {
+		if got, ok := s.mutateRecipe(content, s.wrongARType); ok || got != content {
+			t.Fatalf("mutated %q to %q", content, got)
+		}
+	}
+}
+
+func TestExtraActions(t *testing.T) {
+	s := seeded(5)
+	mutated, ok := s.mutateRecipe(recipeResponse, s.extraActions)
+	if !ok {
+		t.Fatal("recipe not mutated")
+	}
+	extra := 0
+	for _, step := range recipeSteps(t, mutated) {
+		actions := step["actions"].([]interface{})
+		switch n := len(actions); {
+		case n == 1:
+		case n == 2 || n == 3:
+			extra++
+			added := actions[n-1].(map[string]interface{})
+			if added["action"] != "額外動作" || added["material_required"] == nil {
+				t.Fatalf("added action: %v", added)
+			}
+		default:
+			t.Fatalf("step has %d actions", n)
+		}
+	}
+	if extra != 1 {
+		t.Fatalf("%d steps with extra actions", extra)
+	}
+}
+
+func TestScenarioFaultsDeterministic(t *testing.T) {
+	path := writeScenario(t, `{"seed": 99, "faults": {"wrong_ar_type": 1, "extra_actions": 1, "unquoted_keys": 1, "fence": 1, "truncate": 0.5}}`, nil)
+	run := func() []string {
+		s, err := LoadScenario(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for i := 0; i < 5; i++ {
+			content, err := s.respond(context.Background(), "dish_name")
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, content)
+		}
+		return out
+	}
+	first, second := run(), run()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("response %d differs between runs with the same seed", i)
+		}
+		if !strings.HasPrefix(first[i], "以下是您要求的結果：\n```json\n{") || !strings.Contains(first[i], "dish_name:") {
+			t.Fatalf("response %d not fenced and unquoted: %.60q", i, first[i])
+		}
+	}
+}
@@ -1,33 +1,71 @@
 package image
 
 import (
+	"context"
+	"encoding/base64"
 	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	imagecore "recipe-generator/internal/core/image"
 )
 
-// Processor 圖片處理器
+// Processor 圖片處理器，在送進服務前將上傳的圖片整理成統一格式
 type Processor struct {
-	maxSize int
+	images *imagecore.Service
 }
 
 // NewProcessor 創建圖片處理器
-func NewProcessor(maxSize int) *Processor {
+func NewProcessor(images *imagecore.Service) *Processor {
 	return &Processor{
-		maxSize: maxSize,
+		images: images,
 	}
 }
 
-// Compress 壓縮圖片
-func (p *Processor) Compress(imageData string) (string, error) {
+// Compress 壓縮圖片：套用 EXIF 方向、移除中繼資料、縮小並調整品質，回傳 JPEG data URI
+func (p *Processor) Compress(ctx context.Context, imageData string) (string, error) {
 	if imageData == "" {
 		return "", errors.New("image data is empty")
 	}
-	return imageData, nil
+	return p.images.ProcessImage(ctx, imageData)
 }
 
-// FormatImageData 格式化圖片數據
-func (p *Processor) FormatImageData(imageData string) (string, error) {
+// FormatImageData 格式化圖片數據：網址檢查下載政策後原樣保留，交由 AI 服務下載，
+// 沒有 data URI 前綴的 base64 會依內容補上，再經過 Compress 前處理
+func (p *Processor) FormatImageData(ctx context.Context, imageData string) (string, error) {
+	imageData = strings.TrimSpace(imageData)
 	if imageData == "" {
 		return "", errors.New("image data is empty")
 	}
-	return imageData, nil
+	if strings.HasPrefix(imageData, "http://") || strings.HasPrefix(imageData, "https://") {
+		if err := p.images.CheckURL(imageData); err != nil {
+			return "", err
+		}
+		return imageData, nil
+	}
+	if !strings.HasPrefix(imageData, "data:") {
+		// 移除 base64 中的換行與空白後依內容判斷圖片類型
+		raw := strings.Join(strings.Fields(imageData), "")
+		decoded, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			return "", errors.New("invalid image data format")
+		}
+		contentType := http.DetectContentType(decoded)
+		if !strings.HasPrefix(contentType, "image/") {
+			return "", errors.New("invalid image data format")
+		}
+		imageData = "data:" + contentType + ";base64," + raw
+	}
+	return p.Compress(ctx, imageData)
+}
+
+// MaxEncodedSize 以文字傳送的圖片長度上限
+func (p *Processor) MaxEncodedSize() int64 {
+	return p.images.MaxEncodedSize()
+}
+
+// FormatImageReader 前處理上傳的圖片檔案內容，回傳 JPEG data URI
+func (p *Processor) FormatImageReader(r io.Reader) (string, error) {
+	return p.images.ProcessReader(r)
 }
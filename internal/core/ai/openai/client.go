@@ -0,0 +1,207 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/pkg/common"
+	"recipe-generator/internal/pkg/httpfixture"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// ProviderName OpenAI 相容提供者註冊名稱
+	ProviderName = "openai"
+
+	defaultBaseURL = "http://localhost:11434/v1"
+	defaultTimeout = 120 * time.Second
+)
+
+func init() {
+	provider.Register(ProviderName, func(cfg provider.Config) (provider.Provider, error) {
+		return NewClient(cfg)
+	})
+}
+
+// Client OpenAI 相容 API 客戶端（Ollama、llama.cpp、vLLM 等本地服務）
+type Client struct {
+	httpClient *http.Client
+	config     provider.Config
+}
+
+// contentPart 多模態內容片段
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+// imageURL 圖片 URL 結構
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+// chatMessage 請求訊息
+type chatMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+// chatRequest chat/completions 請求
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	Stop        []string      `json:"stop,omitempty"`
+	Stream      bool          `json:"stream"`
+
+	ResponseFormat map[string]interface{} `json:"response_format,omitempty"`
+}
+
+// chatResponse chat/completions 響應
+type chatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// NewClient 創建 OpenAI 相容客戶端
+func NewClient(cfg provider.Config) (*Client, error) {
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("openai provider requires a model name")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	return &Client{
+		httpClient: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: httpfixture.Wrap(cfg.FixtureMode, cfg.FixtureDir, nil),
+		},
+		config: cfg,
+	}, nil
+}
+
+// Generate 實作 provider.Provider
+func (c *Client) Generate(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	body := chatRequest{
+		Model:       provider.ModelFor(c, req),
+		Messages:    make([]chatMessage, 0, len(req.Messages)),
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		Stop:        req.Stop,
+	}
+	if body.MaxTokens <= 0 {
+		body.MaxTokens = c.config.MaxTokens
+	}
+	if c.config.JSONSchema && req.ResponseFormat != nil {
+		body.ResponseFormat = req.ResponseFormat.OpenAIFormat()
+	}
+
+	for _, m := range req.Messages {
+		// 純文字訊息使用字串內容，兼容不支援多模態陣列的本地模型
+		if len(m.Images) == 0 {
+			body.Messages = append(body.Messages, chatMessage{Role: m.Role, Content: m.Content})
+			continue
+		}
+		parts := []contentPart{{Type: "text", Text: m.Content}}
+		for _, img := range m.Images {
+			parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURL{URL: img}})
+		}
+		body.Messages = append(body.Messages, chatMessage{Role: m.Role, Content: parts})
+	}
+
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.BaseURL+"/chat/completions", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.config.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+
+	common.LogDebug("Sending request to OpenAI-compatible endpoint",
+		zap.String("base_url", c.config.BaseURL),
+		zap.String("model", body.Model),
+		zap.Int("messages", len(body.Messages)),
+	)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &provider.StatusError{Provider: "OpenAI-compatible", StatusCode: resp.StatusCode, Body: truncate(string(respBody), 500)}
+	}
+
+	var result chatResponse
+	if err := common.ParseJSONBytes(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in OpenAI-compatible response")
+	}
+
+	out := &provider.Response{Content: result.Choices[0].Message.Content}
+	out.Usage.PromptTokens = result.Usage.PromptTokens
+	out.Usage.CompletionTokens = result.Usage.CompletionTokens
+	out.Usage.TotalTokens = result.Usage.TotalTokens
+	return out, nil
+}
+
+// GetModel 實作 provider.Provider
+func (c *Client) GetModel() string {
+	return c.config.Model
+}
+
+// GetTimeout 實作 provider.Provider
+func (c *Client) GetTimeout() time.Duration {
+	return c.config.Timeout
+}
+
+// Close 實作 provider.Provider
+func (c *Client) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// truncate 截斷過長的錯誤內容
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
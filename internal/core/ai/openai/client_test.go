@@ -0,0 +1,65 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	common.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+func TestGenerateSendsTemperature(t *testing.T) {
+	zero, warm := 0.0, 0.7
+	tests := []struct {
+		name        string
+		temperature *float64
+		want        *float64
+	}{
+		{"unset", nil, nil},
+		{"zero", &zero, &zero},
+		{"set", &warm, &warm},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("decode request: %v", err)
+				}
+				w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}]}`))
+			}))
+			defer server.Close()
+
+			c, err := NewClient(provider.Config{Model: "test", BaseURL: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.Generate(context.Background(), &provider.Request{
+				Messages:    []provider.Message{{Role: "user", Content: "hi"}},
+				Temperature: tt.temperature,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, ok := body["temperature"]
+			switch {
+			case tt.want == nil && ok:
+				t.Fatalf("temperature sent: %v", got)
+			case tt.want != nil && (!ok || got != *tt.want):
+				t.Fatalf("temperature = %v (present %v), want %v", got, ok, *tt.want)
+			}
+		})
+	}
+}
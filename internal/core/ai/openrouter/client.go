@@ -10,9 +10,10 @@ import (
 	"strings"
 	"time"
 
-	"recipe-generator/internal/core/ai/image"
+	"recipe-generator/internal/core/ai/provider"
 	"recipe-generator/internal/infrastructure/config"
 	"recipe-generator/internal/pkg/common"
+	"recipe-generator/internal/pkg/httpfixture"
 
 	"go.uber.org/zap"
 )
@@ -23,9 +24,9 @@ const (
 
 // Client OpenRouter API 客戶端
 type Client struct {
-	httpClient     *http.Client
-	config         *config.Config
-	imageProcessor *image.Processor
+	httpClient *http.Client
+	config     *config.Config
+	retry      provider.RetryPolicy
 }
 
 // Message 消息結構
@@ -101,10 +102,11 @@ type Error struct {
 func NewClient(cfg *config.Config) *Client {
 	return &Client{
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: httpfixture.Wrap(cfg.AI.Fixtures.Mode, cfg.AI.Fixtures.Dir, nil),
 		},
-		config:         cfg,
-		imageProcessor: image.NewProcessor(1024), // 最大尺寸 1024 像素
+		config: cfg,
+		retry:  provider.DefaultRetryPolicy(cfg.OpenRouter.MaxRetries),
 	}
 }
 
@@ -216,8 +218,37 @@ func (c *Client) Generate(ctx context.Context, prompt, imageData string) (*Respo
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	common.LogInfo("Sending request to OpenRouter",
+		zap.String("model", req.Model),
+		zap.Int("messages", len(req.Messages)),
+		zap.Bool("is_vision_model", isVisionModel),
+		zap.Int("max_retries", c.retry.MaxRetries),
+	)
+
+	// 發送請求，暫時性錯誤依策略重試
+	var response *Response
+	err = c.retry.Do(ctx, "openrouter.client.generate", func(attempt int) error {
+		var err error
+		response, err = c.send(ctx, req.Model, reqBody, attempt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 記錄成功響應
+	common.LogInfo("Successfully generated response from AI service",
+		zap.String("model", req.Model),
+		zap.Int("content_length", len(response.Choices[0].Message.Content)),
+	)
+
+	return response, nil
+}
+
+// send 發送單次請求並驗證響應
+func (c *Client) send(ctx context.Context, model string, reqBody []byte, attempt int) (*Response, error) {
 	// 創建 HTTP 請求
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/chat/completions", bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/chat/completions", bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -228,18 +259,12 @@ func (c *Client) Generate(ctx context.Context, prompt, imageData string) (*Respo
 	httpReq.Header.Set("HTTP-Referer", "https://recipe-generator.com")
 	httpReq.Header.Set("X-Title", "Recipe Generator")
 
-	// 發送請求
-	common.LogInfo("Sending request to OpenRouter",
-		zap.String("model", req.Model),
-		zap.Int("messages", len(req.Messages)),
-		zap.Bool("is_vision_model", isVisionModel),
-	)
-
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
 		common.LogError("Failed to send request to AI service",
 			zap.Error(err),
-			zap.String("model", req.Model),
+			zap.String("model", model),
+			zap.Int("attempt", attempt),
 		)
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -250,7 +275,8 @@ func (c *Client) Generate(ctx context.Context, prompt, imageData string) (*Respo
 	if err != nil {
 		common.LogError("Failed to read response body",
 			zap.Error(err),
-			zap.String("model", req.Model),
+			zap.String("model", model),
+			zap.Int("attempt", attempt),
 		)
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
@@ -262,10 +288,16 @@ func (c *Client) Generate(ctx context.Context, prompt, imageData string) (*Respo
 	if resp.StatusCode != http.StatusOK {
 		common.LogError("AI service returned error status",
 			zap.Int("status_code", resp.StatusCode),
-			zap.String("model", req.Model),
+			zap.String("model", model),
+			zap.Int("attempt", attempt),
 			zap.String("response", sanitizedBody),
 		)
-		return nil, fmt.Errorf("AI service error (status %d): %s", resp.StatusCode, sanitizedBody)
+		return nil, &provider.StatusError{
+			Provider:   "OpenRouter",
+			StatusCode: resp.StatusCode,
+			Body:       sanitizedBody,
+			RetryAfter: provider.ParseRetryAfter(resp.Header.Get("Retry-After")),
+		}
 	}
 
 	// 解析響應
@@ -273,37 +305,33 @@ func (c *Client) Generate(ctx context.Context, prompt, imageData string) (*Respo
 	if err := common.ParseJSONBytes(body, &response); err != nil {
 		common.LogError("Failed to parse AI service response",
 			zap.Error(err),
-			zap.String("model", req.Model),
+			zap.String("model", model),
+			zap.Int("attempt", attempt),
 			zap.String("response", sanitizedBody),
 		)
-		return nil, fmt.Errorf("failed to parse response: %w (response: %s)", err, sanitizedBody)
+		return nil, fmt.Errorf("failed to parse response: %w: %v (response: %s)", provider.ErrUnparseableResponse, err, sanitizedBody)
 	}
 
 	// 檢查響應內容
 	if len(response.Choices) == 0 {
 		common.LogError("Empty choices in AI service response",
-			zap.String("model", req.Model),
+			zap.String("model", model),
+			zap.Int("attempt", attempt),
 			zap.String("response", sanitizedBody),
 		)
-		return nil, fmt.Errorf("empty choices in response (response: %s)", sanitizedBody)
+		return nil, fmt.Errorf("%w (response: %s)", provider.ErrEmptyChoices, sanitizedBody)
 	}
 
 	// 檢查消息內容
-	content := response.Choices[0].Message.Content
-	if len(content) == 0 {
+	if len(response.Choices[0].Message.Content) == 0 {
 		common.LogError("Empty content in AI service response",
-			zap.String("model", req.Model),
+			zap.String("model", model),
+			zap.Int("attempt", attempt),
 			zap.String("response", sanitizedBody),
 		)
 		return nil, fmt.Errorf("empty content in response (response: %s)", sanitizedBody)
 	}
 
-	// 記錄成功響應
-	common.LogInfo("Successfully generated response from AI service",
-		zap.String("model", req.Model),
-		zap.Int("content_length", len(content)),
-	)
-
 	return &response, nil
 }
 
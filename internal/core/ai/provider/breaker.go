@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState 斷路器狀態
+type BreakerState int
+
+const (
+	// BreakerClosed 正常放行
+	BreakerClosed BreakerState = iota
+	// BreakerOpen 暫停放行，等待冷卻
+	BreakerOpen
+	// BreakerHalfOpen 冷卻後放行少量探測請求
+	BreakerHalfOpen
+)
+
+// String 回傳狀態名稱
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerConfig 斷路器設定
+type BreakerConfig struct {
+	FailureThreshold int           // 連續失敗幾次後開啟
+	Cooldown         time.Duration // 開啟後多久進入半開
+	HalfOpenRequests int           // 半開時允許同時探測的請求數
+}
+
+// CircuitBreaker 單一提供者的斷路器
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	config   BreakerConfig
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	inFlight int
+}
+
+// NewCircuitBreaker 創建斷路器
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	return &CircuitBreaker{config: cfg}
+}
+
+// Allow 判斷是否放行請求；放行後必須呼叫 Success、Failure 或 Release 其中之一
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.config.Cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.inFlight = 0
+		fallthrough
+	case BreakerHalfOpen:
+		if b.inFlight >= b.config.HalfOpenRequests {
+			return false
+		}
+		b.inFlight++
+		return true
+	default:
+		return true
+	}
+}
+
+// Success 記錄成功，半開狀態下會關閉斷路器
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.inFlight = 0
+	b.state = BreakerClosed
+}
+
+// Failure 記錄失敗，達到門檻或半開探測失敗時開啟斷路器
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+		b.inFlight = 0
+	}
+}
+
+// Release 釋放放行名額但不影響狀態（例如請求被呼叫方取消）
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen && b.inFlight > 0 {
+		b.inFlight--
+	}
+}
+
+// State 回傳目前狀態
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.config.Cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
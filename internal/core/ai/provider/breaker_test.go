@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	b := NewCircuitBreaker(BreakerConfig{FailureThreshold: 2, Cooldown: 20 * time.Millisecond, HalfOpenRequests: 1})
+
+	// 未達門檻前維持關閉，成功會重置失敗計數
+	b.Failure()
+	b.Success()
+	b.Failure()
+	if !b.Allow() || b.State() != BreakerClosed {
+		t.Fatalf("state after one failure: %s", b.State())
+	}
+	b.Failure()
+	if b.State() != BreakerOpen {
+		t.Fatalf("state after threshold: %s", b.State())
+	}
+	if b.Allow() {
+		t.Fatal("open breaker allowed a request during cooldown")
+	}
+
+	// 冷卻後進入半開並放行探測，探測成功即關閉
+	time.Sleep(30 * time.Millisecond)
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("state after cooldown: %s", b.State())
+	}
+	if !b.Allow() {
+		t.Fatal("half-open breaker rejected the probe")
+	}
+	b.Success()
+	if b.State() != BreakerClosed {
+		t.Fatalf("state after successful probe: %s", b.State())
+	}
+
+	// 半開探測失敗立即重新開啟，不需再累積到門檻
+	b.Failure()
+	b.Failure()
+	time.Sleep(30 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("half-open breaker rejected the probe")
+	}
+	b.Failure()
+	if b.State() != BreakerOpen || b.Allow() {
+		t.Fatalf("state after failed probe: %s", b.State())
+	}
+}
+
+func TestCircuitBreakerLimitsHalfOpenProbes(t *testing.T) {
+	b := NewCircuitBreaker(BreakerConfig{FailureThreshold: 1, Cooldown: 20 * time.Millisecond, HalfOpenRequests: 2})
+	b.Failure()
+	time.Sleep(30 * time.Millisecond)
+
+	if !b.Allow() || !b.Allow() {
+		t.Fatal("half-open breaker rejected a probe within the limit")
+	}
+	if b.Allow() {
+		t.Fatal("half-open breaker allowed more probes than configured")
+	}
+
+	// 被取消的探測釋放名額但不改變狀態
+	b.Release()
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("state after release: %s", b.State())
+	}
+	if !b.Allow() {
+		t.Fatal("released probe slot was not reusable")
+	}
+	if b.Allow() {
+		t.Fatal("half-open breaker allowed more probes than configured after release")
+	}
+}
+
+func TestCircuitBreakerDefaults(t *testing.T) {
+	b := NewCircuitBreaker(BreakerConfig{})
+	for i := 0; i < 4; i++ {
+		b.Failure()
+	}
+	if b.State() != BreakerClosed {
+		t.Fatalf("opened before default threshold: %s", b.State())
+	}
+	b.Failure()
+	if b.State() != BreakerOpen || b.Allow() {
+		t.Fatalf("state after default threshold: %s", b.State())
+	}
+}
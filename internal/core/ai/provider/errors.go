@@ -0,0 +1,38 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrNoProviderAvailable 所有提供者的斷路器皆為開啟狀態
+var ErrNoProviderAvailable = errors.New("no AI provider available: all circuit breakers are open")
+
+// StatusError 表示上游 API 回傳非 200 狀態碼
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // 上游 Retry-After 標頭指定的等待時間
+}
+
+// Error 實現 error 介面
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s API returned error (status %d): %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// IsClientError 判斷錯誤是否由請求內容本身造成，換提供者也無法修正
+func IsClientError(err error) bool {
+	var se *StatusError
+	if !errors.As(err, &se) {
+		return false
+	}
+	switch se.StatusCode {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return true
+	default:
+		return false
+	}
+}
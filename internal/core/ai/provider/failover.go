@@ -0,0 +1,229 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+// healthAlpha 健康分數的指數移動平均權重
+const healthAlpha = 0.2
+
+// Member 失敗轉移鏈中的單一提供者
+type Member struct {
+	Name     string // 例如 openrouter@qwen/qwen2.5-vl-72b-instruct:free
+	Provider Provider
+}
+
+// MemberStatus 提供者健康狀態
+type MemberStatus struct {
+	Name         string  `json:"name"`
+	Model        string  `json:"model"`
+	State        string  `json:"state"`
+	HealthScore  float64 `json:"health_score"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	Successes    int64   `json:"successes"`
+	Failures     int64   `json:"failures"`
+}
+
+// chainMember 帶有斷路器與健康統計的提供者
+type chainMember struct {
+	Member
+	breaker *CircuitBreaker
+
+	mu        sync.Mutex
+	health    float64
+	latency   float64
+	successes int64
+	failures  int64
+}
+
+// record 更新健康分數與平均延遲
+func (m *chainMember) record(ok bool, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	outcome := 0.0
+	if ok {
+		outcome = 1.0
+		m.successes++
+	} else {
+		m.failures++
+	}
+	m.health = m.health*(1-healthAlpha) + outcome*healthAlpha
+
+	ms := float64(latency.Milliseconds())
+	if m.latency == 0 {
+		m.latency = ms
+	} else {
+		m.latency = m.latency*(1-healthAlpha) + ms*healthAlpha
+	}
+}
+
+// Chain 依序嘗試多個提供者的失敗轉移提供者
+type Chain struct {
+	members []*chainMember
+}
+
+// NewChain 創建失敗轉移鏈，members 依優先順序排列
+func NewChain(members []Member, cfg BreakerConfig) (*Chain, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("failover chain requires at least one provider")
+	}
+
+	c := &Chain{members: make([]*chainMember, 0, len(members))}
+	for _, m := range members {
+		if m.Provider == nil {
+			return nil, fmt.Errorf("failover chain member %q has no provider", m.Name)
+		}
+		c.members = append(c.members, &chainMember{
+			Member:  m,
+			breaker: NewCircuitBreaker(cfg),
+			health:  1.0,
+		})
+	}
+	return c, nil
+}
+
+// Generate 實作 Provider，依序嘗試直到成功
+func (c *Chain) Generate(ctx context.Context, req *Request) (*Response, error) {
+	return c.run(ctx, req, func(p Provider, req *Request) (*Response, error) {
+		return p.Generate(ctx, req)
+	}, nil)
+}
+
+// GenerateStream 實作 StreamProvider
+// 已轉發任何增量內容後便無法再轉移，避免客戶端收到兩份不同的輸出
+func (c *Chain) GenerateStream(ctx context.Context, req *Request, onDelta DeltaFunc) (*Response, error) {
+	emitted := false
+	forward := func(delta string) error {
+		emitted = true
+		return onDelta(delta)
+	}
+	return c.run(ctx, req, func(p Provider, req *Request) (*Response, error) {
+		return Stream(ctx, p, req, forward)
+	}, func() bool { return !emitted })
+}
+
+// run 依序以 call 呼叫各提供者；canFailover 回傳 false 時不再嘗試下一個。
+// 請求指定的模型只套用於主要提供者，備援使用各自設定的模型
+func (c *Chain) run(ctx context.Context, req *Request, call func(p Provider, req *Request) (*Response, error), canFailover func() bool) (*Response, error) {
+	var errs []string
+	var lastErr error
+
+	for i, m := range c.members {
+		if !m.breaker.Allow() {
+			common.LogWarn("AI 提供者斷路器開啟，略過",
+				zap.String("provider", m.Name),
+				zap.String("state", m.breaker.State().String()),
+			)
+			continue
+		}
+
+		memberReq := req
+		if i > 0 && req.Model != "" {
+			cp := *req
+			cp.Model = ""
+			memberReq = &cp
+		}
+
+		start := time.Now()
+		resp, err := call(m.Provider, memberReq)
+		latency := time.Since(start)
+
+		if err == nil {
+			m.breaker.Success()
+			m.record(true, latency)
+			resp.Provider = m.Name
+			resp.Model = ModelFor(m.Provider, memberReq)
+			if i > 0 {
+				common.LogWarn("AI 請求已轉移至備援提供者",
+					zap.String("provider", m.Name),
+					zap.Int("position", i),
+					zap.Strings("failed", errs),
+				)
+			}
+			return resp, nil
+		}
+
+		// 呼叫方取消或請求本身有誤時，不計入提供者失敗也不轉移
+		if ctx.Err() != nil {
+			m.breaker.Release()
+			return nil, err
+		}
+		if IsClientError(err) {
+			m.breaker.Release()
+			return nil, err
+		}
+
+		m.breaker.Failure()
+		m.record(false, latency)
+		lastErr = err
+		errs = append(errs, m.Name)
+		common.LogWarn("AI 提供者請求失敗，嘗試下一個",
+			zap.String("provider", m.Name),
+			zap.Duration("latency", latency),
+			zap.String("breaker_state", m.breaker.State().String()),
+			zap.Error(err),
+		)
+		if canFailover != nil && !canFailover() {
+			return nil, err
+		}
+	}
+
+	if lastErr == nil {
+		return nil, ErrNoProviderAvailable
+	}
+	return nil, fmt.Errorf("all AI providers failed (%s): %w", strings.Join(errs, ", "), lastErr)
+}
+
+// GetModel 回傳主要提供者的模型
+func (c *Chain) GetModel() string {
+	return c.members[0].Provider.GetModel()
+}
+
+// GetTimeout 回傳所有提供者超時時間的總和
+func (c *Chain) GetTimeout() time.Duration {
+	var total time.Duration
+	for _, m := range c.members {
+		total += m.Provider.GetTimeout()
+	}
+	return total
+}
+
+// Close 關閉所有提供者
+func (c *Chain) Close() error {
+	var errs []error
+	for _, m := range c.members {
+		if err := m.Provider.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", m.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Status 回傳每個提供者的斷路器與健康狀態
+func (c *Chain) Status() []MemberStatus {
+	out := make([]MemberStatus, 0, len(c.members))
+	for _, m := range c.members {
+		m.mu.Lock()
+		out = append(out, MemberStatus{
+			Name:         m.Name,
+			Model:        m.Provider.GetModel(),
+			State:        m.breaker.State().String(),
+			HealthScore:  m.health,
+			AvgLatencyMs: m.latency,
+			Successes:    m.successes,
+			Failures:     m.failures,
+		})
+		m.mu.Unlock()
+	}
+	return out
+}
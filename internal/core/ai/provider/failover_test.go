@@ -0,0 +1,167 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// stubProvider 回傳固定結果並記錄收到的請求
+type stubProvider struct {
+	model string
+	err   error
+	calls []*Request
+}
+
+func (p *stubProvider) Generate(ctx context.Context, req *Request) (*Response, error) {
+	p.calls = append(p.calls, req)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &Response{Content: "ok from " + p.model}, nil
+}
+
+func (p *stubProvider) GetModel() string          { return p.model }
+func (p *stubProvider) GetTimeout() time.Duration { return time.Second }
+func (p *stubProvider) Close() error              { return nil }
+
+func newTestChain(t *testing.T, cfg BreakerConfig, providers ...*stubProvider) *Chain {
+	t.Helper()
+	members := make([]Member, len(providers))
+	for i, p := range providers {
+		members[i] = Member{Name: "stub@" + p.model, Provider: p}
+	}
+	c, err := NewChain(members, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestChainFailsOverInOrder(t *testing.T) {
+	unavailable := &StatusError{StatusCode: http.StatusServiceUnavailable}
+	first := &stubProvider{model: "a", err: unavailable}
+	second := &stubProvider{model: "b", err: unavailable}
+	third := &stubProvider{model: "c"}
+	c := newTestChain(t, BreakerConfig{FailureThreshold: 3}, first, second, third)
+
+	resp, err := c.Generate(context.Background(), &Request{Model: "override"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Provider != "stub@c" || resp.Model != "c" || resp.Content != "ok from c" {
+		t.Fatalf("response: %+v", resp)
+	}
+	if len(first.calls) != 1 || len(second.calls) != 1 || len(third.calls) != 1 {
+		t.Fatalf("calls: %d %d %d", len(first.calls), len(second.calls), len(third.calls))
+	}
+	// 指定的模型只套用於主要提供者
+	if first.calls[0].Model != "override" || second.calls[0].Model != "" || third.calls[0].Model != "" {
+		t.Fatalf("models: %q %q %q", first.calls[0].Model, second.calls[0].Model, third.calls[0].Model)
+	}
+
+	status := c.Status()
+	if status[0].Failures != 1 || status[1].Failures != 1 || status[2].Successes != 1 || status[2].Failures != 0 {
+		t.Fatalf("status: %+v", status)
+	}
+	if status[0].HealthScore >= 1 || status[2].HealthScore != 1 {
+		t.Fatalf("health: %+v", status)
+	}
+}
+
+func TestChainStopsOnClientError(t *testing.T) {
+	badRequest := &StatusError{StatusCode: http.StatusBadRequest}
+	first := &stubProvider{model: "a", err: badRequest}
+	second := &stubProvider{model: "b"}
+	c := newTestChain(t, BreakerConfig{FailureThreshold: 1}, first, second)
+
+	_, err := c.Generate(context.Background(), &Request{})
+	if !errors.Is(err, badRequest) {
+		t.Fatalf("err = %v, want the client error", err)
+	}
+	if len(second.calls) != 0 {
+		t.Fatal("client error failed over to the next provider")
+	}
+	// 請求本身有誤不計入提供者失敗
+	if status := c.Status(); status[0].State != "closed" || status[0].Failures != 0 {
+		t.Fatalf("status: %+v", status[0])
+	}
+}
+
+func TestChainStopsWhenCallerCancels(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	first := &stubProvider{model: "a", err: context.Canceled}
+	second := &stubProvider{model: "b"}
+	c := newTestChain(t, BreakerConfig{FailureThreshold: 1}, first, second)
+
+	if _, err := c.Generate(ctx, &Request{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v", err)
+	}
+	if len(second.calls) != 0 || c.Status()[0].State != "closed" {
+		t.Fatalf("canceled request failed over or tripped the breaker: %+v", c.Status())
+	}
+}
+
+func TestChainSkipsOpenBreaker(t *testing.T) {
+	first := &stubProvider{model: "a", err: errors.New("connection reset")}
+	second := &stubProvider{model: "b"}
+	c := newTestChain(t, BreakerConfig{FailureThreshold: 1, Cooldown: time.Minute}, first, second)
+
+	if _, err := c.Generate(context.Background(), &Request{}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Status()[0].State != "open" {
+		t.Fatalf("primary state: %s", c.Status()[0].State)
+	}
+
+	// 斷路器開啟期間直接使用備援，不再呼叫主要提供者
+	resp, err := c.Generate(context.Background(), &Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Provider != "stub@b" || len(first.calls) != 1 || len(second.calls) != 2 {
+		t.Fatalf("provider %s, calls %d %d", resp.Provider, len(first.calls), len(second.calls))
+	}
+}
+
+func TestChainAllFailed(t *testing.T) {
+	first := &stubProvider{model: "a", err: errors.New("timeout")}
+	second := &stubProvider{model: "b", err: &StatusError{StatusCode: http.StatusBadGateway}}
+	c := newTestChain(t, BreakerConfig{FailureThreshold: 1, Cooldown: time.Minute}, first, second)
+
+	_, err := c.Generate(context.Background(), &Request{})
+	if err == nil || errors.Is(err, ErrNoProviderAvailable) {
+		t.Fatalf("err = %v, want all providers failed", err)
+	}
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusBadGateway {
+		t.Fatalf("err = %v, want the last provider error wrapped", err)
+	}
+
+	// 所有斷路器皆開啟時不呼叫任何提供者
+	if _, err := c.Generate(context.Background(), &Request{}); !errors.Is(err, ErrNoProviderAvailable) {
+		t.Fatalf("err = %v, want ErrNoProviderAvailable", err)
+	}
+	if len(first.calls) != 1 || len(second.calls) != 1 {
+		t.Fatalf("calls: %d %d", len(first.calls), len(second.calls))
+	}
+}
+
+func TestChainStreamDoesNotFailOverAfterOutput(t *testing.T) {
+	first := &stubProvider{model: "a"}
+	second := &stubProvider{model: "b"}
+	c := newTestChain(t, BreakerConfig{FailureThreshold: 3}, first, second)
+
+	// 主要提供者已送出內容後，轉發失敗不可再轉移至備援
+	sendErr := errors.New("client gone")
+	_, err := c.GenerateStream(context.Background(), &Request{}, func(string) error { return sendErr })
+	if !errors.Is(err, sendErr) {
+		t.Fatalf("err = %v", err)
+	}
+	if len(second.calls) != 0 {
+		t.Fatal("stream failed over after emitting output")
+	}
+}
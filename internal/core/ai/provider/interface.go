@@ -3,32 +3,68 @@ package provider
 import (
 	"context"
 	"time"
+
+	"recipe-generator/internal/pkg/jsonschema"
 )
 
 // Message 表示與 AI 模型的對話消息
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"` // 圖片 data URI 或 URL
 }
 
 // Request 表示發送到 AI 提供者的請求
 type Request struct {
 	Messages    []Message `json:"messages"`
+	Model       string    `json:"model,omitempty"` // 覆寫提供者設定的模型，空字串時使用預設模型
 	MaxTokens   int       `json:"max_tokens,omitempty"`
-	Temperature float64   `json:"temperature,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"` // nil 時使用提供者預設值，0 也會送出
 	Stop        []string  `json:"stop,omitempty"`
+
+	// ResponseFormat 要求回應符合的 JSON Schema，提供者不支援或未啟用時忽略
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormat 結構化輸出的 JSON Schema
+type ResponseFormat struct {
+	Name   string             `json:"name"`
+	Schema *jsonschema.Schema `json:"schema"`
+}
+
+// OpenAIFormat 轉為 OpenAI 相容 API 的 response_format 欄位；
+// 不使用 strict 模式，選填欄位與可為 null 的欄位才能照結構描述原樣傳送
+func (f *ResponseFormat) OpenAIFormat() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "json_schema",
+		"json_schema": map[string]interface{}{
+			"name":   f.Name,
+			"strict": false,
+			"schema": f.Schema,
+		},
+	}
 }
 
 // Response 表示從 AI 提供者收到的響應
 type Response struct {
-	Content string `json:"content"`
-	Usage   struct {
+	Content  string `json:"content"`
+	Provider string `json:"provider,omitempty"` // 實際回應的提供者（失敗轉移時填入）
+	Model    string `json:"model,omitempty"`    // 實際使用的模型
+	Usage    struct {
 		PromptTokens     int `json:"prompt_tokens"`
 		CompletionTokens int `json:"completion_tokens"`
 		TotalTokens      int `json:"total_tokens"`
 	} `json:"usage"`
 }
 
+// ModelFor 回傳請求實際使用的模型：請求指定模型時優先，否則為提供者的預設模型
+func ModelFor(p Provider, req *Request) string {
+	if req != nil && req.Model != "" {
+		return req.Model
+	}
+	return p.GetModel()
+}
+
 // Provider 定義 AI 提供者介面
 type Provider interface {
 	// Generate 生成 AI 響應
@@ -51,4 +87,11 @@ type Config struct {
 	Timeout    time.Duration
 	MaxRetries int
 	BaseURL    string
+	MaxTokens  int
+	JSONSchema bool // 以 response_format: json_schema 傳送 Request.ResponseFormat
+
+	FixtureMode string // HTTP 請求錄製模式：off、record 或 replay
+	FixtureDir  string // fixture 檔案目錄
+
+	Scenario string // 假提供者的腳本檔路徑
 }
@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"context"
+	"sync"
+)
+
+type metadataKey struct{}
+
+// Metadata 記錄單次 HTTP 請求中實際使用的 AI 提供者、提示詞模板與實驗變體
+type Metadata struct {
+	mu       sync.Mutex
+	provider string
+	model    string
+	template string
+	variant  string
+}
+
+// WithMetadata 在 context 中放入新的提供者記錄
+func WithMetadata(ctx context.Context) (context.Context, *Metadata) {
+	md := &Metadata{}
+	return context.WithValue(ctx, metadataKey{}, md), md
+}
+
+// MetadataFromContext 取得 context 中的提供者記錄，不存在時回傳 nil
+func MetadataFromContext(ctx context.Context) *Metadata {
+	md, _ := ctx.Value(metadataKey{}).(*Metadata)
+	return md
+}
+
+// Record 記錄提供者與模型
+func (m *Metadata) Record(provider, model string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.provider = provider
+	m.model = model
+}
+
+// Get 回傳記錄的提供者與模型
+func (m *Metadata) Get() (provider, model string) {
+	if m == nil {
+		return "", ""
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.provider, m.model
+}
+
+// RecordTemplate 記錄提示詞模板 ID 與版本
+func (m *Metadata) RecordTemplate(template string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.template = template
+}
+
+// Template 回傳記錄的提示詞模板 ID 與版本
+func (m *Metadata) Template() string {
+	if m == nil {
+		return ""
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.template
+}
+
+// RecordVariant 記錄分配到的實驗與變體
+func (m *Metadata) RecordVariant(variant string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.variant = variant
+}
+
+// Variant 回傳記錄的實驗與變體
+func (m *Metadata) Variant() string {
+	if m == nil {
+		return ""
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.variant
+}
@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory 依設定建立 AI 提供者
+type Factory func(cfg Config) (Provider, error)
+
+var (
+	registryMu sync.RWMutex
+	factories  = make(map[string]Factory)
+)
+
+// Register 註冊 AI 提供者工廠，名稱重複時 panic
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("provider: Register factory is nil for " + name)
+	}
+	if _, dup := factories[name]; dup {
+		panic("provider: Register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// New 依名稱建立已註冊的 AI 提供者
+func New(name string, cfg Config) (Provider, error) {
+	registryMu.RLock()
+	factory, ok := factories[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown AI provider %q (registered: %v)", name, Names())
+	}
+	return factory(cfg)
+}
+
+// Names 回傳所有已註冊的提供者名稱
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrEmptyChoices 上游回應中沒有任何 choice，通常為暫時性問題
+	ErrEmptyChoices = errors.New("empty choices in AI response")
+	// ErrUnparseableResponse 上游回應無法解析為預期格式
+	ErrUnparseableResponse = errors.New("unparseable AI response")
+)
+
+// permanentError 標記不應重試的錯誤
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 包裝錯誤使其不被重試，例如串流已轉發部分內容後中斷
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// RetryPolicy 重試策略：指數退避加上隨機抖動
+type RetryPolicy struct {
+	MaxRetries int           // 首次請求之外的最大重試次數
+	BaseDelay  time.Duration // 第一次重試前的基礎等待時間
+	MaxDelay   time.Duration // 單次等待上限
+}
+
+// DefaultRetryPolicy 依最大重試次數建立預設策略
+func DefaultRetryPolicy(maxRetries int) RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: maxRetries,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   10 * time.Second,
+	}
+}
+
+// IsRetryable 判斷錯誤是否值得重試
+// 網路錯誤（含單次請求的 HTTP client 逾時）、408、429、5xx、空 choices 與無法解析的回應視為暫時性錯誤；
+// 取消與其他 4xx 則不重試。呼叫端逾時與單次請求逾時在錯誤鏈上無法區分，由 Do 檢查呼叫端的 ctx.Err() 判斷
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var pe *permanentError
+	if errors.As(err, &pe) {
+		return false
+	}
+
+	var se *StatusError
+	if errors.As(err, &se) {
+		switch {
+		case se.StatusCode == http.StatusRequestTimeout,
+			se.StatusCode == http.StatusTooManyRequests,
+			se.StatusCode >= 500:
+			return true
+		default:
+			return false
+		}
+	}
+
+	if errors.Is(err, ErrEmptyChoices) || errors.Is(err, ErrUnparseableResponse) {
+		return true
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// ParseRetryAfter 解析 Retry-After 標頭，支援秒數與 HTTP 日期兩種格式
+func ParseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// backoff 計算第 attempt 次重試前的等待時間（attempt 從 1 起算）
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	base := p.BaseDelay
+	if base <= 0 {
+		base = 500 * time.Millisecond
+	}
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = 10 * time.Second
+	}
+
+	d := base << (attempt - 1)
+	if d <= 0 || d > maxDelay {
+		d = maxDelay
+	}
+	// 在 [d/2, d] 之間抖動，避免多個請求同時重試
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// Do 依策略執行 fn，直到成功、遇到不可重試的錯誤、重試次數用盡或 context 截止
+// op 用於日誌，fn 收到的 attempt 從 1 起算
+func (p RetryPolicy) Do(ctx context.Context, op string, fn func(attempt int) error) error {
+	maxAttempts := p.MaxRetries + 1
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = fn(attempt)
+		if err == nil {
+			if attempt > 1 {
+				common.LogInfo("AI 請求重試成功",
+					zap.String("op", op),
+					zap.Int("attempt", attempt),
+				)
+			}
+			return nil
+		}
+		if attempt == maxAttempts || !IsRetryable(err) || ctx.Err() != nil {
+			break
+		}
+
+		delay := p.backoff(attempt)
+		var se *StatusError
+		if errors.As(err, &se) && se.RetryAfter > delay {
+			delay = se.RetryAfter
+		}
+
+		// 等待後已超過請求截止時間則不再重試
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			common.LogWarn("剩餘時間不足，放棄重試",
+				zap.String("op", op),
+				zap.Int("attempt", attempt),
+				zap.Duration("delay", delay),
+				zap.Error(err),
+			)
+			break
+		}
+
+		common.LogWarn("AI 請求失敗，準備重試",
+			zap.String("op", op),
+			zap.Int("attempt", attempt),
+			zap.Int("max_attempts", maxAttempts),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	common.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"canceled", context.Canceled, false},
+		{"permanent", Permanent(ErrEmptyChoices), false},
+		{"429", &StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{"503", &StatusError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"400", &StatusError{StatusCode: http.StatusBadRequest}, false},
+		{"empty choices", ErrEmptyChoices, true},
+		// 單次請求逾時與呼叫端逾時同樣帶有 DeadlineExceeded，交由 Do 依 ctx.Err() 區分
+		{"deadline exceeded", context.DeadlineExceeded, true},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("%s: IsRetryable = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDoRetriesPerAttemptTimeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+	client := &http.Client{Timeout: 20 * time.Millisecond}
+
+	policy := RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	attempts := 0
+	err := policy.Do(context.Background(), "test", func(int) error {
+		attempts++
+		resp, err := client.Get(slow.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want client timeout", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestDoStopsAtCallerDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	policy := RetryPolicy{MaxRetries: 5, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	attempts := 0
+	err := policy.Do(ctx, "test", func(int) error {
+		attempts++
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || attempts != 1 {
+		t.Fatalf("err = %v, attempts = %d; want one attempt", err, attempts)
+	}
+}
@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"recipe-generator/internal/pkg/common"
+)
+
+// DeltaFunc 接收串流中的增量內容，回傳錯誤時中止串流
+type DeltaFunc func(delta string) error
+
+// StreamProvider 支援串流輸出的提供者
+type StreamProvider interface {
+	Provider
+
+	// GenerateStream 以串流方式生成響應，每收到一段內容即呼叫 onDelta，
+	// 結束後回傳完整內容
+	GenerateStream(ctx context.Context, req *Request, onDelta DeltaFunc) (*Response, error)
+}
+
+// Stream 以串流方式呼叫提供者；不支援串流的提供者會在完成後一次送出全部內容
+func Stream(ctx context.Context, p Provider, req *Request, onDelta DeltaFunc) (*Response, error) {
+	if sp, ok := p.(StreamProvider); ok {
+		return sp.GenerateStream(ctx, req, onDelta)
+	}
+
+	resp, err := p.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Content != "" {
+		if err := onDelta(resp.Content); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// chatStreamChunk OpenAI 相容 chat/completions 串流片段
+type chatStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// ReadChatStream 解析 OpenAI 相容的 SSE 串流（data: {...} / data: [DONE]），
+// 逐段轉發內容並組合完整響應
+func ReadChatStream(r io.Reader, onDelta DeltaFunc) (*Response, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var content strings.Builder
+	out := &Response{}
+	chunks := 0
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// 空行分隔事件，冒號開頭為註解（OpenRouter 以此保持連線）
+		if line == "" || strings.HasPrefix(line, ":") {
+			continue
+		}
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatStreamChunk
+		if err := common.ParseJSON(data, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse stream chunk: %w: %v", ErrUnparseableResponse, err)
+		}
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("stream error: %s", chunk.Error.Message)
+		}
+		chunks++
+
+		if chunk.Usage != nil {
+			out.Usage.PromptTokens = chunk.Usage.PromptTokens
+			out.Usage.CompletionTokens = chunk.Usage.CompletionTokens
+			out.Usage.TotalTokens = chunk.Usage.TotalTokens
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			if err := onDelta(choice.Delta.Content); err != nil {
+				return nil, Permanent(err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	if chunks == 0 || content.Len() == 0 {
+		return nil, fmt.Errorf("no content in stream: %w", ErrEmptyChoices)
+	}
+
+	out.Content = content.String()
+	return out, nil
+}
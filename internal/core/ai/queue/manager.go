@@ -2,6 +2,7 @@ package queue
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -13,13 +14,23 @@ import (
 	"go.uber.org/zap"
 )
 
+// ErrQueueFull 隊列已滿
+var ErrQueueFull = errors.New("queue is full")
+
+// ErrQueueClosed 隊列管理器已關閉
+var ErrQueueClosed = errors.New("queue manager is closed")
+
 // Request 隊列請求
 type Request struct {
 	Context context.Context
 	Request *openrouter.Request
+	JobID   string // 非同步任務 ID，由任務管理器處理
 	Result  chan Result
 }
 
+// Handler 工作者處理隊列請求的函式
+type Handler func(req *Request)
+
 // Result 處理結果
 type Result struct {
 	Response *openrouter.Response
@@ -32,6 +43,7 @@ type Status struct {
 	ProcessedCount int `json:"processed_count"`
 	MaxQueueSize   int `json:"max_queue_size"`
 	Workers        int `json:"workers"`
+	ActiveWorkers  int `json:"active_workers"`
 }
 
 // Manager 隊列管理器
@@ -40,7 +52,11 @@ type Manager struct {
 	queue     chan *Request
 	done      chan struct{}
 	processed int64
+	active    int64
 	mu        sync.RWMutex
+	wg        sync.WaitGroup
+	started   bool
+	closeOnce sync.Once
 }
 
 // NewManager 創建新的隊列管理器
@@ -62,7 +78,7 @@ func (m *Manager) GetQueue() <-chan *Request {
 func (m *Manager) Enqueue(ctx context.Context, req *openrouter.Request) (chan Result, error) {
 	// 檢查隊列容量
 	if len(m.queue) >= m.config.Queue.MaxSize {
-		return nil, fmt.Errorf("queue is full")
+		return nil, ErrQueueFull
 	}
 
 	// 創建隊列請求
@@ -83,7 +99,111 @@ func (m *Manager) Enqueue(ctx context.Context, req *openrouter.Request) (chan Re
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	case <-m.done:
-		return nil, fmt.Errorf("queue manager is closed")
+		return nil, ErrQueueClosed
+	}
+}
+
+// EnqueueJob 將非同步任務加入隊列，隊列已滿時立即返回 ErrQueueFull
+func (m *Manager) EnqueueJob(jobID string) error {
+	select {
+	case <-m.done:
+		return ErrQueueClosed
+	default:
+	}
+
+	select {
+	case m.queue <- &Request{Context: context.Background(), JobID: jobID}:
+		common.LogDebug("Job enqueued",
+			zap.String("job_id", jobID),
+			zap.Int("queue_length", len(m.queue)),
+		)
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// EnqueueJobWait 將非同步任務加入隊列，隊列已滿時等待空位直到 ctx 結束
+func (m *Manager) EnqueueJobWait(ctx context.Context, jobID string) error {
+	select {
+	case m.queue <- &Request{Context: context.Background(), JobID: jobID}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-m.done:
+		return ErrQueueClosed
+	}
+}
+
+// Start 啟動工作者池，依 Queue.Workers 設定的數量消費隊列
+func (m *Manager) Start(handler Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.started {
+		return
+	}
+	m.started = true
+
+	workers := m.config.Queue.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go m.worker(i, handler)
+	}
+
+	common.LogInfo("Queue workers started", zap.Int("workers", workers))
+}
+
+// worker 持續從隊列取出請求，直到管理器關閉
+func (m *Manager) worker(id int, handler Handler) {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.done:
+			return
+		case req, ok := <-m.queue:
+			if !ok {
+				return
+			}
+			m.run(id, handler, req)
+		}
+	}
+}
+
+// run 執行單一請求並攔截 panic，避免工作者退出
+func (m *Manager) run(id int, handler Handler, req *Request) {
+	atomic.AddInt64(&m.active, 1)
+	defer atomic.AddInt64(&m.active, -1)
+	defer m.IncrementProcessed()
+	defer func() {
+		if r := recover(); r != nil {
+			common.LogError("Queue worker panic recovered",
+				zap.Int("worker", id),
+				zap.String("job_id", req.JobID),
+				zap.Any("panic", r),
+			)
+		}
+	}()
+	handler(req)
+}
+
+// Stop 停止接收新請求並等待工作者完成手上的請求，ctx 到期時直接返回
+func (m *Manager) Stop(ctx context.Context) error {
+	m.closeOnce.Do(func() { close(m.done) })
+
+	finished := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for queue workers: %w", ctx.Err())
 	}
 }
 
@@ -94,9 +214,10 @@ func (m *Manager) GetQueueStatus() *Status {
 
 	return &Status{
 		QueueLength:    len(m.queue),
-		ProcessedCount: int(m.processed),
+		ProcessedCount: int(atomic.LoadInt64(&m.processed)),
 		MaxQueueSize:   m.config.Queue.MaxSize,
 		Workers:        m.config.Queue.Workers,
+		ActiveWorkers:  int(atomic.LoadInt64(&m.active)),
 	}
 }
 
@@ -107,11 +228,11 @@ func (m *Manager) IncrementProcessed() {
 
 // Done 關閉隊列管理器
 func (m *Manager) Done() {
-	close(m.done)
+	m.closeOnce.Do(func() { close(m.done) })
 }
 
 // Close 關閉隊列管理器
+// 不關閉 queue channel，避免與仍在進行的 Enqueue 競爭而 panic
 func (m *Manager) Close() {
-	close(m.done)
-	close(m.queue)
+	m.closeOnce.Do(func() { close(m.done) })
 }
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+// CoalesceStats 進行中請求合併統計
+type CoalesceStats struct {
+	InFlight  int   `json:"in_flight"` // 目前進行中的上游呼叫
+	Calls     int64 `json:"calls"`     // 實際發出的上游呼叫
+	Coalesced int64 `json:"coalesced"` // 合併到既有呼叫、因此省下的上游呼叫
+	Canceled  int64 `json:"canceled"`  // 所有等待者都離開而中止的上游呼叫
+}
+
+// flightFunc 實際執行上游呼叫；onDelta 不為 nil 時以串流方式呼叫
+type flightFunc func(ctx context.Context, onDelta provider.DeltaFunc) (*Response, error)
+
+// flight 一個進行中的上游呼叫，由所有相同請求共享
+type flight struct {
+	key    string
+	done   chan struct{}
+	cancel context.CancelFunc
+	resp   *Response
+	err    error
+
+	// mu 保護串流內容與訂閱者；轉發增量時持有，確保中途加入者補送與後續增量順序一致
+	mu        sync.Mutex
+	streaming bool
+	content   strings.Builder
+	subs      map[int]provider.DeltaFunc
+	nextID    int
+	waiters   int // 由 flightGroup.mu 與 mu 共同保護，加入與離開時都持有兩者
+}
+
+// flightGroup 依請求鍵合併同時進行的相同 AI 請求，共用一次上游呼叫與結果
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+
+	calls     atomic.Int64
+	coalesced atomic.Int64
+	canceled  atomic.Int64
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{flights: make(map[string]*flight)}
+}
+
+// do 執行或加入鍵為 key 的上游呼叫
+// 上游呼叫與發起者的 context 脫鉤，只有在所有等待者都離開時才取消，取消的呼叫同時移出群組，
+// 之後的相同請求會發起新的呼叫；streamed 表示結果是否已透過 onDelta 逐段送出
+func (g *flightGroup) do(ctx context.Context, key string, onDelta provider.DeltaFunc, fn flightFunc) (resp *Response, streamed, shared bool, err error) {
+	g.mu.Lock()
+	f, shared := g.flights[key]
+	if !shared {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{
+			key:       key,
+			done:      make(chan struct{}),
+			cancel:    cancel,
+			streaming: onDelta != nil,
+			subs:      make(map[int]provider.DeltaFunc),
+			waiters:   1,
+		}
+		g.flights[key] = f
+		g.calls.Add(1)
+		go g.run(callCtx, f, fn)
+	} else {
+		f.mu.Lock()
+		f.waiters++
+		f.mu.Unlock()
+		g.coalesced.Add(1)
+	}
+	g.mu.Unlock()
+
+	if shared {
+		common.LogInfo("合併進行中的相同 AI 請求",
+			zap.String("key", key),
+			zap.Bool("stream", onDelta != nil),
+		)
+	}
+
+	id, err := f.join(onDelta)
+	if err != nil {
+		g.leave(f)
+		return nil, false, shared, err
+	}
+
+	select {
+	case <-f.done:
+		f.unsubscribe(id)
+		return f.resp, f.streaming && onDelta != nil, shared, f.err
+	case <-ctx.Done():
+		f.unsubscribe(id)
+		g.leave(f)
+		return nil, false, shared, ctx.Err()
+	}
+}
+
+// run 執行上游呼叫並廣播結果
+func (g *flightGroup) run(ctx context.Context, f *flight, fn flightFunc) {
+	defer f.cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			common.LogError("AI 請求發生 panic", zap.Any("panic", r), zap.String("key", f.key))
+			f.err = fmt.Errorf("AI request panicked: %v", r)
+		}
+
+		g.unlink(f)
+		close(f.done)
+	}()
+
+	var onDelta provider.DeltaFunc
+	if f.streaming {
+		onDelta = f.broadcast
+	}
+	f.resp, f.err = fn(ctx, onDelta)
+}
+
+// leave 等待者離開；最後一個等待者離開時在同一把鎖內移出群組並取消上游呼叫，
+// 新的相同請求不會加入已取消的呼叫
+func (g *flightGroup) leave(f *flight) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	f.mu.Lock()
+	f.waiters--
+	last := f.waiters == 0
+	f.mu.Unlock()
+
+	if !last {
+		return
+	}
+	select {
+	case <-f.done:
+	default:
+		if g.flights[f.key] == f {
+			delete(g.flights, f.key)
+		}
+		g.canceled.Add(1)
+		f.cancel()
+	}
+}
+
+// unlink 呼叫結束時移出群組；已被取消並由新的呼叫取代時不影響新的呼叫
+func (g *flightGroup) unlink(f *flight) {
+	g.mu.Lock()
+	if g.flights[f.key] == f {
+		delete(g.flights, f.key)
+	}
+	g.mu.Unlock()
+}
+
+// stats 回傳合併統計
+func (g *flightGroup) stats() CoalesceStats {
+	g.mu.Lock()
+	inFlight := len(g.flights)
+	g.mu.Unlock()
+
+	return CoalesceStats{
+		InFlight:  inFlight,
+		Calls:     g.calls.Load(),
+		Coalesced: g.coalesced.Load(),
+		Canceled:  g.canceled.Load(),
+	}
+}
+
+// join 訂閱增量（等待者已在 do 中計入）；串流呼叫會先補送已收到的內容再訂閱後續增量
+func (f *flight) join(onDelta provider.DeltaFunc) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if onDelta == nil || !f.streaming {
+		return -1, nil
+	}
+	if f.content.Len() > 0 {
+		if err := onDelta(f.content.String()); err != nil {
+			return -1, err
+		}
+	}
+	id := f.nextID
+	f.nextID++
+	f.subs[id] = onDelta
+	return id, nil
+}
+
+// unsubscribe 取消增量訂閱
+func (f *flight) unsubscribe(id int) {
+	if id < 0 {
+		return
+	}
+	f.mu.Lock()
+	delete(f.subs, id)
+	f.mu.Unlock()
+}
+
+// broadcast 將增量轉發給所有訂閱者；單一訂閱者失敗只移除該訂閱者，不中止上游呼叫
+func (f *flight) broadcast(delta string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.content.WriteString(delta)
+	for id, sub := range f.subs {
+		if err := sub(delta); err != nil {
+			delete(f.subs, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	common.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+func TestFlightGroupCoalesces(t *testing.T) {
+	g := newFlightGroup()
+	release := make(chan struct{})
+	var calls atomic.Int32
+	fn := func(ctx context.Context, onDelta provider.DeltaFunc) (*Response, error) {
+		calls.Add(1)
+		<-release
+		return &Response{Content: "ok"}, nil
+	}
+
+	const n = 5
+	var wg sync.WaitGroup
+	var sharedCount atomic.Int32
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, _, shared, err := g.do(context.Background(), "key", nil, fn)
+			if err != nil || resp.Content != "ok" {
+				t.Errorf("do: %v, %v", resp, err)
+			}
+			if shared {
+				sharedCount.Add(1)
+			}
+		}()
+	}
+	waitFor(t, func() bool { return g.stats().Coalesced == n-1 })
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 || sharedCount.Load() != n-1 {
+		t.Fatalf("calls=%d shared=%d", calls.Load(), sharedCount.Load())
+	}
+	if s := g.stats(); s.InFlight != 0 || s.Calls != 1 {
+		t.Fatalf("stats: %+v", s)
+	}
+}
+
+func TestFlightGroupStreamingLateJoiner(t *testing.T) {
+	g := newFlightGroup()
+	first := make(chan struct{})
+	release := make(chan struct{})
+	fn := func(ctx context.Context, onDelta provider.DeltaFunc) (*Response, error) {
+		onDelta("hello ")
+		close(first)
+		<-release
+		onDelta("world")
+		return &Response{Content: "hello world"}, nil
+	}
+
+	var mu sync.Mutex
+	var a, b string
+	done := make(chan struct{})
+	go func() {
+		g.do(context.Background(), "key", func(d string) error { mu.Lock(); a += d; mu.Unlock(); return nil }, fn)
+		close(done)
+	}()
+	<-first
+
+	// 中途加入者先收到已送出的內容，再收到後續增量
+	joined := make(chan struct{})
+	go func() {
+		_, streamed, shared, err := g.do(context.Background(), "key", func(d string) error { mu.Lock(); b += d; mu.Unlock(); return nil }, fn)
+		if err != nil || !streamed || !shared {
+			t.Errorf("late joiner: streamed=%v shared=%v err=%v", streamed, shared, err)
+		}
+		close(joined)
+	}()
+	waitFor(t, func() bool { mu.Lock(); defer mu.Unlock(); return b != "" })
+	close(release)
+	<-done
+	<-joined
+
+	if a != "hello world" || b != "hello world" {
+		t.Fatalf("a=%q b=%q", a, b)
+	}
+}
+
+func TestFlightGroupCancelWhenAllWaitersLeave(t *testing.T) {
+	g := newFlightGroup()
+	started := make(chan struct{})
+	upstreamCanceled := make(chan struct{})
+	fn := func(ctx context.Context, onDelta provider.DeltaFunc) (*Response, error) {
+		close(started)
+		<-ctx.Done()
+		close(upstreamCanceled)
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, _, _, err := g.do(ctx, "key", nil, fn)
+		errc <- err
+	}()
+	<-started
+	cancel()
+
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("waiter err: %v", err)
+	}
+	select {
+	case <-upstreamCanceled:
+	case <-time.After(time.Second):
+		t.Fatal("upstream call not canceled")
+	}
+	if s := g.stats(); s.Canceled != 1 {
+		t.Fatalf("stats: %+v", s)
+	}
+}
+
+// 最後一個等待者離開到上游呼叫實際結束之間，新的相同請求不可加入已取消的呼叫
+func TestFlightGroupNewRequestAfterCancel(t *testing.T) {
+	g := newFlightGroup()
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	var calls atomic.Int32
+	slowCancel := func(ctx context.Context, onDelta provider.DeltaFunc) (*Response, error) {
+		calls.Add(1)
+		close(started)
+		<-ctx.Done()
+		<-finish // 模擬取消後仍需一段時間才返回的上游呼叫
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, _, _, err := g.do(ctx, "key", nil, slowCancel)
+		errc <- err
+	}()
+	<-started
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first waiter err: %v", err)
+	}
+
+	// 加入已取消的呼叫時會一直等到 finish，以逾時避免測試卡住
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel2()
+	resp, _, shared, err := g.do(ctx2, "key", nil, func(ctx context.Context, onDelta provider.DeltaFunc) (*Response, error) {
+		calls.Add(1)
+		return &Response{Content: "fresh"}, nil
+	})
+	close(finish)
+	if err != nil || shared || resp.Content != "fresh" {
+		t.Fatalf("new request joined the canceled flight: resp=%v shared=%v err=%v", resp, shared, err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("calls=%d, want 2", calls.Load())
+	}
+	waitFor(t, func() bool { return g.stats().InFlight == 0 })
+}
+
+// waitFor 等待條件成立
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
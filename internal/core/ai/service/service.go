@@ -9,9 +9,17 @@ import (
 	"time"
 
 	"recipe-generator/internal/core/ai/cache"
+	"recipe-generator/internal/core/ai/fake"
+	"recipe-generator/internal/core/ai/openai"
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/core/experiment"
 	"recipe-generator/internal/core/image"
+	promptpkg "recipe-generator/internal/core/prompt"
 	openrouter "recipe-generator/internal/core/service"
 	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
 )
 
 // Response AI 回應結構
@@ -20,42 +28,299 @@ import (
 // 若有多欄位可自訂 struct
 
 type Response struct {
-	Content string
+	Content  string
+	Provider string // 實際回應的提供者，快取命中時為 "cache"
+	Model    string // 實際使用的模型
+	Template string // 提示詞模板 ID 與版本，未使用模板時為空
 }
 
 // Service AI 服務
 type Service struct {
 	config       *config.Config
-	openRouter   *openrouter.OpenRouterService
-	cacheManager *cache.CacheManager
+	provider     provider.Provider
+	cacheManager cache.Cache
 	imageSvc     *image.Service
+	flights      *flightGroup
 	mu           sync.RWMutex
-	lastRequest  time.Time
+	windowStart  time.Time // 目前限流視窗的起點
+	windowCount  int       // 目前視窗內已送出的請求數
 }
 
 // NewService 創建 AI 服務
-func NewService(cfg *config.Config, cacheManager *cache.CacheManager) (*Service, error) {
-	// 創建 OpenRouter 服務
-	openRouter := openrouter.NewOpenRouterService(cfg)
+func NewService(cfg *config.Config, cacheManager cache.Cache) (*Service, error) {
+	p, err := NewProvider(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AI provider: %w", err)
+	}
+
+	common.LogInfo("AI provider initialized",
+		zap.String("provider", cfg.AI.Provider),
+		zap.Strings("fallbacks", cfg.AI.Fallbacks),
+		zap.String("model", p.GetModel()),
+		zap.Duration("timeout", p.GetTimeout()),
+	)
 
+	return NewServiceWithProvider(cfg, cacheManager, p), nil
+}
+
+// NewProvider 依設定從註冊表建立 AI 提供者，設定備援時組成失敗轉移鏈
+func NewProvider(cfg *config.Config) (provider.Provider, error) {
+	primary, err := provider.New(cfg.AI.Provider, ProviderConfig(cfg, cfg.AI.Provider))
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.AI.Fallbacks) == 0 {
+		return primary, nil
+	}
+
+	members := []provider.Member{{
+		Name:     cfg.AI.Provider + "@" + primary.GetModel(),
+		Provider: primary,
+	}}
+	for _, spec := range cfg.AI.Fallbacks {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		name, model, _ := strings.Cut(spec, "@")
+		pcfg := ProviderConfig(cfg, name)
+		if model != "" {
+			pcfg.Model = model
+		}
+		p, err := provider.New(name, pcfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fallback %q: %w", spec, err)
+		}
+		members = append(members, provider.Member{Name: name + "@" + p.GetModel(), Provider: p})
+	}
+
+	return provider.NewChain(members, provider.BreakerConfig{
+		FailureThreshold: cfg.AI.Breaker.FailureThreshold,
+		Cooldown:         cfg.AI.Breaker.Cooldown,
+		HalfOpenRequests: cfg.AI.Breaker.HalfOpenRequests,
+	})
+}
+
+// NewServiceWithProvider 使用指定的 AI 提供者創建 AI 服務
+func NewServiceWithProvider(cfg *config.Config, cacheManager cache.Cache, p provider.Provider) *Service {
 	// 創建圖片處理服務
-	imageSvc := image.NewService(cfg.Image.MaxSizeBytes)
+	imageSvc := image.NewService(cfg.Image)
 
 	return &Service{
 		config:       cfg,
-		openRouter:   openRouter,
+		provider:     p,
 		cacheManager: cacheManager,
 		imageSvc:     imageSvc,
-	}, nil
+		flights:      newFlightGroup(),
+	}
+}
+
+// ProviderConfig 將應用設定轉換為指定提供者的設定
+func ProviderConfig(cfg *config.Config, name string) provider.Config {
+	pcfg := providerConfig(cfg, name)
+	pcfg.FixtureMode = cfg.AI.Fixtures.Mode
+	pcfg.FixtureDir = cfg.AI.Fixtures.Dir
+	return pcfg
+}
+
+// providerConfig 各提供者的連線設定
+func providerConfig(cfg *config.Config, name string) provider.Config {
+	switch name {
+	case openai.ProviderName:
+		return provider.Config{
+			APIKey:     cfg.OpenAI.APIKey,
+			Model:      cfg.OpenAI.Model,
+			Timeout:    cfg.OpenAI.Timeout,
+			BaseURL:    cfg.OpenAI.BaseURL,
+			MaxTokens:  cfg.OpenAI.MaxTokens,
+			JSONSchema: cfg.OpenAI.JSONSchema,
+		}
+	case fake.ProviderName:
+		return provider.Config{Model: "fake-model", Scenario: cfg.Fake.Scenario}
+	case openrouter.ProviderName:
+		fallthrough
+	default:
+		return provider.Config{
+			APIKey:     cfg.OpenRouter.APIKey,
+			Model:      cfg.OpenRouter.Model,
+			Timeout:    cfg.OpenRouter.Timeout,
+			BaseURL:    cfg.OpenRouter.BaseURL,
+			MaxTokens:  cfg.OpenRouter.MaxTokens,
+			MaxRetries: cfg.OpenRouter.MaxRetries,
+			JSONSchema: cfg.OpenRouter.JSONSchema,
+		}
+	}
+}
+
+// Provider 回傳目前使用的 AI 提供者
+func (s *Service) Provider() provider.Provider {
+	return s.provider
+}
+
+// ProviderStatus 回傳失敗轉移鏈中各提供者的健康狀態，未設定備援時回傳 nil
+func (s *Service) ProviderStatus() []provider.MemberStatus {
+	if chain, ok := s.provider.(*provider.Chain); ok {
+		return chain.Status()
+	}
+	return nil
+}
+
+// CoalesceStats 回傳進行中請求合併的統計
+func (s *Service) CoalesceStats() CoalesceStats {
+	return s.flights.stats()
+}
+
+// Close 關閉 AI 提供者連接
+func (s *Service) Close() error {
+	return s.provider.Close()
 }
 
 // ProcessRequest 統一對外方法
 func (s *Service) ProcessRequest(ctx context.Context, prompt string, imageData string) (*Response, error) {
+	return s.process(ctx, prompt, nil, imageData, nil, nil)
+}
+
+// ProcessRequestStream 與 ProcessRequest 相同，但以串流方式將增量內容交給 onDelta；
+// 快取命中時會一次送出完整內容
+func (s *Service) ProcessRequestStream(ctx context.Context, prompt string, imageData string, onDelta provider.DeltaFunc) (*Response, error) {
+	return s.process(ctx, prompt, nil, imageData, nil, onDelta)
+}
+
+// process 處理 AI 請求：tmpl 為產生 prompt 的模板（可為 nil），其版本與覆寫的模型、溫度會納入快取鍵；
+// format 不為 nil 時驗證回應結構，onDelta 不為 nil 時使用串流
+func (s *Service) process(ctx context.Context, prompt string, tmpl *promptpkg.Prompt, imageData string, format *provider.ResponseFormat, onDelta provider.DeltaFunc) (*Response, error) {
+	prompt, processedImageData, err := s.normalize(ctx, prompt, imageData)
+	if err != nil {
+		return nil, err
+	}
+	template := templateLabel(tmpl)
+	if template != "" {
+		provider.MetadataFromContext(ctx).RecordTemplate(template)
+	}
+	keyPrompt := cachePrompt(templateKey(tmpl), prompt)
+
+	// 檢查緩存（用 cacheManager）
+	if s.config.Cache.Enabled && s.cacheManager != nil {
+		// 不符合結構描述的舊條目（例如升級前寫入的）視為未命中
+		if val, err := s.cacheManager.Get(ctx, keyPrompt, processedImageData); err == nil && val != "" && conforms(format, val) {
+			provider.MetadataFromContext(ctx).Record("cache", "")
+			if tmpl != nil {
+				experiment.ObserveCacheHit(ctx, tmpl.ID)
+			}
+			if onDelta != nil {
+				if err := onDelta(val); err != nil {
+					return nil, err
+				}
+			}
+			return &Response{Content: val, Provider: "cache", Template: template}, nil
+		}
+	}
+
+	// 未開啟合併時每個請求各自呼叫上游
+	if !s.config.AI.Coalesce {
+		return s.generate(ctx, prompt, tmpl, processedImageData, format, onDelta)
+	}
+
+	// 相同的請求同時進行時共用一次上游呼叫，鍵與快取一致
+	resp, streamed, shared, err := s.flights.do(ctx, cache.Key(keyPrompt, processedImageData), onDelta,
+		func(ctx context.Context, onDelta provider.DeltaFunc) (*Response, error) {
+			return s.generate(ctx, prompt, tmpl, processedImageData, format, onDelta)
+		})
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		provider.MetadataFromContext(ctx).Record(resp.Provider, resp.Model)
+	}
+	// 加入的是非串流呼叫時，一次送出完整內容
+	if onDelta != nil && !streamed {
+		if err := onDelta(resp.Content); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// generate 呼叫上游 AI 提供者並寫入快取，onDelta 不為 nil 時使用串流；
+// format 不為 nil 時回應不符合結構描述會要求模型修正，修正失敗的內容不寫入快取
+func (s *Service) generate(ctx context.Context, prompt string, tmpl *promptpkg.Prompt, processedImageData string, format *provider.ResponseFormat, onDelta provider.DeltaFunc) (*Response, error) {
 	if err := s.checkRequestRate(); err != nil {
 		return nil, err
 	}
 
-	// 統一 prompt 格式，去除多餘空白、tab、換行，確保快取 key 一致
+	msg := provider.Message{Role: "user", Content: prompt}
+	if processedImageData != "" {
+		msg.Images = []string{processedImageData}
+	}
+
+	req := &provider.Request{Messages: []provider.Message{msg}, ResponseFormat: format}
+	template, variant := "", ""
+	if tmpl != nil {
+		template, variant = tmpl.Label(), tmpl.Variant
+		req.Model = tmpl.Model
+		req.Temperature = tmpl.Temperature
+	}
+	start := time.Now()
+	var resp *provider.Response
+	var err error
+	if onDelta != nil {
+		resp, err = provider.Stream(ctx, s.provider, req, onDelta)
+	} else {
+		resp, err = s.provider.Generate(ctx, req)
+	}
+	if err != nil {
+		common.LogError("AI 請求失敗",
+			zap.String("provider", s.config.AI.Provider),
+			zap.String("prompt_template", template),
+			zap.String("variant", variant),
+			zap.Bool("stream", onDelta != nil),
+			zap.Duration("latency", time.Since(start)),
+			zap.Error(err),
+		)
+		if tmpl != nil {
+			experiment.ObserveError(ctx, tmpl.ID)
+		}
+		return nil, err
+	}
+	valid := true
+	if format != nil {
+		resp, valid = s.repair(ctx, req, resp)
+	}
+	content := resp.Content
+
+	response := &Response{Content: content, Provider: resp.Provider, Model: resp.Model, Template: template}
+	if response.Provider == "" {
+		response.Provider = s.config.AI.Provider
+	}
+	if response.Model == "" {
+		response.Model = provider.ModelFor(s.provider, req)
+	}
+	provider.MetadataFromContext(ctx).Record(response.Provider, response.Model)
+
+	latency := time.Since(start)
+	common.LogInfo("AI 請求完成",
+		zap.String("provider", response.Provider),
+		zap.String("model", response.Model),
+		zap.String("prompt_template", template),
+		zap.String("variant", variant),
+		zap.Duration("latency", latency),
+		zap.Int("total_tokens", resp.Usage.TotalTokens),
+		zap.Bool("stream", onDelta != nil),
+	)
+	if tmpl != nil {
+		experiment.ObserveCall(ctx, tmpl.ID, latency, resp.Usage.TotalTokens, valid)
+	}
+
+	if valid && s.config.Cache.Enabled && s.cacheManager != nil {
+		_ = s.cacheManager.Set(ctx, cachePrompt(templateKey(tmpl), prompt), processedImageData, content)
+	}
+
+	return response, nil
+}
+
+// normalize 統一 prompt 格式並處理圖片，確保相同請求得到相同的快取鍵
+func (s *Service) normalize(ctx context.Context, prompt, imageData string) (string, string, error) {
+	// 去除多餘空白、tab、換行
 	prompt = strings.TrimSpace(prompt)
 	prompt = strings.ReplaceAll(prompt, "\t", "")
 	prompt = strings.ReplaceAll(prompt, "\n", "")
@@ -64,43 +329,78 @@ func (s *Service) ProcessRequest(ctx context.Context, prompt string, imageData s
 	var processedImageData string
 	if imageData != "" {
 		var err error
-		processedImageData, err = s.imageSvc.ProcessImage(imageData)
+		processedImageData, err = s.imageSvc.ProcessImage(ctx, imageData)
 		if err != nil {
-			return nil, fmt.Errorf("failed to process image: %w", err)
-		}
-	}
-
-	// 檢查緩存（用 cacheManager）
-	if s.config.Cache.Enabled && s.cacheManager != nil {
-		if val, err := s.cacheManager.Get(ctx, prompt, processedImageData); err == nil && val != "" {
-			return &Response{Content: val}, nil
+			return "", "", fmt.Errorf("failed to process image: %w", err)
 		}
 	}
+	return prompt, processedImageData, nil
+}
 
-	content, err := s.openRouter.GenerateResponse(ctx, prompt, processedImageData)
+// InvalidateCache 以與 ProcessRequest 相同的正規化計算快取鍵並刪除該條目，回傳快取鍵；
+// prompt 由模板產生時 template 需填入模板 ID 與版本，實驗變體覆寫模型或溫度時再附加
+// |model=...|temperature=...（同 prompt.Prompt.Key）。圖片無法處理時回傳空鍵
+func (s *Service) InvalidateCache(ctx context.Context, prompt, template, imageData string) (string, error) {
+	prompt, processedImageData, err := s.normalize(ctx, prompt, imageData)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	prompt = cachePrompt(template, prompt)
+	key := cache.Key(prompt, processedImageData)
+	if s.cacheManager == nil {
+		return key, nil
+	}
+	return key, s.cacheManager.Delete(ctx, prompt, processedImageData)
+}
 
-	response := &Response{Content: content}
+// templateLabel 模板 ID 與版本，未使用模板時為空
+func templateLabel(tmpl *promptpkg.Prompt) string {
+	if tmpl == nil {
+		return ""
+	}
+	return tmpl.Label()
+}
 
-	if s.config.Cache.Enabled && s.cacheManager != nil {
-		_ = s.cacheManager.Set(ctx, prompt, processedImageData, content)
+// templateKey 快取鍵中的模板與呼叫參數，未使用模板時為空
+func templateKey(tmpl *promptpkg.Prompt) string {
+	if tmpl == nil {
+		return ""
 	}
+	return tmpl.Key()
+}
 
-	return response, nil
+// cachePrompt 計算快取鍵用的 prompt：由模板產生時加上模板 ID、版本與覆寫的呼叫參數，
+// 模板改版或實驗變體不同時不會沿用彼此的快取
+func cachePrompt(template, prompt string) string {
+	if template == "" {
+		return prompt
+	}
+	return "template=" + template + "|" + prompt
 }
 
-// checkRequestRate 檢查請求頻率
+// checkRequestRate 檢查請求頻率：每個視窗內最多送出 RateLimit.Requests 個請求，
+// 多張圖片並行辨識時才不會互相擋下
 func (s *Service) checkRequestRate() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if !s.config.RateLimit.Enabled {
+		return nil
+	}
+
 	now := time.Now()
-	if s.config.RateLimit.Enabled && now.Sub(s.lastRequest) < s.config.RateLimit.Window {
+	if now.Sub(s.windowStart) >= s.config.RateLimit.Window {
+		s.windowStart = now
+		s.windowCount = 0
+	}
+	limit := s.config.RateLimit.Requests
+	if limit <= 0 {
+		limit = 1
+	}
+	if s.windowCount >= limit {
 		return errors.New("request rate limit exceeded")
 	}
 
-	s.lastRequest = now
+	s.windowCount++
 	return nil
 }
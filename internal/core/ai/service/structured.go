@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/core/prompt"
+	"recipe-generator/internal/pkg/common"
+	"recipe-generator/internal/pkg/jsonschema"
+
+	"go.uber.org/zap"
+)
+
+// ProcessStructured 以模板產生的提示詞呼叫 AI，並要求回應符合 format 的 JSON Schema：
+// 提供者啟用時以 response_format 原生傳送，回應不符合時附上驗證錯誤要求模型修正，
+// 最多 AI_REPAIR_ATTEMPTS 次；仍不符合時回傳最後一次的內容，由呼叫端以寬鬆解析與預設值處理
+func (s *Service) ProcessStructured(ctx context.Context, p *prompt.Prompt, imageData string, format *provider.ResponseFormat) (*Response, error) {
+	return s.process(ctx, p.Text, p, imageData, format, nil)
+}
+
+// ProcessStructuredStream 與 ProcessStructured 相同，但以串流方式將第一次回應的增量內容交給 onDelta；
+// 修正請求不串流，呼叫端應以回傳的完整內容為準
+func (s *Service) ProcessStructuredStream(ctx context.Context, p *prompt.Prompt, imageData string, format *provider.ResponseFormat, onDelta provider.DeltaFunc) (*Response, error) {
+	return s.process(ctx, p.Text, p, imageData, format, onDelta)
+}
+
+// repair 驗證回應內容，不符合結構描述時把上一次的回應與驗證錯誤加入對話重新要求；
+// 回傳最後一次的回應與是否通過驗證
+func (s *Service) repair(ctx context.Context, req *provider.Request, resp *provider.Response) (*provider.Response, bool) {
+	format := req.ResponseFormat
+	errs := validate(format.Schema, resp.Content)
+	for attempt := 1; len(errs) > 0 && attempt <= s.config.AI.RepairAttempts; attempt++ {
+		common.LogWarn("AI 回應不符合 JSON Schema，要求模型修正",
+			zap.String("schema", format.Name),
+			zap.Int("attempt", attempt),
+			zap.Strings("errors", errs.Strings()),
+		)
+		if err := s.checkRequestRate(); err != nil {
+			common.LogWarn("AI 回應修正請求被限流", zap.String("schema", format.Name), zap.Error(err))
+			break
+		}
+
+		req.Messages = append(req.Messages,
+			provider.Message{Role: "assistant", Content: resp.Content},
+			provider.Message{Role: "user", Content: repairPrompt(errs)},
+		)
+		next, err := s.provider.Generate(ctx, req)
+		if err != nil {
+			common.LogWarn("AI 回應修正請求失敗",
+				zap.String("schema", format.Name),
+				zap.Int("attempt", attempt),
+				zap.Error(err),
+			)
+			break
+		}
+		resp = next
+		errs = validate(format.Schema, resp.Content)
+	}
+
+	if len(errs) > 0 {
+		common.LogWarn("AI 回應仍不符合 JSON Schema，改用寬鬆解析",
+			zap.String("schema", format.Name),
+			zap.Strings("errors", errs.Strings()),
+		)
+		return resp, false
+	}
+	return resp, true
+}
+
+// validate 取出回應中的 JSON 並驗證
+func validate(schema *jsonschema.Schema, content string) jsonschema.Errors {
+	return schema.Validate([]byte(common.ExtractJSON(content)))
+}
+
+// conforms 判斷內容是否符合結構描述，未指定結構描述時一律符合
+func conforms(format *provider.ResponseFormat, content string) bool {
+	return format == nil || len(validate(format.Schema, content)) == 0
+}
+
+// repairPrompt 產生修正提示，列出每個驗證錯誤的欄位路徑
+func repairPrompt(errs jsonschema.Errors) string {
+	var sb strings.Builder
+	sb.WriteString("你上一次的回應不符合要求的 JSON 結構，錯誤如下（$ 代表最外層物件）：\n")
+	for _, e := range errs {
+		sb.WriteString(fmt.Sprintf("- %s\n", e.Error()))
+	}
+	sb.WriteString("請修正以上所有錯誤，保留其他正確的內容，只回傳完整的 JSON，不要加入任何說明文字或 Markdown 標記。")
+	return sb.String()
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"recipe-generator/internal/core/ai/cache"
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/core/prompt"
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/jsonschema"
+)
+
+type dish struct {
+	Name    string `json:"name"`
+	Minutes int    `json:"minutes"`
+}
+
+var dishFormat = &provider.ResponseFormat{Name: "dish", Schema: jsonschema.For(dish{})}
+
+// scriptedProvider 依序回傳預先準備的回應，並保留每次收到的請求
+type scriptedProvider struct {
+	mu        sync.Mutex
+	responses []string
+	requests  []provider.Request
+}
+
+func (p *scriptedProvider) Generate(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := *req
+	r.Messages = append([]provider.Message(nil), req.Messages...)
+	p.requests = append(p.requests, r)
+	content := p.responses[0]
+	p.responses = p.responses[1:]
+	return &provider.Response{Content: content}, nil
+}
+
+func (p *scriptedProvider) GetModel() string          { return "scripted" }
+func (p *scriptedProvider) GetTimeout() time.Duration { return time.Minute }
+func (p *scriptedProvider) Close() error              { return nil }
+
+// newStructuredService 建立開啟記憶體快取的服務，修正次數為 repairs
+func newStructuredService(t *testing.T, p provider.Provider, repairs int) (*Service, *cache.CacheManager) {
+	t.Helper()
+	cfg := &config.Config{
+		AI: config.AIConfig{Provider: "scripted", RepairAttempts: repairs},
+		Cache: config.CacheConfig{
+			Enabled:         true,
+			MaxBytes:        1 << 20,
+			Shards:          1,
+			TTL:             time.Hour,
+			CleanupInterval: time.Hour,
+		},
+	}
+	m := cache.NewManager(cfg)
+	t.Cleanup(func() { m.Close() })
+	return NewServiceWithProvider(cfg, m, p), m
+}
+
+var dishPrompt = &prompt.Prompt{ID: "dish.test", Version: 1, Text: "給我一道菜"}
+
+func TestProcessStructuredRepairs(t *testing.T) {
+	p := &scriptedProvider{responses: []string{
+		`{"name":"番茄炒蛋","minutes":"十"}`,
+		"```json\n{\"name\":\"番茄炒蛋\",\"minutes\":10}\n```",
+	}}
+	s, m := newStructuredService(t, p, 2)
+	ctx := context.Background()
+
+	resp, err := s.ProcessStructured(ctx, dishPrompt, "", dishFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp.Content, `"minutes":10`) {
+		t.Fatalf("content = %q", resp.Content)
+	}
+	if len(p.requests) != 2 {
+		t.Fatalf("provider called %d times, want 2", len(p.requests))
+	}
+
+	// 修正請求帶上前一次的回應與驗證錯誤
+	repair := p.requests[1].Messages
+	if len(repair) != 3 || repair[1].Role != "assistant" || repair[1].Content != `{"name":"番茄炒蛋","minutes":"十"}` {
+		t.Fatalf("repair messages: %+v", repair)
+	}
+	if !strings.Contains(repair[2].Content, "$.minutes: expected integer, got string") {
+		t.Fatalf("repair prompt: %q", repair[2].Content)
+	}
+	if p.requests[1].ResponseFormat != dishFormat {
+		t.Fatal("repair request lost the response format")
+	}
+
+	// 修正後的回應寫入快取，再次請求不呼叫提供者
+	if _, err := m.Get(ctx, cachePrompt(dishPrompt.Key(), dishPrompt.Text), ""); err != nil {
+		t.Fatalf("repaired response not cached: %v", err)
+	}
+	again, err := s.ProcessStructured(ctx, dishPrompt, "", dishFormat)
+	if err != nil || again.Provider != "cache" || len(p.requests) != 2 {
+		t.Fatalf("second call: %+v, %v, %d requests", again, err, len(p.requests))
+	}
+}
+
+func TestProcessStructuredRepairFailsNotCached(t *testing.T) {
+	p := &scriptedProvider{responses: []string{
+		`{"name":"番茄炒蛋"}`,
+		`{"name":"番茄炒蛋","minutes":null}`,
+		`{"name":"番茄炒蛋","minutes":"10"}`,
+	}}
+	s, m := newStructuredService(t, p, 2)
+	ctx := context.Background()
+
+	// 修正次數用完仍不符合時回傳最後一次的內容，交給呼叫端寬鬆解析
+	resp, err := s.ProcessStructured(ctx, dishPrompt, "", dishFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != `{"name":"番茄炒蛋","minutes":"10"}` || len(p.requests) != 3 {
+		t.Fatalf("content = %q after %d requests", resp.Content, len(p.requests))
+	}
+	if _, err := m.Get(ctx, cachePrompt(dishPrompt.Key(), dishPrompt.Text), ""); err == nil {
+		t.Fatal("invalid response was cached")
+	}
+}
+
+func TestProcessStructuredIgnoresNonConformingCacheEntry(t *testing.T) {
+	p := &scriptedProvider{responses: []string{`{"name":"番茄炒蛋","minutes":10}`}}
+	s, m := newStructuredService(t, p, 0)
+	ctx := context.Background()
+
+	// 升級前寫入、不符合結構描述的條目視為未命中
+	if err := m.Set(ctx, cachePrompt(dishPrompt.Key(), dishPrompt.Text), "", `{"name":"舊條目"}`); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := s.ProcessStructured(ctx, dishPrompt, "", dishFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Provider == "cache" || len(p.requests) != 1 {
+		t.Fatalf("stale entry served: %+v", resp)
+	}
+}
@@ -0,0 +1,311 @@
+// Package experiment 提示詞 A/B 實驗：依用戶端 ID 或請求雜湊固定分配變體（模板版本、模型、取樣溫度），
+// 並依變體彙總解析成功率、AR 參數回退率、延遲、token 用量與使用者評分
+package experiment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sync"
+
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/core/prompt"
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+// ratingWindow 保留可評分的最近請求數，超過時最舊的請求無法再評分
+const ratingWindow = 10000
+
+// ErrUnknownRequest 請求 ID 未參與實驗、已評分或已超出可評分的範圍
+var ErrUnknownRequest = errors.New("request not found in experiments")
+
+// Variant 實驗變體，未指定的欄位沿用預設值
+type Variant struct {
+	Name          string   `json:"name"`
+	Weight        int      `json:"weight"`                   // 分配權重
+	PromptVersion int      `json:"prompt_version,omitempty"` // 模板版本，0 表示最新版本
+	Model         string   `json:"model,omitempty"`          // 覆寫模型，只套用於主要提供者
+	Temperature   *float64 `json:"temperature,omitempty"`    // 覆寫取樣溫度
+
+	stats *Stats
+}
+
+// Experiment 針對單一提示詞模板的實驗
+type Experiment struct {
+	ID       string     `json:"id"`
+	Template string     `json:"template"` // 模板 ID，例如 recipe.generate
+	Variants []*Variant `json:"variants"`
+
+	totalWeight int
+}
+
+// label 實驗與變體名稱，記錄於日誌與響應標頭
+func (e *Experiment) label(v *Variant) string {
+	return e.ID + "/" + v.Name
+}
+
+// assign 以實驗 ID 與分配單位的雜湊挑選變體，同一單位在同一實驗中永遠得到相同變體
+func (e *Experiment) assign(unit string) *Variant {
+	h := fnv.New64a()
+	h.Write([]byte(e.ID))
+	h.Write([]byte{0})
+	h.Write([]byte(unit))
+	bucket := int(h.Sum64() % uint64(e.totalWeight))
+	for _, v := range e.Variants {
+		if bucket < v.Weight {
+			return v
+		}
+		bucket -= v.Weight
+	}
+	return e.Variants[len(e.Variants)-1]
+}
+
+// file 實驗設定檔格式
+type file struct {
+	Experiments []*Experiment `json:"experiments"`
+}
+
+// Manager 實驗管理器，實作 prompt.Selector
+type Manager struct {
+	experiments []*Experiment
+	byTemplate  map[string]*Experiment
+
+	mu      sync.Mutex
+	pending map[string][]*Stats // 可評分的請求 ID 與其分配到的變體
+	order   []string            // pending 的加入順序，用於淘汰最舊的請求
+}
+
+// NewManager 載入實驗設定檔並以 prompts 驗證模板與版本；path 為空時不進行任何實驗
+func NewManager(path string, prompts *prompt.Registry) (*Manager, error) {
+	m := &Manager{
+		byTemplate: make(map[string]*Experiment),
+		pending:    make(map[string][]*Stats),
+	}
+	if path == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read experiments file: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse experiments file: %w", err)
+	}
+
+	ids := make(map[string]bool)
+	for _, e := range f.Experiments {
+		if err := validate(e, prompts); err != nil {
+			return nil, fmt.Errorf("experiment %q: %w", e.ID, err)
+		}
+		if ids[e.ID] {
+			return nil, fmt.Errorf("duplicate experiment %q", e.ID)
+		}
+		if other, ok := m.byTemplate[e.Template]; ok {
+			return nil, fmt.Errorf("experiments %q and %q both target template %s", other.ID, e.ID, e.Template)
+		}
+		ids[e.ID] = true
+		m.byTemplate[e.Template] = e
+		m.experiments = append(m.experiments, e)
+	}
+
+	for _, e := range m.experiments {
+		names := make([]string, len(e.Variants))
+		for i, v := range e.Variants {
+			names[i] = v.Name
+		}
+		common.LogInfo("提示詞實驗已載入",
+			zap.String("experiment", e.ID),
+			zap.String("template", e.Template),
+			zap.Strings("variants", names),
+		)
+	}
+	return m, nil
+}
+
+// validate 檢查實驗設定，並初始化權重總和與各變體的統計
+func validate(e *Experiment, prompts *prompt.Registry) error {
+	if e.ID == "" {
+		return errors.New("id is required")
+	}
+	if _, err := prompts.Get(e.Template, 0); err != nil {
+		return err
+	}
+	if len(e.Variants) == 0 {
+		return errors.New("at least one variant is required")
+	}
+
+	names := make(map[string]bool)
+	e.totalWeight = 0
+	for _, v := range e.Variants {
+		if v.Name == "" {
+			return errors.New("variant name is required")
+		}
+		if names[v.Name] {
+			return fmt.Errorf("duplicate variant %q", v.Name)
+		}
+		names[v.Name] = true
+		if v.Weight <= 0 {
+			return fmt.Errorf("variant %q: weight must be positive", v.Name)
+		}
+		if v.PromptVersion < 0 {
+			return fmt.Errorf("variant %q: invalid prompt_version %d", v.Name, v.PromptVersion)
+		}
+		if v.PromptVersion > 0 {
+			if _, err := prompts.Get(e.Template, v.PromptVersion); err != nil {
+				return fmt.Errorf("variant %q: %w", v.Name, err)
+			}
+		}
+		if v.Temperature != nil && (*v.Temperature < 0 || *v.Temperature > 2) {
+			return fmt.Errorf("variant %q: temperature must be between 0 and 2", v.Name)
+		}
+		e.totalWeight += v.Weight
+		v.stats = &Stats{}
+	}
+	return nil
+}
+
+// Enabled 是否有進行中的實驗
+func (m *Manager) Enabled() bool {
+	return len(m.experiments) > 0
+}
+
+// Select 實作 prompt.Selector：請求經過 Begin 且模板有進行中的實驗時分配變體，
+// 同一請求多次渲染同一模板（例如多張圖片盤點）沿用第一次的分配
+func (m *Manager) Select(ctx context.Context, id string) *prompt.Selection {
+	e, ok := m.byTemplate[id]
+	if !ok {
+		return nil
+	}
+	sc := scopeFrom(ctx)
+	if sc == nil {
+		return nil
+	}
+
+	sc.mu.Lock()
+	v, assigned := sc.assigned[id]
+	if !assigned {
+		v = e.assign(sc.unit)
+		sc.assigned[id] = v
+		v.stats.assign()
+	}
+	sc.mu.Unlock()
+
+	label := e.label(v)
+	provider.MetadataFromContext(ctx).RecordVariant(label)
+	if !assigned {
+		common.LogDebug("分配提示詞實驗變體",
+			zap.String("experiment", e.ID),
+			zap.String("variant", v.Name),
+			zap.String("request_id", sc.requestID),
+		)
+	}
+	return &prompt.Selection{
+		Version:     v.PromptVersion,
+		Model:       v.Model,
+		Temperature: v.Temperature,
+		Variant:     label,
+	}
+}
+
+// scope 單次請求的分配單位與已分配的變體
+type scope struct {
+	unit      string
+	requestID string
+
+	mu       sync.Mutex
+	assigned map[string]*Variant // 模板 ID 對應的變體
+}
+
+type scopeKey struct{}
+
+func scopeFrom(ctx context.Context) *scope {
+	sc, _ := ctx.Value(scopeKey{}).(*scope)
+	return sc
+}
+
+// Begin 在 context 中放入請求的分配單位（用戶端 ID 或請求雜湊），之後渲染的模板才會參與實驗
+func (m *Manager) Begin(ctx context.Context, unit, requestID string) context.Context {
+	return context.WithValue(ctx, scopeKey{}, &scope{
+		unit:      unit,
+		requestID: requestID,
+		assigned:  make(map[string]*Variant),
+	})
+}
+
+// End 請求結束時記錄分配結果，之後可依請求 ID 評分
+func (m *Manager) End(ctx context.Context) {
+	sc := scopeFrom(ctx)
+	if sc == nil || sc.requestID == "" {
+		return
+	}
+
+	sc.mu.Lock()
+	stats := make([]*Stats, 0, len(sc.assigned))
+	for _, v := range sc.assigned {
+		stats = append(stats, v.stats)
+	}
+	sc.mu.Unlock()
+	if len(stats) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.pending[sc.requestID]; !exists {
+		m.order = append(m.order, sc.requestID)
+	}
+	m.pending[sc.requestID] = stats
+	for len(m.order) > ratingWindow {
+		delete(m.pending, m.order[0])
+		m.order = m.order[1:]
+	}
+}
+
+// Rate 記錄使用者對請求結果的評分，每個請求只能評分一次
+func (m *Manager) Rate(requestID string, rating int) error {
+	m.mu.Lock()
+	stats, ok := m.pending[requestID]
+	delete(m.pending, requestID)
+	m.mu.Unlock()
+	if !ok {
+		return ErrUnknownRequest
+	}
+
+	for _, s := range stats {
+		s.rate(rating)
+	}
+	return nil
+}
+
+// VariantReport 變體設定與統計
+type VariantReport struct {
+	*Variant
+	Stats StatsSnapshot `json:"stats"`
+}
+
+// Report 實驗設定與各變體統計
+type Report struct {
+	ID       string          `json:"id"`
+	Template string          `json:"template"`
+	Variants []VariantReport `json:"variants"`
+}
+
+// Reports 回傳所有實驗的統計
+func (m *Manager) Reports() []Report {
+	reports := make([]Report, 0, len(m.experiments))
+	for _, e := range m.experiments {
+		r := Report{ID: e.ID, Template: e.Template, Variants: make([]VariantReport, len(e.Variants))}
+		for i, v := range e.Variants {
+			r.Variants[i] = VariantReport{Variant: v, Stats: v.stats.Snapshot()}
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
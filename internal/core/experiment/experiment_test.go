@@ -0,0 +1,195 @@
+package experiment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"recipe-generator/internal/core/prompt"
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	common.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// newTestManager 建立有兩個版本 exp 模板的註冊表，並載入 experiments 設定
+func newTestManager(t *testing.T, experiments string) (*Manager, error) {
+	t.Helper()
+	dir := t.TempDir()
+	for v := 1; v <= 2; v++ {
+		content := fmt.Sprintf("id: exp\nversion: %d\n---\n第%d版", v, v)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("exp_v%d.tmpl", v)), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prompts, err := prompt.NewRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "experiments.json")
+	if err := os.WriteFile(path, []byte(experiments), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return NewManager(path, prompts)
+}
+
+const weightedExperiment = `{"experiments":[{"id":"exp-test","template":"exp","variants":[
+	{"name":"control","weight":1,"prompt_version":1},
+	{"name":"treatment","weight":3,"prompt_version":2,"model":"m","temperature":0}
+]}]}`
+
+func TestAssignStickyAndWeighted(t *testing.T) {
+	m, err := newTestManager(t, weightedExperiment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := m.byTemplate["exp"]
+
+	const units = 4000
+	counts := make(map[string]int)
+	for i := 0; i < units; i++ {
+		unit := fmt.Sprintf("client-%d", i)
+		v := e.assign(unit)
+		for j := 0; j < 3; j++ {
+			if again := e.assign(unit); again != v {
+				t.Fatalf("unit %s assigned %s then %s", unit, v.Name, again.Name)
+			}
+		}
+		counts[v.Name]++
+	}
+	if share := float64(counts["treatment"]) / units; math.Abs(share-0.75) > 0.03 {
+		t.Fatalf("treatment share %.3f, want about 0.75 (%v)", share, counts)
+	}
+}
+
+func TestSelectReusesAssignment(t *testing.T) {
+	m, err := newTestManager(t, weightedExperiment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Enabled() {
+		t.Fatal("manager not enabled")
+	}
+
+	// 未經過 Begin 或模板沒有實驗時不參與
+	if sel := m.Select(context.Background(), "exp"); sel != nil {
+		t.Fatalf("Select without scope: %+v", sel)
+	}
+	ctx := m.Begin(context.Background(), "client-1", "req-1")
+	if sel := m.Select(ctx, prompt.RecipeGenerate); sel != nil {
+		t.Fatalf("Select for template without experiment: %+v", sel)
+	}
+
+	first := m.Select(ctx, "exp")
+	if first == nil || !strings.HasPrefix(first.Variant, "exp-test/") {
+		t.Fatalf("Select: %+v", first)
+	}
+	for i := 0; i < 3; i++ {
+		if again := m.Select(ctx, "exp"); *again != *first {
+			t.Fatalf("Select %d: %+v, want %+v", i, again, first)
+		}
+	}
+	if first.Variant == "exp-test/treatment" && (first.Version != 2 || first.Model != "m" || first.Temperature == nil || *first.Temperature != 0) {
+		t.Fatalf("treatment selection: %+v", first)
+	}
+
+	var assigned int64
+	for _, r := range m.Reports()[0].Variants {
+		assigned += r.Stats.Assigned
+	}
+	if assigned != 1 {
+		t.Fatalf("assigned %d times, want once per request", assigned)
+	}
+}
+
+func TestEndAndRate(t *testing.T) {
+	m, err := newTestManager(t, weightedExperiment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := m.Begin(context.Background(), "client-1", "req-1")
+	sel := m.Select(ctx, "exp")
+	m.End(ctx)
+	if err := m.Rate("req-1", 4); err != nil {
+		t.Fatalf("Rate: %v", err)
+	}
+	// 每個請求只能評分一次
+	if err := m.Rate("req-1", 5); !errors.Is(err, ErrUnknownRequest) {
+		t.Fatalf("second Rate: %v", err)
+	}
+	if err := m.Rate("unknown", 5); !errors.Is(err, ErrUnknownRequest) {
+		t.Fatalf("Rate unknown: %v", err)
+	}
+	for _, r := range m.Reports()[0].Variants {
+		if "exp-test/"+r.Name == sel.Variant && (r.Stats.Ratings != 1 || r.Stats.AvgRating != 4) {
+			t.Fatalf("stats: %+v", r.Stats)
+		}
+	}
+
+	// 沒有渲染實驗模板的請求不可評分
+	ctx = m.Begin(context.Background(), "client-2", "req-2")
+	m.End(ctx)
+	if err := m.Rate("req-2", 5); !errors.Is(err, ErrUnknownRequest) {
+		t.Fatalf("Rate request without assignment: %v", err)
+	}
+}
+
+func TestEndEvictsOldestBeyondWindow(t *testing.T) {
+	m, err := newTestManager(t, weightedExperiment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i <= ratingWindow; i++ {
+		ctx := m.Begin(context.Background(), "client", fmt.Sprintf("req-%d", i))
+		m.Select(ctx, "exp")
+		m.End(ctx)
+	}
+	if len(m.pending) != ratingWindow || len(m.order) != ratingWindow {
+		t.Fatalf("pending %d, order %d, want %d", len(m.pending), len(m.order), ratingWindow)
+	}
+	if err := m.Rate("req-0", 5); !errors.Is(err, ErrUnknownRequest) {
+		t.Fatalf("Rate evicted request: %v", err)
+	}
+	if err := m.Rate(fmt.Sprintf("req-%d", ratingWindow), 5); err != nil {
+		t.Fatalf("Rate newest request: %v", err)
+	}
+}
+
+func TestNewManagerValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"unknown template", `{"experiments":[{"id":"a","template":"nope","variants":[{"name":"x","weight":1}]}]}`, "not found"},
+		{"no variants", `{"experiments":[{"id":"a","template":"exp","variants":[]}]}`, "at least one variant"},
+		{"zero weight", `{"experiments":[{"id":"a","template":"exp","variants":[{"name":"x","weight":0}]}]}`, "weight must be positive"},
+		{"duplicate variant", `{"experiments":[{"id":"a","template":"exp","variants":[{"name":"x","weight":1},{"name":"x","weight":1}]}]}`, "duplicate variant"},
+		{"missing version", `{"experiments":[{"id":"a","template":"exp","variants":[{"name":"x","weight":1,"prompt_version":3}]}]}`, "not found"},
+		{"temperature", `{"experiments":[{"id":"a","template":"exp","variants":[{"name":"x","weight":1,"temperature":2.5}]}]}`, "temperature"},
+		{"same template", `{"experiments":[{"id":"a","template":"exp","variants":[{"name":"x","weight":1}]},{"id":"b","template":"exp","variants":[{"name":"x","weight":1}]}]}`, "both target"},
+		{"invalid json", `{"experiments":`, "failed to parse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newTestManager(t, tt.file); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("NewManager: %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+
+	m, err := NewManager("", nil)
+	if err != nil || m.Enabled() {
+		t.Fatalf("NewManager without file: %v, enabled=%v", err, m.Enabled())
+	}
+}
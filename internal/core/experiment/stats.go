@@ -0,0 +1,141 @@
+package experiment
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Stats 單一變體的結果訊號
+type Stats struct {
+	mu          sync.Mutex
+	assigned    int64
+	calls       int64
+	errors      int64
+	cacheHits   int64
+	parsed      int64
+	latency     time.Duration
+	tokens      int64
+	arSteps     int64
+	arFallbacks int64
+	ratings     int64
+	ratingSum   int64
+}
+
+// StatsSnapshot 變體統計快照，比率在分母為 0 時為 0
+type StatsSnapshot struct {
+	Assigned         int64   `json:"assigned"`           // 分配到此變體的請求數
+	Calls            int64   `json:"calls"`              // 上游 AI 呼叫成功次數
+	Errors           int64   `json:"errors"`             // 上游 AI 呼叫失敗次數
+	CacheHits        int64   `json:"cache_hits"`         // 快取命中次數，不計入其他訊號
+	ParseSuccess     int64   `json:"parse_success"`      // 回應（含修正）符合 JSON Schema 的次數
+	ParseSuccessRate float64 `json:"parse_success_rate"` // parse_success / calls
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`     // 上游呼叫平均延遲（含修正請求）
+	AvgTokens        float64 `json:"avg_tokens"`         // 每次呼叫平均 token 數
+	TotalTokens      int64   `json:"total_tokens"`
+	ARSteps          int64   `json:"ar_steps"`         // 檢查過 AR 參數的步驟數
+	ARFallbacks      int64   `json:"ar_fallbacks"`     // 使用 fallbackARParams 回退的步驟數
+	ARFallbackRate   float64 `json:"ar_fallback_rate"` // ar_fallbacks / ar_steps
+	Ratings          int64   `json:"ratings"`
+	AvgRating        float64 `json:"avg_rating"`
+}
+
+func (s *Stats) assign() {
+	s.mu.Lock()
+	s.assigned++
+	s.mu.Unlock()
+}
+
+func (s *Stats) rate(rating int) {
+	s.mu.Lock()
+	s.ratings++
+	s.ratingSum += int64(rating)
+	s.mu.Unlock()
+}
+
+// Snapshot 回傳目前的統計
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ratio := func(n, d int64) float64 {
+		if d == 0 {
+			return 0
+		}
+		return float64(n) / float64(d)
+	}
+	return StatsSnapshot{
+		Assigned:         s.assigned,
+		Calls:            s.calls,
+		Errors:           s.errors,
+		CacheHits:        s.cacheHits,
+		ParseSuccess:     s.parsed,
+		ParseSuccessRate: ratio(s.parsed, s.calls),
+		AvgLatencyMs:     ratio(s.latency.Microseconds(), s.calls) / 1000,
+		AvgTokens:        ratio(s.tokens, s.calls),
+		TotalTokens:      s.tokens,
+		ARSteps:          s.arSteps,
+		ARFallbacks:      s.arFallbacks,
+		ARFallbackRate:   ratio(s.arFallbacks, s.arSteps),
+		Ratings:          s.ratings,
+		AvgRating:        ratio(s.ratingSum, s.ratings),
+	}
+}
+
+// statsFor 取得請求中模板 id 分配到的變體統計，未參與實驗時回傳 nil
+func statsFor(ctx context.Context, id string) *Stats {
+	sc := scopeFrom(ctx)
+	if sc == nil {
+		return nil
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if v, ok := sc.assigned[id]; ok {
+		return v.stats
+	}
+	return nil
+}
+
+// ObserveCall 記錄一次上游 AI 呼叫：parsed 表示回應（含修正）是否符合 JSON Schema
+func ObserveCall(ctx context.Context, id string, latency time.Duration, tokens int, parsed bool) {
+	s := statsFor(ctx, id)
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	s.latency += latency
+	s.tokens += int64(tokens)
+	if parsed {
+		s.parsed++
+	}
+}
+
+// ObserveError 記錄一次失敗的上游 AI 呼叫
+func ObserveError(ctx context.Context, id string) {
+	if s := statsFor(ctx, id); s != nil {
+		s.mu.Lock()
+		s.errors++
+		s.mu.Unlock()
+	}
+}
+
+// ObserveCacheHit 記錄一次快取命中
+func ObserveCacheHit(ctx context.Context, id string) {
+	if s := statsFor(ctx, id); s != nil {
+		s.mu.Lock()
+		s.cacheHits++
+		s.mu.Unlock()
+	}
+}
+
+// ObserveAR 記錄食譜中檢查 AR 參數的步驟數與回退的步驟數
+func ObserveAR(ctx context.Context, id string, steps, fallbacks int) {
+	if s := statsFor(ctx, id); s != nil {
+		s.mu.Lock()
+		s.arSteps += int64(steps)
+		s.arFallbacks += int64(fallbacks)
+		s.mu.Unlock()
+	}
+}
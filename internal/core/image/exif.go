@@ -0,0 +1,147 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+)
+
+// jpegInfo 從 JPEG 檔頭取得的資訊
+type jpegInfo struct {
+	orientation int  // EXIF 方向（1~8），沒有 EXIF 時為 1
+	metadata    bool // 是否含 EXIF、XMP、IPTC 等中繼資料區段
+}
+
+// readJPEGInfo 掃描 JPEG 影像資料前的區段，取得 EXIF 方向並判斷是否含中繼資料；非 JPEG 時回傳預設值
+func readJPEGInfo(data []byte) jpegInfo {
+	info := jpegInfo{orientation: 1}
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return info
+	}
+
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return info
+		}
+		marker := data[pos+1]
+		// 填充位元組
+		if marker == 0xFF {
+			pos++
+			continue
+		}
+		// 影像資料開始或檔案結束，之後不會再有中繼資料
+		if marker == 0xDA || marker == 0xD9 {
+			return info
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return info
+		}
+		segment := data[pos+4 : pos+2+length]
+
+		switch {
+		case marker == 0xE1: // APP1：EXIF 或 XMP
+			info.metadata = true
+			if bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+				if o := exifOrientation(segment[6:]); o >= 1 && o <= 8 {
+					info.orientation = o
+				}
+			}
+		case marker >= 0xE2 && marker <= 0xEF: // 其他 APP 區段：ICC、IPTC 等
+			info.metadata = true
+		case marker == 0xFE: // 註解
+			info.metadata = true
+		}
+		pos += 2 + length
+	}
+	return info
+}
+
+// exifOrientation 從 TIFF 結構的 IFD0 讀取 Orientation（0x0112），找不到時回傳 0
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	if order.Uint16(tiff[2:]) != 0x002A {
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		// 類型 3 為 SHORT，值直接存放在值欄位的前兩個位元組
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// applyOrientation 依 EXIF 方向旋轉或翻轉圖片，讓像素方向與拍攝時的畫面一致
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	src := toRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for dy := 0; dy < dh; dy++ {
+		for dx := 0; dx < dw; dx++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // 水平翻轉
+				sx, sy = w-1-dx, dy
+			case 3: // 旋轉 180 度
+				sx, sy = w-1-dx, h-1-dy
+			case 4: // 垂直翻轉
+				sx, sy = dx, h-1-dy
+			case 5: // 沿左上到右下的對角線翻轉
+				sx, sy = dy, dx
+			case 6: // 順時針旋轉 90 度
+				sx, sy = dy, h-1-dx
+			case 7: // 沿右上到左下的對角線翻轉
+				sx, sy = w-1-dy, h-1-dx
+			case 8: // 逆時針旋轉 90 度
+				sx, sy = w-1-dy, dx
+			}
+			si := sy*src.Stride + sx*4
+			di := dy*dst.Stride + dx*4
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// toRGBA 轉成原點為 (0,0) 的 RGBA，方便直接存取像素
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
@@ -0,0 +1,150 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+var (
+	red   = color.RGBA{255, 0, 0, 255}
+	green = color.RGBA{0, 255, 0, 255}
+)
+
+// exifSegment 建立只含 Orientation 一個欄位的 APP1 EXIF 區段
+func exifSegment(orientation int, order binary.ByteOrder) []byte {
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 0x002A)
+	order.PutUint32(tiff[4:], 8) // IFD0 緊接在檔頭之後
+	order.PutUint16(tiff[8:], 1) // 欄位數
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], uint16(orientation))
+	// 之後四個位元組為下一個 IFD 的位移，0 表示沒有
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// withSegments 在 JPEG 的 SOI 之後插入區段
+func withSegments(jpegData []byte, segments ...[]byte) []byte {
+	out := append([]byte{}, jpegData[:2]...)
+	for _, s := range segments {
+		out = append(out, s...)
+	}
+	return append(out, jpegData[2:]...)
+}
+
+// encodeTestJPEG 以高品質編碼測試圖片
+func encodeTestJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// markedImage 白底圖片，左上角為紅色、右上角為綠色的色塊，用來辨識旋轉與翻轉的方向
+func markedImage(w, h, block int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if y < block && x < block {
+				c = red
+			} else if y < block && x >= w-block {
+				c = green
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestReadJPEGInfo(t *testing.T) {
+	plain := encodeTestJPEG(t, markedImage(16, 8, 4))
+	if info := readJPEGInfo(plain); info.orientation != 1 || info.metadata {
+		t.Fatalf("plain JPEG: %+v", info)
+	}
+
+	for o := 1; o <= 8; o++ {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			info := readJPEGInfo(withSegments(plain, exifSegment(o, order)))
+			if info.orientation != o || !info.metadata {
+				t.Fatalf("orientation %d (%v): %+v", o, order, info)
+			}
+		}
+	}
+
+	comment := []byte{0xFF, 0xFE, 0x00, 0x07, 'h', 'e', 'l', 'l', 'o'}
+	icc := []byte{0xFF, 0xE2, 0x00, 0x04, 0x00, 0x00}
+	tests := []struct {
+		name string
+		data []byte
+		want jpegInfo
+	}{
+		{"comment", withSegments(plain, comment), jpegInfo{orientation: 1, metadata: true}},
+		{"icc profile", withSegments(plain, icc), jpegInfo{orientation: 1, metadata: true}},
+		{"out of range orientation", withSegments(plain, exifSegment(9, binary.BigEndian)), jpegInfo{orientation: 1, metadata: true}},
+		{"exif after padding", withSegments(plain, []byte{0xFF}, exifSegment(6, binary.BigEndian)), jpegInfo{orientation: 6, metadata: true}},
+		{"truncated segment", plain[:2:2], jpegInfo{orientation: 1}},
+		{"not jpeg", pngHeader, jpegInfo{orientation: 1}},
+	}
+	for _, tt := range tests {
+		if got := readJPEGInfo(tt.data); got != tt.want {
+			t.Fatalf("%s: %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// pngHeader PNG 檔頭
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+
+func TestApplyOrientation(t *testing.T) {
+	// 3x2 圖片，左上角紅色、右上角綠色；表中為套用方向後兩個像素的位置與圖片大小
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	src.SetRGBA(0, 0, red)
+	src.SetRGBA(2, 0, green)
+
+	tests := []struct {
+		orientation int
+		size        image.Point
+		red, green  image.Point
+	}{
+		{1, image.Pt(3, 2), image.Pt(0, 0), image.Pt(2, 0)},
+		{2, image.Pt(3, 2), image.Pt(2, 0), image.Pt(0, 0)},
+		{3, image.Pt(3, 2), image.Pt(2, 1), image.Pt(0, 1)},
+		{4, image.Pt(3, 2), image.Pt(0, 1), image.Pt(2, 1)},
+		{5, image.Pt(2, 3), image.Pt(0, 0), image.Pt(0, 2)},
+		{6, image.Pt(2, 3), image.Pt(1, 0), image.Pt(1, 2)},
+		{7, image.Pt(2, 3), image.Pt(1, 2), image.Pt(1, 0)},
+		{8, image.Pt(2, 3), image.Pt(0, 2), image.Pt(0, 0)},
+	}
+	for _, tt := range tests {
+		out := toRGBA(applyOrientation(src, tt.orientation))
+		if out.Bounds().Size() != tt.size {
+			t.Fatalf("orientation %d: size %v, want %v", tt.orientation, out.Bounds().Size(), tt.size)
+		}
+		if out.RGBAAt(tt.red.X, tt.red.Y) != red || out.RGBAAt(tt.green.X, tt.green.Y) != green {
+			t.Fatalf("orientation %d: markers not at %v and %v", tt.orientation, tt.red, tt.green)
+		}
+	}
+
+	// 原點不為 (0,0) 的子圖片同樣正確處理
+	sub := markedImage(6, 4, 1).SubImage(image.Rect(3, 0, 6, 2))
+	if out := toRGBA(applyOrientation(sub, 2)); out.RGBAAt(0, 0) != green {
+		t.Fatalf("sub image: %v", out.RGBAAt(0, 0))
+	}
+}
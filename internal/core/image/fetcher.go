@@ -0,0 +1,206 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"recipe-generator/internal/infrastructure/config"
+)
+
+// ErrURLNotAllowed 圖片網址不符合下載政策（協定、網域或位址）
+var ErrURLNotAllowed = errors.New("image url not allowed")
+
+// blockedPrefixes 不允許連線的位址範圍；IsPrivate、IsLoopback 等未涵蓋的保留網段列在這裡
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // 本網路
+	netip.MustParsePrefix("100.64.0.0/10"),   // 電信級 NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF 協定指派
+	netip.MustParsePrefix("198.18.0.0/15"),   // 效能測試
+	netip.MustParsePrefix("240.0.0.0/4"),     // 保留位址與廣播
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64，可能轉送到內部 IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // 本地 NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // 文件範例
+	netip.MustParsePrefix("2002::/16"),       // 6to4，可能內嵌內部 IPv4
+	netip.MustParsePrefix("fec0::/10"),       // 已棄用的 site-local
+	netip.MustParsePrefix("100::/64"),        // 丟棄
+	netip.MustParsePrefix("2001::/32"),       // Teredo，可能內嵌內部 IPv4
+	netip.MustParsePrefix("::ffff:0:0:0/96"), // IPv4 轉譯
+}
+
+// fetcher 下載使用者提供的圖片網址；只連線公開位址，
+// 每次連線（包含重新導向）都在 DNS 解析後檢查實際連線的 IP，避免 DNS rebinding
+type fetcher struct {
+	config config.ImageConfig
+	client *http.Client
+
+	// allowAddr 判斷是否可連線到位址，預設為 publicAddr；測試時放行本機的 httptest 伺服器
+	allowAddr func(netip.Addr) bool
+}
+
+func newFetcher(cfg config.ImageConfig) *fetcher {
+	f := &fetcher{config: cfg, allowAddr: publicAddr}
+
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrURLNotAllowed, host)
+			}
+			if !f.allowAddr(addr) {
+				return fmt.Errorf("%w: address %s is not public", ErrURLNotAllowed, addr)
+			}
+			return nil
+		},
+	}
+
+	f.client = &http.Client{
+		Timeout: cfg.FetchTimeout,
+		Transport: &http.Transport{
+			// 不走環境變數設定的代理，否則檢查到的會是代理的位址
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: cfg.FetchTimeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > cfg.FetchMaxRedirects {
+				return fmt.Errorf("%w: too many redirects", ErrURLNotAllowed)
+			}
+			return f.checkURL(req.URL)
+		},
+	}
+	return f
+}
+
+// CheckURL 檢查網址的協定與網域是否允許下載；實際位址在連線時才檢查
+func (f *fetcher) CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrURLNotAllowed, err)
+	}
+	return f.checkURL(u)
+}
+
+// checkURL 檢查協定、帳密與網域清單；網址直接寫 IP 時也先檢查是否為公開位址
+func (f *fetcher) checkURL(u *url.URL) error {
+	if !containsFold(f.config.FetchSchemes, u.Scheme) {
+		return fmt.Errorf("%w: scheme %q", ErrURLNotAllowed, u.Scheme)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: credentials in url", ErrURLNotAllowed)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrURLNotAllowed)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !f.allowAddr(addr) {
+		return fmt.Errorf("%w: address %s is not public", ErrURLNotAllowed, addr)
+	}
+	if matchDomain(f.config.FetchDenyDomains, host) {
+		return fmt.Errorf("%w: domain %s is denied", ErrURLNotAllowed, host)
+	}
+	if len(f.config.FetchAllowDomains) > 0 && !matchDomain(f.config.FetchAllowDomains, host) {
+		return fmt.Errorf("%w: domain %s is not allowed", ErrURLNotAllowed, host)
+	}
+	return nil
+}
+
+// fetch 下載圖片；邊讀邊限制大小，並以內容判斷確認是圖片
+func (f *fetcher) fetch(ctx context.Context, rawURL string, maxBytes int64) ([]byte, error) {
+	if err := f.CheckURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrURLNotAllowed) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download image: status code %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, fmt.Errorf("image size exceeds maximum limit of %d bytes", maxBytes)
+	}
+
+	// 多讀一個位元組以判斷是否超過上限，超過時不會把整個回應讀進記憶體
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image data: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("image size exceeds maximum limit of %d bytes", maxBytes)
+	}
+
+	// 不信任回應標頭，以內容判斷類型
+	if contentType := http.DetectContentType(data); !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("invalid image format: downloaded content is %s", contentType)
+	}
+	return data, nil
+}
+
+// publicAddr 判斷位址是否為可公開連線的單播位址
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchDomain 判斷主機是否為清單中的網域或其子網域
+func matchDomain(domains []string, host string) bool {
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d)), "."), "*.")
+		if d == "" {
+			continue
+		}
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold 不分大小寫判斷字串是否在清單中
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
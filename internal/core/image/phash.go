@@ -0,0 +1,138 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// pHash 參數：先縮成 32x32 灰階，取 DCT 左上 8x8 的低頻係數
+const (
+	hashSampleSize = 32
+	hashLowFreq    = 8
+)
+
+// PerceptualHash 計算 data URI 圖片的 64 位元感知雜湊（pHash）；
+// 同一張照片經過縮放、重新壓縮或輕微裁切後，雜湊的漢明距離通常仍很小。
+// 網址圖片不在此下載，回傳錯誤由呼叫端略過
+func (s *Service) PerceptualHash(imageData string) (uint64, error) {
+	if !strings.HasPrefix(imageData, "data:image/") {
+		return 0, fmt.Errorf("perceptual hash requires a data URI image")
+	}
+	data, err := s.decodeDataURI(imageData)
+	if err != nil {
+		return 0, err
+	}
+	img, _, err := decode(data)
+	if err != nil {
+		return 0, err
+	}
+	// 套用 EXIF 方向，同一張照片不論是否已轉正都得到相同雜湊
+	return PerceptualHash(applyOrientation(img, readJPEGInfo(data).orientation)), nil
+}
+
+// PerceptualHash 計算圖片的 64 位元感知雜湊：縮成 32x32 灰階後做二維 DCT，
+// 以左上 8x8 低頻係數（不含直流分量）的中位數為界，高於中位數的位元設為 1
+func PerceptualHash(img image.Image) uint64 {
+	gray := grayscaleSample(img, hashSampleSize)
+	coeffs := dct2D(gray, hashSampleSize, hashLowFreq)
+
+	// 直流分量只反映整體亮度，不參與中位數計算
+	ac := make([]float64, 0, hashLowFreq*hashLowFreq-1)
+	for i, c := range coeffs {
+		if i != 0 {
+			ac = append(ac, c)
+		}
+	}
+	sorted := append([]float64(nil), ac...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	// 63 個交流係數對應第 1～63 位元，第 0 位元（直流分量）固定為 0
+	var hash uint64
+	for i, c := range ac {
+		if c > median {
+			hash |= 1 << uint(i+1)
+		}
+	}
+	return hash
+}
+
+// HammingDistance 兩個感知雜湊不同的位元數
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// grayscaleSample 以區域平均將圖片縮成 size x size 的灰階矩陣，縮小時不會因取樣點而失真
+func grayscaleSample(img image.Image, size int) []float64 {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	out := make([]float64, size*size)
+	if w == 0 || h == 0 {
+		return out
+	}
+
+	for y := 0; y < size; y++ {
+		y0 := bounds.Min.Y + y*h/size
+		y1 := bounds.Min.Y + (y+1)*h/size
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < size; x++ {
+			x0 := bounds.Min.X + x*w/size
+			x1 := bounds.Min.X + (x+1)*w/size
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var sum float64
+			for py := y0; py < y1; py++ {
+				for px := x0; px < x1; px++ {
+					r, g, b, _ := img.At(px, py).RGBA()
+					// ITU-R BT.601 亮度
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+				}
+			}
+			out[y*size+x] = sum / float64((y1-y0)*(x1-x0))
+		}
+	}
+	return out
+}
+
+// dct2D 對 n x n 矩陣做二維 DCT-II，只計算左上 k x k 的係數
+func dct2D(in []float64, n, k int) []float64 {
+	// cos 表：table[u*n+x] = cos((2x+1)uπ / 2n)
+	table := make([]float64, k*n)
+	for u := 0; u < k; u++ {
+		for x := 0; x < n; x++ {
+			table[u*n+x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / float64(2*n))
+		}
+	}
+
+	// 先對每一列做一維 DCT，再對結果的每一行做一維 DCT
+	rows := make([]float64, n*k)
+	for y := 0; y < n; y++ {
+		for u := 0; u < k; u++ {
+			var sum float64
+			for x := 0; x < n; x++ {
+				sum += in[y*n+x] * table[u*n+x]
+			}
+			rows[y*k+u] = sum
+		}
+	}
+
+	out := make([]float64, k*k)
+	for v := 0; v < k; v++ {
+		for u := 0; u < k; u++ {
+			var sum float64
+			for y := 0; y < n; y++ {
+				sum += rows[y*k+u] * table[v*n+y]
+			}
+			out[v*k+u] = sum
+		}
+	}
+	return out
+}
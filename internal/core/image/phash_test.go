@@ -0,0 +1,140 @@
+package image
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/draw"
+)
+
+// maxDistance CACHE_PERCEPTUAL_MAX_DISTANCE 的預設值
+const maxDistance = 6
+
+// scene 以漸層背景與數個色塊合成測試圖片，不同 seed 得到構圖不同的圖片
+func scene(seed int64, w, h int) *image.RGBA {
+	rng := rand.New(rand.NewSource(seed))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	angle := rng.Intn(4)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var t float64
+			switch angle {
+			case 0:
+				t = float64(x) / float64(w)
+			case 1:
+				t = float64(y) / float64(h)
+			case 2:
+				t = 1 - float64(x)/float64(w)
+			default:
+				t = 1 - float64(y)/float64(h)
+			}
+			v := uint8(60 + 150*t)
+			img.SetRGBA(x, y, color.RGBA{v, v, uint8(200 - 100*t), 255})
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		cx, cy := rng.Intn(w), rng.Intn(h)
+		r := w/10 + rng.Intn(w/5)
+		c := color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}
+		for y := max(cy-r, 0); y < min(cy+r, h); y++ {
+			for x := max(cx-r, 0); x < min(cx+r, w); x++ {
+				if (x-cx)*(x-cx)+(y-cy)*(y-cy) < r*r {
+					img.SetRGBA(x, y, c)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// resize 以雙線性內插縮放
+func resize(src image.Image, w, h int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.BiLinear.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// recompress 以指定品質重新壓縮成 JPEG
+func recompress(t *testing.T, src image.Image, quality int) image.Image {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+// crop 每邊裁掉 pct 比例的邊緣；每邊 2% 以上時部分構圖的距離會超過預設門檻
+func crop(src image.Image, pct float64) image.Image {
+	b := src.Bounds()
+	dx, dy := int(float64(b.Dx())*pct), int(float64(b.Dy())*pct)
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()-2*dx, b.Dy()-2*dy))
+	draw.Copy(dst, image.Point{}, src, image.Rect(b.Min.X+dx, b.Min.Y+dy, b.Max.X-dx, b.Max.Y-dy), draw.Src, nil)
+	return dst
+}
+
+func TestPerceptualHashSimilarImages(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		orig := scene(seed, 640, 480)
+		base := PerceptualHash(orig)
+
+		variants := map[string]image.Image{
+			"downscaled":     resize(orig, 320, 240),
+			"upscaled":       resize(orig, 1280, 960),
+			"recompressed":   recompress(t, orig, 40),
+			"cropped":        crop(orig, 0.01),
+			"scaled+jpeg":    recompress(t, resize(orig, 400, 300), 60),
+			"cropped+scaled": resize(crop(orig, 0.01), 500, 370),
+		}
+		for name, img := range variants {
+			if d := HammingDistance(base, PerceptualHash(img)); d > maxDistance {
+				t.Errorf("seed %d %s: distance %d > %d", seed, name, d, maxDistance)
+			}
+		}
+	}
+}
+
+func TestPerceptualHashUnrelatedImages(t *testing.T) {
+	var hashes []uint64
+	for seed := int64(1); seed <= 8; seed++ {
+		hashes = append(hashes, PerceptualHash(scene(seed*101, 640, 480)))
+	}
+	for i := range hashes {
+		for j := i + 1; j < len(hashes); j++ {
+			if d := HammingDistance(hashes[i], hashes[j]); d <= maxDistance {
+				t.Errorf("images %d and %d: distance %d <= %d", i, j, d, maxDistance)
+			}
+		}
+	}
+}
+
+func TestPerceptualHashExcludesDC(t *testing.T) {
+	// 只改變整體亮度時直流分量改變，雜湊不應受影響
+	orig := scene(7, 320, 240)
+	brighter := image.NewRGBA(orig.Bounds())
+	for i, v := range orig.Pix {
+		if i%4 == 3 {
+			brighter.Pix[i] = v
+			continue
+		}
+		brighter.Pix[i] = uint8(min(int(v)+20, 255))
+	}
+
+	a, b := PerceptualHash(orig), PerceptualHash(brighter)
+	if a&1 != 0 || b&1 != 0 {
+		t.Fatalf("DC bit set: %016x %016x", a, b)
+	}
+	if d := HammingDistance(a, b); d > maxDistance {
+		t.Fatalf("brightness change: distance %d > %d", d, maxDistance)
+	}
+}
@@ -2,212 +2,270 @@ package image
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"image"
+	"image/color"
 	"image/jpeg"
 	"io"
-	"net/http"
 	"strings"
 	"time"
 
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+
 	_ "image/gif" // 支援 GIF
+	_ "image/png" // 支援 PNG
 
+	"go.uber.org/zap"
+	"golang.org/x/image/draw"
 	_ "golang.org/x/image/webp" // 支援 WebP
 )
 
+// minScaledEdge 超過位元組預算時逐步縮小圖片的最長邊下限
+const minScaledEdge = 256
+
 // Service 圖片處理服務
 type Service struct {
-	maxSizeBytes int64
-	httpClient   *http.Client
+	config  config.ImageConfig
+	fetcher *fetcher
 }
 
 // NewService 創建新的圖片處理服務
-func NewService(maxSizeBytes int64) *Service {
+func NewService(cfg config.ImageConfig) *Service {
 	return &Service{
-		maxSizeBytes: maxSizeBytes,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		config:  cfg,
+		fetcher: newFetcher(cfg),
 	}
 }
 
-// ProcessImage 處理圖片
-func (s *Service) ProcessImage(imageData string) (string, error) {
-	// 檢查是否為 URL
-	if strings.HasPrefix(imageData, "http://") || strings.HasPrefix(imageData, "https://") {
-		// 下載圖片
-		resp, err := s.httpClient.Get(imageData)
-		if err != nil {
-			return "", fmt.Errorf("failed to download image: %w", err)
-		}
-		defer resp.Body.Close()
+// MaxEncodedSize 以文字傳送（base64 或 data URI）的圖片長度上限：maxBytes 經 base64 編碼後的長度，
+// 加上每 76 字元換行與 data URI 前綴的餘裕
+func MaxEncodedSize(maxBytes int64) int64 {
+	n := int64(base64.StdEncoding.EncodedLen(int(maxBytes)))
+	return n + n/38 + 1<<10
+}
 
-		if resp.StatusCode != http.StatusOK {
-			return "", fmt.Errorf("failed to download image: status code %d", resp.StatusCode)
-		}
+// MaxEncodedSize 依設定的 MaxSizeBytes 計算文字圖片的長度上限
+func (s *Service) MaxEncodedSize() int64 {
+	return MaxEncodedSize(s.config.MaxSizeBytes)
+}
 
-		// 讀取圖片數據
-		imageBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return "", fmt.Errorf("failed to read image data: %w", err)
-		}
+// ProcessImage 處理圖片：套用 EXIF 方向、移除中繼資料、將最長邊縮到上限，
+// 並調整 JPEG 品質讓結果不超過位元組預算，回傳 JPEG data URI。
+// 已符合所有條件的 JPEG 原樣回傳，重複處理不會再次壓縮；網址的下載隨 ctx 取消
+func (s *Service) ProcessImage(ctx context.Context, imageData string) (string, error) {
+	data, err := s.load(ctx, imageData)
+	if err != nil {
+		return "", err
+	}
+	return s.process(data)
+}
 
-		// 檢查文件大小
-		if int64(len(imageBytes)) > s.maxSizeBytes {
-			return "", fmt.Errorf("image size exceeds maximum limit of %d bytes", s.maxSizeBytes)
-		}
+// ProcessReader 與 ProcessImage 相同，但直接讀取圖片位元組（例如 multipart 上傳的檔案），
+// 讀取時即限制大小，超過上限不會繼續讀入記憶體
+func (s *Service) ProcessReader(r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.config.MaxSizeBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read image data: %w", err)
+	}
+	if int64(len(data)) > s.config.MaxSizeBytes {
+		return "", fmt.Errorf("image size exceeds maximum limit of %d bytes", s.config.MaxSizeBytes)
+	}
+	if len(data) == 0 {
+		return "", fmt.Errorf("invalid image data format: empty image")
+	}
+	return s.process(data)
+}
 
-		// 解碼圖片
-		img, format, err := image.Decode(bytes.NewReader(imageBytes))
-		if err != nil {
-			return "", fmt.Errorf("failed to decode image: %w", err)
-		}
+// process 對已取得的圖片位元組執行前處理
+func (s *Service) process(data []byte) (string, error) {
+	img, format, err := decode(data)
+	if err != nil {
+		return "", err
+	}
 
-		// 檢查圖片格式
-		if !isSupportedFormat(format) {
-			return "", fmt.Errorf("unsupported image format: %s", format)
-		}
+	info := readJPEGInfo(data)
+	bounds := img.Bounds()
+	if format == "jpeg" && !info.metadata &&
+		longestEdge(bounds) <= s.config.MaxDimension && len(data) <= s.config.TargetSize {
+		return toDataURI(data), nil
+	}
 
-		// 將圖片轉換為 JPEG 格式
-		var buf bytes.Buffer
-		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
-			return "", fmt.Errorf("failed to encode image as JPEG: %w", err)
-		}
+	// 先縮小再旋轉，避免在原始解析度上逐像素搬移
+	start := time.Now()
+	canvas := toRGBA(applyOrientation(flatten(img, s.config.MaxDimension), info.orientation))
+	out, quality, size, err := s.encode(canvas)
+	if err != nil {
+		return "", err
+	}
+
+	common.LogDebug("圖片前處理完成",
+		zap.String("format", format),
+		zap.Int("original_bytes", len(data)),
+		zap.Int("original_width", bounds.Dx()),
+		zap.Int("original_height", bounds.Dy()),
+		zap.Int("orientation", info.orientation),
+		zap.Int("bytes", len(out)),
+		zap.Int("width", size.X),
+		zap.Int("height", size.Y),
+		zap.Int("quality", quality),
+		zap.Duration("latency", time.Since(start)),
+	)
+	return toDataURI(out), nil
+}
 
-		// 重新編碼為 base64
-		encodedData := base64.StdEncoding.EncodeToString(buf.Bytes())
-		return fmt.Sprintf("data:image/jpeg;base64,%s", encodedData), nil
+// load 取得圖片原始位元組：網址會經過下載政策檢查後下載，data URI 會解碼 base64
+func (s *Service) load(ctx context.Context, imageData string) ([]byte, error) {
+	if strings.HasPrefix(imageData, "http://") || strings.HasPrefix(imageData, "https://") {
+		return s.fetcher.fetch(ctx, imageData, s.config.MaxSizeBytes)
 	}
+	return s.decodeDataURI(imageData)
+}
+
+// CheckURL 檢查圖片網址是否符合下載政策（協定、網域清單與直接寫在網址中的位址），不發出請求
+func (s *Service) CheckURL(rawURL string) error {
+	return s.fetcher.CheckURL(rawURL)
+}
 
-	// 處理 base64 格式
+// decodeDataURI 解碼 base64 data URI 並檢查大小
+func (s *Service) decodeDataURI(imageData string) ([]byte, error) {
 	if !strings.HasPrefix(imageData, "data:image/") {
-		return "", fmt.Errorf("invalid image data format")
+		return nil, fmt.Errorf("invalid image data format")
 	}
 
 	// 解析 base64 數據
 	parts := strings.Split(imageData, ",")
 	if len(parts) != 2 {
-		return "", fmt.Errorf("invalid base64 data format")
+		return nil, fmt.Errorf("invalid base64 data format")
 	}
 
 	// 解碼 base64 數據
 	decodedData, err := base64.StdEncoding.DecodeString(parts[1])
 	if err != nil {
-		return "", fmt.Errorf("failed to decode base64 data: %w", err)
+		return nil, fmt.Errorf("failed to decode base64 data: %w", err)
 	}
 
 	// 檢查文件大小
-	if int64(len(decodedData)) > s.maxSizeBytes {
-		return "", fmt.Errorf("image size exceeds maximum limit of %d bytes", s.maxSizeBytes)
+	if int64(len(decodedData)) > s.config.MaxSizeBytes {
+		return nil, fmt.Errorf("image size exceeds maximum limit of %d bytes", s.config.MaxSizeBytes)
 	}
+	return decodedData, nil
+}
 
-	// 解碼圖片
-	img, format, err := image.Decode(bytes.NewReader(decodedData))
+// decode 解碼圖片並檢查格式
+func decode(data []byte) (image.Image, string, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
-		return "", fmt.Errorf("failed to decode image: %w", err)
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
 	}
-
-	// 檢查圖片格式
 	if !isSupportedFormat(format) {
-		return "", fmt.Errorf("unsupported image format: %s", format)
-	}
-
-	// 將圖片轉換為 JPEG 格式
-	var buf bytes.Buffer
-	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
-		return "", fmt.Errorf("failed to encode image as JPEG: %w", err)
-	}
-
-	// 重新編碼為 base64
-	encodedData := base64.StdEncoding.EncodeToString(buf.Bytes())
-	return fmt.Sprintf("data:image/jpeg;base64,%s", encodedData), nil
-}
-
-// isSupportedFormat 檢查圖片格式是否支援
-func isSupportedFormat(format string) bool {
-	supportedFormats := map[string]bool{
-		"jpeg": true,
-		"jpg":  true,
-		"png":  true,
-		"gif":  true,
-		"webp": true,
+		return nil, "", fmt.Errorf("unsupported image format: %s", format)
 	}
-	return supportedFormats[format]
+	return img, format, nil
 }
 
-// ValidateImage 驗證圖片
-func (s *Service) ValidateImage(imageData string) error {
-	// 檢查是否為 URL
-	if strings.HasPrefix(imageData, "http://") || strings.HasPrefix(imageData, "https://") {
-		// 下載圖片
-		resp, err := s.httpClient.Get(imageData)
-		if err != nil {
-			return fmt.Errorf("failed to download image: %w", err)
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("failed to download image: status code %d", resp.StatusCode)
-		}
-
-		// 讀取圖片數據
-		imageBytes, err := io.ReadAll(resp.Body)
+// encode 編碼為 JPEG；以二分搜尋找出不超過位元組預算的最高品質，
+// 最低品質仍超過預算時依 FinalScale 逐步縮小，直到符合預算或最長邊達到下限
+func (s *Service) encode(canvas *image.RGBA) ([]byte, int, image.Point, error) {
+	for {
+		out, quality, err := s.fitQuality(canvas)
 		if err != nil {
-			return fmt.Errorf("failed to read image data: %w", err)
+			return nil, 0, image.Point{}, err
 		}
-
-		// 檢查文件大小
-		if int64(len(imageBytes)) > s.maxSizeBytes {
-			return fmt.Errorf("image size exceeds maximum limit of %d bytes", s.maxSizeBytes)
+		size := canvas.Bounds().Size()
+		edge := longestEdge(canvas.Bounds())
+		next := int(float64(edge) * s.config.FinalScale)
+		if len(out) <= s.config.TargetSize || next < minScaledEdge || next >= edge {
+			return out, quality, size, nil
 		}
+		canvas = flatten(canvas, next)
+	}
+}
 
-		// 解碼圖片
-		_, format, err := image.Decode(bytes.NewReader(imageBytes))
+// fitQuality 在 [MinQuality, InitialQuality] 之間找出結果不超過預算的最高品質；
+// 都超過時回傳最低品質的結果
+func (s *Service) fitQuality(img image.Image) ([]byte, int, error) {
+	lo, hi := s.config.MinQuality, s.config.InitialQuality
+	var best []byte
+	bestQuality := 0
+	for lo <= hi {
+		q := (lo + hi) / 2
+		out, err := encodeJPEG(img, q)
 		if err != nil {
-			return fmt.Errorf("failed to decode image: %w", err)
+			return nil, 0, err
 		}
-
-		// 檢查圖片格式
-		if !isSupportedFormat(format) {
-			return fmt.Errorf("unsupported image format: %s", format)
+		if len(out) <= s.config.TargetSize {
+			best, bestQuality = out, q
+			lo = q + 1
+		} else {
+			hi = q - 1
 		}
-
-		return nil
 	}
-
-	// 處理 base64 格式
-	if !strings.HasPrefix(imageData, "data:image/") {
-		return fmt.Errorf("invalid image data format")
+	if best != nil {
+		return best, bestQuality, nil
 	}
+	out, err := encodeJPEG(img, s.config.MinQuality)
+	return out, s.config.MinQuality, err
+}
 
-	// 解析 base64 數據
-	parts := strings.Split(imageData, ",")
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid base64 data format")
+// encodeJPEG 以指定品質編碼；Go 的 JPEG 編碼器不寫入任何中繼資料，因此 EXIF 與 GPS 會一併移除
+func encodeJPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode image as JPEG: %w", err)
 	}
+	return buf.Bytes(), nil
+}
 
-	// 解碼 base64 數據
-	decodedData, err := base64.StdEncoding.DecodeString(parts[1])
-	if err != nil {
-		return fmt.Errorf("failed to decode base64 data: %w", err)
+// flatten 將圖片縮放到最長邊不超過 maxEdge，並以白色背景合成透明區域（JPEG 不支援透明）
+func flatten(img image.Image, maxEdge int) *image.RGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if edge := longestEdge(b); maxEdge > 0 && edge > maxEdge {
+		w = max(1, w*maxEdge/edge)
+		h = max(1, h*maxEdge/edge)
 	}
 
-	// 檢查文件大小
-	if int64(len(decodedData)) > s.maxSizeBytes {
-		return fmt.Errorf("image size exceeds maximum limit of %d bytes", s.maxSizeBytes)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	if w == b.Dx() && h == b.Dy() {
+		draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	} else {
+		draw.BiLinear.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
 	}
+	return dst
+}
 
-	// 解碼圖片
-	_, format, err := image.Decode(bytes.NewReader(decodedData))
-	if err != nil {
-		return fmt.Errorf("failed to decode image: %w", err)
-	}
+// longestEdge 最長邊長度
+func longestEdge(b image.Rectangle) int {
+	return max(b.Dx(), b.Dy())
+}
 
-	// 檢查圖片格式
-	if !isSupportedFormat(format) {
-		return fmt.Errorf("unsupported image format: %s", format)
+// toDataURI 將 JPEG 位元組包成 data URI
+func toDataURI(data []byte) string {
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// isSupportedFormat 檢查圖片格式是否支援
+func isSupportedFormat(format string) bool {
+	supportedFormats := map[string]bool{
+		"jpeg": true,
+		"jpg":  true,
+		"png":  true,
+		"gif":  true,
+		"webp": true,
 	}
+	return supportedFormats[format]
+}
 
-	return nil
+// ValidateImage 驗證圖片
+func (s *Service) ValidateImage(ctx context.Context, imageData string) error {
+	data, err := s.load(ctx, imageData)
+	if err != nil {
+		return err
+	}
+	_, _, err = decode(data)
+	return err
 }
@@ -0,0 +1,220 @@
+package image
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	common.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// decodeResult 解碼 ProcessReader 回傳的 data URI
+func decodeResult(t *testing.T, uri string) ([]byte, image.Image) {
+	t.Helper()
+	data, ok := strings.CutPrefix(uri, "data:image/jpeg;base64,")
+	if !ok {
+		t.Fatalf("not a JPEG data URI: %.40s", uri)
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw, img
+}
+
+// noise 隨機像素的圖片，JPEG 難以壓縮，用來測試品質與大小的取捨
+func noise(seed int64, w, h int) *image.RGBA {
+	rng := rand.New(rand.NewSource(seed))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	rng.Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	return img
+}
+
+// near 兩個顏色在 JPEG 壓縮誤差內相同
+func near(a, b color.Color) bool {
+	ar, ag, ab, _ := a.RGBA()
+	br, bg, bb, _ := b.RGBA()
+	diff := func(x, y uint32) bool { return x>>8 > y>>8+60 || y>>8 > x>>8+60 }
+	return !diff(ar, br) && !diff(ag, bg) && !diff(ab, bb)
+}
+
+func TestProcessAppliesOrientation(t *testing.T) {
+	s := NewService(testImageConfig())
+	src := markedImage(120, 80, 30)
+	plain := encodeTestJPEG(t, src)
+
+	for o := 2; o <= 8; o++ {
+		uri, err := s.ProcessReader(bytes.NewReader(withSegments(plain, exifSegment(o, binary.BigEndian))))
+		if err != nil {
+			t.Fatalf("orientation %d: %v", o, err)
+		}
+		raw, got := decodeResult(t, uri)
+		want := toRGBA(applyOrientation(src, o))
+		if got.Bounds().Size() != want.Bounds().Size() {
+			t.Fatalf("orientation %d: size %v, want %v", o, got.Bounds().Size(), want.Bounds().Size())
+		}
+		// 在每個色塊中央取樣
+		for y := 15; y < want.Bounds().Dy(); y += 30 {
+			for x := 15; x < want.Bounds().Dx(); x += 30 {
+				if !near(got.At(x, y), want.At(x, y)) {
+					t.Fatalf("orientation %d: pixel (%d,%d) = %v, want %v", o, x, y, got.At(x, y), want.At(x, y))
+				}
+			}
+		}
+		// 輸出不再帶有 EXIF，避免用戶端再次旋轉
+		if info := readJPEGInfo(raw); info.metadata || info.orientation != 1 || bytes.Contains(raw, []byte("Exif")) {
+			t.Fatalf("orientation %d: output still has metadata: %+v", o, info)
+		}
+	}
+}
+
+func TestProcessStripsMetadata(t *testing.T) {
+	s := NewService(testImageConfig())
+	plain := encodeTestJPEG(t, markedImage(64, 48, 16))
+
+	// 已符合條件且沒有中繼資料的 JPEG 原樣回傳
+	uri, err := s.ProcessReader(bytes.NewReader(plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := decodeResult(t, uri); !bytes.Equal(raw, plain) {
+		t.Fatal("compliant JPEG re-encoded")
+	}
+
+	// 帶有 EXIF 與註解（方向為 1）時重新編碼以移除
+	comment := []byte{0xFF, 0xFE, 0x00, 0x0A, 'G', 'P', 'S', ' ', 'd', 'a', 't', 'a'}
+	tagged := withSegments(plain, exifSegment(1, binary.LittleEndian), comment)
+	uri, err = s.ProcessReader(bytes.NewReader(tagged))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, img := decodeResult(t, uri)
+	if readJPEGInfo(raw).metadata || bytes.Contains(raw, []byte("GPS data")) {
+		t.Fatal("metadata not stripped")
+	}
+	if img.Bounds().Size() != image.Pt(64, 48) {
+		t.Fatalf("size changed: %v", img.Bounds().Size())
+	}
+}
+
+func TestProcessDownscales(t *testing.T) {
+	cfg := testImageConfig()
+	cfg.MaxSizeBytes = 8 << 20
+	s := NewService(cfg)
+
+	tests := []struct {
+		w, h int
+		want image.Point
+	}{
+		{3000, 1000, image.Pt(1200, 400)},
+		{900, 2400, image.Pt(450, 1200)},
+		{800, 600, image.Pt(800, 600)},
+	}
+	for _, tt := range tests {
+		// PNG 輸入一律轉成 JPEG
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, markedImage(tt.w, tt.h, 100)); err != nil {
+			t.Fatal(err)
+		}
+		uri, err := s.ProcessReader(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, img := decodeResult(t, uri); img.Bounds().Size() != tt.want {
+			t.Fatalf("%dx%d: got %v, want %v", tt.w, tt.h, img.Bounds().Size(), tt.want)
+		}
+	}
+}
+
+func TestFitQualityStaysWithinBudget(t *testing.T) {
+	img := noise(1, 300, 300)
+	at := func(q int) int {
+		out, err := encodeJPEG(img, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(out)
+	}
+
+	cfg := testImageConfig()
+	cfg.TargetSize = at(70)
+	s := NewService(cfg)
+	out, quality, err := s.fitQuality(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 二分搜尋找到預算內的最高品質
+	if len(out) > cfg.TargetSize || quality < 70 || quality > cfg.InitialQuality {
+		t.Fatalf("quality %d, %d bytes, budget %d", quality, len(out), cfg.TargetSize)
+	}
+	if quality < cfg.InitialQuality && at(quality+1) <= cfg.TargetSize {
+		t.Fatalf("quality %d not the highest within budget", quality)
+	}
+
+	// 預算足夠時使用品質上限
+	cfg.TargetSize = at(cfg.InitialQuality)
+	if _, quality, _ = NewService(cfg).fitQuality(img); quality != cfg.InitialQuality {
+		t.Fatalf("quality %d, want %d", quality, cfg.InitialQuality)
+	}
+
+	// 最低品質仍超過預算時回傳最低品質的結果
+	cfg.TargetSize = 1 << 10
+	if out, quality, _ = NewService(cfg).fitQuality(img); quality != cfg.MinQuality || len(out) <= cfg.TargetSize {
+		t.Fatalf("quality %d, %d bytes", quality, len(out))
+	}
+}
+
+func TestProcessShrinksToBudget(t *testing.T) {
+	cfg := testImageConfig()
+	cfg.MaxSizeBytes = 8 << 20
+	cfg.TargetSize = 60 << 10
+	s := NewService(cfg)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, noise(2, 1000, 800)); err != nil {
+		t.Fatal(err)
+	}
+	uri, err := s.ProcessReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 最低品質仍超過預算，依 FinalScale 縮小直到符合
+	raw, img := decodeResult(t, uri)
+	edge := longestEdge(img.Bounds())
+	if len(raw) > cfg.TargetSize || edge >= 1000 || edge < minScaledEdge {
+		t.Fatalf("%d bytes (budget %d), longest edge %d", len(raw), cfg.TargetSize, edge)
+	}
+	// 長寬比維持 5:4（容許取整誤差）
+	if size := img.Bounds().Size(); abs(size.X*4-size.Y*5) > 5 {
+		t.Fatalf("aspect ratio not kept: %v", size)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
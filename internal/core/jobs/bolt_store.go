@@ -0,0 +1,131 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// jobsBucket BoltDB 中存放任務的 bucket
+var jobsBucket = []byte("jobs")
+
+// BoltStore 以 BoltDB 檔案保存任務，重啟後仍可恢復
+type BoltStore struct {
+	leaseTable // 資料檔有檔案鎖，同時只有一個程序使用
+
+	db *bolt.DB
+}
+
+// NewBoltStore 開啟（必要時建立）BoltDB 任務儲存
+func NewBoltStore(path string) (*BoltStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create job store directory: %w", err)
+		}
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job store %s: %w", path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(jobsBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize job store: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Save 實作 Store
+func (s *BoltStore) Save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Put([]byte(job.ID), data)
+	})
+}
+
+// Get 實作 Store
+func (s *BoltStore) Get(id string) (*Job, error) {
+	var job Job
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(jobsBucket).Get([]byte(id))
+		if data == nil {
+			return ErrJobNotFound
+		}
+		return json.Unmarshal(data, &job)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Update 實作 Store
+func (s *BoltStore) Update(id string, fn func(job *Job) error) (*Job, error) {
+	var job Job
+	var fnErr error
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(jobsBucket)
+		data := b.Get([]byte(id))
+		if data == nil {
+			return ErrJobNotFound
+		}
+		if err := json.Unmarshal(data, &job); err != nil {
+			return err
+		}
+		if fnErr = fn(&job); fnErr != nil {
+			job = Job{}
+			return json.Unmarshal(data, &job)
+		}
+		data, err := json.Marshal(&job)
+		if err != nil {
+			return fmt.Errorf("failed to encode job: %w", err)
+		}
+		return b.Put([]byte(id), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, fnErr
+}
+
+// Delete 實作 Store
+func (s *BoltStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Delete([]byte(id))
+	})
+}
+
+// List 實作 Store
+func (s *BoltStore) List() ([]*Job, error) {
+	var out []*Job
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(_, data []byte) error {
+			var job Job
+			if err := json.Unmarshal(data, &job); err != nil {
+				return err
+			}
+			out = append(out, &job)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Close 實作 Store
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Type 任務類型，對應同步 API 端點
+type Type string
+
+const (
+	// TypeFood 食物圖片辨識
+	TypeFood Type = "food"
+	// TypeIngredient 食材圖片辨識
+	TypeIngredient Type = "ingredient"
+	// TypeGenerate 依名稱生成食譜
+	TypeGenerate Type = "generate"
+	// TypeSuggest 依食材推薦食譜
+	TypeSuggest Type = "suggest"
+)
+
+// Status 任務狀態
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Terminal 是否為結束狀態
+func (s Status) Terminal() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCanceled
+}
+
+// Job 非同步任務
+type Job struct {
+	ID         string          `json:"id"`
+	Type       Type            `json:"type"`
+	Status     Status          `json:"status"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Provider   string          `json:"provider,omitempty"` // 實際回應的 AI 提供者
+	Model      string          `json:"model,omitempty"`
+	Attempts   int             `json:"attempts"`
+	Owner      string          `json:"owner,omitempty"` // 持有任務的實例
+	CreatedAt  time.Time       `json:"created_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+// clone 複製任務，避免呼叫端修改共享狀態
+func (j *Job) clone() *Job {
+	cp := *j
+	return &cp
+}
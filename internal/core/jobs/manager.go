@@ -0,0 +1,521 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/core/ai/queue"
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrUnknownType 未註冊的任務類型
+	ErrUnknownType = errors.New("unknown job type")
+	// ErrJobFinished 任務已結束，無法取消
+	ErrJobFinished = errors.New("job already finished")
+
+	// errSkipJob 任務狀態或持有者已改變，放棄本次更新
+	errSkipJob = errors.New("job skipped")
+)
+
+// Runner 任務執行器
+type Runner struct {
+	// Validate 提交時驗證 payload，錯誤會直接回報給客戶端
+	Validate func(payload json.RawMessage) error
+	// Run 執行任務，回傳值會以 JSON 存入任務結果
+	Run func(ctx context.Context, payload json.RawMessage) (interface{}, error)
+}
+
+// Manager 非同步任務管理器，透過 queue.Manager 的工作者池執行任務
+type Manager struct {
+	config  config.JobsConfig
+	store   Store
+	queue   *queue.Manager
+	runners map[Type]Runner
+
+	// instance 本實例識別碼，記錄在本實例建立或接手的任務上
+	instance string
+	started  time.Time
+
+	// mu 保護任務狀態轉換與執行中任務的取消函式
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+
+	ctx    context.Context
+	stop   context.CancelFunc
+	wg     sync.WaitGroup
+	closed bool
+}
+
+// NewManager 創建任務管理器
+func NewManager(cfg *config.Config, q *queue.Manager, store Store) *Manager {
+	ctx, stop := context.WithCancel(context.Background())
+	return &Manager{
+		config:   cfg.Jobs,
+		store:    store,
+		queue:    q,
+		runners:  make(map[Type]Runner),
+		instance: instanceID(cfg.Jobs),
+		cancels:  make(map[string]context.CancelFunc),
+		ctx:      ctx,
+		stop:     stop,
+	}
+}
+
+// instanceID 未設定時以主機名稱與 PID 識別實例；容器重啟後通常沿用相同識別碼，可直接接回上次的任務
+func instanceID(cfg config.JobsConfig) string {
+	if cfg.InstanceID != "" {
+		return cfg.InstanceID
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = common.GenerateUUID()
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Register 註冊任務類型的執行器，需在 Start 之前呼叫
+func (m *Manager) Register(t Type, r Runner) {
+	if r.Run == nil {
+		panic(fmt.Sprintf("jobs: runner for %q has no Run function", t))
+	}
+	m.runners[t] = r
+}
+
+// Start 啟動工作者池、租約續約與過期任務清理，並重新排入上次未完成的任務
+func (m *Manager) Start() {
+	m.started = time.Now()
+	m.heartbeat()
+	m.queue.Start(m.handle)
+
+	m.wg.Add(3)
+	go func() {
+		defer m.wg.Done()
+		m.recoverJobs(true)
+	}()
+	go m.heartbeatLoop()
+	go m.cleanupLoop()
+
+	common.LogInfo("Job manager started",
+		zap.String("instance", m.instance),
+		zap.Duration("ttl", m.config.TTL),
+		zap.Duration("timeout", m.config.Timeout),
+	)
+}
+
+// Submit 建立任務並加入隊列
+func (m *Manager) Submit(t Type, payload json.RawMessage) (*Job, error) {
+	runner, ok := m.runners[t]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, t)
+	}
+	if runner.Validate != nil {
+		if err := runner.Validate(payload); err != nil {
+			return nil, err
+		}
+	}
+
+	job := &Job{
+		ID:        common.GenerateUUID(),
+		Type:      t,
+		Status:    StatusQueued,
+		Payload:   payload,
+		Owner:     m.instance,
+		CreatedAt: time.Now(),
+	}
+	if err := m.store.Save(job); err != nil {
+		return nil, fmt.Errorf("failed to save job: %w", err)
+	}
+
+	if err := m.queue.EnqueueJob(job.ID); err != nil {
+		_ = m.store.Delete(job.ID)
+		return nil, err
+	}
+
+	common.LogInfo("Job submitted",
+		zap.String("job_id", job.ID),
+		zap.String("type", string(t)),
+	)
+	return job, nil
+}
+
+// Get 取得任務
+func (m *Manager) Get(id string) (*Job, error) {
+	return m.store.Get(id)
+}
+
+// Cancel 取消排隊中或執行中的任務
+func (m *Manager) Cancel(id string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	job, err := m.store.Update(id, func(job *Job) error {
+		if job.Status.Terminal() {
+			return ErrJobFinished
+		}
+		job.Status = StatusCanceled
+		job.FinishedAt = &now
+		return nil
+	})
+	switch {
+	case errors.Is(err, ErrJobFinished), errors.Is(err, ErrJobNotFound):
+		return job, err
+	case err != nil:
+		return nil, fmt.Errorf("failed to save job: %w", err)
+	}
+
+	// 在其他實例執行的任務由該實例於結束時發現已取消
+	if cancel, ok := m.cancels[id]; ok {
+		cancel()
+	}
+
+	common.LogInfo("Job canceled", zap.String("job_id", id))
+	return job, nil
+}
+
+// handle 工作者處理單一任務
+func (m *Manager) handle(req *queue.Request) {
+	if req.JobID == "" {
+		return
+	}
+
+	job, runner, ctx, ok := m.begin(req.JobID)
+	if !ok {
+		return
+	}
+
+	ctx, md := provider.WithMetadata(ctx)
+	start := time.Now()
+	result, runErr := runner.Run(ctx, job.Payload)
+	providerName, model := md.Get()
+
+	m.finish(job.ID, result, runErr, providerName, model, time.Since(start))
+}
+
+// begin 以原子更新將任務標記為執行中，並建立可取消的 context
+func (m *Manager) begin(id string) (*Job, Runner, context.Context, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var runner Runner
+	now := time.Now()
+	job, err := m.store.Update(id, func(job *Job) error {
+		// 排隊期間已被取消，或已由其他實例接手
+		if job.Status != StatusQueued || job.Owner != m.instance {
+			return errSkipJob
+		}
+		r, ok := m.runners[job.Type]
+		if !ok {
+			job.Status = StatusFailed
+			job.Error = fmt.Sprintf("%v: %s", ErrUnknownType, job.Type)
+			job.FinishedAt = &now
+			return nil
+		}
+		runner = r
+		job.Status = StatusRunning
+		job.StartedAt = &now
+		job.Attempts++
+		return nil
+	})
+	switch {
+	case errors.Is(err, errSkipJob):
+		return nil, Runner{}, nil, false
+	case errors.Is(err, ErrJobNotFound):
+		common.LogWarn("Queued job not found", zap.String("job_id", id), zap.Error(err))
+		return nil, Runner{}, nil, false
+	case err != nil:
+		common.LogError("Failed to save job", zap.String("job_id", id), zap.Error(err))
+		return nil, Runner{}, nil, false
+	}
+	if job.Status != StatusRunning {
+		return nil, Runner{}, nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, m.config.Timeout)
+	m.cancels[id] = cancel
+	return job, runner, ctx, true
+}
+
+// finish 寫入任務結果；已被取消或已由其他實例接手的任務不覆寫狀態
+func (m *Manager) finish(id string, result interface{}, runErr error, providerName, model string, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cancel, ok := m.cancels[id]; ok {
+		cancel()
+		delete(m.cancels, id)
+	}
+
+	// 服務關閉導致中斷的任務保持排隊狀態，待重啟後恢復
+	interrupted := runErr != nil && m.ctx.Err() != nil
+
+	var data json.RawMessage
+	if runErr == nil {
+		if b, err := json.Marshal(result); err == nil {
+			data = b
+		} else {
+			runErr = fmt.Errorf("failed to encode result: %w", err)
+		}
+	}
+
+	now := time.Now()
+	job, err := m.store.Update(id, func(job *Job) error {
+		if job.Status == StatusCanceled || job.Owner != m.instance {
+			return errSkipJob
+		}
+		if interrupted {
+			job.Status = StatusQueued
+			job.StartedAt = nil
+			return nil
+		}
+		job.FinishedAt = &now
+		job.Provider = providerName
+		job.Model = model
+		if runErr == nil {
+			job.Result = data
+			job.Status = StatusSucceeded
+		} else {
+			job.Status = StatusFailed
+			job.Error = runErr.Error()
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errSkipJob):
+		return
+	case errors.Is(err, ErrJobNotFound):
+		common.LogWarn("Finished job not found", zap.String("job_id", id), zap.Error(err))
+		return
+	case err != nil:
+		common.LogError("Failed to save job", zap.String("job_id", id), zap.Error(err))
+		return
+	}
+
+	if interrupted {
+		common.LogWarn("Job interrupted by shutdown, will resume on restart",
+			zap.String("job_id", id),
+			zap.Int("attempts", job.Attempts),
+		)
+		return
+	}
+
+	common.LogInfo("Job finished",
+		zap.String("job_id", id),
+		zap.String("type", string(job.Type)),
+		zap.String("status", string(job.Status)),
+		zap.String("provider", providerName),
+		zap.Duration("latency", latency),
+		zap.Int("attempts", job.Attempts),
+		zap.NamedError("job_error", runErr),
+	)
+}
+
+// recoverJobs 接手持有者已離線的排隊中或執行到一半的任務，重新排入隊列；
+// 啟動時一併恢復本實例上次未完成的任務。接手以原子更新進行，同一任務只會由一個實例取得。
+// 已達最大嘗試次數的任務標記為失敗，避免反覆觸發同一個問題
+func (m *Manager) recoverJobs(startup bool) {
+	list, err := m.store.List()
+	if err != nil {
+		common.LogError("Failed to list jobs for recovery", zap.Error(err))
+		return
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+
+	var pending []string
+	failed := 0
+	alive := make(map[string]bool)
+	m.mu.Lock()
+	for _, listed := range list {
+		if listed.Status != StatusQueued && listed.Status != StatusRunning {
+			continue
+		}
+		if !m.orphaned(listed, startup, alive) {
+			continue
+		}
+
+		now := time.Now()
+		job, err := m.store.Update(listed.ID, func(job *Job) error {
+			// 列出後狀態或持有者已改變，表示已由其他實例處理
+			if (job.Status != StatusQueued && job.Status != StatusRunning) || job.Owner != listed.Owner {
+				return errSkipJob
+			}
+			job.Owner = m.instance
+			if job.Attempts >= m.config.MaxAttempts {
+				job.Status = StatusFailed
+				job.Error = fmt.Sprintf("job interrupted after %d attempts", job.Attempts)
+				job.FinishedAt = &now
+				return nil
+			}
+			job.Status = StatusQueued
+			job.StartedAt = nil
+			return nil
+		})
+		switch {
+		case errors.Is(err, errSkipJob), errors.Is(err, ErrJobNotFound):
+			continue
+		case err != nil:
+			common.LogError("Failed to save job", zap.String("job_id", listed.ID), zap.Error(err))
+			continue
+		}
+		if job.Status == StatusFailed {
+			failed++
+			continue
+		}
+		pending = append(pending, job.ID)
+	}
+	m.mu.Unlock()
+
+	if len(pending) == 0 && failed == 0 {
+		return
+	}
+	common.LogInfo("Recovering unfinished jobs",
+		zap.Int("requeued", len(pending)),
+		zap.Int("failed", failed),
+	)
+
+	// 隊列容量可能小於待恢復數量，逐一等待空位
+	for _, id := range pending {
+		if err := m.queue.EnqueueJobWait(m.ctx, id); err != nil {
+			common.LogWarn("Job recovery interrupted",
+				zap.String("job_id", id),
+				zap.Error(err),
+			)
+			return
+		}
+	}
+}
+
+// orphaned 任務是否應由本實例接手：持有者租約已過期，或是本實例啟動前留下的任務
+func (m *Manager) orphaned(job *Job, startup bool, alive map[string]bool) bool {
+	if job.Owner == m.instance {
+		return startup && job.CreatedAt.Before(m.started)
+	}
+	if job.Owner == "" {
+		return true
+	}
+	ok, checked := alive[job.Owner]
+	if !checked {
+		var err error
+		if ok, err = m.store.Alive(job.Owner); err != nil {
+			// 無法確認時不接手，避免重複執行
+			common.LogWarn("Failed to check job owner lease",
+				zap.String("owner", job.Owner),
+				zap.Error(err),
+			)
+			ok = true
+		}
+		alive[job.Owner] = ok
+	}
+	return !ok
+}
+
+// heartbeatLoop 定期續約實例租約，讓其他實例知道本實例持有的任務仍在處理
+func (m *Manager) heartbeatLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.config.LeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.heartbeat()
+		}
+	}
+}
+
+// heartbeat 續約實例租約
+func (m *Manager) heartbeat() {
+	if err := m.store.Heartbeat(m.instance, m.config.LeaseTTL); err != nil {
+		common.LogWarn("Failed to renew job lease",
+			zap.String("instance", m.instance),
+			zap.Error(err),
+		)
+	}
+}
+
+// cleanupLoop 定期刪除已結束且超過 TTL 的任務，並接手已離線實例留下的任務
+func (m *Manager) cleanupLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.config.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.cleanup()
+			m.recoverJobs(false)
+		}
+	}
+}
+
+// cleanup 刪除過期任務
+func (m *Manager) cleanup() {
+	jobs, err := m.store.List()
+	if err != nil {
+		common.LogError("Failed to list jobs for cleanup", zap.Error(err))
+		return
+	}
+
+	cutoff := time.Now().Add(-m.config.TTL)
+	removed := 0
+	for _, job := range jobs {
+		if !job.Status.Terminal() || job.FinishedAt == nil || job.FinishedAt.After(cutoff) {
+			continue
+		}
+		if err := m.store.Delete(job.ID); err != nil {
+			common.LogWarn("Failed to delete expired job", zap.String("job_id", job.ID), zap.Error(err))
+			continue
+		}
+		removed++
+	}
+	if removed > 0 {
+		common.LogInfo("Expired jobs removed", zap.Int("count", removed))
+	}
+}
+
+// QueueStatus 回傳隊列狀態
+func (m *Manager) QueueStatus() *queue.Status {
+	return m.queue.GetQueueStatus()
+}
+
+// Close 停止工作者並關閉儲存；ctx 到期時中斷仍在執行的任務
+func (m *Manager) Close(ctx context.Context) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	m.mu.Unlock()
+
+	// 先等待執行中的任務完成；逾時則中斷任務，讓它們回到排隊狀態後再關閉儲存
+	err := m.queue.Stop(ctx)
+	m.stop()
+	if err != nil {
+		drain, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if derr := m.queue.Stop(drain); derr != nil {
+			common.LogWarn("Queue workers did not exit after cancellation", zap.Error(derr))
+		}
+	}
+	m.wg.Wait()
+
+	return errors.Join(err, m.store.Close())
+}
@@ -0,0 +1,202 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"recipe-generator/internal/core/ai/queue"
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+
+	"github.com/alicebob/miniredis/v2"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	common.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// newTestRedisStore 以 miniredis 建立多個實例共用的任務儲存
+func newTestRedisStore(t *testing.T, mr *miniredis.Miniredis) *RedisStore {
+	t.Helper()
+	s, err := NewRedisStore(config.RedisConfig{Addr: mr.Addr(), Prefix: "test:"})
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	return s
+}
+
+// newTestManager 建立指定實例識別碼的任務管理器，run 為 generate 任務的執行器
+func newTestManager(t *testing.T, instance string, store Store, run func(ctx context.Context) error) *Manager {
+	t.Helper()
+	cfg := &config.Config{
+		Queue: config.QueueConfig{Workers: 2, MaxSize: 10},
+		Jobs: config.JobsConfig{
+			TTL:             time.Hour,
+			Timeout:         time.Minute,
+			CleanupInterval: time.Hour,
+			MaxAttempts:     3,
+			InstanceID:      instance,
+			LeaseTTL:        time.Minute,
+		},
+	}
+	m := NewManager(cfg, queue.NewManager(cfg), store)
+	m.Register(TypeGenerate, Runner{Run: func(ctx context.Context, _ json.RawMessage) (interface{}, error) {
+		return "ok", run(ctx)
+	}})
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		m.Close(ctx)
+	})
+	return m
+}
+
+// waitStatus 等待任務進入指定狀態
+func waitStatus(t *testing.T, store Store, id string, status Status) *Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, err := store.Get(id)
+		if err == nil && job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s: %+v, %v; want %s", id, job, err, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRecoverSkipsJobsOfLiveInstance(t *testing.T) {
+	mr := miniredis.RunT(t)
+	release := make(chan struct{})
+	defer close(release)
+
+	a := newTestManager(t, "a", newTestRedisStore(t, mr), func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	a.Start()
+	job, err := a.Submit(TypeGenerate, json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeB := newTestRedisStore(t, mr)
+	waitStatus(t, storeB, job.ID, StatusRunning)
+
+	// 另一個實例啟動時，不可重新排入仍在運作的實例上執行中的任務
+	var ran atomic.Int32
+	b := newTestManager(t, "b", storeB, func(ctx context.Context) error {
+		ran.Add(1)
+		return nil
+	})
+	b.Start()
+	b.recoverJobs(false)
+
+	got, err := storeB.Get(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusRunning || got.Owner != "a" || got.Attempts != 1 || ran.Load() != 0 {
+		t.Fatalf("job taken over from live instance: %+v, ran=%d", got, ran.Load())
+	}
+}
+
+func TestRecoverTakesOverExpiredInstance(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := newTestRedisStore(t, mr)
+
+	// 已離線實例留下執行到一半的任務
+	if err := store.Heartbeat("dead", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	started := time.Now()
+	orphan := &Job{ID: "orphan", Type: TypeGenerate, Status: StatusRunning, Payload: json.RawMessage(`{}`),
+		Owner: "dead", Attempts: 1, CreatedAt: time.Now(), StartedAt: &started}
+	if err := store.Save(orphan); err != nil {
+		t.Fatal(err)
+	}
+	mr.FastForward(2 * time.Minute)
+
+	b := newTestManager(t, "b", store, func(ctx context.Context) error { return nil })
+	b.Start()
+
+	job := waitStatus(t, store, orphan.ID, StatusSucceeded)
+	if job.Owner != "b" || job.Attempts != 2 {
+		t.Fatalf("recovered job: %+v", job)
+	}
+}
+
+func TestRecoverOwnJobsOnlyAtStartup(t *testing.T) {
+	store := NewMemoryStore()
+	past := time.Now().Add(-time.Minute)
+	leftover := &Job{ID: "leftover", Type: TypeGenerate, Status: StatusQueued, Payload: json.RawMessage(`{}`),
+		Owner: "a", CreatedAt: past}
+	if err := store.Save(leftover); err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	m := newTestManager(t, "a", store, func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	m.Start()
+	waitStatus(t, store, leftover.ID, StatusRunning)
+
+	// 執行期間的定期恢復不可把本實例正在執行的任務重新排入
+	m.recoverJobs(false)
+	if job, _ := store.Get(leftover.ID); job.Status != StatusRunning || job.Attempts != 1 {
+		t.Fatalf("running job requeued: %+v", job)
+	}
+	close(release)
+	waitStatus(t, store, leftover.ID, StatusSucceeded)
+}
+
+func TestRedisStoreUpdateIsAtomic(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := newTestRedisStore(t, mr)
+	if err := store.Save(&Job{ID: "j", Status: StatusQueued, Owner: "dead"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 多個實例同時接手同一個任務，只有一個成功
+	var wg sync.WaitGroup
+	var claimed atomic.Int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(instance string) {
+			defer wg.Done()
+			s := newTestRedisStore(t, mr)
+			defer s.Close()
+			_, err := s.Update("j", func(job *Job) error {
+				if job.Owner != "dead" {
+					return errSkipJob
+				}
+				job.Owner = instance
+				return nil
+			})
+			if err == nil {
+				claimed.Add(1)
+			} else if !errors.Is(err, errSkipJob) {
+				t.Errorf("Update: %v", err)
+			}
+		}(string(rune('a' + i)))
+	}
+	wg.Wait()
+	if claimed.Load() != 1 {
+		t.Fatalf("claimed by %d instances", claimed.Load())
+	}
+
+	if _, err := store.Update("missing", func(*Job) error { return nil }); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("Update missing: %v", err)
+	}
+}
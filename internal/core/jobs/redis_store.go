@@ -0,0 +1,206 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"recipe-generator/internal/infrastructure/config"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redisTimeout 單次 Redis 操作的超時
+const redisTimeout = 5 * time.Second
+
+// maxUpdateRetries 樂觀鎖衝突時的最大重試次數
+const maxUpdateRetries = 10
+
+// RedisStore 以 Redis 保存任務，適合多實例部署
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore 連接 Redis 任務儲存
+func NewRedisStore(cfg config.RedisConfig) (*RedisStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis %s: %w", cfg.Addr, err)
+	}
+
+	return &RedisStore{client: client, prefix: cfg.Prefix}, nil
+}
+
+// jobKey 任務資料的 key
+func (s *RedisStore) jobKey(id string) string {
+	return s.prefix + "job:" + id
+}
+
+// indexKey 所有任務 ID 的集合
+func (s *RedisStore) indexKey() string {
+	return s.prefix + "jobs"
+}
+
+// instanceKey 實例租約的 key
+func (s *RedisStore) instanceKey(instance string) string {
+	return s.prefix + "jobs-instance:" + instance
+}
+
+// Save 實作 Store
+func (s *RedisStore) Save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.jobKey(job.ID), data, 0)
+		pipe.SAdd(ctx, s.indexKey(), job.ID)
+		return nil
+	})
+	return err
+}
+
+// Get 實作 Store
+func (s *RedisStore) Get(id string) (*Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	data, err := s.client.Get(ctx, s.jobKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %w", err)
+	}
+	return &job, nil
+}
+
+// Update 實作 Store，以 WATCH 偵測其他實例的並行修改，衝突時重新讀取後重試
+func (s *RedisStore) Update(id string, fn func(job *Job) error) (*Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	key := s.jobKey(id)
+	for i := 0; i < maxUpdateRetries; i++ {
+		var job *Job
+		var fnErr error
+		err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+			data, err := tx.Get(ctx, key).Bytes()
+			if err == redis.Nil {
+				return ErrJobNotFound
+			}
+			if err != nil {
+				return err
+			}
+			job = &Job{}
+			if err := json.Unmarshal(data, job); err != nil {
+				return fmt.Errorf("failed to decode job: %w", err)
+			}
+
+			updated := job.clone()
+			if fnErr = fn(updated); fnErr != nil {
+				return nil
+			}
+			if data, err = json.Marshal(updated); err != nil {
+				return fmt.Errorf("failed to encode job: %w", err)
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, data, 0)
+				return nil
+			})
+			if err == nil {
+				job = updated
+			}
+			return err
+		}, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return job, fnErr
+	}
+	return nil, fmt.Errorf("failed to update job %s: too many concurrent modifications", id)
+}
+
+// Delete 實作 Store
+func (s *RedisStore) Delete(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, s.jobKey(id))
+		pipe.SRem(ctx, s.indexKey(), id)
+		return nil
+	})
+	return err
+}
+
+// List 實作 Store
+func (s *RedisStore) List() ([]*Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	ids, err := s.client.SMembers(ctx, s.indexKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*Job, 0, len(ids))
+	for _, id := range ids {
+		data, err := s.client.Get(ctx, s.jobKey(id)).Bytes()
+		if err == redis.Nil {
+			// 索引殘留，順手清除
+			s.client.SRem(ctx, s.indexKey(), id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			return nil, fmt.Errorf("failed to decode job %s: %w", id, err)
+		}
+		out = append(out, &job)
+	}
+	return out, nil
+}
+
+// Heartbeat 實作 Store
+func (s *RedisStore) Heartbeat(instance string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return s.client.Set(ctx, s.instanceKey(instance), time.Now().Unix(), ttl).Err()
+}
+
+// Alive 實作 Store
+func (s *RedisStore) Alive(instance string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	n, err := s.client.Exists(ctx, s.instanceKey(instance)).Result()
+	return n > 0, err
+}
+
+// Close 實作 Store
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
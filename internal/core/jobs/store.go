@@ -0,0 +1,143 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"recipe-generator/internal/infrastructure/config"
+)
+
+// ErrJobNotFound 任務不存在或已過期
+var ErrJobNotFound = errors.New("job not found")
+
+// Store 任務儲存介面
+type Store interface {
+	// Save 新增或更新任務
+	Save(job *Job) error
+	// Get 取得任務，不存在時回傳 ErrJobNotFound
+	Get(id string) (*Job, error)
+	// Update 以原子方式讀取、修改並寫回任務；fn 回傳錯誤時不寫入，連同讀到的任務回傳該錯誤
+	Update(id string, fn func(job *Job) error) (*Job, error)
+	// Delete 刪除任務
+	Delete(id string) error
+	// List 列出所有任務
+	List() ([]*Job, error)
+	// Heartbeat 續約實例租約，超過 ttl 未續約的實例視為離線
+	Heartbeat(instance string, ttl time.Duration) error
+	// Alive 實例租約是否仍有效
+	Alive(instance string) (bool, error)
+	// Close 關閉儲存
+	Close() error
+}
+
+// leaseTable 程序內的實例租約；memory 與 bolt 儲存無法跨程序共用，只會有本程序的租約
+type leaseTable struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+// Heartbeat 實作 Store
+func (l *leaseTable) Heartbeat(instance string, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.until == nil {
+		l.until = make(map[string]time.Time)
+	}
+	l.until[instance] = time.Now().Add(ttl)
+	return nil
+}
+
+// Alive 實作 Store
+func (l *leaseTable) Alive(instance string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return time.Now().Before(l.until[instance]), nil
+}
+
+// MemoryStore 記憶體任務儲存，重啟後資料會遺失
+type MemoryStore struct {
+	leaseTable
+
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+// NewMemoryStore 創建記憶體任務儲存
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: make(map[string]*Job)}
+}
+
+// Save 實作 Store
+func (s *MemoryStore) Save(job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job.clone()
+	return nil
+}
+
+// Get 實作 Store
+func (s *MemoryStore) Get(id string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return job.clone(), nil
+}
+
+// Update 實作 Store
+func (s *MemoryStore) Update(id string, fn func(job *Job) error) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	job := stored.clone()
+	if err := fn(job); err != nil {
+		return stored.clone(), err
+	}
+	s.jobs[id] = job.clone()
+	return job, nil
+}
+
+// Delete 實作 Store
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	return nil
+}
+
+// List 實作 Store
+func (s *MemoryStore) List() ([]*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		out = append(out, job.clone())
+	}
+	return out, nil
+}
+
+// Close 實作 Store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// NewStore 依設定建立任務儲存：bolt（預設）、redis 或 memory
+func NewStore(cfg config.JobsConfig) (Store, error) {
+	switch cfg.Store {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		return NewRedisStore(cfg.Redis)
+	case "bolt", "":
+		return NewBoltStore(cfg.Path)
+	default:
+		return nil, fmt.Errorf("unknown job store %q", cfg.Store)
+	}
+}
@@ -0,0 +1,357 @@
+// Package prompt 管理送給 AI 的提示詞模板：預設模板內嵌於執行檔，
+// 可由覆寫目錄中的同 ID 模板取代或新增版本，不需重新編譯即可調整措辭
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+// 內建模板 ID
+const (
+	RecipeGenerate      = "recipe.generate"
+	RecipeSuggest       = "recipe.suggest"
+	FoodRecognize       = "food.recognize"
+	IngredientRecognize = "ingredient.recognize"
+	CookQA              = "cook.qa"
+)
+
+// templateExt 模板檔副檔名
+const templateExt = ".tmpl"
+
+//go:embed templates/*.tmpl
+var embedded embed.FS
+
+var (
+	// ErrNotFound 模板 ID 或版本不存在
+	ErrNotFound = errors.New("prompt template not found")
+	// ErrVariables 渲染時的變數與模板宣告不符
+	ErrVariables = errors.New("prompt variables mismatch")
+)
+
+// Vars 渲染模板的變數
+type Vars map[string]interface{}
+
+// Template 單一版本的提示詞模板
+type Template struct {
+	ID          string   `json:"id"`
+	Version     int      `json:"version"`
+	Description string   `json:"description,omitempty"`
+	Variables   []string `json:"variables"`
+	Source      string   `json:"source"` // embedded 或覆寫檔案路徑
+
+	tmpl *template.Template
+}
+
+// Label 模板 ID 與版本，例如 recipe.generate@v2
+func (t *Template) Label() string {
+	return fmt.Sprintf("%s@v%d", t.ID, t.Version)
+}
+
+// Prompt 渲染後的提示詞，以及選擇器指定的呼叫參數
+type Prompt struct {
+	ID      string
+	Version int
+	Text    string
+
+	Model       string   // 覆寫提供者的預設模型，空字串時不覆寫
+	Temperature *float64 // 覆寫取樣溫度，nil 時不覆寫
+	Variant     string   // 實驗與變體名稱，例如 recipe-v2/treatment，未參與實驗時為空
+}
+
+// Label 模板 ID 與版本，記錄在日誌、響應標頭與快取鍵中
+func (p *Prompt) Label() string {
+	return fmt.Sprintf("%s@v%d", p.ID, p.Version)
+}
+
+// Selection 選擇器為單次請求指定的模板版本與呼叫參數
+type Selection struct {
+	Version     int      // 模板版本，0 表示最新版本
+	Model       string   // 覆寫模型，空字串時不覆寫
+	Temperature *float64 // 覆寫取樣溫度，nil 時不覆寫
+	Variant     string   // 實驗與變體名稱，記錄於日誌與響應標頭
+}
+
+// Selector 依請求選擇模板版本與呼叫參數，例如 A/B 實驗；回傳 nil 時使用最新版本
+type Selector interface {
+	Select(ctx context.Context, id string) *Selection
+}
+
+// Key 區分快取用的模板與呼叫參數：模板 ID 與版本，覆寫模型或溫度時附加在後
+func (p *Prompt) Key() string {
+	key := p.Label()
+	if p.Model != "" {
+		key += "|model=" + p.Model
+	}
+	if p.Temperature != nil {
+		key += "|temperature=" + strconv.FormatFloat(*p.Temperature, 'f', -1, 64)
+	}
+	return key
+}
+
+// Registry 提示詞模板註冊表，同一 ID 可有多個版本，預設使用最新版本
+type Registry struct {
+	dir      string
+	selector Selector
+
+	mu        sync.RWMutex
+	templates map[string]map[int]*Template
+}
+
+// NewRegistry 載入內嵌模板與 dir 中的覆寫模板；dir 為空時只使用內嵌模板
+func NewRegistry(dir string) (*Registry, error) {
+	r := &Registry{dir: dir}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload 重新載入所有模板；任一模板無效時保留原本的模板並回傳錯誤
+func (r *Registry) Reload() error {
+	templates := make(map[string]map[int]*Template)
+
+	if err := loadDir(templates, embedded, "templates", "embedded"); err != nil {
+		return fmt.Errorf("failed to load embedded prompts: %w", err)
+	}
+	if r.dir != "" {
+		if err := loadDir(templates, os.DirFS(r.dir), ".", r.dir); err != nil {
+			return fmt.Errorf("failed to load prompts from %s: %w", r.dir, err)
+		}
+	}
+
+	r.mu.Lock()
+	r.templates = templates
+	r.mu.Unlock()
+
+	common.LogInfo("提示詞模板已載入",
+		zap.String("dir", r.dir),
+		zap.Strings("templates", r.labels()),
+	)
+	return nil
+}
+
+// loadDir 載入目錄下所有模板檔；覆寫目錄中相同 ID 與版本的模板會取代內嵌模板
+func loadDir(templates map[string]map[int]*Template, fsys fs.FS, dir, source string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != templateExt {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, filepath.ToSlash(filepath.Join(dir, entry.Name())))
+		if err != nil {
+			return err
+		}
+		src := "embedded"
+		if source != "embedded" {
+			src = filepath.Join(source, entry.Name())
+		}
+		t, err := parse(string(data), src)
+		if err != nil {
+			return fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		if templates[t.ID] == nil {
+			templates[t.ID] = make(map[int]*Template)
+		}
+		templates[t.ID][t.Version] = t
+	}
+	return nil
+}
+
+// parse 解析模板檔：開頭為 key: value 標頭（id、version、description、variables），
+// 以單獨一行 --- 與模板內容分隔
+func parse(data, source string) (*Template, error) {
+	header, body, ok := strings.Cut(strings.ReplaceAll(data, "\r\n", "\n"), "\n---\n")
+	if !ok {
+		return nil, errors.New("missing --- separator after header")
+	}
+
+	t := &Template{Source: source}
+	for _, line := range strings.Split(header, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid header line %q", line)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "id":
+			t.ID = value
+		case "version":
+			v, err := strconv.Atoi(value)
+			if err != nil || v <= 0 {
+				return nil, fmt.Errorf("invalid version %q", value)
+			}
+			t.Version = v
+		case "description":
+			t.Description = value
+		case "variables":
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					t.Variables = append(t.Variables, name)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unknown header %q", key)
+		}
+	}
+	if t.ID == "" || t.Version == 0 {
+		return nil, errors.New("id and version are required")
+	}
+
+	tmpl, err := template.New(t.Label()).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	t.tmpl = tmpl
+
+	// 以空字串試渲染一次，載入時就能發現引用了未宣告變數的模板
+	probe := make(Vars, len(t.Variables))
+	for _, name := range t.Variables {
+		probe[name] = ""
+	}
+	if err := t.tmpl.Execute(&bytes.Buffer{}, probe); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// SetSelector 設定選擇器，需在開始處理請求前呼叫
+func (r *Registry) SetSelector(s Selector) {
+	r.selector = s
+}
+
+// Render 渲染模板：設定選擇器時依其選擇的版本與參數，否則使用最新版本；
+// 選擇的版本已不存在（例如覆寫模板被移除後重新載入）時改用最新版本
+func (r *Registry) Render(ctx context.Context, id string, vars Vars) (*Prompt, error) {
+	if r.selector == nil {
+		return r.RenderVersion(id, 0, vars)
+	}
+	sel := r.selector.Select(ctx, id)
+	if sel == nil {
+		return r.RenderVersion(id, 0, vars)
+	}
+
+	p, err := r.RenderVersion(id, sel.Version, vars)
+	if errors.Is(err, ErrNotFound) && sel.Version != 0 {
+		common.LogWarn("選擇的提示詞模板版本不存在，改用最新版本",
+			zap.String("template", id),
+			zap.Int("version", sel.Version),
+			zap.String("variant", sel.Variant),
+		)
+		p, err = r.RenderVersion(id, 0, vars)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.Model = sel.Model
+	p.Temperature = sel.Temperature
+	p.Variant = sel.Variant
+	return p, nil
+}
+
+// RenderVersion 渲染指定版本的模板，version 為 0 時使用最新版本；
+// vars 必須剛好提供模板宣告的所有變數
+func (r *Registry) RenderVersion(id string, version int, vars Vars) (*Prompt, error) {
+	t, err := r.Get(id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range t.Variables {
+		if _, ok := vars[name]; !ok {
+			return nil, fmt.Errorf("%w: %s missing %q", ErrVariables, t.Label(), name)
+		}
+	}
+	for name := range vars {
+		if !contains(t.Variables, name) {
+			return nil, fmt.Errorf("%w: %s does not declare %q", ErrVariables, t.Label(), name)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, vars); err != nil {
+		return nil, fmt.Errorf("failed to render prompt %s: %w", t.Label(), err)
+	}
+	return &Prompt{ID: t.ID, Version: t.Version, Text: buf.String()}, nil
+}
+
+// Get 取得指定版本的模板，version 為 0 時回傳最新版本
+func (r *Registry) Get(id string, version int) (*Template, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := r.templates[id]
+	if version == 0 {
+		for v := range versions {
+			if v > version {
+				version = v
+			}
+		}
+	}
+	t, ok := versions[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s@v%d", ErrNotFound, id, version)
+	}
+	return t, nil
+}
+
+// List 列出所有模板，依 ID 與版本排序
+func (r *Registry) List() []*Template {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []*Template
+	for _, versions := range r.templates {
+		for _, t := range versions {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ID != out[j].ID {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].Version < out[j].Version
+	})
+	return out
+}
+
+// labels 所有模板的 ID 與版本，用於記錄
+func (r *Registry) labels() []string {
+	list := r.List()
+	out := make([]string, len(list))
+	for i, t := range list {
+		out[i] = t.Label() + "(" + t.Source + ")"
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
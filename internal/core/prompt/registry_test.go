@@ -0,0 +1,268 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	common.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// writeTemplate 在覆寫目錄寫入模板檔
+func writeTemplate(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fixedSelector 固定選擇同一組參數
+type fixedSelector struct{ sel *Selection }
+
+func (s fixedSelector) Select(ctx context.Context, id string) *Selection { return s.sel }
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string // 預期錯誤訊息片段，空字串表示成功
+	}{
+		{"valid", "id: a\nversion: 2\n# 註解\ndescription: 測試\nvariables: X, Y\n---\n{{.X}}{{.Y}}", ""},
+		{"crlf", "id: a\r\nversion: 1\r\n---\r\nbody", ""},
+		{"missing separator", "id: a\nversion: 1\nbody", "missing --- separator"},
+		{"separator without newline", "id: a\nversion: 1\n---", "missing --- separator"},
+		{"header without colon", "id: a\nversion 1\n---\nbody", "invalid header line"},
+		{"unknown header", "id: a\nversion: 1\nmodel: x\n---\nbody", "unknown header"},
+		{"version not a number", "id: a\nversion: v2\n---\nbody", "invalid version"},
+		{"version zero", "id: a\nversion: 0\n---\nbody", "invalid version"},
+		{"missing id", "version: 1\n---\nbody", "id and version are required"},
+		{"missing version", "id: a\n---\nbody", "id and version are required"},
+		{"bad template", "id: a\nversion: 1\n---\n{{.X", "unclosed action"},
+		// 載入時以空字串試渲染，引用未宣告的變數應立即失敗
+		{"undeclared variable", "id: a\nversion: 1\nvariables: X\n---\n{{.X}}{{.Y}}", `map has no entry for key "Y"`},
+		{"no variables declared", "id: a\nversion: 1\nvariables:\n---\n{{.X}}", `map has no entry for key "X"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parse(tt.data, "test")
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("parse: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("parse = %v, %v; want error containing %q", tmpl, err, tt.want)
+			}
+		})
+	}
+
+	tmpl, err := parse("id: a\nversion: 2\ndescription: 測試\nvariables: X, Y,\n---\nbody", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.ID != "a" || tmpl.Version != 2 || tmpl.Description != "測試" || len(tmpl.Variables) != 2 || tmpl.Label() != "a@v2" {
+		t.Fatalf("parsed header: %+v", tmpl)
+	}
+}
+
+func TestRenderVersionChecksVariables(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "greet.tmpl", "id: greet\nversion: 1\nvariables: Name, Place\n---\n{{.Name}}@{{.Place}}")
+	r, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := r.RenderVersion("greet", 1, Vars{"Name": "小明", "Place": "廚房"})
+	if err != nil || p.Text != "小明@廚房" || p.Label() != "greet@v1" {
+		t.Fatalf("RenderVersion = %+v, %v", p, err)
+	}
+	if _, err := r.RenderVersion("greet", 1, Vars{"Name": "小明"}); !errors.Is(err, ErrVariables) {
+		t.Fatalf("missing variable: %v", err)
+	}
+	if _, err := r.RenderVersion("greet", 1, Vars{"Name": "小明", "Place": "廚房", "Extra": 1}); !errors.Is(err, ErrVariables) {
+		t.Fatalf("extra variable: %v", err)
+	}
+	if _, err := r.RenderVersion("greet", 2, Vars{}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing version: %v", err)
+	}
+	if _, err := r.RenderVersion("nope", 0, Vars{}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing id: %v", err)
+	}
+}
+
+func TestOverrideReplacesEmbedded(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "food.tmpl", "id: food.recognize\nversion: 1\nvariables: DescriptionHint\n---\n覆寫：{{.DescriptionHint}}")
+	writeTemplate(t, dir, "food_v2.tmpl", "id: food.recognize\nversion: 2\nvariables: DescriptionHint\n---\n新版：{{.DescriptionHint}}")
+	writeTemplate(t, dir, "notes.txt", "不是模板")
+	r, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v1, err := r.Get(FoodRecognize, 1)
+	if err != nil || v1.Source != filepath.Join(dir, "food.tmpl") {
+		t.Fatalf("v1 = %+v, %v; want the override", v1, err)
+	}
+	p, err := r.RenderVersion(FoodRecognize, 1, Vars{"DescriptionHint": "剩菜"})
+	if err != nil || p.Text != "覆寫：剩菜" {
+		t.Fatalf("RenderVersion v1 = %+v, %v", p, err)
+	}
+	// 未指定版本時使用最新版本
+	p, err = r.RenderVersion(FoodRecognize, 0, Vars{"DescriptionHint": "剩菜"})
+	if err != nil || p.Version != 2 || p.Text != "新版：剩菜" {
+		t.Fatalf("RenderVersion latest = %+v, %v", p, err)
+	}
+	// 其他內嵌模板不受影響
+	if other, err := r.Get(CookQA, 1); err != nil || other.Source != "embedded" {
+		t.Fatalf("cook.qa = %+v, %v", other, err)
+	}
+}
+
+func TestReloadKeepsTemplatesOnError(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "extra.tmpl", "id: extra\nversion: 1\n---\n第一版")
+	r, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTemplate(t, dir, "extra.tmpl", "id: extra\nversion: 1\n---\n第二版")
+	writeTemplate(t, dir, "broken.tmpl", "id: broken\nversion: 1\nvariables:\n---\n{{.Missing}}")
+	if err := r.Reload(); err == nil || !strings.Contains(err.Error(), "broken.tmpl") {
+		t.Fatalf("Reload = %v, want error naming the invalid file", err)
+	}
+	p, err := r.RenderVersion("extra", 0, Vars{})
+	if err != nil || p.Text != "第一版" {
+		t.Fatalf("templates replaced by a failed reload: %+v, %v", p, err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "broken.tmpl")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := r.RenderVersion("extra", 0, Vars{}); p.Text != "第二版" {
+		t.Fatalf("Reload did not pick up the change: %q", p.Text)
+	}
+}
+
+func TestRenderFallsBackToLatest(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "v1.tmpl", "id: exp\nversion: 1\n---\n第一版")
+	writeTemplate(t, dir, "v2.tmpl", "id: exp\nversion: 2\n---\n第二版")
+	r, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	temperature := 0.0
+	r.SetSelector(fixedSelector{&Selection{Version: 2, Model: "m", Temperature: &temperature, Variant: "exp/treatment"}})
+
+	p, err := r.Render(context.Background(), "exp", Vars{})
+	if err != nil || p.Version != 2 || p.Variant != "exp/treatment" || p.Key() != "exp@v2|model=m|temperature=0" {
+		t.Fatalf("Render = %+v, %v", p, err)
+	}
+
+	// 選擇的版本被移除後重新載入，改用最新版本並保留選擇器參數
+	if err := os.Remove(filepath.Join(dir, "v2.tmpl")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	p, err = r.Render(context.Background(), "exp", Vars{})
+	if err != nil || p.Version != 1 || p.Text != "第一版" || p.Model != "m" || p.Variant != "exp/treatment" {
+		t.Fatalf("Render after removal = %+v, %v", p, err)
+	}
+
+	// 選擇器未指定時使用最新版本且不帶參數
+	r.SetSelector(fixedSelector{})
+	if p, err := r.Render(context.Background(), "exp", Vars{}); err != nil || p.Key() != "exp@v1" {
+		t.Fatalf("Render without selection = %+v, %v", p, err)
+	}
+}
+
+// normalizeLines 去除每行前後空白與空行；舊的內嵌提示詞以程式碼縮排排版，模板檔則否
+func normalizeLines(s string) []string {
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// TestEmbeddedMatchLegacyPrompts 內嵌模板渲染結果須與改用模板前寫在程式碼中的提示詞一致；
+// testdata/legacy 保存舊程式以相同變數組出的提示詞
+func TestEmbeddedMatchLegacyPrompts(t *testing.T) {
+	r, err := NewRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const recipeJSON = `{"dish_name":"番茄炒蛋"}`
+	tests := []struct {
+		golden string
+		id     string
+		vars   Vars
+	}{
+		{"recipe.generate", RecipeGenerate, Vars{
+			"DishName":            "番茄炒蛋",
+			"Ingredients":         "- 番茄 2顆\n- 雞蛋 3顆",
+			"CookingMethod":       "炒",
+			"DietaryRestrictions": "無麩質、低鹽",
+			"ServingSize":         "2人份",
+		}},
+		{"recipe.suggest", RecipeSuggest, Vars{
+			"Ingredients":         "- 番茄 2顆\n- 雞蛋 3顆",
+			"Equipment":           "- 平底鍋",
+			"CookingMethod":       "炒",
+			"DietaryRestrictions": "無麩質、低鹽",
+			"ServingSize":         "2人份",
+			"PreviousRecipe":      recipeJSON,
+			"SessionToken":        "SessionToken:42",
+		}},
+		{"food.recognize", FoodRecognize, Vars{"DescriptionHint": "晚餐剩菜"}},
+		{"ingredient.recognize", IngredientRecognize, Vars{}},
+		{"cook.qa", CookQA, Vars{"Question": "蛋要炒多久？", "CurrentStep": "第二步：下蛋液", "RecipeJSON": recipeJSON}},
+		{"cook.qa-nostep", CookQA, Vars{"Question": "可以用橄欖油嗎？", "CurrentStep": "", "RecipeJSON": recipeJSON}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			legacy, err := os.ReadFile(filepath.Join("testdata", "legacy", tt.golden+".txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := r.RenderVersion(tt.id, 1, tt.vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, got := normalizeLines(string(legacy)), normalizeLines(p.Text)
+			for i := 0; i < len(want) || i < len(got); i++ {
+				var w, g string
+				if i < len(want) {
+					w = want[i]
+				}
+				if i < len(got) {
+					g = got[i]
+				}
+				if w != g {
+					t.Fatalf("line %d differs:\nlegacy:   %q\ntemplate: %q", i+1, w, g)
+				}
+			}
+		})
+	}
+}
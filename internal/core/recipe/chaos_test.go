@@ -0,0 +1,71 @@
+package recipe
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"recipe-generator/internal/core/ai/fake"
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/core/ai/service"
+	"recipe-generator/internal/core/experiment"
+	"recipe-generator/internal/core/prompt"
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+)
+
+// TestGenerateRecipeSurvivesInjectedFaults 以固定種子的假提供者腳本注入未加引號的鍵與錯誤的 AR 類型，
+// 食譜仍須經由 QuoteJSONKeys 解析成功，且錯誤的步驟改用 fallbackARParams
+func TestGenerateRecipeSurvivesInjectedFaults(t *testing.T) {
+	dir := t.TempDir()
+	scenario := filepath.Join(dir, "scenario.json")
+	if err := os.WriteFile(scenario, []byte(`{"seed": 20240601, "faults": {"wrong_ar_type": 1, "unquoted_keys": 1}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	experiments := filepath.Join(dir, "experiments.json")
+	if err := os.WriteFile(experiments, []byte(`{"experiments":[{"id":"chaos","template":"recipe.generate","variants":[{"name":"only","weight":1}]}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := fake.New(provider.Config{Scenario: scenario})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompts, err := prompt.NewRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 以實驗統計觀察 AR 參數回退的步驟數
+	m, err := experiment.NewManager(experiments, prompts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompts.SetSelector(m)
+
+	// 不要求模型修正，直接走寬鬆解析與回退
+	cfg := &config.Config{AI: config.AIConfig{RepairAttempts: 0}}
+	recipes := NewRecipeService(service.NewServiceWithProvider(cfg, nil, p), nil, cfg.Cache.Semantic, prompts)
+
+	ctx := m.Begin(context.Background(), "client", "req-1")
+	recipe, err := recipes.GenerateRecipe(ctx, "番茄炒蛋", []common.Ingredient{{Name: "番茄"}, {Name: "雞蛋"}}, common.RecipePreferences{})
+	if err != nil {
+		t.Fatalf("GenerateRecipe: %v", err)
+	}
+	if recipe.DishName != "番茄炒蛋" || len(recipe.Recipe) != 3 {
+		t.Fatalf("recipe: %+v", recipe)
+	}
+	for _, step := range recipe.Recipe {
+		if step.ARParameters == nil {
+			t.Fatalf("step %d has no AR parameters", step.StepNumber)
+		}
+		if err := validateARParams(*step.ARParameters); err != nil || step.ARtype != step.ARParameters.Type {
+			t.Fatalf("step %d: ARtype %s, parameters %+v, %v", step.StepNumber, step.ARtype, *step.ARParameters, err)
+		}
+	}
+
+	stats := m.Reports()[0].Variants[0].Stats
+	if stats.ARSteps != 3 || stats.ARFallbacks != 1 {
+		t.Fatalf("AR steps %d, fallbacks %d; want 3 and 1", stats.ARSteps, stats.ARFallbacks)
+	}
+}
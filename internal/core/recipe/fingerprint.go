@@ -0,0 +1,323 @@
+package recipe
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"recipe-generator/internal/core/ai/cache"
+	"recipe-generator/internal/pkg/common"
+)
+
+// fingerprintVersion 指紋格式版本，正規化規則改變時遞增，讓舊快取自然失效
+const fingerprintVersion = "v1"
+
+// simplifiedToTraditional 常見料理用字的簡體到繁體對照，菜名與食材統一折疊為繁體
+// 只收錄一對一的字，像「干/乾/幹」、「面/麵」這類在料理外另有意思的字不在此列，另由詞彙對照處理
+var simplifiedToTraditional = buildCharFold(
+	"鸡雞猪豬鱼魚虾蝦汤湯烧燒卤滷酱醬炖燉红紅烩燴凉涼饭飯饺餃肠腸葱蔥姜薑萝蘿卜蔔贝貝鸭鴨鹅鵝锅鍋铁鐵" +
+		"宫宮咸鹹鲜鮮丝絲块塊条條饼餅酿釀腊臘熏燻焖燜炝熗鳝鱔鲈鱸鲤鯉鲫鯽蚝蠔鱿魷蛎蠣芦蘆笋筍黄黃莲蓮莴萵" +
+		"苋莧荠薺蓝藍鸽鴿鲍鮑鳕鱈鲑鮭鳗鰻蛏蟶蚬蜆卷捲团團汉漢罗羅麦麥馄餛饨飩鸳鴛鸯鴦边邊凤鳳盐鹽浓濃热熱" +
+		"冻凍粤粵鲁魯闽閩苏蘇东東广廣双雙两兩软軟脑腦盖蓋浇澆",
+)
+
+// termAliases 同義詞對照，左邊的詞會折疊成右邊的詞；在字元折疊之後套用，因此一律以繁體書寫
+var termAliases = []struct{ from, to string }{
+	{"西紅柿", "番茄"},
+	{"蕃茄", "番茄"},
+	{"洋芋", "馬鈴薯"},
+	{"地瓜", "番薯"},
+	{"蕃薯", "番薯"},
+	{"紅薯", "番薯"},
+	{"包心菜", "高麗菜"},
+	{"捲心菜", "高麗菜"},
+	{"雞卵", "雞蛋"},
+	{"面條", "麵條"},
+	{"湯面", "湯麵"},
+	{"炒面", "炒麵"},
+	{"拌面", "拌麵"},
+	{"涼面", "涼麵"},
+	{"牛肉面", "牛肉麵"},
+	{"豆干", "豆乾"},
+	{"番茄炒雞蛋", "番茄炒蛋"},
+}
+
+// buildCharFold 將「簡繁簡繁…」交錯的字串轉成對照表
+func buildCharFold(pairs string) map[rune]rune {
+	runes := []rune(pairs)
+	m := make(map[rune]rune, len(runes)/2)
+	for i := 0; i+1 < len(runes); i += 2 {
+		m[runes[i]] = runes[i+1]
+	}
+	return m
+}
+
+// chineseDigits 份量中常見的中文數字
+var chineseDigits = map[rune]rune{
+	'一': '1', '二': '2', '兩': '2', '三': '3', '四': '4', '五': '5',
+	'六': '6', '七': '7', '八': '8', '九': '9',
+}
+
+// normalizeTerm 正規化菜名或食材名稱：全形轉半形、英文轉小寫、簡體折疊為繁體、
+// 去除空白與標點，再套用同義詞對照
+func normalizeTerm(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		// 全形 ASCII（！到～）轉為半形
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFEE0
+		}
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		if t, ok := simplifiedToTraditional[r]; ok {
+			r = t
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	out := b.String()
+	for _, a := range termAliases {
+		out = strings.ReplaceAll(out, a.from, a.to)
+	}
+	return out
+}
+
+// normalizeServingSize 正規化份量，例如「兩人份」、「2 人份」與「２人份」視為相同
+func normalizeServingSize(s string) string {
+	var b strings.Builder
+	for _, r := range normalizeTerm(s) {
+		if d, ok := chineseDigits[r]; ok {
+			r = d
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// canonicalIngredients 取出正規化後的食材名稱，去重並排序；數量、單位與處理方式不影響指紋。
+// 食譜的份量由偏好中的份量決定，因此「1顆蛋」與「12顆蛋」刻意共用同一份食譜
+func canonicalIngredients(ingredients []common.Ingredient) []string {
+	seen := make(map[string]bool, len(ingredients))
+	names := make([]string, 0, len(ingredients))
+	for _, ing := range ingredients {
+		name := normalizeTerm(ing.Name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// canonicalList 正規化、去重並排序字串清單
+func canonicalList(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		v := normalizeTerm(item)
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// recipeFingerprint 食譜請求的正規化表示
+type recipeFingerprint struct {
+	// template 提示詞模板 ID、版本與實驗覆寫的呼叫參數，模板改版或變體不同時不沿用彼此產生的食譜
+	template    string
+	dish        string
+	ingredients []string
+	// preferences 正規化後的偏好，相似度查找只在偏好完全相同的請求之間進行
+	preferences string
+}
+
+// newRecipeFingerprint 由提示詞模板與請求內容建立指紋；偏好需已套用預設值
+func newRecipeFingerprint(template, dishName string, ingredients []common.Ingredient, preferences common.RecipePreferences) recipeFingerprint {
+	return recipeFingerprint{
+		template:    template,
+		dish:        normalizeTerm(dishName),
+		ingredients: canonicalIngredients(ingredients),
+		preferences: strings.Join([]string{
+			"method=" + normalizeTerm(preferences.CookingMethod),
+			"restrictions=" + strings.Join(canonicalList(preferences.DietaryRestrictions), ","),
+			"serving=" + normalizeServingSize(preferences.ServingSize),
+		}, "|"),
+	}
+}
+
+// key 指紋的快取 prompt，相同指紋的請求共用同一份快取
+func (f recipeFingerprint) key() string {
+	return strings.Join([]string{
+		cache.NamespaceRecipeFingerprint + ":" + fingerprintVersion,
+		"template=" + f.template,
+		"dish=" + f.dish,
+		"ingredients=" + strings.Join(f.ingredients, ","),
+		f.preferences,
+	}, "|")
+}
+
+// dishSimilarityWeight 相似度中菜名所佔的權重，其餘為食材集合
+const dishSimilarityWeight = 0.7
+
+// similarityVector 相似度計算用的特徵
+type similarityVector struct {
+	grams       map[string]float64 // 菜名的字元 n-gram
+	norm        float64
+	ingredients map[string]bool
+}
+
+// newSimilarityVector 以菜名的字元 unigram 與 bigram 建立向量；unigram 讓短菜名也有足夠特徵
+func newSimilarityVector(f recipeFingerprint) similarityVector {
+	runes := []rune(f.dish)
+	grams := make(map[string]float64, len(runes)*2)
+	for i, r := range runes {
+		grams[string(r)]++
+		if i+1 < len(runes) {
+			grams[string(runes[i:i+2])] += 2 // bigram 保留字序，權重較高
+		}
+	}
+	var sum float64
+	for _, w := range grams {
+		sum += w * w
+	}
+
+	ingredients := make(map[string]bool, len(f.ingredients))
+	for _, name := range f.ingredients {
+		ingredients[name] = true
+	}
+	return similarityVector{grams: grams, norm: math.Sqrt(sum), ingredients: ingredients}
+}
+
+// similarity 菜名 n-gram 餘弦相似度與食材 Jaccard 相似度的加權和，範圍 0~1
+func (v similarityVector) similarity(o similarityVector) float64 {
+	var dish float64
+	if v.norm > 0 && o.norm > 0 {
+		var dot float64
+		for g, w := range v.grams {
+			dot += w * o.grams[g]
+		}
+		dish = dot / (v.norm * o.norm)
+	}
+
+	ing := 1.0 // 兩邊都沒有指定食材時視為相同
+	if len(v.ingredients) > 0 || len(o.ingredients) > 0 {
+		inter := 0
+		for name := range v.ingredients {
+			if o.ingredients[name] {
+				inter++
+			}
+		}
+		union := len(v.ingredients) + len(o.ingredients) - inter
+		ing = float64(inter) / float64(union)
+	}
+	return dishSimilarityWeight*dish + (1-dishSimilarityWeight)*ing
+}
+
+// similarityEntry 相似度索引中的一筆請求
+type similarityEntry struct {
+	key         string
+	template    string
+	preferences string
+	vector      similarityVector
+}
+
+// similarityIndex 近期已快取請求的本地相似度索引；容量固定，滿了淘汰最早加入的請求
+// 索引只記錄指紋，實際內容仍存放在快取中，因此多實例共用 Redis 時各自維護索引即可
+type similarityIndex struct {
+	mu      sync.Mutex
+	size    int
+	entries []similarityEntry
+	byKey   map[string]bool
+}
+
+func newSimilarityIndex(size int) *similarityIndex {
+	return &similarityIndex{size: size, byKey: make(map[string]bool)}
+}
+
+// add 加入請求指紋，已存在時略過
+func (idx *similarityIndex) add(f recipeFingerprint) {
+	key := f.key()
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.byKey[key] {
+		return
+	}
+	if len(idx.entries) >= idx.size {
+		delete(idx.byKey, idx.entries[0].key)
+		idx.entries = idx.entries[1:]
+	}
+	idx.entries = append(idx.entries, similarityEntry{
+		key:         key,
+		template:    f.template,
+		preferences: f.preferences,
+		vector:      newSimilarityVector(f),
+	})
+	idx.byKey[key] = true
+}
+
+// remove 移除快取已失效的指紋
+func (idx *similarityIndex) remove(key string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if !idx.byKey[key] {
+		return
+	}
+	delete(idx.byKey, key)
+	for i, e := range idx.entries {
+		if e.key == key {
+			idx.entries = append(idx.entries[:i], idx.entries[i+1:]...)
+			break
+		}
+	}
+}
+
+// prune 只保留快取條目仍存在的指紋，回傳移除的數量
+func (idx *similarityIndex) prune(live map[string]bool) int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	kept := idx.entries[:0]
+	for _, e := range idx.entries {
+		if live[cache.Key(e.key, "")] {
+			kept = append(kept, e)
+			continue
+		}
+		delete(idx.byKey, e.key)
+	}
+	removed := len(idx.entries) - len(kept)
+	idx.entries = kept
+	return removed
+}
+
+// nearest 找出模板與偏好相同且相似度不低於 threshold 的最相近請求
+func (idx *similarityIndex) nearest(f recipeFingerprint, threshold float64) (string, float64, bool) {
+	v := newSimilarityVector(f)
+	self := f.key()
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	best, bestScore := "", 0.0
+	for _, e := range idx.entries {
+		if e.key == self || e.template != f.template || e.preferences != f.preferences {
+			continue
+		}
+		if score := v.similarity(e.vector); score > bestScore {
+			best, bestScore = e.key, score
+		}
+	}
+	if best == "" || bestScore < threshold {
+		return "", bestScore, false
+	}
+	return best, bestScore, true
+}
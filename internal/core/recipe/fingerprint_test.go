@@ -0,0 +1,146 @@
+package recipe
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"recipe-generator/internal/core/ai/cache"
+	"recipe-generator/internal/pkg/common"
+)
+
+func TestSimilarityIndexPrune(t *testing.T) {
+	ctx := context.Background()
+	store := newTestCache(t)
+	idx := newSimilarityIndex(10)
+
+	prefs := common.RecipePreferences{CookingMethod: "炒", ServingSize: "2人份"}
+	tomato := newRecipeFingerprint("recipe.generate@v1", "番茄炒蛋", nil, prefs)
+	beef := newRecipeFingerprint("recipe.generate@v1", "青椒牛肉", nil, prefs)
+	for _, f := range []recipeFingerprint{tomato, beef} {
+		if err := store.Set(ctx, f.key(), "", "{}"); err != nil {
+			t.Fatal(err)
+		}
+		idx.add(f)
+	}
+
+	key := cache.Key(tomato.key(), "")
+	if !strings.HasPrefix(key, cache.NamespaceRecipeFingerprint+":") {
+		t.Fatalf("key %q has no namespace", key)
+	}
+	if _, err := store.Purge(ctx, cache.Filter{Prefix: key}); err != nil {
+		t.Fatal(err)
+	}
+
+	live, err := liveKeys(ctx, store, cache.NamespaceRecipeFingerprint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := idx.prune(live); n != 1 {
+		t.Fatalf("pruned %d, want 1", n)
+	}
+	query := newRecipeFingerprint("recipe.generate@v1", "番茄炒雞蛋", nil, prefs)
+	if match, _, ok := idx.nearest(query, 0.5); ok && match == tomato.key() {
+		t.Fatal("pruned fingerprint still matched")
+	}
+	if idx.byKey[tomato.key()] || !idx.byKey[beef.key()] {
+		t.Fatalf("byKey: %v", idx.byKey)
+	}
+}
+
+func TestNormalizeTerm(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"西红柿炒鸡蛋", "番茄炒蛋"}, // 簡體折疊後再套用同義詞
+		{"番茄炒雞蛋", "番茄炒蛋"},
+		{"蕃茄 炒蛋！", "番茄炒蛋"},
+		{"红烧牛肉面", "紅燒牛肉麵"},
+		{"宫保鸡丁", "宮保雞丁"},
+		{"ＢＢＱ 豬肋排", "bbq豬肋排"},
+		{"包心菜", "高麗菜"},
+		{"地瓜", "番薯"},
+		{"豆干", "豆乾"},
+	}
+	for _, tt := range tests {
+		if got := normalizeTerm(tt.in); got != tt.want {
+			t.Errorf("normalizeTerm(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got := normalizeServingSize("兩 人份"); got != normalizeServingSize("２人份") || got != "2人份" {
+		t.Fatalf("normalizeServingSize = %q", got)
+	}
+}
+
+func TestCanonicalIngredients(t *testing.T) {
+	got := canonicalIngredients([]common.Ingredient{
+		{Name: "鸡蛋", Amount: "1", Unit: "顆"},
+		{Name: "西紅柿", Amount: "2", Unit: "顆", Preparation: "切塊"},
+		{Name: "雞蛋", Amount: "12", Unit: "顆"},
+		{Name: " "},
+		{Name: "蕃茄"},
+	})
+	want := []string{"番茄", "雞蛋"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("canonicalIngredients = %v, want %v", got, want)
+	}
+
+	// 順序、數量與寫法不同的相同請求得到相同指紋
+	prefs := common.RecipePreferences{CookingMethod: "炒", ServingSize: "兩人份"}
+	a := newRecipeFingerprint("recipe.generate@v1", "番茄炒蛋", []common.Ingredient{{Name: "番茄"}, {Name: "雞蛋", Amount: "1"}}, prefs)
+	prefs.ServingSize = "2 人份"
+	b := newRecipeFingerprint("recipe.generate@v1", "西红柿炒鸡蛋", []common.Ingredient{{Name: "鸡蛋", Amount: "12"}, {Name: "西紅柿"}}, prefs)
+	if a.key() != b.key() {
+		t.Fatalf("keys differ:\n%s\n%s", a.key(), b.key())
+	}
+	if c := newRecipeFingerprint("recipe.generate@v2", "番茄炒蛋", []common.Ingredient{{Name: "番茄"}, {Name: "雞蛋"}}, prefs); c.key() == a.key() {
+		t.Fatal("template version not part of the key")
+	}
+}
+
+func TestSimilarityIndexThreshold(t *testing.T) {
+	const threshold = 0.9 // 預設門檻
+	prefs := common.RecipePreferences{CookingMethod: "炒", ServingSize: "2人份"}
+	ingredients := func(names ...string) []common.Ingredient {
+		var out []common.Ingredient
+		for _, n := range names {
+			out = append(out, common.Ingredient{Name: n})
+		}
+		return out
+	}
+
+	idx := newSimilarityIndex(10)
+	stirFry := newRecipeFingerprint("recipe.generate@v1", "番茄炒蛋", ingredients("番茄", "雞蛋", "蔥"), prefs)
+	soup := newRecipeFingerprint("recipe.generate@v1", "番茄蛋花湯", ingredients("番茄", "雞蛋", "蔥"), prefs)
+	idx.add(stirFry)
+	idx.add(soup)
+
+	// 菜名相同、食材多一項：0.7 + 0.3 × 3/4
+	query := newRecipeFingerprint("recipe.generate@v1", "番茄炒雞蛋", ingredients("番茄", "雞蛋", "蔥", "鹽"), prefs)
+	match, score, ok := idx.nearest(query, threshold)
+	if !ok || match != stirFry.key() || score < threshold {
+		t.Fatalf("nearest = %q, %.3f, %v; want %q", match, score, ok, stirFry.key())
+	}
+
+	// 共用大部分食材但菜名不同的湯品不應命中
+	idx = newSimilarityIndex(10)
+	idx.add(soup)
+	if match, score, ok := idx.nearest(query, threshold); ok {
+		t.Fatalf("matched %q with score %.3f", match, score)
+	} else if score >= 0.6 {
+		t.Fatalf("soup similarity %.3f unexpectedly high", score)
+	}
+
+	// 偏好或模板不同時不比較
+	idx.add(stirFry)
+	other := prefs
+	other.ServingSize = "4人份"
+	for _, f := range []recipeFingerprint{
+		newRecipeFingerprint("recipe.generate@v1", "番茄炒雞蛋", ingredients("番茄", "雞蛋", "蔥", "鹽"), other),
+		newRecipeFingerprint("recipe.generate@v2", "番茄炒雞蛋", ingredients("番茄", "雞蛋", "蔥", "鹽"), prefs),
+	} {
+		if match, _, ok := idx.nearest(f, threshold); ok {
+			t.Fatalf("matched %q across preferences or templates", match)
+		}
+	}
+}
@@ -11,7 +11,11 @@ import (
 	"time"
 
 	"recipe-generator/internal/core/ai/cache"
+	"recipe-generator/internal/core/ai/provider"
 	"recipe-generator/internal/core/ai/service"
+	"recipe-generator/internal/core/experiment"
+	promptpkg "recipe-generator/internal/core/prompt"
+	"recipe-generator/internal/infrastructure/config"
 	"recipe-generator/internal/pkg/common"
 
 	"go.uber.org/zap"
@@ -20,14 +24,20 @@ import (
 // FoodService 食物識別服務
 type FoodService struct {
 	aiService    *service.Service
-	cacheManager *cache.CacheManager
+	cacheManager cache.Cache
+	imageCache   *ImageCache
+	recognition  config.RecognitionConfig
+	prompts      *promptpkg.Registry
 }
 
 // NewFoodService 創建新的食物識別服務
-func NewFoodService(aiService *service.Service, cacheManager *cache.CacheManager) *FoodService {
+func NewFoodService(aiService *service.Service, cacheManager cache.Cache, imageCache *ImageCache, recognition config.RecognitionConfig, prompts *promptpkg.Registry) *FoodService {
 	return &FoodService{
 		aiService:    aiService,
 		cacheManager: cacheManager,
+		imageCache:   imageCache,
+		recognition:  recognition,
+		prompts:      prompts,
 	}
 }
 
@@ -84,41 +94,10 @@ func (s *FoodService) IdentifyFood(ctx context.Context, imageData string, descri
 	)
 
 	// 構建提示詞
-	prompt := fmt.Sprintf(`請仔細分析圖片中的食物，並以 JSON 格式返回結果(並且用繁體中文回答）。要求：
-1. 只識別圖片中實際可見的食物
-2. 不要添加圖片中未出現的食物
-3. 如果無法確定某個屬性，請使用 "未知" 而不是猜測
-4. 所有欄位必須使用雙引號
-5. 不要使用預設值或猜測值
-6. 請確保識別結果與圖片內容完全相符
-7. 如果圖片中沒有食物，請返回空列表
-8. 不要使用\n，不需要換行
-9. 根據辨識到的食物給出推論後可能需要用到的食材與製作廚具
-10. 不需要考慮可讀性，請省略所有空格和換行，返回最緊湊的 JSON 格式
-11. 所有欄位都必須要有不能漏掉，如果不知道填什麼請留空 "" or null
-
-請以以下 JSON 格式返回：
-{
-    "recognized_foods": [
-        {
-            "name": "食物名稱",
-					"description": "此食物的特徵與可能料理方式說明",
-            "possible_ingredients": [
-                {
-                    "name": "食材名稱",
-                    "type": "食材類型"
-                }
-            ],
-            "possible_equipment": [
-                {
-                    "name": "設備名稱",
-                    "type": "設備類型"
-                }
-            ]
-        }
-    ]
-}
-%s`, descriptionHint)
+	prompt, err := s.prompts.Render(ctx, promptpkg.FoodRecognize, promptpkg.Vars{"DescriptionHint": descriptionHint})
+	if err != nil {
+		return nil, err
+	}
 
 	// 保存請求數據
 	// if err := saveRequestData(prompt, imageData); err != nil {
@@ -128,23 +107,32 @@ func (s *FoodService) IdentifyFood(ctx context.Context, imageData string, descri
 	// 	// 繼續處理，不中斷請求
 	// }
 
-	// 調用 AI 服務
-	response, err := s.aiService.ProcessRequest(ctx, prompt, imageData)
-	if err != nil {
-		common.LogError("AI 服務請求失敗",
-			zap.Error(err),
-		)
-		return nil, err
+	// 先以感知雜湊查找相同或相近圖片的辨識結果
+	scope := imageScope("food", prompt)
+	hash, hashed := s.imageCache.Hash(imageData)
+	var content string
+	cached := false
+	if hashed {
+		content, cached = s.imageCache.Lookup(ctx, scope, hash)
+	}
+	if cached {
+		provider.MetadataFromContext(ctx).Record("cache", "")
+		provider.MetadataFromContext(ctx).RecordTemplate(prompt.Label())
+		experiment.ObserveCacheHit(ctx, prompt.ID)
+	} else {
+		// 調用 AI 服務
+		response, err := s.aiService.ProcessStructured(ctx, prompt, imageData, foodFormat)
+		if err != nil {
+			common.LogError("AI 服務請求失敗",
+				zap.Error(err),
+			)
+			return nil, err
+		}
+		content = response.Content
 	}
 
 	// 解析響應
-	content := response.Content
-	content = strings.TrimSpace(content)
-	start := strings.Index(content, "{")
-	end := strings.LastIndex(content, "}")
-	if start != -1 && end != -1 && end > start {
-		content = content[start : end+1]
-	}
+	content = common.ExtractJSON(content)
 	var result common.FoodRecognitionResult
 	if err := common.ParseJSON(content, &result); err != nil {
 		common.LogError("AI 響應解析失敗",
@@ -152,6 +140,12 @@ func (s *FoodService) IdentifyFood(ctx context.Context, imageData string, descri
 		)
 		return nil, fmt.Errorf("failed to parse AI response: %w", err)
 	}
+	if hashed && !cached {
+		s.imageCache.Store(ctx, scope, hash, content)
+	}
+
+	// 校正信心分數與位置，過濾低信心的食物；快取保存原始回應，調整門檻後仍會重新套用
+	sanitizeFoods(&result, s.recognition.MinConfidence)
 
 	// 檢查並補充空值
 	for i := range result.RecognizedFoods {
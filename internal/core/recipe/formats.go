@@ -0,0 +1,23 @@
+package recipe
+
+import (
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/pkg/common"
+	"recipe-generator/internal/pkg/jsonschema"
+)
+
+// 各服務要求 AI 回傳的 JSON 結構，由回應型別推導
+var (
+	recipeFormat = &provider.ResponseFormat{
+		Name:   "recipe",
+		Schema: jsonschema.For(common.Recipe{}),
+	}
+	foodFormat = &provider.ResponseFormat{
+		Name:   "food_recognition",
+		Schema: jsonschema.For(common.FoodRecognitionResult{}),
+	}
+	ingredientFormat = &provider.ResponseFormat{
+		Name:   "ingredient_recognition",
+		Schema: jsonschema.For(common.IngredientRecognitionResult{}),
+	}
+)
@@ -0,0 +1,198 @@
+package recipe
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"recipe-generator/internal/core/ai/cache"
+	imagecore "recipe-generator/internal/core/image"
+	promptpkg "recipe-generator/internal/core/prompt"
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+// imageHashEntry 感知雜湊索引中的一張已快取圖片
+type imageHashEntry struct {
+	scope string
+	hash  uint64
+}
+
+// ImageCache 以圖片感知雜湊快取辨識結果，同一張照片重拍、縮放或重新壓縮後仍可命中
+// 結果以「範圍 + 雜湊」存入快取；本地索引記錄近期的雜湊，供漢明距離查找相近圖片
+type ImageCache struct {
+	cacheManager cache.Cache
+	cfg          config.PerceptualCacheConfig
+	images       *imagecore.Service
+
+	mu      sync.Mutex
+	entries []imageHashEntry
+	byKey   map[string]bool
+}
+
+// NewImageCache 創建感知雜湊圖片快取；未啟用或沒有快取後端時回傳 nil，呼叫端視為不快取
+func NewImageCache(cfg config.PerceptualCacheConfig, cacheManager cache.Cache, images *imagecore.Service) *ImageCache {
+	if !cfg.Enabled || cacheManager == nil {
+		return nil
+	}
+	return &ImageCache{
+		cacheManager: cacheManager,
+		cfg:          cfg,
+		images:       images,
+		byKey:        make(map[string]bool),
+	}
+}
+
+// imageScope 辨識類型、提示詞模板版本與呼叫參數、prompt 組成的範圍，
+// 不同提示詞（例如描述提示）、模板版本或實驗變體的結果不會互相沿用
+func imageScope(kind string, p *promptpkg.Prompt) string {
+	sum := sha256.Sum256([]byte(p.Text))
+	return kind + ":" + p.Key() + ":" + hex.EncodeToString(sum[:8])
+}
+
+// imageHashKey 快取中保存辨識結果的 prompt
+func imageHashKey(scope string, hash uint64) string {
+	return fmt.Sprintf("%s:%s:%016x", cache.NamespaceImagePHash, scope, hash)
+}
+
+// Hash 計算圖片的感知雜湊；無法計算（例如網址圖片）時回傳 false
+func (c *ImageCache) Hash(imageData string) (uint64, bool) {
+	if c == nil {
+		return 0, false
+	}
+	hash, err := c.images.PerceptualHash(imageData)
+	if err != nil {
+		common.LogDebug("略過圖片感知雜湊快取", zap.Error(err))
+		return 0, false
+	}
+	return hash, true
+}
+
+// Lookup 先查相同雜湊，再於索引中找漢明距離最小且不超過門檻的圖片
+func (c *ImageCache) Lookup(ctx context.Context, scope string, hash uint64) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+
+	key := imageHashKey(scope, hash)
+	if val, err := c.cacheManager.Get(ctx, key, ""); err == nil && val != "" {
+		// 快取可能來自快照或其他實例，命中時補進本地索引
+		c.add(scope, hash)
+		common.LogInfo("圖片感知雜湊快取命中", zap.String("key", key), zap.Int("distance", 0))
+		return val, true
+	}
+
+	match, distance, ok := c.nearest(scope, hash)
+	if !ok {
+		return "", false
+	}
+	matchKey := imageHashKey(scope, match)
+	val, err := c.cacheManager.Get(ctx, matchKey, "")
+	if err != nil || val == "" {
+		c.remove(scope, match)
+		return "", false
+	}
+	common.LogInfo("圖片感知雜湊快取命中相近圖片",
+		zap.String("key", key),
+		zap.String("matched", matchKey),
+		zap.Int("distance", distance),
+	)
+	return val, true
+}
+
+// Store 寫入辨識結果並加入索引
+func (c *ImageCache) Store(ctx context.Context, scope string, hash uint64, content string) {
+	if c == nil {
+		return
+	}
+	if err := c.cacheManager.Set(ctx, imageHashKey(scope, hash), "", content); err != nil {
+		common.LogWarn("圖片感知雜湊快取寫入失敗", zap.Error(err))
+		return
+	}
+	c.add(scope, hash)
+}
+
+// add 加入索引；容量滿時淘汰最早加入的圖片
+func (c *ImageCache) add(scope string, hash uint64) {
+	key := imageHashKey(scope, hash)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.byKey[key] {
+		return
+	}
+	if len(c.entries) >= c.cfg.IndexSize {
+		oldest := c.entries[0]
+		delete(c.byKey, imageHashKey(oldest.scope, oldest.hash))
+		c.entries = c.entries[1:]
+	}
+	c.entries = append(c.entries, imageHashEntry{scope: scope, hash: hash})
+	c.byKey[key] = true
+}
+
+// remove 移除快取已失效的圖片
+func (c *ImageCache) remove(scope string, hash uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := imageHashKey(scope, hash)
+	if !c.byKey[key] {
+		return
+	}
+	delete(c.byKey, key)
+	for i, e := range c.entries {
+		if e.scope == scope && e.hash == hash {
+			c.entries = append(c.entries[:i], c.entries[i+1:]...)
+			break
+		}
+	}
+}
+
+// Prune 實作 cache.Index：移除索引中快取條目已被刪除的圖片
+func (c *ImageCache) Prune(ctx context.Context) (int, error) {
+	if c == nil {
+		return 0, nil
+	}
+	live, err := liveKeys(ctx, c.cacheManager, cache.NamespaceImagePHash)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	kept := c.entries[:0]
+	for _, e := range c.entries {
+		key := imageHashKey(e.scope, e.hash)
+		if live[cache.Key(key, "")] {
+			kept = append(kept, e)
+			continue
+		}
+		delete(c.byKey, key)
+	}
+	removed := len(c.entries) - len(kept)
+	c.entries = kept
+	return removed, nil
+}
+
+// nearest 在相同範圍內找漢明距離最小且不超過門檻的雜湊
+func (c *ImageCache) nearest(scope string, hash uint64) (uint64, int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	best, bestDistance := uint64(0), -1
+	for _, e := range c.entries {
+		if e.scope != scope {
+			continue
+		}
+		d := imagecore.HammingDistance(hash, e.hash)
+		if d <= c.cfg.MaxDistance && (bestDistance < 0 || d < bestDistance) {
+			best, bestDistance = e.hash, d
+		}
+	}
+	return best, bestDistance, bestDistance >= 0
+}
@@ -0,0 +1,59 @@
+package recipe
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"recipe-generator/internal/core/ai/cache"
+	"recipe-generator/internal/infrastructure/config"
+)
+
+// newTestCache 建立不保存快照的記憶體快取
+func newTestCache(t *testing.T) *cache.CacheManager {
+	t.Helper()
+	m := cache.NewManager(&config.Config{Cache: config.CacheConfig{
+		Enabled:         true,
+		MaxBytes:        1 << 20,
+		Shards:          1,
+		TTL:             time.Hour,
+		CleanupInterval: time.Hour,
+	}})
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestImageCachePrune(t *testing.T) {
+	ctx := context.Background()
+	store := newTestCache(t)
+	c := NewImageCache(config.PerceptualCacheConfig{Enabled: true, IndexSize: 10, MaxDistance: 6}, store, nil)
+
+	c.Store(ctx, "food", 0x0f, `{"a":1}`)
+	c.Store(ctx, "food", 0xf0, `{"b":2}`)
+
+	// 衍生條目的快取鍵保留命名空間，管理介面可依前綴找到
+	key := cache.Key(imageHashKey("food", 0x0f), "")
+	if !strings.HasPrefix(key, cache.NamespaceImagePHash+":") {
+		t.Fatalf("key %q has no namespace", key)
+	}
+	if n, err := store.Purge(ctx, cache.Filter{Prefix: key}); err != nil || n != 1 {
+		t.Fatalf("Purge: %d, %v", n, err)
+	}
+
+	if n, err := c.Prune(ctx); err != nil || n != 1 {
+		t.Fatalf("Prune: %d, %v", n, err)
+	}
+	// 已刪除的圖片不再作為相近圖片的候選
+	if match, _, ok := c.nearest("food", 0x0e); ok && match == 0x0f {
+		t.Fatal("pruned hash still matched")
+	}
+	if _, ok := c.Lookup(ctx, "food", 0xf0); !ok {
+		t.Fatal("remaining entry not served")
+	}
+
+	var nilCache *ImageCache
+	if n, err := nilCache.Prune(ctx); n != 0 || err != nil {
+		t.Fatalf("nil Prune: %d, %v", n, err)
+	}
+}
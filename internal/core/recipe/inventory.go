@@ -0,0 +1,388 @@
+package recipe
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+// 食材辨識補上的預設值，合併時不視為有效資訊
+var (
+	placeholderAmounts      = map[string]bool{"": true, "適量": true, "未知": true}
+	placeholderPreparations = map[string]bool{"": true, "無特殊處理": true, "未知": true}
+	placeholderNames        = map[string]bool{"": true, "未知食材": true, "未知設備": true}
+	placeholderValues       = map[string]bool{"": true, "未知": true, "未知類型": true, "標準": true}
+)
+
+// InventorySource 合併後項目的來源圖片，保留該圖片辨識出的原始數量
+type InventorySource struct {
+	Image       int                 `json:"image"`                  // 圖片序號，從 0 開始依請求順序
+	Amount      string              `json:"amount,omitempty"`       // 該圖片辨識出的數量
+	Unit        string              `json:"unit,omitempty"`         // 該圖片辨識出的單位
+	Confidence  *float64            `json:"confidence,omitempty"`   // 該圖片辨識的信心分數
+	BoundingBox *common.BoundingBox `json:"bounding_box,omitempty"` // 項目在該圖片中的位置
+}
+
+// InventoryIngredient 合併後的食材；位置依圖片不同，只記錄在 Sources，信心分數取各來源最高者
+type InventoryIngredient struct {
+	common.Ingredient
+	Sources []InventorySource `json:"sources"`
+}
+
+// InventoryEquipment 合併後的設備；位置與信心分數的處理同 InventoryIngredient
+type InventoryEquipment struct {
+	common.Equipment
+	Sources []InventorySource `json:"sources"`
+}
+
+// InventoryImage 單張圖片的辨識狀況
+type InventoryImage struct {
+	Image            int    `json:"image"`
+	Summary          string `json:"summary,omitempty"`
+	IngredientsCount int    `json:"ingredients_count"`
+	EquipmentCount   int    `json:"equipment_count"`
+	Error            string `json:"error,omitempty"` // 辨識失敗的原因，其他圖片的結果仍會合併
+}
+
+// Inventory 多張圖片合併後的食材與設備清單
+type Inventory struct {
+	AvailableIngredients []InventoryIngredient `json:"available_ingredients"`
+	AvailableEquipment   []InventoryEquipment  `json:"available_equipment"`
+	Images               []InventoryImage      `json:"images"`
+	Summary              string                `json:"summary"`
+}
+
+// InventoryService 多張圖片的食材盤點服務：逐張辨識後依正規化名稱合併
+type InventoryService struct {
+	ingredientService *IngredientService
+	config            config.InventoryConfig
+}
+
+// NewInventoryService 創建食材盤點服務
+func NewInventoryService(ingredientService *IngredientService, cfg config.InventoryConfig) *InventoryService {
+	return &InventoryService{
+		ingredientService: ingredientService,
+		config:            cfg,
+	}
+}
+
+// IdentifyInventory 並行辨識多張圖片（同時進行的數量受 Concurrency 限制），
+// 並將食材與設備依正規化名稱去重合併；部分圖片失敗時記錄在 Images，全部失敗才回傳錯誤
+func (s *InventoryService) IdentifyInventory(ctx context.Context, images []string) (*Inventory, error) {
+	if len(images) == 0 {
+		return nil, fmt.Errorf("invalid image: no images provided")
+	}
+
+	results := make([]*common.IngredientRecognitionResult, len(images))
+	errs := make([]error, len(images))
+
+	concurrency := s.config.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, imageData := range images {
+		wg.Add(1)
+		go func(i int, imageData string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+			results[i], errs[i] = s.ingredientService.IdentifyIngredient(ctx, imageData)
+		}(i, imageData)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var firstErr error
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = fmt.Errorf("image %d: %w", i, err)
+			}
+			common.LogWarn("Inventory image recognition failed",
+				zap.Int("image", i),
+				zap.Error(err))
+		}
+	}
+	if failed == len(images) {
+		return nil, firstErr
+	}
+
+	inventory := mergeInventory(results, errs)
+	common.LogInfo("Successfully merged inventory",
+		zap.Int("images", len(images)),
+		zap.Int("failed_images", failed),
+		zap.Int("ingredients_count", len(inventory.AvailableIngredients)),
+		zap.Int("equipment_count", len(inventory.AvailableEquipment)))
+	return inventory, nil
+}
+
+// mergeInventory 依圖片順序合併辨識結果；同一正規化名稱的項目合併為一筆並記錄來源圖片
+func mergeInventory(results []*common.IngredientRecognitionResult, errs []error) *Inventory {
+	inventory := &Inventory{
+		AvailableIngredients: []InventoryIngredient{},
+		AvailableEquipment:   []InventoryEquipment{},
+		Images:               make([]InventoryImage, len(results)),
+	}
+	ingredientIndex := make(map[string]int)
+	equipmentIndex := make(map[string]int)
+	var summaries []string
+
+	for i, result := range results {
+		inventory.Images[i].Image = i
+		if errs[i] != nil || result == nil {
+			if errs[i] != nil {
+				inventory.Images[i].Error = errs[i].Error()
+			}
+			continue
+		}
+		inventory.Images[i].Summary = result.Summary
+		inventory.Images[i].IngredientsCount = len(result.Ingredients)
+		inventory.Images[i].EquipmentCount = len(result.Equipment)
+		if result.Summary != "" && result.Summary != "無摘要" {
+			summaries = append(summaries, result.Summary)
+		}
+
+		for _, ing := range result.Ingredients {
+			source := InventorySource{Image: i, Amount: ing.Amount, Unit: ing.Unit, Confidence: ing.Confidence, BoundingBox: ing.BoundingBox}
+			ing.BoundingBox = nil
+			key := inventoryKey(ing.Name)
+			if j, ok := ingredientIndex[key]; ok && key != "" {
+				inventory.AvailableIngredients[j].Sources = append(inventory.AvailableIngredients[j].Sources, source)
+				mergeIngredient(&inventory.AvailableIngredients[j].Ingredient, ing)
+				continue
+			}
+			if key != "" {
+				ingredientIndex[key] = len(inventory.AvailableIngredients)
+			}
+			inventory.AvailableIngredients = append(inventory.AvailableIngredients, InventoryIngredient{
+				Ingredient: ing,
+				Sources:    []InventorySource{source},
+			})
+		}
+
+		for _, equip := range result.Equipment {
+			source := InventorySource{Image: i, Confidence: equip.Confidence, BoundingBox: equip.BoundingBox}
+			equip.BoundingBox = nil
+			key := inventoryKey(equip.Name)
+			if j, ok := equipmentIndex[key]; ok && key != "" {
+				inventory.AvailableEquipment[j].Sources = append(inventory.AvailableEquipment[j].Sources, source)
+				mergeEquipment(&inventory.AvailableEquipment[j].Equipment, equip)
+				continue
+			}
+			if key != "" {
+				equipmentIndex[key] = len(inventory.AvailableEquipment)
+			}
+			inventory.AvailableEquipment = append(inventory.AvailableEquipment, InventoryEquipment{
+				Equipment: equip,
+				Sources:   []InventorySource{source},
+			})
+		}
+	}
+
+	// 數量依各來源重新計算，避免合併順序影響結果
+	for i := range inventory.AvailableIngredients {
+		item := &inventory.AvailableIngredients[i]
+		if len(item.Sources) > 1 {
+			item.Amount, item.Unit = mergeAmounts(item.Sources)
+		}
+	}
+
+	inventory.Summary = strings.Join(summaries, "；")
+	if inventory.Summary == "" {
+		inventory.Summary = "無摘要"
+	}
+	return inventory
+}
+
+// inventoryKey 合併用的名稱鍵；未知名稱回傳空字串，不與其他項目合併
+func inventoryKey(name string) string {
+	if placeholderNames[strings.TrimSpace(name)] {
+		return ""
+	}
+	return normalizeTerm(name)
+}
+
+// mergeIngredient 補上缺少的類型、取較高的信心分數，並合併不同的處理方式；數量另由 mergeAmounts 計算
+func mergeIngredient(dst *common.Ingredient, src common.Ingredient) {
+	dst.Confidence = maxConfidence(dst.Confidence, src.Confidence)
+	if placeholderValues[dst.Type] && !placeholderValues[src.Type] {
+		dst.Type = src.Type
+	}
+	if placeholderPreparations[src.Preparation] || strings.Contains(dst.Preparation, src.Preparation) {
+		return
+	}
+	if placeholderPreparations[dst.Preparation] {
+		dst.Preparation = src.Preparation
+	} else {
+		dst.Preparation += "、" + src.Preparation
+	}
+}
+
+// mergeEquipment 以第一個有效值為準補上缺少的屬性，並取較高的信心分數
+func mergeEquipment(dst *common.Equipment, src common.Equipment) {
+	dst.Confidence = maxConfidence(dst.Confidence, src.Confidence)
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&dst.Type, src.Type},
+		{&dst.Size, src.Size},
+		{&dst.Material, src.Material},
+		{&dst.PowerSource, src.PowerSource},
+	} {
+		if placeholderValues[*f.dst] && !placeholderValues[f.src] {
+			*f.dst = f.src
+		}
+	}
+}
+
+// maxConfidence 取較高的信心分數；一方未提供時沿用另一方
+func maxConfidence(a, b *float64) *float64 {
+	if a == nil || (b != nil && *b > *a) {
+		return b
+	}
+	return a
+}
+
+// mergeAmounts 合併各來源的數量：同單位的數字相加，不同單位以「+」串接，文字數量去重後串接；
+// 「適量」等預設值只在沒有其他數量時使用
+func mergeAmounts(sources []InventorySource) (string, string) {
+	type group struct {
+		unit  string
+		total float64
+	}
+	var groups []*group
+	byUnit := make(map[string]*group)
+	var texts []string
+	seenText := make(map[string]bool)
+
+	for _, src := range sources {
+		if n, ok := parseAmount(src.Amount); ok {
+			key := normalizeTerm(src.Unit)
+			g, exists := byUnit[key]
+			if !exists {
+				g = &group{unit: src.Unit}
+				byUnit[key] = g
+				groups = append(groups, g)
+			}
+			g.total += n
+			continue
+		}
+		// 「份」是辨識時補上的預設單位，不接在文字數量後面
+		text := strings.TrimSpace(src.Amount)
+		if !placeholderAmounts[text] && !placeholderValues[src.Unit] && src.Unit != "份" {
+			text += src.Unit
+		}
+		if !seenText[text] {
+			seenText[text] = true
+			texts = append(texts, text)
+		}
+	}
+
+	if len(groups) == 1 && len(nonPlaceholder(texts)) == 0 {
+		return formatAmount(groups[0].total), groups[0].unit
+	}
+
+	var parts []string
+	for _, g := range groups {
+		parts = append(parts, formatAmount(g.total)+g.unit)
+	}
+	parts = append(parts, nonPlaceholder(texts)...)
+	if len(parts) == 0 {
+		return "適量", ""
+	}
+	return strings.Join(parts, "+"), ""
+}
+
+// nonPlaceholder 過濾掉「適量」、「未知」等預設數量
+func nonPlaceholder(texts []string) []string {
+	var out []string
+	for _, t := range texts {
+		if !placeholderAmounts[t] {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// parseAmount 解析數量，支援整數、小數、分數（1/2）、中文數字與「半」；
+// 負數、NaN、Inf 與指數或十六進位寫法不視為數量
+func parseAmount(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "一半" {
+		return 0.5, true
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		// 全形數字與符號轉為半形
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFEE0
+		}
+		if d, ok := chineseDigits[r]; ok {
+			r = d
+		}
+		b.WriteRune(r)
+	}
+	s = b.String()
+
+	switch s {
+	case "":
+		return 0, false
+	case "半":
+		return 0.5, true
+	}
+	if strings.HasSuffix(s, "半") {
+		n, ok := parseNumber(strings.TrimSuffix(s, "半"))
+		if !ok {
+			return 0, false
+		}
+		return n + 0.5, true
+	}
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, ok1 := parseNumber(num)
+		d, ok2 := parseNumber(den)
+		if !ok1 || !ok2 || d == 0 {
+			return 0, false
+		}
+		return n / d, true
+	}
+	return parseNumber(s)
+}
+
+// parseNumber 解析只由數字與小數點組成的非負十進位數
+func parseNumber(s string) (float64, bool) {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' }) >= 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(n, 0) {
+		return 0, false
+	}
+	return n, true
+}
+
+// formatAmount 輸出數量，整數不帶小數點，其餘最多保留兩位小數
+func formatAmount(n float64) string {
+	return strconv.FormatFloat(float64(int64(n*100+0.5))/100, 'f', -1, 64)
+}
@@ -0,0 +1,212 @@
+package recipe
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"recipe-generator/internal/pkg/common"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"2", 2, true},
+		{" 3 ", 3, true},
+		{"1.5", 1.5, true},
+		{"0", 0, true},
+		{"1/2", 0.5, true},
+		{"3/4", 0.75, true},
+		{"３", 3, true},
+		{"１．５", 1.5, true},
+		{"１／２", 0.5, true},
+		{"三", 3, true},
+		{"兩", 2, true},
+		{"半", 0.5, true},
+		{"一半", 0.5, true},
+		{"1半", 1.5, true},
+		{"兩半", 2.5, true},
+		{"", 0, false},
+		{"適量", 0, false},
+		{"少許", 0, false},
+		{"2顆", 0, false},
+		{"NaN", 0, false},
+		{"nan", 0, false},
+		{"Inf", 0, false},
+		{"+Inf", 0, false},
+		{"infinity", 0, false},
+		{"-1", 0, false},
+		{"-1/2", 0, false},
+		{"1/-2", 0, false},
+		{"-半", 0, false},
+		{"+1", 0, false},
+		{"1e3", 0, false},
+		{"0x1p4", 0, false},
+		{"1/0", 0, false},
+		{"1/2/3", 0, false},
+		{"1.2.3", 0, false},
+		{"NaN/2", 0, false},
+		{"Inf半", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := parseAmount(tt.in)
+			if ok != tt.ok || (ok && math.Abs(got-tt.want) > 1e-9) {
+				t.Fatalf("parseAmount(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestMergeAmounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []InventorySource
+		amount  string
+		unit    string
+	}{
+		{
+			name:    "same unit adds up",
+			sources: []InventorySource{{Amount: "2", Unit: "顆"}, {Amount: "3", Unit: "顆"}},
+			amount:  "5", unit: "顆",
+		},
+		{
+			name:    "fractions and half",
+			sources: []InventorySource{{Amount: "1/2", Unit: "顆"}, {Amount: "半", Unit: "顆"}, {Amount: "1半", Unit: "顆"}},
+			amount:  "2.5", unit: "顆",
+		},
+		{
+			name:    "fullwidth and chinese digits",
+			sources: []InventorySource{{Amount: "２", Unit: "條"}, {Amount: "三", Unit: "条"}},
+			amount:  "5", unit: "條",
+		},
+		{
+			name:    "unit conflict",
+			sources: []InventorySource{{Amount: "2", Unit: "顆"}, {Amount: "300", Unit: "克"}, {Amount: "1", Unit: "顆"}},
+			amount:  "3顆+300克", unit: "",
+		},
+		{
+			name:    "placeholder ignored next to a number",
+			sources: []InventorySource{{Amount: "2", Unit: "顆"}, {Amount: "適量", Unit: "份"}},
+			amount:  "2", unit: "顆",
+		},
+		{
+			name:    "text amounts deduplicated",
+			sources: []InventorySource{{Amount: "少許", Unit: "份"}, {Amount: "少許", Unit: "份"}, {Amount: "一小撮", Unit: "份"}, {Amount: "適量", Unit: "份"}},
+			amount:  "少許+一小撮", unit: "",
+		},
+		{
+			name:    "number and text",
+			sources: []InventorySource{{Amount: "2", Unit: "顆"}, {Amount: "少許", Unit: "份"}},
+			amount:  "2顆+少許", unit: "",
+		},
+		{
+			name:    "only placeholders",
+			sources: []InventorySource{{Amount: "適量", Unit: "份"}, {Amount: "", Unit: ""}},
+			amount:  "適量", unit: "",
+		},
+		{
+			name:    "invalid numbers kept as text",
+			sources: []InventorySource{{Amount: "2", Unit: "顆"}, {Amount: "NaN", Unit: "顆"}, {Amount: "-1", Unit: "顆"}},
+			amount:  "2顆+NaN顆+-1顆", unit: "",
+		},
+		{
+			name:    "rounded to two decimals",
+			sources: []InventorySource{{Amount: "1/3", Unit: "杯"}, {Amount: "1/3", Unit: "杯"}},
+			amount:  "0.67", unit: "杯",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, unit := mergeAmounts(tt.sources)
+			if amount != tt.amount || unit != tt.unit {
+				t.Fatalf("mergeAmounts = %q, %q; want %q, %q", amount, unit, tt.amount, tt.unit)
+			}
+		})
+	}
+}
+
+func TestMergeInventory(t *testing.T) {
+	box := func(x float64) *common.BoundingBox {
+		return &common.BoundingBox{X: x, Y: 0.1, Width: 0.2, Height: 0.2}
+	}
+	results := []*common.IngredientRecognitionResult{
+		{
+			Ingredients: []common.Ingredient{
+				{Name: "番茄", Type: "蔬菜", Amount: "2", Unit: "顆", Preparation: "無特殊處理", Confidence: ptr(0.7), BoundingBox: box(0.1)},
+				{Name: "雞蛋", Type: "未知類型", Amount: "適量", Unit: "份", Preparation: "無特殊處理"},
+				{Name: "未知食材", Type: "未知類型", Amount: "1", Unit: "份"},
+			},
+			Equipment: []common.Equipment{{Name: "平底鍋", Type: "未知類型", Size: "標準", Material: "不沾", PowerSource: "未知", BoundingBox: box(0.5)}},
+			Summary:   "冰箱",
+		},
+		nil,
+		{
+			Ingredients: []common.Ingredient{
+				{Name: "西红柿", Type: "未知類型", Amount: "３", Unit: "顆", Preparation: "切塊", Confidence: ptr(0.9), BoundingBox: box(0.3)},
+				{Name: "雞蛋", Type: "蛋類", Amount: "6", Unit: "顆", Preparation: "無特殊處理"},
+				{Name: "未知食材", Type: "未知類型", Amount: "2", Unit: "份"},
+			},
+			Equipment: []common.Equipment{{Name: "平底鍋", Type: "鍋具", Size: "中型", Material: "鐵", PowerSource: "瓦斯"}},
+			Summary:   "無摘要",
+		},
+	}
+	errs := []error{nil, errors.New("failed to process request"), nil}
+
+	inv := mergeInventory(results, errs)
+
+	if len(inv.AvailableIngredients) != 4 {
+		t.Fatalf("ingredients: %+v", inv.AvailableIngredients)
+	}
+	tomato := inv.AvailableIngredients[0]
+	if tomato.Name != "番茄" || tomato.Type != "蔬菜" || tomato.Amount != "5" || tomato.Unit != "顆" || tomato.Preparation != "切塊" {
+		t.Fatalf("tomato: %+v", tomato.Ingredient)
+	}
+	if tomato.BoundingBox != nil || *tomato.Confidence != 0.9 {
+		t.Fatalf("tomato position or confidence: %+v", tomato.Ingredient)
+	}
+	wantSources := []InventorySource{
+		{Image: 0, Amount: "2", Unit: "顆", Confidence: ptr(0.7), BoundingBox: box(0.1)},
+		{Image: 2, Amount: "３", Unit: "顆", Confidence: ptr(0.9), BoundingBox: box(0.3)},
+	}
+	if !reflect.DeepEqual(tomato.Sources, wantSources) {
+		t.Fatalf("tomato sources: %+v", tomato.Sources)
+	}
+
+	egg := inv.AvailableIngredients[1]
+	if egg.Type != "蛋類" || egg.Amount != "6" || egg.Unit != "顆" || len(egg.Sources) != 2 {
+		t.Fatalf("egg: %+v", egg)
+	}
+
+	// 未知名稱的項目不互相合併
+	if inv.AvailableIngredients[2].Name != "未知食材" || inv.AvailableIngredients[3].Name != "未知食材" {
+		t.Fatalf("unknown ingredients merged: %+v", inv.AvailableIngredients)
+	}
+
+	if len(inv.AvailableEquipment) != 1 {
+		t.Fatalf("equipment: %+v", inv.AvailableEquipment)
+	}
+	pan := inv.AvailableEquipment[0]
+	if pan.Type != "鍋具" || pan.Size != "中型" || pan.Material != "不沾" || pan.PowerSource != "瓦斯" || pan.BoundingBox != nil {
+		t.Fatalf("pan: %+v", pan.Equipment)
+	}
+	if len(pan.Sources) != 2 || pan.Sources[0].Image != 0 || pan.Sources[0].BoundingBox == nil || pan.Sources[1].Image != 2 {
+		t.Fatalf("pan sources: %+v", pan.Sources)
+	}
+
+	wantImages := []InventoryImage{
+		{Image: 0, Summary: "冰箱", IngredientsCount: 3, EquipmentCount: 1},
+		{Image: 1, Error: "failed to process request"},
+		{Image: 2, Summary: "無摘要", IngredientsCount: 3, EquipmentCount: 1},
+	}
+	if !reflect.DeepEqual(inv.Images, wantImages) {
+		t.Fatalf("images: %+v", inv.Images)
+	}
+	if inv.Summary != "冰箱" {
+		t.Fatalf("summary: %q", inv.Summary)
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"strings"
 
 	"recipe-generator/internal/core/ai/cache"
+	"recipe-generator/internal/core/ai/provider"
 	"recipe-generator/internal/core/ai/service"
+	"recipe-generator/internal/core/experiment"
+	promptpkg "recipe-generator/internal/core/prompt"
+	"recipe-generator/internal/infrastructure/config"
 	"recipe-generator/internal/pkg/common"
 
 	"go.uber.org/zap"
@@ -16,19 +20,39 @@ import (
 // --------------------------------------------------
 type RecipeService struct {
 	aiService    *service.Service
-	cacheManager *cache.CacheManager
+	cacheManager cache.Cache
+	semantic     config.SemanticCacheConfig
+	index        *similarityIndex // 未開啟相似度查找時為 nil
+	prompts      *promptpkg.Registry
 }
 
 // NewRecipeService 創建新的食譜生成服務
-func NewRecipeService(aiService *service.Service, cacheManager *cache.CacheManager) *RecipeService {
-	return &RecipeService{
+func NewRecipeService(aiService *service.Service, cacheManager cache.Cache, semantic config.SemanticCacheConfig, prompts *promptpkg.Registry) *RecipeService {
+	s := &RecipeService{
 		aiService:    aiService,
 		cacheManager: cacheManager,
+		semantic:     semantic,
+		prompts:      prompts,
 	}
+	if semantic.Enabled && semantic.Similarity {
+		s.index = newSimilarityIndex(semantic.IndexSize)
+	}
+	return s
 }
 
 // GenerateRecipe 根據食材和偏好生成食譜
 func (s *RecipeService) GenerateRecipe(ctx context.Context, dishName string, ingredients []common.Ingredient, preferences common.RecipePreferences) (*common.Recipe, error) {
+	return s.generateRecipe(ctx, dishName, ingredients, preferences, nil)
+}
+
+// GenerateRecipeStream 與 GenerateRecipe 相同，但會將 AI 的增量輸出交給 onDelta，
+// 完成後回傳經過相同後處理的食譜
+func (s *RecipeService) GenerateRecipeStream(ctx context.Context, dishName string, ingredients []common.Ingredient, preferences common.RecipePreferences, onDelta provider.DeltaFunc) (*common.Recipe, error) {
+	return s.generateRecipe(ctx, dishName, ingredients, preferences, onDelta)
+}
+
+// generateRecipe 生成並後處理食譜，onDelta 不為 nil 時使用串流
+func (s *RecipeService) generateRecipe(ctx context.Context, dishName string, ingredients []common.Ingredient, preferences common.RecipePreferences, onDelta provider.DeltaFunc) (*common.Recipe, error) {
 	// 驗證必要欄位
 	if preferences.CookingMethod == "" {
 		preferences.CookingMethod = "炒" // 預設為炒
@@ -37,115 +61,24 @@ func (s *RecipeService) GenerateRecipe(ctx context.Context, dishName string, ing
 		preferences.ServingSize = "2人份" // 預設為2人份
 	}
 
-	prompt := fmt.Sprintf(`請根據以下食材和偏好，生成一個適合新手的食譜(並且用繁體中文回答）。
-		菜名：%s
-		食材：
-		%s
-		偏好：
-		- 烹飪方式：%s
-		- 飲食限制：%s
-		- 份量：%s
-		要求：格式：「UTF-8」
-		1. 只根據提供的食材和偏好生成內容，不要添加未出現的食材或步驟
-		2. 不要使用預設值或猜測值，若無法確定請填寫 "未知"
-		3. 每個步驟都要非常詳細，適合新手操作
-		4. 動作描述要具體明確，包含具體的時間和溫度
-		5. 注意事項要特別提醒新手容易忽略的細節
-		6. 所有字段都必須使用雙引號
-		7. 不需要考慮可讀性，請省略所有空格和換行，返回最緊湊的 JSON 格式
-		8. 營養資訊要根據實際食材和份量估算
-		9. 烹飪時間要包含準備時間和烹飪時間的總和
-		10. time_minutes 欄位必須是整數，不能有小數點（以秒為單位）
-		11. warnings 欄位必須是字串類型，如果沒有警告事項請填寫 null
-		12. 每個步驟都必須包含 warnings 欄位，不能省略此欄位
-		13. 不要使用\n，不需要換行
-		14. 每個步驟只能描述一個主要的烹飪動作，對應單一的 ARtype
-		15. 每個步驟必須提供 ARtype 與 ar_parameters，且 ar_parameters.type 必須等於 ARtype
-		16. ar_parameters 欄位若無資料請填 null，ingredient 必須使用具體英文小寫名稱，不得使用 "ingredient"、"food" 等泛用詞
-		17. 每個步驟只允許一個 action，必須對應單一 ARtype，禁止拆分多個子動作
-		18. 嚴格輸出單一 JSON 物件，不要額外輸出自然語言或程式碼區塊
-		19. 除了 ar_parameters 內部欄位維持英文，其餘所有欄位內容一律使用繁體中文描述
-		20. ar_parameters."type" 必須使用以下白名單其中之一：putIntoContainer、stir、pourLiquid、flipPan、countdown、temperature、flame、sprinkle、torch、cut、peel、flip、beatEgg，禁止使用其他字詞（例如 mix、heat、soak、fry、plating 等）
-		21. ar_parameters."ingredient":"ingredient" 不要直接寫 ingredient，一定要用英文小寫，如果是倒調味料或倒液體要使用他的調味料或液體，名稱如果有兩個ingredient用請使用英文逗號 ","隔開，不得出現空白或非 ASCII 字元；若描述涉及特定食材請使用該食材對應的英文代碼
-		22. 必須依照 ar_parameters.type 提供所需欄位：例如 temperature 類型「一定要」填寫 ar_parameters.temperature 為攝氏整數或可被解析的數值（如 180 表示 180°C）並同時填寫 ar_parameters.container；countdown 類型需提供整數秒數到 ar_parameters.time；pourLiquid 類型一定要填寫 container、color（如 brown、clear）、ingredient（英文小寫代碼）；flame 類型一定要填寫 ar_parameters.flameLevel，值只能是 small、medium、large；beatEgg 類型一定要填寫 ar_parameters.container；若 AI 無法取得精確數值請估算合理的整數而非留空或填 null
-		23. 只能使用輸入資料中出現過的設備名稱與容器，不得新增其他設備或容器
-		24. ar_parameters.container 只能使用提供的設備清單中可對應的英文容器名稱，不得新增其他設備或容器
-		25.請只輸出 JSON，不要包含任何自然語言或程式碼區塊標記，並確保所有輸出皆為 「UTF-8」 編碼以避免亂碼。
-	    26.生成的食譜步驟和description只能使用equipment有的
-		請以以下 JSON 格式返回（僅作為範例，請勿直接複製內容）：
-		{
-		"dish_name": "菜名",
-		"dish_description": "描述",
-		"ingredients": [
-			{
-			"name": "食材名稱",
-			"type": "食材類型",
-			"amount": "數量",
-			"unit": "單位",
-			"preparation": "處理方式"
-			}
-		],
-		"equipment": [
-			{
-			"name": "設備名稱",
-			"type": "設備類型",
-			"size": "尺寸",
-			"material": "材質",
-			"power_source": "能源類型"
-			}
-		],
-		"recipe": [
-			{
-			"step_number": 步驟整數,
-			"ARtype": "stir",
-			"ar_parameters": {
-				"type": "stir",
-				"container": "pan",
-				"ingredient": "egg",
-				"color": null,
-				"time": null,
-				"temperature": null,
-				"flameLevel": null
-			},
-			"title": "步驟標題",
-			"description": "步驟描述",
-			"actions": [{
-				"action": "動作",
-				"tool_required": "工具",
-				"material_required": ["材料"],
-				"time_minutes": 時間秒數,
-				"instruction_detail": "細節"
-			}],
-			"estimated_total_time": "時間",
-			"temperature": "火侯",
-			"warnings": "警告事項",
-			"notes": "備註"
-			}
-		]
-		}
-		`,
-		dishName,
-		common.FormatIngredients(ingredients),
-		preferences.CookingMethod,
-		strings.Join(preferences.DietaryRestrictions, "、"),
-		preferences.ServingSize)
-
-	resp, err := s.aiService.ProcessRequest(ctx, prompt, "")
+	prompt, err := s.prompts.Render(ctx, promptpkg.RecipeGenerate, promptpkg.Vars{
+		"DishName":            dishName,
+		"Ingredients":         common.FormatIngredients(ingredients),
+		"CookingMethod":       preferences.CookingMethod,
+		"DietaryRestrictions": strings.Join(preferences.DietaryRestrictions, "、"),
+		"ServingSize":         preferences.ServingSize,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("AI service error: %w", err)
+		return nil, err
 	}
 
-	if resp == nil || resp.Content == "" {
-		return nil, fmt.Errorf("empty AI response")
+	fingerprint := newRecipeFingerprint(prompt.Key(), dishName, ingredients, preferences)
+	resp, cached, err := s.recipeContent(ctx, fingerprint, prompt, onDelta)
+	if err != nil {
+		return nil, err
 	}
 
-	content := resp.Content
-	content = strings.TrimSpace(content)
-	start := strings.Index(content, "{")
-	end := strings.LastIndex(content, "}")
-	if start != -1 && end != -1 && end > start {
-		content = content[start : end+1]
-	}
+	content := common.ExtractJSON(resp.Content)
 
 	// 新增 debug log 輸出 AI 回應內容
 	preview := content
@@ -156,20 +89,7 @@ func (s *RecipeService) GenerateRecipe(ctx context.Context, dishName string, ing
 
 	var result common.Recipe
 	if err := common.ParseJSON(content, &result); err != nil {
-		fixed := common.QuoteJSONKeys(content)
-		if fixed != content {
-			if ferr := common.ParseJSON(fixed, &result); ferr == nil {
-				common.LogWarn("AI 回傳 JSON 含未加引號鍵，已自動修正",
-					zap.String("dish_name", dishName),
-					zap.Int("ai_response_length", len(content)),
-				)
-				content = fixed
-			} else {
-				return nil, fmt.Errorf("failed to parse AI response: %w", err)
-			}
-		} else {
-			return nil, fmt.Errorf("failed to parse AI response: %w", err)
-		}
+		return nil, fmt.Errorf("failed to parse AI response: %w", err)
 	}
 
 	// 檢查並補充空值
@@ -273,6 +193,7 @@ func (s *RecipeService) GenerateRecipe(ctx context.Context, dishName string, ing
 
 	// 確保每個步驟具備 ARtype 與 AR 參數
 	containerChoices := inferContainerChoices(result.Equipment)
+	fallbacks := 0
 	for i := range result.Recipe {
 		params := result.Recipe[i].ARParameters
 		if params != nil {
@@ -301,6 +222,7 @@ func (s *RecipeService) GenerateRecipe(ctx context.Context, dishName string, ing
 			)
 		}
 
+		fallbacks++
 		fallback, ferr := fallbackARParams(result.Recipe[i], containerChoices, result.Ingredients)
 		if ferr != nil {
 			common.LogWarn("AR 參數回退失敗，採用預設值",
@@ -321,11 +243,114 @@ func (s *RecipeService) GenerateRecipe(ctx context.Context, dishName string, ing
 		result.Recipe[i].ARtype = fallback.Type
 		result.Recipe[i].ARParameters = fallback
 	}
+	// 快取命中的內容已在產生時記錄過
+	if resp.Provider != "cache" {
+		experiment.ObserveAR(ctx, prompt.ID, len(result.Recipe), fallbacks)
+	}
 
 	// 驗證必要欄位
 	if len(result.Recipe) == 0 {
 		return nil, fmt.Errorf("recipe steps cannot be empty")
 	}
 
+	// 只快取能成功解析的回應，避免錯誤內容被其他相同或相近的請求沿用
+	if !cached {
+		s.storeFingerprint(ctx, fingerprint, content)
+	}
+
 	return &result, nil
 }
+
+// recipeContent 先以請求指紋查找語意快取，未命中才呼叫 AI；cached 表示內容來自語意快取，
+// 任一層快取命中時回應的 Provider 為 "cache"
+func (s *RecipeService) recipeContent(ctx context.Context, fingerprint recipeFingerprint, prompt *promptpkg.Prompt, onDelta provider.DeltaFunc) (*service.Response, bool, error) {
+	if val, ok := s.lookupFingerprint(ctx, fingerprint); ok {
+		provider.MetadataFromContext(ctx).Record("cache", "")
+		provider.MetadataFromContext(ctx).RecordTemplate(prompt.Label())
+		experiment.ObserveCacheHit(ctx, prompt.ID)
+		if onDelta != nil {
+			if err := onDelta(val); err != nil {
+				return nil, false, err
+			}
+		}
+		return &service.Response{Content: val, Provider: "cache", Template: prompt.Label()}, true, nil
+	}
+
+	var resp *service.Response
+	var err error
+	if onDelta != nil {
+		resp, err = s.aiService.ProcessStructuredStream(ctx, prompt, "", recipeFormat, onDelta)
+	} else {
+		resp, err = s.aiService.ProcessStructured(ctx, prompt, "", recipeFormat)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("AI service error: %w", err)
+	}
+
+	if resp == nil || resp.Content == "" {
+		return nil, false, fmt.Errorf("empty AI response")
+	}
+	return resp, false, nil
+}
+
+// lookupFingerprint 以指紋查找快取；未命中且開啟相似度查找時，改用最相近的已快取請求
+func (s *RecipeService) lookupFingerprint(ctx context.Context, fingerprint recipeFingerprint) (string, bool) {
+	if !s.semantic.Enabled || s.cacheManager == nil {
+		return "", false
+	}
+
+	key := fingerprint.key()
+	if val, err := s.cacheManager.Get(ctx, key, ""); err == nil && val != "" {
+		// 快取可能來自快照或其他實例，命中時補進本地索引
+		if s.index != nil {
+			s.index.add(fingerprint)
+		}
+		common.LogInfo("食譜指紋快取命中", zap.String("fingerprint", key))
+		return val, true
+	}
+
+	if s.index == nil {
+		return "", false
+	}
+	match, score, ok := s.index.nearest(fingerprint, s.semantic.Threshold)
+	if !ok {
+		return "", false
+	}
+	val, err := s.cacheManager.Get(ctx, match, "")
+	if err != nil || val == "" {
+		s.index.remove(match)
+		return "", false
+	}
+	common.LogInfo("食譜相似請求快取命中",
+		zap.String("fingerprint", key),
+		zap.String("matched", match),
+		zap.Float64("similarity", score),
+	)
+	return val, true
+}
+
+// Prune 實作 cache.Index：移除相似度索引中快取條目已被刪除的指紋
+func (s *RecipeService) Prune(ctx context.Context) (int, error) {
+	if s.index == nil || s.cacheManager == nil {
+		return 0, nil
+	}
+	live, err := liveKeys(ctx, s.cacheManager, cache.NamespaceRecipeFingerprint)
+	if err != nil {
+		return 0, err
+	}
+	return s.index.prune(live), nil
+}
+
+// storeFingerprint 以指紋寫入快取並加入相似度索引
+func (s *RecipeService) storeFingerprint(ctx context.Context, fingerprint recipeFingerprint, content string) {
+	if !s.semantic.Enabled || s.cacheManager == nil {
+		return
+	}
+	if err := s.cacheManager.Set(ctx, fingerprint.key(), "", content); err != nil {
+		common.LogWarn("食譜指紋快取寫入失敗", zap.Error(err))
+		return
+	}
+	if s.index != nil {
+		s.index.add(fingerprint)
+	}
+}
@@ -0,0 +1,96 @@
+package recipe
+
+import (
+	"math"
+
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+// clampConfidence 將信心分數限制在 0~1；模型以百分比回傳（2~100）時換算，無效數值視為未提供
+func clampConfidence(c *float64) *float64 {
+	if c == nil || math.IsNaN(*c) || math.IsInf(*c, 0) {
+		return nil
+	}
+	v := *c
+	if v >= 2 && v <= 100 {
+		v /= 100
+	}
+	v = math.Max(0, math.Min(1, v))
+	return &v
+}
+
+// clampBoundingBox 將位置裁切在圖片範圍內；數值無效或裁切後寬高為 0 時移除
+func clampBoundingBox(b *common.BoundingBox) *common.BoundingBox {
+	if b == nil {
+		return nil
+	}
+	for _, v := range []float64{b.X, b.Y, b.Width, b.Height} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil
+		}
+	}
+	clamp := func(v float64) float64 { return math.Max(0, math.Min(1, v)) }
+	// 四捨五入到小數第四位，約為 4K 影像的一個像素
+	round := func(v float64) float64 { return math.Round(v*1e4) / 1e4 }
+	x0, y0 := clamp(b.X), clamp(b.Y)
+	x1, y1 := clamp(b.X+b.Width), clamp(b.Y+b.Height)
+	box := &common.BoundingBox{X: round(x0), Y: round(y0), Width: round(x1 - x0), Height: round(y1 - y0)}
+	if box.Width <= 0 || box.Height <= 0 {
+		return nil
+	}
+	return box
+}
+
+// confident 判斷項目是否達到最低信心分數；模型未提供分數時保留
+func confident(c *float64, minConfidence float64) bool {
+	return c == nil || *c >= minConfidence
+}
+
+// sanitizeFoods 校正食物辨識的信心分數與位置，並移除低於最低信心分數的食物
+func sanitizeFoods(result *common.FoodRecognitionResult, minConfidence float64) {
+	kept := result.RecognizedFoods[:0]
+	for _, food := range result.RecognizedFoods {
+		food.Confidence = clampConfidence(food.Confidence)
+		food.BoundingBox = clampBoundingBox(food.BoundingBox)
+		if confident(food.Confidence, minConfidence) {
+			kept = append(kept, food)
+		}
+	}
+	if dropped := len(result.RecognizedFoods) - len(kept); dropped > 0 {
+		common.LogDebug("已過濾低信心的食物",
+			zap.Int("dropped", dropped),
+			zap.Float64("min_confidence", minConfidence))
+	}
+	result.RecognizedFoods = kept
+}
+
+// sanitizeIngredients 校正食材與設備的信心分數與位置，並移除低於最低信心分數的項目
+func sanitizeIngredients(result *common.IngredientRecognitionResult, minConfidence float64) {
+	ingredients := result.Ingredients[:0]
+	for _, ing := range result.Ingredients {
+		ing.Confidence = clampConfidence(ing.Confidence)
+		ing.BoundingBox = clampBoundingBox(ing.BoundingBox)
+		if confident(ing.Confidence, minConfidence) {
+			ingredients = append(ingredients, ing)
+		}
+	}
+
+	equipment := result.Equipment[:0]
+	for _, equip := range result.Equipment {
+		equip.Confidence = clampConfidence(equip.Confidence)
+		equip.BoundingBox = clampBoundingBox(equip.BoundingBox)
+		if confident(equip.Confidence, minConfidence) {
+			equipment = append(equipment, equip)
+		}
+	}
+
+	if dropped := len(result.Ingredients) - len(ingredients) + len(result.Equipment) - len(equipment); dropped > 0 {
+		common.LogDebug("已過濾低信心的食材與設備",
+			zap.Int("dropped", dropped),
+			zap.Float64("min_confidence", minConfidence))
+	}
+	result.Ingredients = ingredients
+	result.Equipment = equipment
+}
@@ -0,0 +1,142 @@
+package recipe
+
+import (
+	"math"
+	"os"
+	"testing"
+
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	common.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+func ptr(v float64) *float64 { return &v }
+
+func TestClampConfidence(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *float64
+		want *float64
+	}{
+		{"nil", nil, nil},
+		{"NaN", ptr(math.NaN()), nil},
+		{"Inf", ptr(math.Inf(1)), nil},
+		{"in range", ptr(0.42), ptr(0.42)},
+		{"one", ptr(1), ptr(1)},
+		{"percentage", ptr(85), ptr(0.85)},
+		{"percentage boundary", ptr(2), ptr(0.02)},
+		{"between 1 and 2", ptr(1.5), ptr(1)},
+		{"above 100", ptr(150), ptr(1)},
+		{"negative", ptr(-0.3), ptr(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := clampConfidence(tt.in)
+			switch {
+			case tt.want == nil && got != nil:
+				t.Fatalf("got %v, want nil", *got)
+			case tt.want != nil && got == nil:
+				t.Fatalf("got nil, want %v", *tt.want)
+			case tt.want != nil && math.Abs(*got-*tt.want) > 1e-9:
+				t.Fatalf("got %v, want %v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestClampBoundingBox(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *common.BoundingBox
+		want *common.BoundingBox
+	}{
+		{"nil", nil, nil},
+		{"inside", &common.BoundingBox{X: 0.1, Y: 0.2, Width: 0.3, Height: 0.4}, &common.BoundingBox{X: 0.1, Y: 0.2, Width: 0.3, Height: 0.4}},
+		{"overflow right and bottom", &common.BoundingBox{X: 0.8, Y: 0.7, Width: 0.5, Height: 0.6}, &common.BoundingBox{X: 0.8, Y: 0.7, Width: 0.2, Height: 0.3}},
+		{"negative origin", &common.BoundingBox{X: -0.2, Y: -0.1, Width: 0.5, Height: 0.3}, &common.BoundingBox{X: 0, Y: 0, Width: 0.3, Height: 0.2}},
+		{"rounded", &common.BoundingBox{X: 0.123456, Y: 0.5, Width: 0.2, Height: 0.2}, &common.BoundingBox{X: 0.1235, Y: 0.5, Width: 0.2, Height: 0.2}},
+		{"outside", &common.BoundingBox{X: 1.2, Y: 0.5, Width: 0.3, Height: 0.2}, nil},
+		{"zero width", &common.BoundingBox{X: 0.5, Y: 0.5, Width: 0, Height: 0.2}, nil},
+		{"negative size", &common.BoundingBox{X: 0.5, Y: 0.5, Width: -0.2, Height: 0.2}, nil},
+		{"NaN", &common.BoundingBox{X: math.NaN(), Y: 0.5, Width: 0.2, Height: 0.2}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := clampBoundingBox(tt.in)
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			if got == nil {
+				return
+			}
+			const eps = 1e-9
+			if math.Abs(got.X-tt.want.X) > eps || math.Abs(got.Y-tt.want.Y) > eps ||
+				math.Abs(got.Width-tt.want.Width) > eps || math.Abs(got.Height-tt.want.Height) > eps {
+				t.Fatalf("got %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeIngredients(t *testing.T) {
+	result := &common.IngredientRecognitionResult{
+		Ingredients: []common.Ingredient{
+			{Name: "番茄", Confidence: ptr(94), BoundingBox: &common.BoundingBox{X: 0.9, Y: 0.1, Width: 0.3, Height: 0.3}},
+			{Name: "雞蛋", Confidence: ptr(0.3)},
+			{Name: "蔥", Confidence: nil},
+			{Name: "蒜", Confidence: ptr(0.2), BoundingBox: &common.BoundingBox{X: 2, Y: 2, Width: 1, Height: 1}},
+		},
+		Equipment: []common.Equipment{
+			{Name: "平底鍋", Confidence: ptr(0.88)},
+			{Name: "湯匙", Confidence: ptr(-1)},
+		},
+	}
+
+	sanitizeIngredients(result, 0.5)
+
+	names := func() []string {
+		var out []string
+		for _, ing := range result.Ingredients {
+			out = append(out, ing.Name)
+		}
+		for _, equip := range result.Equipment {
+			out = append(out, equip.Name)
+		}
+		return out
+	}()
+	want := []string{"番茄", "蔥", "平底鍋"}
+	if len(names) != len(want) {
+		t.Fatalf("kept %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("kept %v, want %v", names, want)
+		}
+	}
+
+	tomato := result.Ingredients[0]
+	if *tomato.Confidence != 0.94 {
+		t.Fatalf("percentage not converted: %v", *tomato.Confidence)
+	}
+	if box := tomato.BoundingBox; box == nil || math.Abs(box.Width-0.1) > 1e-9 {
+		t.Fatalf("bounding box not clamped: %+v", box)
+	}
+	if result.Ingredients[1].Confidence != nil {
+		t.Fatal("missing confidence should stay nil")
+	}
+
+	// 未設定最低信心分數時只校正數值，不移除項目
+	all := &common.IngredientRecognitionResult{
+		Ingredients: []common.Ingredient{{Name: "蒜", Confidence: ptr(0.01)}},
+		Equipment:   []common.Equipment{{Name: "湯匙", Confidence: ptr(-1)}},
+	}
+	sanitizeIngredients(all, 0)
+	if len(all.Ingredients) != 1 || len(all.Equipment) != 1 || *all.Equipment[0].Confidence != 0 {
+		t.Fatalf("min confidence 0 dropped items: %+v", all)
+	}
+}
@@ -6,35 +6,48 @@ import (
 	"strings"
 
 	"recipe-generator/internal/core/ai/cache"
-	"recipe-generator/internal/core/ai/openrouter"
+	"recipe-generator/internal/core/ai/provider"
 	"recipe-generator/internal/core/ai/service"
 )
 
 // Service 食譜服務基礎結構
 type Service struct {
 	aiService    *service.Service
-	cacheManager *cache.CacheManager
+	cacheManager cache.Cache
 }
 
 // NewService 創建新的食譜服務
-func NewService(aiService *service.Service, cacheManager *cache.CacheManager) *Service {
+func NewService(aiService *service.Service, cacheManager cache.Cache) *Service {
 	return &Service{
 		aiService:    aiService,
 		cacheManager: cacheManager,
 	}
 }
 
+// liveKeys 快取中仍存在的指定命名空間條目鍵，供本地索引移除已刪除的條目
+func liveKeys(ctx context.Context, c cache.Cache, namespace string) (map[string]bool, error) {
+	entries, err := c.Entries(ctx, cache.Filter{Prefix: namespace + ":"}, 0)
+	if err != nil {
+		return nil, err
+	}
+	live := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		live[e.Key] = true
+	}
+	return live, nil
+}
+
 // handleAIResponse 處理 AI 回應
-func (s *Service) handleAIResponse(resp *openrouter.Response, err error) (string, error) {
+func (s *Service) handleAIResponse(resp *provider.Response, err error) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("AI service error: %w", err)
 	}
 
-	if resp == nil || len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
+	if resp == nil || resp.Content == "" {
 		return "", fmt.Errorf("empty AI response")
 	}
 
-	contentText := resp.Choices[0].Message.Content
+	contentText := resp.Content
 	return strings.TrimSpace(contentText), nil
 }
 
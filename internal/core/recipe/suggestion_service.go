@@ -11,7 +11,10 @@ import (
 	"unicode"
 
 	"recipe-generator/internal/core/ai/cache"
+	"recipe-generator/internal/core/ai/provider"
 	"recipe-generator/internal/core/ai/service"
+	"recipe-generator/internal/core/experiment"
+	promptpkg "recipe-generator/internal/core/prompt"
 	"recipe-generator/internal/pkg/common"
 
 	"go.uber.org/zap"
@@ -20,15 +23,17 @@ import (
 // SuggestionService 食譜推薦服務
 type SuggestionService struct {
 	aiService    *service.Service
-	cacheManager *cache.CacheManager
+	cacheManager cache.Cache
 	lastRecipes  sync.Map
+	prompts      *promptpkg.Registry
 }
 
 // NewSuggestionService 創建新的食譜推薦服務
-func NewSuggestionService(aiService *service.Service, cacheManager *cache.CacheManager) *SuggestionService {
+func NewSuggestionService(aiService *service.Service, cacheManager cache.Cache, prompts *promptpkg.Registry) *SuggestionService {
 	return &SuggestionService{
 		aiService:    aiService,
 		cacheManager: cacheManager,
+		prompts:      prompts,
 	}
 }
 
@@ -67,6 +72,17 @@ type looseStep struct {
 
 // SuggestRecipes 根據可用食材和設備推薦食譜
 func (s *SuggestionService) SuggestRecipes(ctx context.Context, req *common.RecipeByIngredientsRequest) (*common.Recipe, error) {
+	return s.suggestRecipes(ctx, req, nil)
+}
+
+// SuggestRecipesStream 與 SuggestRecipes 相同，但會將 AI 的增量輸出交給 onDelta，
+// 完成後回傳經過相同後處理的食譜
+func (s *SuggestionService) SuggestRecipesStream(ctx context.Context, req *common.RecipeByIngredientsRequest, onDelta provider.DeltaFunc) (*common.Recipe, error) {
+	return s.suggestRecipes(ctx, req, onDelta)
+}
+
+// suggestRecipes 推薦並後處理食譜，onDelta 不為 nil 時使用串流
+func (s *SuggestionService) suggestRecipes(ctx context.Context, req *common.RecipeByIngredientsRequest, onDelta provider.DeltaFunc) (*common.Recipe, error) {
 	// 驗證必要欄位
 	cm := strings.TrimSpace(req.Preference.CookingMethod)
 	if cm == "" {
@@ -87,114 +103,27 @@ func (s *SuggestionService) SuggestRecipes(ctx context.Context, req *common.Reci
 		}
 	}
 
-	prompt := fmt.Sprintf(`請根據以下可用食材和設備，推薦適合的食譜(並且用繁體中文回答）。
-
-可用食材：
-%s
-
-可用設備：
-%s
-
-烹飪偏好：
-- 烹飪方式：%s
-- 飲食限制：%s
-- 份量：%s
-
-要求：格式：「UTF-8」
-1. 只根據提供的食材和設備推薦內容，不要添加未出現的食材或設備
-2. 不要使用預設值或猜測值，若無法確定請填寫 "未知"
-3. 每個步驟都要非常詳細，適合新手操作
-4. 動作描述要具體明確，包含具體的時間和溫度
-5. 注意事項要特別提醒新手容易忽略的細節
-6. 所有字段都必須使用雙引號
-7. 不需要考慮可讀性，請省略所有空格和換行，返回最緊湊的 JSON 格式
-8. 推薦的食譜要優先使用已有的食材和設備
-9. 如果某些食材或設備不足，可以建議替代方案
-10. 每個食譜都要考慮到烹飪難度和時間
-11. time_minutes 欄位必須是整數，不能有小數點（以秒為單位）
-12. warnings 欄位必須是字串類型，如果沒有警告事項請填寫 null
-13. 每個步驟都必須包含 warnings 欄位，不能省略此欄位
-14. 不要使用\n，不需要換行
-15. 所有欄位都必須要有不能漏掉，如果不知道填什麼請留空 "" or null
-16. 只回傳一個獨立的json，不要回傳多個json
-17. ar_parameters."type" 必須使用以下白名單其中之一：putIntoContainer、stir、pourLiquid、flipPan、countdown、temperature、flame、sprinkle、torch、cut、peel、flip、beatEgg，禁止使用其他字詞（例如 mix、heat、soak、fry、plating 等）
-18. ar_parameters.ingredient:不要直接寫 ingredient，如果是調味料或液體要使用具體「英文小寫名稱」如果有兩個ingredient用請使用英文逗號 ","隔開，不得出現空白或非 ASCII 字元；若描述涉及特定食材請使用該食材對應的英文代碼
-19. 必須依照 ar_parameters.type 提供所需欄位：例如 temperature 類型「一定要」填寫 ar_parameters.temperature 為攝氏整數或可被解析的數值（如 180 表示 180°C）並同時填寫 ar_parameters.container；countdown 類型需提供整數秒數到 ar_parameters.time；pourLiquid 類型一定要填寫 container、color（如 brown、clear）、ingredient（英文小寫代碼）；flame 類型一定要填寫 ar_parameters.flameLevel 值只能是 small、medium、large；beatEgg 類型一定要填寫 ar_parameters.container；若 AI 無法取得精確數值請估算合理的整數而非留空或填 null
-20. 除了 ar_parameters 內部欄位維持英文，其餘所有欄位內容一律使用繁體中文描述
-21. 每個步驟只能描述一個主要的烹飪動作，對應單一的 ARtype
-22. 每個步驟只允許一個 action 物件，內容需與該 ARtype 完整對應
-23. 每個步驟必須提供 ARtype 與 ar_parameters，且 ar_parameters.type 必須等於 ARtype
-24. ar_parameters 欄位若無資料請填 null，ingredient 必須使用具體英文小寫名稱，不得使用 "ingredient"、"food" 等泛用詞
-25. 所有設備名稱與 ar_parameters.container 只能使用提供的設備清單中可對應的英文容器名稱，不得新增其他設備或容器
-26. 嚴格輸出單一 JSON 物件，不要額外輸出自然語言或程式碼區塊
-27.請只輸出 JSON，不要包含任何自然語言或程式碼區塊標記，並確保所有輸出皆為 「UTF-8」 編碼以避免亂碼。
-28.生成的食譜步驟和description只能使用equipment有的
-請以以下 JSON 格式返回（僅作為範例，請勿直接複製內容）：
-{
-    "dish_name": "菜名",
-    "dish_description": "描述",
-    "ingredients": [
-        {
-            "name": "食材名稱",
-            "type": "食材類型",
-            "amount": "數量",
-            "unit": "單位",
-            "preparation": "處理方式"
-        }
-    ],
-    "equipment": [
-        {
-            "name": "設備名稱",
-            "type": "設備類型",
-            "size": "尺寸",
-            "material": "材質",
-            "power_source": "能源類型"
-        }
-    ],
-    "recipe": [
-        {
-            "step_number": 1,
-            "ARtype": "stir",
-            "ar_parameters": {
-                "type": "stir",
-                "container": "pan",
-                "ingredient": "egg",
-                "color": null,
-                "time": null,
-                "temperature": null,
-                "flameLevel": null
-            },
-            "title": "步驟標題",
-            "description": "步驟描述",
-            "actions": [{
-                "action": "動作",
-                "tool_required": "工具",
-                "material_required": ["材料"],
-                "time_minutes": 1,
-                "instruction_detail": "細節"
-            }],
-            "estimated_total_time": "時間",
-            "temperature": "火侯",
-            "warnings": "警告事項",
-            "notes": "備註"
-        }
-    ]
-}`,
-	common.FormatIngredients(req.AvailableIngredients),
-	common.FormatEquipment(req.AvailableEquipment),
-	cm,
-	strings.Join(req.Preference.DietaryRestrictions, "、"),
-	ss)
-
-	if previousRecipe != "" {
-		prompt += fmt.Sprintf("\n\n上一次生成的食譜 JSON：%s\n請務必提供全新的食譜，確保菜名、步驟描述或食材搭配與上述內容明顯不同，避免輸出與前一次相同或僅做微幅調整的內容。\n", previousRecipe)
-	}
-	uniqueToken := fmt.Sprintf("SessionToken:%d", time.Now().UnixNano())
-	prompt += fmt.Sprintf("\n請忽略識別碼 %s，該識別碼僅用於避免快取，請勿在輸出中提到它。\n", uniqueToken)
-
-	common.LogDebug("SuggestRecipes 組裝的 prompt", zap.String("prompt", prompt))
-
-	resp, err := s.aiService.ProcessRequest(ctx, prompt, "")
+	prompt, err := s.prompts.Render(ctx, promptpkg.RecipeSuggest, promptpkg.Vars{
+		"Ingredients":         common.FormatIngredients(req.AvailableIngredients),
+		"Equipment":           common.FormatEquipment(req.AvailableEquipment),
+		"CookingMethod":       cm,
+		"DietaryRestrictions": strings.Join(req.Preference.DietaryRestrictions, "、"),
+		"ServingSize":         ss,
+		"PreviousRecipe":      previousRecipe,
+		"SessionToken":        fmt.Sprintf("SessionToken:%d", time.Now().UnixNano()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	common.LogDebug("SuggestRecipes 組裝的 prompt", zap.String("prompt", prompt.Text))
+
+	var resp *service.Response
+	if onDelta != nil {
+		resp, err = s.aiService.ProcessStructuredStream(ctx, prompt, "", recipeFormat, onDelta)
+	} else {
+		resp, err = s.aiService.ProcessStructured(ctx, prompt, "", recipeFormat)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("AI service error: %w", err)
 	}
@@ -202,30 +131,14 @@ func (s *SuggestionService) SuggestRecipes(ctx context.Context, req *common.Reci
 		return nil, fmt.Errorf("empty AI response")
 	}
 
-	content := strings.TrimSpace(resp.Content)
-	// 去除 markdown/fence：取第一個 { 到最後一個 }
-	if start, end := strings.Index(content, "{"), strings.LastIndex(content, "}"); start != -1 && end != -1 && end > start {
-		content = content[start : end+1]
-	}
+	// 去除 markdown/fence 與未加引號的鍵
+	content := common.ExtractJSON(resp.Content)
 
 	// 先用「寬鬆版」解析，忽略 ar_parameters 內的型別雜訊
 	var lr looseRecipe
 	if err := common.ParseJSON(content, &lr); err != nil {
-		fixed := common.QuoteJSONKeys(content)
-		if fixed != content {
-			if ferr := common.ParseJSON(fixed, &lr); ferr == nil {
-				common.LogWarn("AI 回傳 JSON 含未加引號鍵，已於 suggestion 流程自動修正",
-					zap.Int("ai_response_length", len(content)),
-				)
-				content = fixed
-			} else {
-				common.LogError("AI 回應解析失敗(loose)", zap.Error(err), zap.Int("ai_response_length", len(content)))
-				return nil, fmt.Errorf("failed to parse AI response (loose): %w", err)
-			}
-		} else {
-			common.LogError("AI 回應解析失敗(loose)", zap.Error(err), zap.Int("ai_response_length", len(content)))
-			return nil, fmt.Errorf("failed to parse AI response (loose): %w", err)
-		}
+		common.LogError("AI 回應解析失敗(loose)", zap.Error(err), zap.Int("ai_response_length", len(content)))
+		return nil, fmt.Errorf("failed to parse AI response (loose): %w", err)
 	}
 
 	// 將寬鬆版轉成正式的 common.Recipe（包含 ARtype / ar_parameters，若缺漏會後續補齊）
@@ -338,6 +251,7 @@ func (s *SuggestionService) SuggestRecipes(ctx context.Context, req *common.Reci
 	}
 
 	containerChoices := inferContainerChoices(result.Equipment)
+	fallbacks := 0
 
 	for i := range result.Recipe {
 		params := result.Recipe[i].ARParameters
@@ -367,6 +281,7 @@ func (s *SuggestionService) SuggestRecipes(ctx context.Context, req *common.Reci
 			)
 		}
 
+		fallbacks++
 		fallback, ferr := fallbackARParams(result.Recipe[i], containerChoices, result.Ingredients)
 		if ferr != nil {
 			common.LogWarn("AR 參數回退失敗，採用預設值",
@@ -387,6 +302,10 @@ func (s *SuggestionService) SuggestRecipes(ctx context.Context, req *common.Reci
 		result.Recipe[i].ARtype = fallback.Type
 		result.Recipe[i].ARParameters = fallback
 	}
+	// 快取命中的內容已在產生時記錄過
+	if resp.Provider != "cache" {
+		experiment.ObserveAR(ctx, prompt.ID, len(result.Recipe), fallbacks)
+	}
 
 	for i := range result.Recipe {
 		if len(result.Recipe[i].Actions) > 1 {
@@ -468,6 +387,11 @@ func inferContainerChoices(eqs []common.Equipment) []string {
 	return out
 }
 
+// ValidateARParams 以與食譜服務相同的規則檢查 AR 參數，供離線評估使用
+func ValidateARParams(p common.ARActionParams) error {
+	return validateARParams(p)
+}
+
 // 嚴格驗證（加入 ARtype 白名單）
 func validateARParams(p common.ARActionParams) error {
 	if p.Type == "" {
@@ -1,35 +1,84 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
+	"time"
 
+	"recipe-generator/internal/core/ai/provider"
 	"recipe-generator/internal/infrastructure/config"
 	"recipe-generator/internal/pkg/common"
+	"recipe-generator/internal/pkg/httpfixture"
 
 	"github.com/go-resty/resty/v2"
 	"go.uber.org/zap"
 )
 
+const (
+	// ProviderName OpenRouter 提供者註冊名稱
+	ProviderName = "openrouter"
+
+	defaultBaseURL = "https://openrouter.ai/api/v1"
+)
+
+func init() {
+	provider.Register(ProviderName, func(cfg provider.Config) (provider.Provider, error) {
+		return NewOpenRouterProvider(cfg), nil
+	})
+}
+
 // OpenRouterService OpenRouter 服務
 type OpenRouterService struct {
-	config *config.Config
+	config provider.Config
 	client *resty.Client
+	retry  provider.RetryPolicy
+	// stream 串流請求不設整體超時，改由請求 context 控制
+	stream *http.Client
 }
 
 // NewOpenRouterService 創建 OpenRouter 服務
 func NewOpenRouterService(cfg *config.Config) *OpenRouterService {
+	return NewOpenRouterProvider(provider.Config{
+		APIKey:     cfg.OpenRouter.APIKey,
+		Model:      cfg.OpenRouter.Model,
+		MaxTokens:  cfg.OpenRouter.MaxTokens,
+		Timeout:    cfg.OpenRouter.Timeout,
+		BaseURL:    cfg.OpenRouter.BaseURL,
+		MaxRetries: cfg.OpenRouter.MaxRetries,
+		JSONSchema: cfg.OpenRouter.JSONSchema,
+
+		FixtureMode: cfg.AI.Fixtures.Mode,
+		FixtureDir:  cfg.AI.Fixtures.Dir,
+	})
+}
+
+// NewOpenRouterProvider 依提供者設定創建 OpenRouter 服務
+func NewOpenRouterProvider(cfg provider.Config) *OpenRouterService {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultBaseURL
+	}
+
 	client := resty.New().
-		SetBaseURL("https://openrouter.ai/api/v1").
-		SetHeader("Authorization", fmt.Sprintf("Bearer %s", cfg.OpenRouter.APIKey)).
+		SetBaseURL(cfg.BaseURL).
+		SetHeader("Authorization", fmt.Sprintf("Bearer %s", cfg.APIKey)).
 		SetHeader("HTTP-Referer", "https://recipe-generator.com").
 		SetHeader("X-Title", "Recipe Generator")
+	if cfg.Timeout > 0 {
+		client.SetTimeout(cfg.Timeout)
+	}
+	// 錄製或重播模式下經由 fixture 送出請求，串流請求共用同一個 transport
+	client.SetTransport(httpfixture.Wrap(cfg.FixtureMode, cfg.FixtureDir, client.GetClient().Transport))
 
 	return &OpenRouterService{
 		config: cfg,
 		client: client,
+		retry:  provider.DefaultRetryPolicy(cfg.MaxRetries),
+		stream: &http.Client{Transport: client.GetClient().Transport},
 	}
 }
 
@@ -40,64 +89,164 @@ func (s *OpenRouterService) GenerateResponse(ctx context.Context, prompt string,
 	simplePrompt = strings.ReplaceAll(simplePrompt, "\n", "")
 	simplePrompt = strings.Join(strings.Fields(simplePrompt), "")
 
-	msgContent := []map[string]interface{}{
-		{
-			"type": "text",
-			"text": simplePrompt,
-		},
-	}
-	imageUrlDebug := ""
+	msg := provider.Message{Role: "user", Content: simplePrompt}
 	if imageData != "" {
-		url := imageData
-		if !strings.HasPrefix(imageData, "data:image/") {
-			url = fmt.Sprintf("data:image/jpeg;base64,%s", imageData)
+		msg.Images = []string{imageData}
+	}
+
+	resp, err := s.Generate(ctx, &provider.Request{Messages: []provider.Message{msg}})
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// Generate 實作 provider.Provider
+func (s *OpenRouterService) Generate(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	body := s.buildBody(req)
+
+	var out *provider.Response
+	err := s.retry.Do(ctx, "openrouter.generate", func(attempt int) error {
+		var err error
+		out, err = s.send(ctx, body)
+		if err != nil {
+			common.LogWarn("OpenRouter 請求失敗",
+				zap.String("model", provider.ModelFor(s, req)),
+				zap.Int("attempt", attempt),
+				zap.Error(err),
+			)
 		}
-		msgContent = append(msgContent, map[string]interface{}{
-			"type": "image_url",
-			"image_url": map[string]string{
-				"url": url,
-			},
-		})
-		imageUrlDebug = url
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	// debug log image_url 前 60 字元與是否有 data:image/ 前綴
-	if imageUrlDebug != "" {
-		prefix := ""
-		if strings.HasPrefix(imageUrlDebug, "data:image/") {
-			prefix = "[HAS_PREFIX]"
-		} else {
-			prefix = "[NO_PREFIX]"
+	return out, nil
+}
+
+// GenerateStream 實作 provider.StreamProvider，以 "stream": true 呼叫 OpenRouter
+// 已轉發內容後中斷的串流不會重試
+func (s *OpenRouterService) GenerateStream(ctx context.Context, req *provider.Request, onDelta provider.DeltaFunc) (*provider.Response, error) {
+	body := s.buildBody(req)
+	body["stream"] = true
+
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var out *provider.Response
+	err = s.retry.Do(ctx, "openrouter.stream", func(attempt int) error {
+		emitted := false
+		forward := func(delta string) error {
+			emitted = true
+			return onDelta(delta)
+		}
+
+		var err error
+		out, err = s.sendStream(ctx, reqBody, forward)
+		if err != nil {
+			common.LogWarn("OpenRouter 串流請求失敗",
+				zap.String("model", provider.ModelFor(s, req)),
+				zap.Int("attempt", attempt),
+				zap.Bool("emitted", emitted),
+				zap.Error(err),
+			)
+			if emitted {
+				return provider.Permanent(err)
+			}
 		}
-		if len(imageUrlDebug) > 60 {
-			common.LogDebug("OpenRouter image_url debug", zap.String("prefix", prefix), zap.String("image_url_start", imageUrlDebug[:60]))
-		} else {
-			common.LogDebug("OpenRouter image_url debug", zap.String("prefix", prefix), zap.String("image_url_start", imageUrlDebug))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// sendStream 發送單次串流請求並逐段轉發內容
+func (s *OpenRouterService) sendStream(ctx context.Context, reqBody []byte, onDelta provider.DeltaFunc) (*provider.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.BaseURL+"/chat/completions", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.config.APIKey))
+	httpReq.Header.Set("HTTP-Referer", "https://recipe-generator.com")
+	httpReq.Header.Set("X-Title", "Recipe Generator")
+
+	resp, err := s.stream.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to OpenRouter: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &provider.StatusError{
+			Provider:   "OpenRouter",
+			StatusCode: resp.StatusCode,
+			Body:       string(errBody),
+			RetryAfter: provider.ParseRetryAfter(resp.Header.Get("Retry-After")),
 		}
 	}
+
+	return provider.ReadChatStream(resp.Body, onDelta)
+}
+
+// buildBody 構建 chat/completions 請求內容
+func (s *OpenRouterService) buildBody(req *provider.Request) map[string]interface{} {
+	messages := make([]map[string]interface{}, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		messages = append(messages, map[string]interface{}{
+			"role":    m.Role,
+			"content": buildContentParts(m),
+		})
+	}
+
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = s.config.MaxTokens
+	}
+
 	// 構建請求
-	req := map[string]interface{}{
-		"model": s.config.OpenRouter.Model,
-		"messages": []map[string]interface{}{
-			{
-				"role":    "user",
-				"content": msgContent,
-			},
-		},
-		"max_tokens": s.config.OpenRouter.MaxTokens,
+	body := map[string]interface{}{
+		"model":      provider.ModelFor(s, req),
+		"messages":   messages,
+		"max_tokens": maxTokens,
+	}
+	if req.Temperature != nil {
+		body["temperature"] = *req.Temperature
+	}
+	if len(req.Stop) > 0 {
+		body["stop"] = req.Stop
+	}
+	// 模型不支援時 OpenRouter 會忽略此參數，由服務層的驗證與修正補足
+	if s.config.JSONSchema && req.ResponseFormat != nil {
+		body["response_format"] = req.ResponseFormat.OpenAIFormat()
 	}
+	return body
+}
 
-	// 發送請求
+// send 發送單次請求並解析回應
+func (s *OpenRouterService) send(ctx context.Context, body map[string]interface{}) (*provider.Response, error) {
 	resp, err := s.client.R().
 		SetContext(ctx).
-		SetBody(req).
+		SetBody(body).
 		Post("/chat/completions")
 
 	if err != nil {
-		return "", fmt.Errorf("failed to send request to OpenRouter: %w", err)
+		return nil, fmt.Errorf("failed to send request to OpenRouter: %w", err)
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		return "", fmt.Errorf("OpenRouter API returned error: %s", resp.String())
+		return nil, &provider.StatusError{
+			Provider:   "OpenRouter",
+			StatusCode: resp.StatusCode(),
+			Body:       resp.String(),
+			RetryAfter: provider.ParseRetryAfter(resp.Header().Get("Retry-After")),
+		}
 	}
 
 	// 解析回應
@@ -107,15 +256,73 @@ func (s *OpenRouterService) GenerateResponse(ctx context.Context, prompt string,
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		} `json:"usage"`
 	}
 
 	if err := common.ParseJSONBytes(resp.Body(), &result); err != nil {
-		return "", fmt.Errorf("failed to parse OpenRouter response: %w", err)
+		return nil, fmt.Errorf("failed to parse OpenRouter response: %w: %v", provider.ErrUnparseableResponse, err)
 	}
 
 	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("no choices in OpenRouter response")
+		return nil, fmt.Errorf("no choices in OpenRouter response: %w", provider.ErrEmptyChoices)
 	}
 
-	return result.Choices[0].Message.Content, nil
+	out := &provider.Response{Content: result.Choices[0].Message.Content}
+	out.Usage.PromptTokens = result.Usage.PromptTokens
+	out.Usage.CompletionTokens = result.Usage.CompletionTokens
+	out.Usage.TotalTokens = result.Usage.TotalTokens
+	return out, nil
+}
+
+// GetModel 實作 provider.Provider
+func (s *OpenRouterService) GetModel() string {
+	return s.config.Model
+}
+
+// GetTimeout 實作 provider.Provider
+func (s *OpenRouterService) GetTimeout() time.Duration {
+	return s.config.Timeout
+}
+
+// Close 實作 provider.Provider
+func (s *OpenRouterService) Close() error {
+	s.client.GetClient().CloseIdleConnections()
+	return nil
+}
+
+// buildContentParts 將訊息轉為 OpenRouter 的多模態 content 陣列
+func buildContentParts(m provider.Message) []map[string]interface{} {
+	parts := []map[string]interface{}{
+		{
+			"type": "text",
+			"text": m.Content,
+		},
+	}
+	for _, imageData := range m.Images {
+		url := imageData
+		if !strings.HasPrefix(imageData, "data:image/") {
+			url = fmt.Sprintf("data:image/jpeg;base64,%s", imageData)
+		}
+		parts = append(parts, map[string]interface{}{
+			"type": "image_url",
+			"image_url": map[string]string{
+				"url": url,
+			},
+		})
+		// debug log image_url 前 60 字元與是否有 data:image/ 前綴
+		prefix := "[NO_PREFIX]"
+		if strings.HasPrefix(url, "data:image/") {
+			prefix = "[HAS_PREFIX]"
+		}
+		start := url
+		if len(start) > 60 {
+			start = start[:60]
+		}
+		common.LogDebug("OpenRouter image_url debug", zap.String("prefix", prefix), zap.String("image_url_start", start))
+	}
+	return parts
 }
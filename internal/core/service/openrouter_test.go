@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	"recipe-generator/internal/core/ai/provider"
+)
+
+func TestBuildBodyTemperature(t *testing.T) {
+	zero, warm := 0.0, 0.7
+	tests := []struct {
+		name        string
+		temperature *float64
+		want        interface{}
+	}{
+		{"unset", nil, nil},
+		{"zero", &zero, 0.0},
+		{"set", &warm, 0.7},
+	}
+	s := NewOpenRouterProvider(provider.Config{Model: "test"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := s.buildBody(&provider.Request{
+				Messages:    []provider.Message{{Role: "user", Content: "hi"}},
+				Temperature: tt.temperature,
+			})
+			got, ok := body["temperature"]
+			if tt.want == nil {
+				if ok {
+					t.Fatalf("temperature sent: %v", got)
+				}
+				return
+			}
+			if !ok || got != tt.want {
+				t.Fatalf("temperature = %v (present %v), want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
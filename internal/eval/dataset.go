@@ -0,0 +1,177 @@
+// Package eval 離線評估：以黃金資料集（JSONL）經由實際的服務程式碼呼叫設定的 AI 提供者，
+// 為輸出的 JSON 有效性、結構完整度、AR 參數、設備、飲食限制、步驟數與時間評分，
+// 產生可比對的報告，用於把關提示詞修改
+package eval
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"recipe-generator/internal/pkg/common"
+)
+
+// 案例類型，對應四個 AI 端點
+const (
+	KindGenerate   = "generate"   // 依菜名生成食譜
+	KindSuggest    = "suggest"    // 依食材與設備推薦食譜
+	KindFood       = "food"       // 食物圖片辨識
+	KindIngredient = "ingredient" // 食材與設備圖片辨識
+)
+
+// maxLineBytes 資料集單行上限，內嵌 base64 圖片的案例可能很長
+const maxLineBytes = 32 << 20
+
+// Case 資料集中的一筆案例
+type Case struct {
+	ID      string          `json:"id"`
+	Kind    string          `json:"kind"`
+	Request json.RawMessage `json:"request"`
+	Expect  Expect          `json:"expect"`
+
+	generate *GenerateRequest
+	suggest  *common.RecipeByIngredientsRequest
+	image    *ImageRequest
+}
+
+// Expect 案例預期的性質，未指定的項目不評分
+type Expect struct {
+	MinSteps        int      `json:"min_steps,omitempty"`         // 步驟數下限，預設 1
+	MaxSteps        int      `json:"max_steps,omitempty"`         // 步驟數上限，預設 30
+	MaxTotalMinutes int      `json:"max_total_minutes,omitempty"` // 所有動作時間總和上限（分鐘），0 表示不檢查
+	Forbidden       []string `json:"forbidden,omitempty"`         // 飲食限制：不得出現在食材與步驟中的詞
+	Equipment       []string `json:"equipment,omitempty"`         // 允許使用的設備，推薦食譜未指定時為請求中的 available_equipment
+	Items           []string `json:"items,omitempty"`             // 辨識結果應包含的食物、食材或設備名稱
+}
+
+// GenerateRequest 依菜名生成食譜的案例請求
+type GenerateRequest struct {
+	DishName    string                   `json:"dish_name"`
+	Ingredients []common.Ingredient      `json:"ingredients"`
+	Preferences common.RecipePreferences `json:"preferences"`
+}
+
+// ImageRequest 圖片辨識的案例請求
+type ImageRequest struct {
+	Image           string `json:"image"`            // 圖片檔路徑（相對於資料集所在目錄）、data URI、base64 或網址
+	DescriptionHint string `json:"description_hint"` // 只用於食物辨識
+}
+
+// LoadDataset 讀取 JSONL 資料集，空行與 # 開頭的行略過；案例 ID 不可重複
+func LoadDataset(path string) ([]*Case, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+	dir := filepath.Dir(path)
+
+	var cases []*Case
+	ids := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		c := &Case{}
+		if err := json.Unmarshal([]byte(text), c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := c.prepare(dir); err != nil {
+			return nil, fmt.Errorf("line %d (%s): %w", line, c.ID, err)
+		}
+		if ids[c.ID] {
+			return nil, fmt.Errorf("line %d: duplicate case id %q", line, c.ID)
+		}
+		ids[c.ID] = true
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+	if len(cases) == 0 {
+		return nil, errors.New("dataset is empty")
+	}
+	return cases, nil
+}
+
+// prepare 依案例類型解析請求並檢查預期值，圖片檔讀入為 base64
+func (c *Case) prepare(dir string) error {
+	if c.ID == "" {
+		return errors.New("id is required")
+	}
+	if len(c.Request) == 0 {
+		return errors.New("request is required")
+	}
+
+	switch c.Kind {
+	case KindGenerate:
+		c.generate = &GenerateRequest{}
+		if err := json.Unmarshal(c.Request, c.generate); err != nil {
+			return fmt.Errorf("invalid request: %w", err)
+		}
+		if c.generate.DishName == "" {
+			return errors.New("request.dish_name is required")
+		}
+	case KindSuggest:
+		c.suggest = &common.RecipeByIngredientsRequest{}
+		if err := json.Unmarshal(c.Request, c.suggest); err != nil {
+			return fmt.Errorf("invalid request: %w", err)
+		}
+		if len(c.suggest.AvailableIngredients) == 0 {
+			return errors.New("request.available_ingredients is required")
+		}
+	case KindFood, KindIngredient:
+		c.image = &ImageRequest{}
+		if err := json.Unmarshal(c.Request, c.image); err != nil {
+			return fmt.Errorf("invalid request: %w", err)
+		}
+		img, err := loadImage(dir, c.image.Image)
+		if err != nil {
+			return err
+		}
+		c.image.Image = img
+	default:
+		return fmt.Errorf("unknown kind %q", c.Kind)
+	}
+
+	if c.Expect.MinSteps < 0 || c.Expect.MaxSteps < 0 || c.Expect.MaxTotalMinutes < 0 {
+		return errors.New("expect values must not be negative")
+	}
+	if c.Expect.MaxSteps > 0 && c.Expect.MinSteps > c.Expect.MaxSteps {
+		return errors.New("expect.min_steps is greater than expect.max_steps")
+	}
+	return nil
+}
+
+// loadImage 網址、data URI 原樣保留；含副檔名（base64 不含句點）時視為圖片檔路徑讀入，其餘視為 base64
+func loadImage(dir, image string) (string, error) {
+	image = strings.TrimSpace(image)
+	if image == "" {
+		return "", errors.New("request.image is required")
+	}
+	if strings.HasPrefix(image, "data:") || strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://") {
+		return image, nil
+	}
+	if !strings.Contains(image, ".") {
+		return image, nil
+	}
+
+	path := image
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,192 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// Metric 單一評分項目的通過數與比率，Total 為適用的案例數（AR 為步驟數、辨識為預期項目數）
+type Metric struct {
+	Passed int     `json:"passed"`
+	Total  int     `json:"total"`
+	Rate   float64 `json:"rate"` // 四捨五入到小數點後 4 位，Total 為 0 時為 0
+}
+
+func (m *Metric) add(ok *bool) {
+	if ok == nil {
+		return
+	}
+	m.Total++
+	if *ok {
+		m.Passed++
+	}
+}
+
+func (m *Metric) finish() {
+	if m.Total > 0 {
+		m.Rate = math.Round(float64(m.Passed)/float64(m.Total)*10000) / 10000
+	}
+}
+
+// Summary 所有案例的彙總
+type Summary struct {
+	Cases   int `json:"cases"`
+	Errors  int `json:"errors"`  // 服務回傳錯誤的案例數
+	Calls   int `json:"calls"`   // 上游呼叫總數
+	Repairs int `json:"repairs"` // 超出每個案例一次的呼叫數（修正請求與重試）
+
+	Passed         Metric `json:"passed"`
+	JSONStrict     Metric `json:"json_strict"`
+	JSONValid      Metric `json:"json_valid"`
+	SchemaComplete Metric `json:"schema_complete"`
+	SchemaRepaired Metric `json:"schema_repaired"`
+	ARValid        Metric `json:"ar_valid"`
+	StepCount      Metric `json:"step_count"`
+	TimeSanity     Metric `json:"time_sanity"`
+	Equipment      Metric `json:"equipment"`
+	Dietary        Metric `json:"dietary"`
+	Recall         Metric `json:"recall"`
+}
+
+// namedMetric 評分項目名稱與彙總
+type namedMetric struct {
+	name   string
+	metric *Metric
+}
+
+// metrics 依報告順序列出評分項目，用於輸出與比對
+func (s *Summary) metrics() []namedMetric {
+	return []namedMetric{
+		{"passed", &s.Passed},
+		{"json_strict", &s.JSONStrict},
+		{"json_valid", &s.JSONValid},
+		{"schema_complete", &s.SchemaComplete},
+		{"schema_repaired", &s.SchemaRepaired},
+		{"ar_valid", &s.ARValid},
+		{"step_count", &s.StepCount},
+		{"time_sanity", &s.TimeSanity},
+		{"equipment", &s.Equipment},
+		{"dietary", &s.Dietary},
+		{"recall", &s.Recall},
+	}
+}
+
+// Report 評估報告：不含時間戳記與延遲，相同輸出的兩次執行產生相同的報告，可直接以 diff 比對
+type Report struct {
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+	Templates []string  `json:"templates"` // 案例使用的提示詞模板與版本
+	Summary   Summary   `json:"summary"`
+	Cases     []*Result `json:"cases"`
+}
+
+// NewReport 彙總案例結果
+func NewReport(providerName, model string, results []*Result) *Report {
+	r := &Report{Provider: providerName, Model: model, Templates: []string{}, Cases: results}
+	templates := make(map[string]bool)
+	s := &r.Summary
+	for _, res := range results {
+		s.Cases++
+		if res.Error != "" {
+			s.Errors++
+		}
+		s.Calls += res.Calls
+		if res.Calls > 1 {
+			s.Repairs += res.Calls - 1
+		}
+		if res.Template != "" && !templates[res.Template] {
+			templates[res.Template] = true
+			r.Templates = append(r.Templates, res.Template)
+		}
+
+		passed := res.Passed
+		s.Passed.add(&passed)
+		s.JSONStrict.add(res.JSONStrict)
+		s.JSONValid.add(res.JSONValid)
+		s.SchemaComplete.add(res.SchemaComplete)
+		s.SchemaRepaired.add(res.SchemaRepaired)
+		s.ARValid.Passed += res.ARValid
+		s.ARValid.Total += res.ARSteps
+		s.StepCount.add(res.StepsOK)
+		s.TimeSanity.add(res.TimeOK)
+		s.Equipment.add(res.EquipmentOK)
+		s.Dietary.add(res.DietaryOK)
+		s.Recall.Passed += res.Found
+		s.Recall.Total += res.Found + len(res.Missing)
+	}
+	for _, m := range s.metrics() {
+		m.metric.finish()
+	}
+	sort.Strings(r.Templates)
+	return r
+}
+
+// LoadReport 讀取先前產生的報告作為比較基準
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse report: %w", err)
+	}
+	return &r, nil
+}
+
+// Write 以縮排 JSON 寫出報告
+func (r *Report) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// PrintSummary 輸出可閱讀的彙總
+func (r *Report) PrintSummary(w io.Writer) {
+	s := &r.Summary
+	fmt.Fprintf(w, "provider: %s (%s)\n", r.Provider, r.Model)
+	fmt.Fprintf(w, "cases: %d, errors: %d, calls: %d, repairs: %d\n", s.Cases, s.Errors, s.Calls, s.Repairs)
+	for _, m := range s.metrics() {
+		if m.metric.Total == 0 {
+			fmt.Fprintf(w, "  %-16s -\n", m.name)
+			continue
+		}
+		fmt.Fprintf(w, "  %-16s %6.2f%%  (%d/%d)\n", m.name, m.metric.Rate*100, m.metric.Passed, m.metric.Total)
+	}
+	for _, res := range r.Cases {
+		if !res.Passed {
+			fmt.Fprintf(w, "  FAIL %s (%s)\n", res.ID, res.Kind)
+		}
+	}
+}
+
+// Compare 與基準報告比較，回傳比率下降超過 tolerance 的項目，以及基準通過但這次失敗的案例；
+// 任一方沒有適用案例的項目不比較
+func Compare(baseline, current *Report, tolerance float64) (regressions, newlyFailing []string) {
+	base := baseline.Summary.metrics()
+	for i, m := range current.Summary.metrics() {
+		b := base[i].metric
+		if b.Total == 0 || m.metric.Total == 0 {
+			continue
+		}
+		if m.metric.Rate < b.Rate-tolerance {
+			regressions = append(regressions, fmt.Sprintf("%s: %.4f -> %.4f", m.name, b.Rate, m.metric.Rate))
+		}
+	}
+
+	passed := make(map[string]bool)
+	for _, res := range baseline.Cases {
+		passed[res.ID] = res.Passed
+	}
+	for _, res := range current.Cases {
+		if passed[res.ID] && !res.Passed {
+			newlyFailing = append(newlyFailing, res.ID)
+		}
+	}
+	return regressions, newlyFailing
+}
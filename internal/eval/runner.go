@@ -0,0 +1,154 @@
+package eval
+
+import (
+	"context"
+	"sync"
+
+	"recipe-generator/internal/core/ai/image"
+	"recipe-generator/internal/core/ai/provider"
+	"recipe-generator/internal/core/ai/service"
+	imagecore "recipe-generator/internal/core/image"
+	"recipe-generator/internal/core/prompt"
+	"recipe-generator/internal/core/recipe"
+	"recipe-generator/internal/infrastructure/config"
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+// Runner 以實際的服務程式碼執行案例，每個案例都直接呼叫提供者
+type Runner struct {
+	recipes     *recipe.RecipeService
+	suggestions *recipe.SuggestionService
+	foods       *recipe.FoodService
+	ingredients *recipe.IngredientService
+	images      *image.Processor
+	concurrency int
+}
+
+// NewRunner 以提供者與提示詞模板建立服務；評估時關閉快取、語意快取、感知雜湊快取、
+// 請求合併與限流，每個案例的輸出都來自一次新的上游呼叫（含修正請求）
+func NewRunner(cfg *config.Config, p provider.Provider, prompts *prompt.Registry, concurrency int) *Runner {
+	c := *cfg
+	c.Cache.Enabled = false
+	c.Cache.Semantic.Enabled = false
+	c.Cache.Perceptual.Enabled = false
+	c.AI.Coalesce = false
+	c.RateLimit.Enabled = false
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ai := service.NewServiceWithProvider(&c, nil, recorder{p})
+	images := image.NewProcessor(imagecore.NewService(c.Image))
+	imageCache := recipe.NewImageCache(c.Cache.Perceptual, nil, nil)
+
+	return &Runner{
+		recipes:     recipe.NewRecipeService(ai, nil, c.Cache.Semantic, prompts),
+		suggestions: recipe.NewSuggestionService(ai, nil, prompts),
+		foods:       recipe.NewFoodService(ai, nil, imageCache, c.Recognition, prompts),
+		ingredients: recipe.NewIngredientService(ai, nil, images, imageCache, c.Recognition, prompts),
+		images:      images,
+		concurrency: concurrency,
+	}
+}
+
+// Run 執行所有案例，結果依資料集順序排列
+func (r *Runner) Run(ctx context.Context, cases []*Case) []*Result {
+	results := make([]*Result, len(cases))
+	sem := make(chan struct{}, r.concurrency)
+	var wg sync.WaitGroup
+	for i, c := range cases {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, c *Case) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = r.runCase(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+	return results
+}
+
+// runCase 執行單一案例並評分
+func (r *Runner) runCase(ctx context.Context, c *Case) *Result {
+	ctx, meta := provider.WithMetadata(ctx)
+	rec := &capture{}
+	ctx = context.WithValue(ctx, captureKey{}, rec)
+
+	out, err := r.call(ctx, c)
+	if err != nil {
+		common.LogWarn("評估案例執行失敗", zap.String("case", c.ID), zap.Error(err))
+	}
+	res := score(c, out, rec.snapshot(), err)
+	res.Template = meta.Template()
+	return res
+}
+
+// call 依案例類型呼叫對應的服務，圖片與處理程序相同先經過前處理
+func (r *Runner) call(ctx context.Context, c *Case) (interface{}, error) {
+	switch c.Kind {
+	case KindGenerate:
+		return r.recipes.GenerateRecipe(ctx, c.generate.DishName, c.generate.Ingredients, c.generate.Preferences)
+	case KindSuggest:
+		return r.suggestions.SuggestRecipes(ctx, c.suggest)
+	case KindFood:
+		img, err := r.images.FormatImageData(ctx, c.image.Image)
+		if err != nil {
+			return nil, err
+		}
+		return r.foods.IdentifyFood(ctx, img, c.image.DescriptionHint)
+	default:
+		img, err := r.images.FormatImageData(ctx, c.image.Image)
+		if err != nil {
+			return nil, err
+		}
+		return r.ingredients.IdentifyIngredient(ctx, img)
+	}
+}
+
+// recorder 包裝提供者，把每次上游回應記錄到案例的 context 中；
+// 只實作 provider.Provider，評估不使用串流
+type recorder struct {
+	provider.Provider
+}
+
+// Generate 實作 provider.Provider
+func (r recorder) Generate(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	resp, err := r.Provider.Generate(ctx, req)
+	if c, ok := ctx.Value(captureKey{}).(*capture); ok {
+		c.add(resp, err)
+	}
+	return resp, err
+}
+
+type captureKey struct{}
+
+// capture 單一案例的上游呼叫紀錄
+type capture struct {
+	mu        sync.Mutex
+	calls     int
+	responses []string // 成功呼叫的回應內容，第一筆為原始回應，其後為修正請求的回應
+}
+
+func (c *capture) add(resp *provider.Response, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if err == nil && resp != nil {
+		c.responses = append(c.responses, resp.Content)
+	}
+}
+
+// record 案例的呼叫次數與回應內容
+type record struct {
+	calls     int
+	responses []string
+}
+
+func (c *capture) snapshot() record {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return record{calls: c.calls, responses: append([]string(nil), c.responses...)}
+}
@@ -0,0 +1,273 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"recipe-generator/internal/core/recipe"
+	"recipe-generator/internal/pkg/common"
+	"recipe-generator/internal/pkg/jsonschema"
+)
+
+// 步驟數與動作時間的預設合理範圍；動作時間以秒為單位（與提示詞一致）
+const (
+	defaultMinSteps  = 1
+	defaultMaxSteps  = 30
+	maxActionSeconds = 4 * 60 * 60
+)
+
+// schemas 各案例類型的回應結構，與服務要求 AI 回傳的 JSON Schema 相同由回應型別推導
+var schemas = map[string]*jsonschema.Schema{
+	KindGenerate:   jsonschema.For(common.Recipe{}),
+	KindSuggest:    jsonschema.For(common.Recipe{}),
+	KindFood:       jsonschema.For(common.FoodRecognitionResult{}),
+	KindIngredient: jsonschema.For(common.IngredientRecognitionResult{}),
+}
+
+// Result 單一案例的評分，不適用的項目省略
+type Result struct {
+	ID       string `json:"id"`
+	Kind     string `json:"kind"`
+	Template string `json:"template,omitempty"` // 使用的提示詞模板與版本
+	Passed   bool   `json:"passed"`             // 沒有錯誤且所有適用項目皆通過
+	Error    string `json:"error,omitempty"`
+	Calls    int    `json:"calls"` // 上游呼叫次數，超過 1 表示有修正請求或重試
+
+	// 模型輸出：JSON 有效性與結構以第一次回應評分，AR 參數以服務最後採用的回應評分（回退前）
+	JSONStrict     *bool    `json:"json_strict,omitempty"`     // 回應本身即為 JSON，不需去除說明文字、Markdown 或補上引號
+	JSONValid      *bool    `json:"json_valid,omitempty"`      // 經 ExtractJSON 後為有效 JSON
+	SchemaComplete *bool    `json:"schema_complete,omitempty"` // 符合 JSON Schema
+	SchemaErrors   []string `json:"schema_errors,omitempty"`
+	SchemaRepaired *bool    `json:"schema_repaired,omitempty"` // 最後一次回應（含修正）符合 JSON Schema
+	ARSteps        int      `json:"ar_steps,omitempty"`
+	ARValid        int      `json:"ar_valid,omitempty"`
+	ARInvalid      []string `json:"ar_invalid,omitempty"`
+
+	// 服務最終輸出
+	Steps          int      `json:"steps,omitempty"`
+	StepsOK        *bool    `json:"steps_ok,omitempty"`
+	TotalSeconds   int      `json:"total_seconds,omitempty"`
+	TimeOK         *bool    `json:"time_ok,omitempty"`
+	TimeIssues     []string `json:"time_issues,omitempty"`
+	EquipmentOK    *bool    `json:"equipment_ok,omitempty"`
+	ExtraEquipment []string `json:"extra_equipment,omitempty"` // 使用了未提供的設備
+	DietaryOK      *bool    `json:"dietary_ok,omitempty"`
+	Violations     []string `json:"violations,omitempty"` // 出現的禁用詞與位置
+	Found          int      `json:"found,omitempty"`
+	Missing        []string `json:"missing,omitempty"` // 辨識結果缺少的預期項目
+}
+
+// score 以上游回應與服務輸出為案例評分
+func score(c *Case, out interface{}, rec record, err error) *Result {
+	res := &Result{ID: c.ID, Kind: c.Kind, Calls: rec.calls}
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	if len(rec.responses) > 0 {
+		scoreModelOutput(res, c, rec.responses[0], rec.responses[len(rec.responses)-1])
+	}
+
+	switch v := out.(type) {
+	case *common.Recipe:
+		if v != nil {
+			scoreRecipe(res, c, v)
+		}
+	case *common.FoodRecognitionResult:
+		if v != nil {
+			var names []string
+			for _, f := range v.RecognizedFoods {
+				names = append(names, f.Name)
+			}
+			scoreItems(res, c.Expect.Items, names)
+		}
+	case *common.IngredientRecognitionResult:
+		if v != nil {
+			var names []string
+			for _, ing := range v.Ingredients {
+				names = append(names, ing.Name)
+			}
+			for _, eq := range v.Equipment {
+				names = append(names, eq.Name)
+			}
+			scoreItems(res, c.Expect.Items, names)
+		}
+	}
+
+	res.Passed = res.passed()
+	return res
+}
+
+// scoreModelOutput 評分 JSON 有效性、結構完整度與 AR 參數
+func scoreModelOutput(res *Result, c *Case, first, last string) {
+	res.JSONStrict = boolPtr(json.Valid([]byte(strings.TrimSpace(first))))
+	extracted := common.ExtractJSON(first)
+	res.JSONValid = boolPtr(json.Valid([]byte(extracted)))
+
+	schema := schemas[c.Kind]
+	errs := schema.Validate([]byte(extracted))
+	res.SchemaComplete = boolPtr(len(errs) == 0)
+	res.SchemaErrors = errs.Strings()
+	res.SchemaRepaired = boolPtr(len(schema.Validate([]byte(common.ExtractJSON(last)))) == 0)
+
+	if c.Kind == KindGenerate || c.Kind == KindSuggest {
+		scoreAR(res, common.ExtractJSON(last))
+	}
+}
+
+// scoreAR 以食譜服務相同的規則檢查每個步驟的 ar_parameters；回應無法解析時不評分
+func scoreAR(res *Result, content string) {
+	var raw struct {
+		Recipe []struct {
+			ARParameters json.RawMessage `json:"ar_parameters"`
+		} `json:"recipe"`
+	}
+	if err := json.Unmarshal([]byte(content), &raw); err != nil {
+		return
+	}
+
+	for i, step := range raw.Recipe {
+		res.ARSteps++
+		var params *common.ARActionParams
+		if len(step.ARParameters) == 0 {
+			res.ARInvalid = append(res.ARInvalid, fmt.Sprintf("step %d: missing ar_parameters", i+1))
+			continue
+		}
+		if err := json.Unmarshal(step.ARParameters, &params); err != nil {
+			res.ARInvalid = append(res.ARInvalid, fmt.Sprintf("step %d: %v", i+1, err))
+			continue
+		}
+		if params == nil {
+			res.ARInvalid = append(res.ARInvalid, fmt.Sprintf("step %d: missing ar_parameters", i+1))
+			continue
+		}
+		if err := recipe.ValidateARParams(*params); err != nil {
+			res.ARInvalid = append(res.ARInvalid, fmt.Sprintf("step %d: %v", i+1, err))
+			continue
+		}
+		res.ARValid++
+	}
+}
+
+// scoreRecipe 評分步驟數、動作時間、設備與飲食限制
+func scoreRecipe(res *Result, c *Case, r *common.Recipe) {
+	minSteps, maxSteps := c.Expect.MinSteps, c.Expect.MaxSteps
+	if minSteps == 0 {
+		minSteps = defaultMinSteps
+	}
+	if maxSteps == 0 {
+		maxSteps = defaultMaxSteps
+	}
+	res.Steps = len(r.Recipe)
+	res.StepsOK = boolPtr(res.Steps >= minSteps && res.Steps <= maxSteps)
+
+	for i, step := range r.Recipe {
+		if len(step.Actions) == 0 {
+			res.TimeIssues = append(res.TimeIssues, fmt.Sprintf("step %d: no actions", i+1))
+		}
+		for j, action := range step.Actions {
+			if action.TimeMinutes < 1 || action.TimeMinutes > maxActionSeconds {
+				res.TimeIssues = append(res.TimeIssues, fmt.Sprintf("step %d action %d: %ds", i+1, j+1, action.TimeMinutes))
+			}
+			res.TotalSeconds += action.TimeMinutes
+		}
+	}
+	if limit := c.Expect.MaxTotalMinutes * 60; limit > 0 && res.TotalSeconds > limit {
+		res.TimeIssues = append(res.TimeIssues, fmt.Sprintf("total %ds exceeds %ds", res.TotalSeconds, limit))
+	}
+	res.TimeOK = boolPtr(len(res.TimeIssues) == 0)
+
+	allowed := c.Expect.Equipment
+	if len(allowed) == 0 && c.suggest != nil {
+		for _, eq := range c.suggest.AvailableEquipment {
+			allowed = append(allowed, eq.Name)
+		}
+	}
+	if len(allowed) > 0 {
+		for _, eq := range r.Equipment {
+			if !matchAny(eq.Name, allowed) {
+				res.ExtraEquipment = append(res.ExtraEquipment, eq.Name)
+			}
+		}
+		res.EquipmentOK = boolPtr(len(res.ExtraEquipment) == 0)
+	}
+
+	if len(c.Expect.Forbidden) > 0 {
+		res.Violations = dietaryViolations(r, c.Expect.Forbidden)
+		res.DietaryOK = boolPtr(len(res.Violations) == 0)
+	}
+}
+
+// dietaryViolations 在食材名稱與步驟內容中查找禁用詞，回傳「位置: 詞」
+func dietaryViolations(r *common.Recipe, forbidden []string) []string {
+	var out []string
+	check := func(where string, texts ...string) {
+		for _, term := range forbidden {
+			for _, text := range texts {
+				if containsFold(text, term) {
+					out = append(out, where+": "+term)
+					break
+				}
+			}
+		}
+	}
+
+	for _, ing := range r.Ingredients {
+		check("ingredient "+ing.Name, ing.Name)
+	}
+	for i, step := range r.Recipe {
+		texts := []string{step.Title, step.Description}
+		for _, action := range step.Actions {
+			texts = append(texts, action.Action, action.InstructionDetail)
+			texts = append(texts, action.MaterialRequired...)
+		}
+		check(fmt.Sprintf("step %d", i+1), texts...)
+	}
+	return out
+}
+
+// scoreItems 計算預期的辨識項目有多少出現在結果中
+func scoreItems(res *Result, expected, names []string) {
+	for _, item := range expected {
+		if matchAny(item, names) {
+			res.Found++
+		} else {
+			res.Missing = append(res.Missing, item)
+		}
+	}
+}
+
+// passed 沒有錯誤且所有適用項目皆通過
+func (r *Result) passed() bool {
+	if r.Error != "" {
+		return false
+	}
+	for _, ok := range []*bool{r.JSONValid, r.SchemaComplete, r.StepsOK, r.TimeOK, r.EquipmentOK, r.DietaryOK} {
+		if ok != nil && !*ok {
+			return false
+		}
+	}
+	return r.ARValid == r.ARSteps && len(r.Missing) == 0
+}
+
+// matchAny 名稱與清單中任一項互相包含即視為相同（忽略大小寫），例如「不沾平底鍋」與「平底鍋」
+func matchAny(name string, list []string) bool {
+	for _, s := range list {
+		if s == "" || name == "" {
+			continue
+		}
+		if containsFold(name, s) || containsFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
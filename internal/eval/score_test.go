@@ -0,0 +1,184 @@
+package eval
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"recipe-generator/internal/core/ai/fake"
+	"recipe-generator/internal/pkg/common"
+)
+
+// recipeOutput 假提供者的固定食譜回應與解析後的服務輸出
+func recipeOutput(t *testing.T) (string, *common.Recipe) {
+	t.Helper()
+	content := fake.Respond("dish_name")
+	var r common.Recipe
+	if err := json.Unmarshal([]byte(content), &r); err != nil {
+		t.Fatal(err)
+	}
+	return content, &r
+}
+
+func TestScoreRecipePasses(t *testing.T) {
+	content, out := recipeOutput(t)
+	c := &Case{ID: "ok", Kind: KindGenerate, Expect: Expect{Equipment: []string{"平底鍋"}, Forbidden: []string{"牛肉"}}}
+	res := score(c, out, record{calls: 1, responses: []string{content}}, nil)
+	if !res.Passed || !*res.JSONStrict || !*res.SchemaComplete || !*res.EquipmentOK || !*res.DietaryOK {
+		t.Fatalf("result: %+v", res)
+	}
+	if res.ARSteps != 3 || res.ARValid != 3 || res.Steps != 3 || res.TotalSeconds != 360 {
+		t.Fatalf("AR %d/%d, steps %d, total %ds", res.ARValid, res.ARSteps, res.Steps, res.TotalSeconds)
+	}
+}
+
+func TestScoreRecipeFlagsViolations(t *testing.T) {
+	content, out := recipeOutput(t)
+
+	// 素食案例禁用雞蛋：食材與提到雞蛋的步驟都列出
+	c := &Case{ID: "vegan", Kind: KindGenerate, Expect: Expect{Forbidden: []string{"雞蛋"}}}
+	res := score(c, out, record{calls: 1, responses: []string{content}}, nil)
+	want := []string{"ingredient 雞蛋: 雞蛋", "step 1: 雞蛋", "step 3: 雞蛋"}
+	if res.Passed || *res.DietaryOK || strings.Join(res.Violations, ",") != strings.Join(want, ",") {
+		t.Fatalf("violations %v, want %v", res.Violations, want)
+	}
+
+	// 推薦食譜未指定設備時以請求中的可用設備為準
+	c = &Case{ID: "wok-only", Kind: KindSuggest}
+	c.suggest = &common.RecipeByIngredientsRequest{AvailableEquipment: []common.Equipment{{Name: "炒鍋"}}}
+	res = score(c, out, record{calls: 1, responses: []string{content}}, nil)
+	if res.Passed || *res.EquipmentOK || len(res.ExtraEquipment) != 1 || res.ExtraEquipment[0] != "平底鍋" {
+		t.Fatalf("equipment: ok=%v extra=%v", *res.EquipmentOK, res.ExtraEquipment)
+	}
+	// 設備名稱互相包含視為相同
+	c.Expect.Equipment = []string{"不沾平底鍋"}
+	if res = score(c, out, record{calls: 1, responses: []string{content}}, nil); !*res.EquipmentOK {
+		t.Fatalf("equipment alias flagged: %v", res.ExtraEquipment)
+	}
+
+	// 總時間上限與步驟數上限
+	c = &Case{ID: "quick", Kind: KindGenerate, Expect: Expect{MaxTotalMinutes: 5, MaxSteps: 2}}
+	res = score(c, out, record{calls: 1, responses: []string{content}}, nil)
+	if res.Passed || *res.TimeOK || *res.StepsOK {
+		t.Fatalf("time ok=%v %v, steps ok=%v", *res.TimeOK, res.TimeIssues, *res.StepsOK)
+	}
+}
+
+func TestScoreModelOutput(t *testing.T) {
+	content, out := recipeOutput(t)
+	c := &Case{ID: "ar", Kind: KindGenerate}
+
+	// 第一次回應包在 Markdown 中，修正後的回應有一個錯誤的 AR 類型
+	fenced := "以下是食譜：\n```json\n" + content + "\n```"
+	invalid := strings.Replace(content, `"type":"cut"`, `"type":"boil"`, 1)
+	res := score(c, out, record{calls: 2, responses: []string{fenced, invalid}}, nil)
+	// AR 類型列舉也在 JSON Schema 中，修正後的回應不符合結構
+	if *res.JSONStrict || !*res.JSONValid || !*res.SchemaComplete || *res.SchemaRepaired {
+		t.Fatalf("json strict=%v valid=%v schema=%v repaired=%v", *res.JSONStrict, *res.JSONValid, *res.SchemaComplete, *res.SchemaRepaired)
+	}
+	if res.Passed || res.ARSteps != 3 || res.ARValid != 2 || len(res.ARInvalid) != 1 || !strings.HasPrefix(res.ARInvalid[0], "step 2: invalid type") {
+		t.Fatalf("AR %d/%d invalid %v", res.ARValid, res.ARSteps, res.ARInvalid)
+	}
+
+	// 缺少 ar_parameters 的步驟同樣計為無效
+	missing := strings.Replace(content, `"ar_parameters":{"type":"beatEgg","container":"bowl","ingredient":null,"color":null,"time":null,"temperature":null,"flameLevel":null},`, "", 1)
+	res = score(c, out, record{calls: 1, responses: []string{missing}}, nil)
+	if res.ARValid != 2 || len(res.ARInvalid) != 1 || res.ARInvalid[0] != "step 1: missing ar_parameters" {
+		t.Fatalf("AR %d/%d invalid %v", res.ARValid, res.ARSteps, res.ARInvalid)
+	}
+
+	// 無法解析的回應與服務錯誤
+	res = score(c, nil, record{calls: 1, responses: []string{"抱歉，無法提供"}}, errors.New("invalid response"))
+	if res.Passed || *res.JSONValid || *res.SchemaComplete || res.ARSteps != 0 || res.Error != "invalid response" {
+		t.Fatalf("result: %+v", res)
+	}
+}
+
+func TestScoreItems(t *testing.T) {
+	var foods common.FoodRecognitionResult
+	if err := json.Unmarshal([]byte(fake.Respond("recognized_foods")), &foods); err != nil {
+		t.Fatal(err)
+	}
+	c := &Case{ID: "food", Kind: KindFood, Expect: Expect{Items: []string{"番茄炒蛋", "白飯"}}}
+	res := score(c, &foods, record{}, nil)
+	if res.Passed || res.Found != 1 || len(res.Missing) != 1 || res.Missing[0] != "白飯" {
+		t.Fatalf("found %d, missing %v", res.Found, res.Missing)
+	}
+
+	ingredients := &common.IngredientRecognitionResult{
+		Ingredients: []common.Ingredient{{Name: "牛番茄"}},
+		Equipment:   []common.Equipment{{Name: "平底鍋"}},
+	}
+	c = &Case{ID: "ingredient", Kind: KindIngredient, Expect: Expect{Items: []string{"番茄", "平底鍋"}}}
+	if res = score(c, ingredients, record{}, nil); !res.Passed || res.Found != 2 {
+		t.Fatalf("found %d, missing %v", res.Found, res.Missing)
+	}
+}
+
+// testReport 以案例 ID 與是否通過建立報告，只統計整體通過率
+func testReport(cases map[string]bool) *Report {
+	var results []*Result
+	for _, id := range []string{"a", "b", "c", "d"} {
+		if passed, ok := cases[id]; ok {
+			results = append(results, &Result{ID: id, Passed: passed, Calls: 1})
+		}
+	}
+	return NewReport("fake", "fake-model", results)
+}
+
+func TestNewReport(t *testing.T) {
+	results := []*Result{
+		{ID: "a", Template: "recipe.generate@v2", Passed: true, Calls: 1, JSONValid: boolPtr(true), ARSteps: 3, ARValid: 3},
+		{ID: "b", Template: "recipe.generate@v2", Calls: 3, JSONValid: boolPtr(false), ARSteps: 3, ARValid: 1, Error: "invalid response"},
+		{ID: "c", Template: "food.recognize@v1", Passed: true, Calls: 1, Found: 2, Missing: []string{"白飯"}},
+	}
+	s := NewReport("fake", "fake-model", results).Summary
+	if s.Cases != 3 || s.Errors != 1 || s.Calls != 5 || s.Repairs != 2 {
+		t.Fatalf("summary: %+v", s)
+	}
+	for name, got := range map[string]Metric{"passed": s.Passed, "json_valid": s.JSONValid, "ar_valid": s.ARValid, "recall": s.Recall} {
+		want := map[string]Metric{
+			"passed":     {Passed: 2, Total: 3, Rate: 0.6667},
+			"json_valid": {Passed: 1, Total: 2, Rate: 0.5},
+			"ar_valid":   {Passed: 4, Total: 6, Rate: 0.6667},
+			"recall":     {Passed: 2, Total: 3, Rate: 0.6667},
+		}[name]
+		if got != want {
+			t.Fatalf("%s: %+v, want %+v", name, got, want)
+		}
+	}
+	if s.Dietary.Total != 0 || s.Dietary.Rate != 0 {
+		t.Fatalf("dietary without applicable cases: %+v", s.Dietary)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := testReport(map[string]bool{"a": true, "b": true, "c": true, "d": false})
+
+	// 通過率 0.75 -> 0.5 超過容許值，b 由通過變為失敗；d 原本就失敗不列出
+	current := testReport(map[string]bool{"a": true, "b": false, "c": true, "d": false})
+	regressions, newlyFailing := Compare(baseline, current, 0.1)
+	if len(regressions) != 1 || regressions[0] != "passed: 0.7500 -> 0.5000" {
+		t.Fatalf("regressions: %v", regressions)
+	}
+	if len(newlyFailing) != 1 || newlyFailing[0] != "b" {
+		t.Fatalf("newly failing: %v", newlyFailing)
+	}
+
+	// 下降在容許值內不算退步，但失敗的案例仍列出
+	regressions, newlyFailing = Compare(baseline, current, 0.3)
+	if len(regressions) != 0 || len(newlyFailing) != 1 {
+		t.Fatalf("within tolerance: %v, %v", regressions, newlyFailing)
+	}
+
+	// 新增案例與改善不算退步
+	current = testReport(map[string]bool{"a": true, "b": true, "c": true, "d": true})
+	if regressions, newlyFailing = Compare(baseline, current, 0); len(regressions) != 0 || len(newlyFailing) != 0 {
+		t.Fatalf("improvement: %v, %v", regressions, newlyFailing)
+	}
+	current = testReport(map[string]bool{"a": false})
+	if _, newlyFailing = Compare(testReport(map[string]bool{"b": true}), current, 0); len(newlyFailing) != 0 {
+		t.Fatalf("case missing from baseline reported: %v", newlyFailing)
+	}
+}
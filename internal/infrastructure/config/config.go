@@ -11,16 +11,23 @@ import (
 
 // Config 應用配置
 type Config struct {
-	App         AppConfig        `mapstructure:"app"`
-	Server      ServerConfig     `mapstructure:"server"`
-	OpenRouter  OpenRouterConfig `mapstructure:"openrouter"`
-	AI          AIConfig         `mapstructure:"ai"`
-	Cache       CacheConfig      `mapstructure:"cache"`
-	Queue       QueueConfig      `mapstructure:"queue"`
-	RateLimit   RateLimitConfig  `mapstructure:"rate_limit"`
-	Image       ImageConfig      `mapstructure:"image"`
-	DedupWindow time.Duration    `mapstructure:"dedup_window"`
-	LogLevel    string           `mapstructure:"log_level"`
+	App         AppConfig         `mapstructure:"app"`
+	Server      ServerConfig      `mapstructure:"server"`
+	OpenRouter  OpenRouterConfig  `mapstructure:"openrouter"`
+	OpenAI      OpenAIConfig      `mapstructure:"openai"`
+	Fake        FakeConfig        `mapstructure:"fake"`
+	AI          AIConfig          `mapstructure:"ai"`
+	Cache       CacheConfig       `mapstructure:"cache"`
+	Queue       QueueConfig       `mapstructure:"queue"`
+	Jobs        JobsConfig        `mapstructure:"jobs"`
+	RateLimit   RateLimitConfig   `mapstructure:"rate_limit"`
+	Image       ImageConfig       `mapstructure:"image"`
+	Inventory   InventoryConfig   `mapstructure:"inventory"`
+	Recognition RecognitionConfig `mapstructure:"recognition"`
+	Prompts     PromptsConfig     `mapstructure:"prompts"`
+	Experiments ExperimentsConfig `mapstructure:"experiments"`
+	Admin       AdminConfig       `mapstructure:"admin"`
+	LogLevel    string            `mapstructure:"log_level"`
 }
 
 // AppConfig 應用程式設定
@@ -34,34 +41,95 @@ type AppConfig struct {
 
 // ServerConfig 服務器配置
 type ServerConfig struct {
-	Port         int           `mapstructure:"port"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout"`
-	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+	Port            int           `mapstructure:"port"`
+	ReadTimeout     time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout    time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout     time.Duration `mapstructure:"idle_timeout"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"` // 優雅關閉等待時間
 }
 
 // OpenRouterConfig OpenRouter 配置
 type OpenRouterConfig struct {
-	Enabled   bool          `mapstructure:"enabled"`
-	APIKey    string        `mapstructure:"api_key"`
-	Model     string        `mapstructure:"model"`
-	MaxTokens int           `mapstructure:"max_tokens"`
-	Timeout   time.Duration `mapstructure:"timeout"`
+	Enabled    bool          `mapstructure:"enabled"`
+	APIKey     string        `mapstructure:"api_key"`
+	Model      string        `mapstructure:"model"`
+	MaxTokens  int           `mapstructure:"max_tokens"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+	BaseURL    string        `mapstructure:"base_url"`
+	MaxRetries int           `mapstructure:"max_retries"` // 暫時性錯誤的最大重試次數
+	JSONSchema bool          `mapstructure:"json_schema"` // 以 response_format: json_schema 要求結構化輸出
+}
+
+// OpenAIConfig OpenAI 相容端點配置（如本地 Ollama、llama.cpp）
+type OpenAIConfig struct {
+	BaseURL    string        `mapstructure:"base_url"`
+	APIKey     string        `mapstructure:"api_key"`
+	Model      string        `mapstructure:"model"`
+	MaxTokens  int           `mapstructure:"max_tokens"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+	JSONSchema bool          `mapstructure:"json_schema"` // 端點支援時以 response_format: json_schema 要求結構化輸出
+}
+
+// FakeConfig 假提供者配置（AI_PROVIDER=fake）
+type FakeConfig struct {
+	Scenario string `mapstructure:"scenario"` // 腳本檔路徑，比對提示詞回傳指定內容、延遲或錯誤並注入模型錯誤；空字串時回傳固定內容
 }
 
 // AIConfig AI 配置
 type AIConfig struct {
-	EnableCache  bool `mapstructure:"enable_cache"`
-	MaxQueueSize int  `mapstructure:"max_queue_size"`
-	Workers      int  `mapstructure:"workers"`
+	Provider       string         `mapstructure:"provider"`
+	Fallbacks      []string       `mapstructure:"fallbacks"` // 備援提供者，格式 provider@model，依序嘗試
+	Breaker        BreakerConfig  `mapstructure:"breaker"`
+	Coalesce       bool           `mapstructure:"coalesce"`        // 合併同時進行的相同請求，共用一次上游呼叫
+	RepairAttempts int            `mapstructure:"repair_attempts"` // 回應不符合 JSON Schema 時附上錯誤重新要求的次數
+	Fixtures       FixturesConfig `mapstructure:"fixtures"`
+	EnableCache    bool           `mapstructure:"enable_cache"`
+	MaxQueueSize   int            `mapstructure:"max_queue_size"`
+	Workers        int            `mapstructure:"workers"`
+}
+
+// FixturesConfig 提供者 HTTP 請求錄製與重播配置
+type FixturesConfig struct {
+	Mode string `mapstructure:"mode"` // off、record（送出請求並寫入 fixture）或 replay（只從 fixture 回應）
+	Dir  string `mapstructure:"dir"`  // fixture 檔案目錄
+}
+
+// BreakerConfig 提供者斷路器配置
+type BreakerConfig struct {
+	FailureThreshold int           `mapstructure:"failure_threshold"`
+	Cooldown         time.Duration `mapstructure:"cooldown"`
+	HalfOpenRequests int           `mapstructure:"half_open_requests"`
 }
 
 // CacheConfig 緩存配置
 type CacheConfig struct {
-	Enabled         bool          `mapstructure:"enabled"`
-	MaxSize         int           `mapstructure:"max_size"`
-	TTL             time.Duration `mapstructure:"ttl"`
-	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+	Enabled          bool                  `mapstructure:"enabled"`
+	Backend          string                `mapstructure:"backend"`   // memory、redis 或 tiered
+	MaxBytes         int64                 `mapstructure:"max_bytes"` // 記憶體快取容量（位元組）
+	Shards           int                   `mapstructure:"shards"`    // 記憶體快取分片數，降低鎖競爭
+	TTL              time.Duration         `mapstructure:"ttl"`
+	CleanupInterval  time.Duration         `mapstructure:"cleanup_interval"`
+	LocalTTL         time.Duration         `mapstructure:"local_ttl"`         // tiered 模式下本地層的有效時間
+	SnapshotPath     string                `mapstructure:"snapshot_path"`     // 記憶體快取快照檔，空字串表示不保存
+	SnapshotInterval time.Duration         `mapstructure:"snapshot_interval"` // 定期寫入快照的間隔，0 表示只在關閉時寫入
+	Redis            RedisConfig           `mapstructure:"redis"`
+	Semantic         SemanticCacheConfig   `mapstructure:"semantic"`
+	Perceptual       PerceptualCacheConfig `mapstructure:"perceptual"`
+}
+
+// SemanticCacheConfig 食譜語意快取設定
+type SemanticCacheConfig struct {
+	Enabled    bool    `mapstructure:"enabled"`    // 以正規化後的請求指紋快取依菜名生成的食譜
+	Similarity bool    `mapstructure:"similarity"` // 指紋未命中時以菜名 n-gram 與食材集合查找相近的請求
+	Threshold  float64 `mapstructure:"threshold"`  // 相似度門檻（0~1）
+	IndexSize  int     `mapstructure:"index_size"` // 相似度索引保留的請求數
+}
+
+// PerceptualCacheConfig 圖片感知雜湊快取設定
+type PerceptualCacheConfig struct {
+	Enabled     bool `mapstructure:"enabled"`      // 食物與食材辨識以圖片感知雜湊快取結果
+	MaxDistance int  `mapstructure:"max_distance"` // 視為同一張圖片的最大漢明距離（0~64）
+	IndexSize   int  `mapstructure:"index_size"`   // 相近圖片索引保留的圖片數
 }
 
 // QueueConfig 請求隊列設定
@@ -70,6 +138,27 @@ type QueueConfig struct {
 	MaxSize int `mapstructure:"max_size"`
 }
 
+// JobsConfig 非同步任務設定
+type JobsConfig struct {
+	TTL             time.Duration `mapstructure:"ttl"`              // 已結束任務的保留時間
+	Timeout         time.Duration `mapstructure:"timeout"`          // 單一任務的執行超時
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"` // 過期任務清理間隔
+	Store           string        `mapstructure:"store"`            // 任務儲存：bolt、redis、memory
+	Path            string        `mapstructure:"path"`             // bolt 資料檔路徑
+	MaxAttempts     int           `mapstructure:"max_attempts"`     // 重啟恢復時的最大嘗試次數
+	InstanceID      string        `mapstructure:"instance_id"`      // 實例識別碼，留空時使用主機名稱與 PID
+	LeaseTTL        time.Duration `mapstructure:"lease_ttl"`        // 實例租約，逾期未續約的實例所持有的任務會被其他實例接手
+	Redis           RedisConfig   `mapstructure:"redis"`
+}
+
+// RedisConfig Redis 連線設定
+type RedisConfig struct {
+	Addr     string `mapstructure:"addr"`
+	Password string `mapstructure:"password"`
+	DB       int    `mapstructure:"db"`
+	Prefix   string `mapstructure:"prefix"` // key 前綴，避免與其他應用衝突
+}
+
 // RateLimitConfig 速率限制配置
 type RateLimitConfig struct {
 	Enabled  bool          `mapstructure:"enabled"`
@@ -79,7 +168,43 @@ type RateLimitConfig struct {
 
 // ImageConfig 圖片配置
 type ImageConfig struct {
-	MaxSizeBytes int64 `mapstructure:"max_size_bytes"`
+	MaxSizeBytes   int64   `mapstructure:"max_size_bytes"`
+	MaxDimension   int     `mapstructure:"max_dimension"`   // 送給模型前的最長邊上限（像素）
+	TargetSize     int     `mapstructure:"target_size"`     // 重新編碼後的位元組預算
+	InitialQuality int     `mapstructure:"initial_quality"` // JPEG 品質上限
+	MinQuality     int     `mapstructure:"min_quality"`     // JPEG 品質下限
+	FinalScale     float64 `mapstructure:"final_scale"`     // 最低品質仍超過預算時每次縮小的比例
+
+	FetchTimeout      time.Duration `mapstructure:"fetch_timeout"`       // 下載圖片網址的總時限
+	FetchMaxRedirects int           `mapstructure:"fetch_max_redirects"` // 下載時允許的重新導向次數
+	FetchSchemes      []string      `mapstructure:"fetch_schemes"`       // 允許的網址協定
+	FetchAllowDomains []string      `mapstructure:"fetch_allow_domains"` // 非空時只允許這些網域及其子網域
+	FetchDenyDomains  []string      `mapstructure:"fetch_deny_domains"`  // 拒絕的網域及其子網域，優先於允許清單
+}
+
+// InventoryConfig 多張圖片食材盤點設定
+type InventoryConfig struct {
+	Concurrency int `mapstructure:"concurrency"` // 同時辨識的圖片數
+}
+
+// RecognitionConfig 圖片辨識結果設定
+type RecognitionConfig struct {
+	MinConfidence float64 `mapstructure:"min_confidence"` // 低於此信心分數的項目不回傳，0 表示不過濾
+}
+
+// PromptsConfig 提示詞模板設定
+type PromptsConfig struct {
+	Dir string `mapstructure:"dir"` // 覆寫模板目錄，空字串時只使用內嵌模板
+}
+
+// ExperimentsConfig 提示詞 A/B 實驗設定
+type ExperimentsConfig struct {
+	File string `mapstructure:"file"` // 實驗設定檔（JSON），空字串時不進行實驗
+}
+
+// AdminConfig 管理介面設定
+type AdminConfig struct {
+	Token string `mapstructure:"token"` // Bearer token，空字串時不開放管理介面
 }
 
 // LoadConfig 載入設定
@@ -101,11 +226,74 @@ func LoadConfig() (*Config, error) {
 	viper.BindEnv("openrouter.api_key", "OPENROUTER_API_KEY")
 	viper.BindEnv("openrouter.model", "OPENROUTER_MODEL")
 	viper.BindEnv("openrouter.max_tokens", "MODEL_MAX_TOKENS")
+	viper.BindEnv("ai.provider", "AI_PROVIDER")
+	viper.BindEnv("ai.fallbacks", "AI_FALLBACKS")
+	viper.BindEnv("ai.breaker.failure_threshold", "AI_BREAKER_FAILURE_THRESHOLD")
+	viper.BindEnv("ai.breaker.cooldown", "AI_BREAKER_COOLDOWN")
+	viper.BindEnv("ai.breaker.half_open_requests", "AI_BREAKER_HALF_OPEN_REQUESTS")
+	viper.BindEnv("ai.coalesce", "AI_COALESCE")
+	viper.BindEnv("ai.repair_attempts", "AI_REPAIR_ATTEMPTS")
+	viper.BindEnv("ai.fixtures.mode", "AI_FIXTURES_MODE")
+	viper.BindEnv("ai.fixtures.dir", "AI_FIXTURES_DIR")
+	viper.BindEnv("openrouter.json_schema", "OPENROUTER_JSON_SCHEMA")
+	viper.BindEnv("openai.json_schema", "OPENAI_JSON_SCHEMA")
+	viper.BindEnv("openai.base_url", "OPENAI_BASE_URL")
+	viper.BindEnv("fake.scenario", "FAKE_SCENARIO_FILE")
+	viper.BindEnv("openai.api_key", "OPENAI_API_KEY")
+	viper.BindEnv("openai.model", "OPENAI_MODEL")
 	viper.BindEnv("cache.enabled", "CACHE_ENABLED")
+	viper.BindEnv("cache.backend", "CACHE_BACKEND")
+	viper.BindEnv("cache.max_bytes", "CACHE_MAX_BYTES")
+	viper.BindEnv("cache.shards", "CACHE_SHARDS")
+	viper.BindEnv("cache.ttl", "CACHE_TTL")
+	viper.BindEnv("cache.cleanup_interval", "CACHE_CLEANUP_INTERVAL")
+	viper.BindEnv("cache.local_ttl", "CACHE_LOCAL_TTL")
+	viper.BindEnv("cache.snapshot_path", "CACHE_SNAPSHOT_PATH")
+	viper.BindEnv("cache.snapshot_interval", "CACHE_SNAPSHOT_INTERVAL")
+	viper.BindEnv("cache.redis.addr", "CACHE_REDIS_ADDR")
+	viper.BindEnv("cache.redis.password", "CACHE_REDIS_PASSWORD")
+	viper.BindEnv("cache.redis.db", "CACHE_REDIS_DB")
+	viper.BindEnv("cache.redis.prefix", "CACHE_REDIS_PREFIX")
+	viper.BindEnv("cache.semantic.enabled", "CACHE_SEMANTIC_ENABLED")
+	viper.BindEnv("cache.semantic.similarity", "CACHE_SEMANTIC_SIMILARITY")
+	viper.BindEnv("cache.semantic.threshold", "CACHE_SEMANTIC_THRESHOLD")
+	viper.BindEnv("cache.semantic.index_size", "CACHE_SEMANTIC_INDEX_SIZE")
+	viper.BindEnv("cache.perceptual.enabled", "CACHE_PERCEPTUAL_ENABLED")
+	viper.BindEnv("cache.perceptual.max_distance", "CACHE_PERCEPTUAL_MAX_DISTANCE")
+	viper.BindEnv("cache.perceptual.index_size", "CACHE_PERCEPTUAL_INDEX_SIZE")
 	viper.BindEnv("rate_limit.enabled", "RATE_LIMIT_ENABLED")
 	viper.BindEnv("rate_limit.requests", "RATE_LIMIT_REQUESTS")
 	viper.BindEnv("rate_limit.window", "RATE_LIMIT_WINDOW")
-	viper.BindEnv("dedup_window", "DEDUP_WINDOW")
+	viper.BindEnv("queue.workers", "QUEUE_WORKERS")
+	viper.BindEnv("queue.max_size", "QUEUE_MAX_SIZE")
+	viper.BindEnv("jobs.ttl", "JOBS_TTL")
+	viper.BindEnv("jobs.timeout", "JOBS_TIMEOUT")
+	viper.BindEnv("jobs.store", "JOBS_STORE")
+	viper.BindEnv("jobs.path", "JOBS_PATH")
+	viper.BindEnv("jobs.max_attempts", "JOBS_MAX_ATTEMPTS")
+	viper.BindEnv("jobs.instance_id", "JOBS_INSTANCE_ID")
+	viper.BindEnv("jobs.lease_ttl", "JOBS_LEASE_TTL")
+	viper.BindEnv("jobs.redis.addr", "JOBS_REDIS_ADDR")
+	viper.BindEnv("jobs.redis.password", "JOBS_REDIS_PASSWORD")
+	viper.BindEnv("jobs.redis.db", "JOBS_REDIS_DB")
+	viper.BindEnv("jobs.redis.prefix", "JOBS_REDIS_PREFIX")
+	viper.BindEnv("server.shutdown_timeout", "SHUTDOWN_TIMEOUT")
+	viper.BindEnv("admin.token", "ADMIN_TOKEN")
+	viper.BindEnv("image.max_size_bytes", "MAX_IMAGE_SIZE")
+	viper.BindEnv("image.max_dimension", "IMAGE_MAX_DIMENSION")
+	viper.BindEnv("image.target_size", "IMAGE_TARGET_SIZE")
+	viper.BindEnv("image.initial_quality", "IMAGE_INITIAL_QUALITY")
+	viper.BindEnv("image.min_quality", "IMAGE_MIN_QUALITY")
+	viper.BindEnv("image.final_scale", "IMAGE_FINAL_SCALE")
+	viper.BindEnv("image.fetch_timeout", "IMAGE_FETCH_TIMEOUT")
+	viper.BindEnv("image.fetch_max_redirects", "IMAGE_FETCH_MAX_REDIRECTS")
+	viper.BindEnv("image.fetch_schemes", "IMAGE_FETCH_SCHEMES")
+	viper.BindEnv("image.fetch_allow_domains", "IMAGE_FETCH_ALLOW_DOMAINS")
+	viper.BindEnv("image.fetch_deny_domains", "IMAGE_FETCH_DENY_DOMAINS")
+	viper.BindEnv("inventory.concurrency", "INVENTORY_CONCURRENCY")
+	viper.BindEnv("recognition.min_confidence", "RECOGNITION_MIN_CONFIDENCE")
+	viper.BindEnv("prompts.dir", "PROMPTS_DIR")
+	viper.BindEnv("experiments.file", "EXPERIMENTS_FILE")
 	viper.BindEnv("log_level", "LOG_LEVEL")
 
 	// 設定設定檔名稱和路徑
@@ -159,28 +347,74 @@ func setDefaults() {
 	viper.SetDefault("server.read_timeout", "30s")
 	viper.SetDefault("server.write_timeout", "30s")
 	viper.SetDefault("server.idle_timeout", "120s")
+	viper.SetDefault("server.shutdown_timeout", "60s")
 
 	// OpenRouter 設定
 	viper.SetDefault("openrouter.enabled", false)
 	viper.SetDefault("openrouter.model", "qwen/qwen2.5-vl-72b-instruct:free")
 	viper.SetDefault("openrouter.max_tokens", 1000)
 	viper.SetDefault("openrouter.timeout", "60s")
+	viper.SetDefault("openrouter.base_url", "https://openrouter.ai/api/v1")
+	viper.SetDefault("openrouter.max_retries", 2)
+	viper.SetDefault("openrouter.json_schema", true)
+
+	// OpenAI 相容端點設定
+	viper.SetDefault("openai.base_url", "http://localhost:11434/v1")
+	viper.SetDefault("openai.max_tokens", 4096)
+	viper.SetDefault("openai.timeout", "120s")
+	viper.SetDefault("openai.json_schema", false)
 
 	// AI 設定
+	viper.SetDefault("ai.provider", "openrouter")
+	viper.SetDefault("ai.breaker.failure_threshold", 5)
+	viper.SetDefault("ai.breaker.cooldown", "30s")
+	viper.SetDefault("ai.breaker.half_open_requests", 1)
+	viper.SetDefault("ai.coalesce", true)
+	viper.SetDefault("ai.repair_attempts", 2)
+	viper.SetDefault("ai.fixtures.mode", "off")
+	viper.SetDefault("ai.fixtures.dir", "fixtures")
+	viper.SetDefault("fake.scenario", "")
 	viper.SetDefault("ai.enable_cache", true)
 	viper.SetDefault("ai.max_queue_size", 100)
 	viper.SetDefault("ai.workers", 5)
 
 	// 快取設定
 	viper.SetDefault("cache.enabled", true)
-	viper.SetDefault("cache.max_size", 1000)
+	viper.SetDefault("cache.max_bytes", 256*1024*1024) // 256MB
+	viper.SetDefault("cache.shards", 16)
 	viper.SetDefault("cache.ttl", "24h")
 	viper.SetDefault("cache.cleanup_interval", "10m")
+	viper.SetDefault("cache.backend", "memory")
+	viper.SetDefault("cache.local_ttl", "5m")
+	viper.SetDefault("cache.snapshot_path", "data/cache.snapshot")
+	viper.SetDefault("cache.snapshot_interval", "5m")
+	viper.SetDefault("cache.redis.addr", "localhost:6379")
+	viper.SetDefault("cache.redis.db", 0)
+	viper.SetDefault("cache.redis.prefix", "recipe-generator:")
+	viper.SetDefault("cache.semantic.enabled", true)
+	viper.SetDefault("cache.semantic.similarity", false)
+	viper.SetDefault("cache.semantic.threshold", 0.9)
+	viper.SetDefault("cache.semantic.index_size", 1000)
+	viper.SetDefault("cache.perceptual.enabled", true)
+	viper.SetDefault("cache.perceptual.max_distance", 6)
+	viper.SetDefault("cache.perceptual.index_size", 1000)
 
 	// 隊列設定
 	viper.SetDefault("queue.workers", 5)
 	viper.SetDefault("queue.max_size", 100)
 
+	// 非同步任務設定
+	viper.SetDefault("jobs.ttl", "1h")
+	viper.SetDefault("jobs.timeout", "120s")
+	viper.SetDefault("jobs.cleanup_interval", "5m")
+	viper.SetDefault("jobs.store", "bolt")
+	viper.SetDefault("jobs.path", "data/jobs.db")
+	viper.SetDefault("jobs.max_attempts", 3)
+	viper.SetDefault("jobs.lease_ttl", "30s")
+	viper.SetDefault("jobs.redis.addr", "localhost:6379")
+	viper.SetDefault("jobs.redis.db", 0)
+	viper.SetDefault("jobs.redis.prefix", "recipe-generator:")
+
 	// 限流設定
 	viper.SetDefault("rate_limit.enabled", true)
 	viper.SetDefault("rate_limit.requests", 100)
@@ -188,9 +422,26 @@ func setDefaults() {
 
 	// 圖片設定
 	viper.SetDefault("image.max_size_bytes", 10*1024*1024) // 10MB
-
-	// 新增 dedup window 預設
-	viper.SetDefault("dedup_window", "1s")
+	viper.SetDefault("image.max_dimension", 1200)
+	viper.SetDefault("image.target_size", 200*1024) // 200KB
+	viper.SetDefault("image.initial_quality", 85)
+	viper.SetDefault("image.min_quality", 40)
+	viper.SetDefault("image.final_scale", 0.8)
+	viper.SetDefault("image.fetch_timeout", "15s")
+	viper.SetDefault("image.fetch_max_redirects", 3)
+	viper.SetDefault("image.fetch_schemes", []string{"https", "http"})
+
+	// 食材盤點設定
+	viper.SetDefault("inventory.concurrency", 3)
+
+	// 圖片辨識設定
+	viper.SetDefault("recognition.min_confidence", 0)
+
+	// 提示詞模板設定
+	viper.SetDefault("prompts.dir", "")
+
+	// 提示詞實驗設定
+	viper.SetDefault("experiments.file", "")
 }
 
 // validateConfig 驗證設定
@@ -200,10 +451,48 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("server port is required")
 	}
 
+	// 驗證圖片前處理設定
+	if config.Image.MaxSizeBytes <= 0 {
+		return fmt.Errorf("invalid image max size")
+	}
+	if config.Image.MaxDimension <= 0 {
+		return fmt.Errorf("invalid image max dimension")
+	}
+	if config.Image.TargetSize <= 0 {
+		return fmt.Errorf("invalid image target size")
+	}
+	if config.Image.MinQuality < 1 || config.Image.InitialQuality > 100 || config.Image.MinQuality > config.Image.InitialQuality {
+		return fmt.Errorf("invalid image quality range")
+	}
+	if config.Image.FinalScale <= 0 || config.Image.FinalScale >= 1 {
+		return fmt.Errorf("invalid image final scale")
+	}
+	if config.Image.FetchTimeout <= 0 {
+		return fmt.Errorf("invalid image fetch timeout")
+	}
+	if config.Image.FetchMaxRedirects < 0 {
+		return fmt.Errorf("invalid image fetch max redirects")
+	}
+	for _, scheme := range config.Image.FetchSchemes {
+		if scheme != "http" && scheme != "https" {
+			return fmt.Errorf("invalid image fetch scheme: %s", scheme)
+		}
+	}
+
+	if config.Inventory.Concurrency <= 0 {
+		return fmt.Errorf("invalid inventory concurrency")
+	}
+	if config.Recognition.MinConfidence < 0 || config.Recognition.MinConfidence > 1 {
+		return fmt.Errorf("invalid recognition min confidence")
+	}
+
 	// 驗證快取設定
 	if config.Cache.Enabled {
-		if config.Cache.MaxSize <= 0 {
-			return fmt.Errorf("invalid cache max size")
+		if config.Cache.MaxBytes <= 0 {
+			return fmt.Errorf("invalid cache max bytes")
+		}
+		if config.Cache.Shards <= 0 {
+			return fmt.Errorf("invalid cache shards")
 		}
 		if config.Cache.TTL <= 0 {
 			return fmt.Errorf("invalid cache ttl")
@@ -211,6 +500,38 @@ func validateConfig(config *Config) error {
 		if config.Cache.CleanupInterval <= 0 {
 			return fmt.Errorf("invalid cache cleanup interval")
 		}
+		if config.Cache.Semantic.Similarity {
+			if config.Cache.Semantic.Threshold <= 0 || config.Cache.Semantic.Threshold > 1 {
+				return fmt.Errorf("invalid semantic cache threshold")
+			}
+			if config.Cache.Semantic.IndexSize <= 0 {
+				return fmt.Errorf("invalid semantic cache index size")
+			}
+		}
+		if config.Cache.Perceptual.Enabled {
+			if config.Cache.Perceptual.MaxDistance < 0 || config.Cache.Perceptual.MaxDistance > 64 {
+				return fmt.Errorf("invalid perceptual cache max distance")
+			}
+			if config.Cache.Perceptual.IndexSize <= 0 {
+				return fmt.Errorf("invalid perceptual cache index size")
+			}
+		}
+	}
+
+	// 驗證 AI 提供者設定
+	if config.AI.Provider == "" {
+		return fmt.Errorf("ai provider is required")
+	}
+	if config.AI.RepairAttempts < 0 {
+		return fmt.Errorf("invalid ai repair attempts")
+	}
+	switch config.AI.Fixtures.Mode {
+	case "off", "record", "replay":
+	default:
+		return fmt.Errorf("invalid ai fixtures mode: %s", config.AI.Fixtures.Mode)
+	}
+	if config.AI.Fixtures.Mode != "off" && config.AI.Fixtures.Dir == "" {
+		return fmt.Errorf("ai fixtures dir is required")
 	}
 
 	// 驗證隊列設定
@@ -221,5 +542,22 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("invalid queue max size")
 	}
 
+	// 驗證非同步任務設定
+	if config.Jobs.TTL <= 0 {
+		return fmt.Errorf("invalid jobs ttl")
+	}
+	if config.Jobs.Timeout <= 0 {
+		return fmt.Errorf("invalid jobs timeout")
+	}
+	if config.Jobs.CleanupInterval <= 0 {
+		return fmt.Errorf("invalid jobs cleanup interval")
+	}
+	if config.Jobs.MaxAttempts <= 0 {
+		return fmt.Errorf("invalid jobs max attempts")
+	}
+	if config.Jobs.LeaseTTL <= 0 {
+		return fmt.Errorf("invalid jobs lease ttl")
+	}
+
 	return nil
 }
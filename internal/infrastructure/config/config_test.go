@@ -0,0 +1,38 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigBindsEnv(t *testing.T) {
+	// LoadConfig 需要工作目錄下的 .env，改在暫存目錄執行
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	t.Setenv("AI_PROVIDER", "fake")
+	t.Setenv("AI_BREAKER_HALF_OPEN_REQUESTS", "3")
+	t.Setenv("JOBS_REDIS_PREFIX", "jobs-test:")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.AI.Breaker.HalfOpenRequests != 3 {
+		t.Errorf("AI_BREAKER_HALF_OPEN_REQUESTS: got %d", cfg.AI.Breaker.HalfOpenRequests)
+	}
+	if cfg.Jobs.Redis.Prefix != "jobs-test:" {
+		t.Errorf("JOBS_REDIS_PREFIX: got %q", cfg.Jobs.Redis.Prefix)
+	}
+}
@@ -33,6 +33,23 @@ func QuoteJSONKeys(raw string) string {
 	return unquotedKeyPattern.ReplaceAllString(raw, `$1"$2":`)
 }
 
+// ExtractJSON 從 AI 回應中取出 JSON 物件：去除前後說明文字與 Markdown 程式碼區塊，
+// 取第一個 { 到最後一個 } 之間的內容；鍵未加雙引號且補上後可解析時回傳修正後的內容
+func ExtractJSON(content string) string {
+	content = strings.TrimSpace(content)
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start != -1 && end != -1 && end > start {
+		content = content[start : end+1]
+	}
+	if !json.Valid([]byte(content)) {
+		if fixed := QuoteJSONKeys(content); fixed != content && json.Valid([]byte(fixed)) {
+			return fixed
+		}
+	}
+	return content
+}
+
 // StringSliceToString 將字符串切片轉換為逗號分隔的字符串
 func StringSliceToString(slice []string) string {
 	if len(slice) == 0 {
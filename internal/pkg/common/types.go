@@ -5,24 +5,38 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"recipe-generator/internal/pkg/jsonschema"
 )
 
+// BoundingBox 辨識項目在圖片中的位置，以圖片寬高正規化到 0~1，原點為左上角
+type BoundingBox struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
 // Ingredient 食材
 type Ingredient struct {
-	Name        string `json:"name"`
-	Type        string `json:"type"`
-	Amount      string `json:"amount"`
-	Unit        string `json:"unit"`
-	Preparation string `json:"preparation"`
+	Name        string       `json:"name"`
+	Type        string       `json:"type"`
+	Amount      string       `json:"amount"`
+	Unit        string       `json:"unit"`
+	Preparation string       `json:"preparation"`
+	Confidence  *float64     `json:"confidence,omitempty" jsonschema:"minimum=0,maximum=1"` // 圖片辨識的信心分數（0~1）
+	BoundingBox *BoundingBox `json:"bounding_box,omitempty"`                                // 圖片辨識的位置
 }
 
 // Equipment 設備
 type Equipment struct {
-	Name        string `json:"name"`
-	Type        string `json:"type"`
-	Size        string `json:"size,omitempty"`
-	Material    string `json:"material,omitempty"`
-	PowerSource string `json:"power_source,omitempty"`
+	Name        string       `json:"name"`
+	Type        string       `json:"type"`
+	Size        string       `json:"size,omitempty"`
+	Material    string       `json:"material,omitempty"`
+	PowerSource string       `json:"power_source,omitempty"`
+	Confidence  *float64     `json:"confidence,omitempty" jsonschema:"minimum=0,maximum=1"` // 圖片辨識的信心分數（0~1）
+	BoundingBox *BoundingBox `json:"bounding_box,omitempty"`                                // 圖片辨識的位置
 }
 
 // IngredientRecognitionResult 食材識別結果
@@ -59,14 +73,14 @@ type RecipeStep struct {
 	Description        string          `json:"description"`
 	Actions            []RecipeAction  `json:"actions"`
 	EstimatedTotalTime string          `json:"estimated_total_time"`
-	Temperature        string          `json:"temperature"`
-	Warnings           string          `json:"warnings"`
-	Notes              string          `json:"notes"`
+	Temperature        string          `json:"temperature" jsonschema:"nullable"`
+	Warnings           string          `json:"warnings" jsonschema:"nullable"`
+	Notes              string          `json:"notes" jsonschema:"nullable"`
 }
 
 type RecipeAction struct {
 	Action            string   `json:"action"`
-	ToolRequired      string   `json:"tool_required"`
+	ToolRequired      string   `json:"tool_required" jsonschema:"nullable"`
 	MaterialRequired  []string `json:"material_required"`
 	TimeMinutes       int      `json:"time_minutes"`
 	InstructionDetail string   `json:"instruction_detail"`
@@ -112,6 +126,8 @@ type FoodRecognitionResult struct {
 type RecognizedFood struct {
 	Name                string               `json:"name"`
 	Description         string               `json:"description"`
+	Confidence          *float64             `json:"confidence,omitempty" jsonschema:"minimum=0,maximum=1"` // 辨識的信心分數（0~1）
+	BoundingBox         *BoundingBox         `json:"bounding_box,omitempty"`                                // 食物在圖片中的位置
 	PossibleIngredients []PossibleIngredient `json:"possible_ingredients"`
 	PossibleEquipment   []PossibleEquipment  `json:"possible_equipment"`
 }
@@ -148,6 +164,15 @@ const (
 	ARBeatEgg          ARtype = "beatEgg"
 )
 
+// SchemaEnum 實作 jsonschema.Enumer，結構化輸出只接受已定義的 AR 動作
+func (ARtype) SchemaEnum() []string {
+	return []string{
+		string(ARPutIntoContainer), string(ARStir), string(ARPourLiquid), string(ARFlipPan),
+		string(ARCountdown), string(ARTemperature), string(ARFlame), string(ARSprinkle),
+		string(ARTorch), string(ARCut), string(ARPeel), string(ARFlip), string(ARBeatEgg),
+	}
+}
+
 type FlameLevel string
 
 const (
@@ -156,6 +181,11 @@ const (
 	FlameLarge  FlameLevel = "large"
 )
 
+// SchemaEnum 實作 jsonschema.Enumer
+func (FlameLevel) SchemaEnum() []string {
+	return []string{string(FlameSmall), string(FlameMedium), string(FlameLarge)}
+}
+
 // 你若有固定的容器清單，可以做枚舉；先用 string 方便擴充
 type ARActionParams struct {
 	Type        ARtype          `json:"type"`                // discriminator
@@ -232,6 +262,11 @@ func (nf *NullableFloat64) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// JSONSchema 實作 jsonschema.Describer：與 UnmarshalJSON 一致，接受數值、數值字串或 null
+func (NullableFloat64) JSONSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{Types: []string{"number", "string", "null"}}
+}
+
 // MarshalJSON 以數值或 null 形式序列化
 func (nf NullableFloat64) MarshalJSON() ([]byte, error) {
 	if nf.Value == nil {
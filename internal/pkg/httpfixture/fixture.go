@@ -0,0 +1,219 @@
+package httpfixture
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// redacted 取代敏感標頭的值
+const redacted = "REDACTED"
+
+// sensitiveHeaders 寫入 fixture 前遮蔽的請求標頭
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key", "Api-Key"}
+
+// responseHeaders 重播時客戶端會讀取的響應標頭，其餘不寫入 fixture
+var responseHeaders = []string{"Content-Type", "Retry-After"}
+
+// dataURIPattern base64 圖片 data URI，可能嵌在提示詞文字中
+var dataURIPattern = regexp.MustCompile(`data:([\w.+-]+/[\w.+-]+);base64,([A-Za-z0-9+/=]+)`)
+
+// volatilePatterns 每次請求都不同、比對時忽略的內容，例如推薦食譜提示詞中避免快取的識別碼
+var volatilePatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`SessionToken:\d+`), "SessionToken:*"},
+}
+
+// Fixture 一組錄製的請求與響應
+type Fixture struct {
+	Key      string   `json:"key"`
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request 錄製的請求：網址不含查詢字串，內容為正規化並遮蔽圖片後的結果
+type Request struct {
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Header   http.Header     `json:"header,omitempty"`
+	Body     json.RawMessage `json:"body,omitempty"`      // JSON 內容
+	BodyText string          `json:"body_text,omitempty"` // 非 JSON 內容
+}
+
+// Response 錄製的響應
+type Response struct {
+	Status   int             `json:"status"`
+	Header   http.Header     `json:"header,omitempty"`
+	Body     json.RawMessage `json:"body,omitempty"`      // JSON 內容
+	BodyText string          `json:"body_text,omitempty"` // 非 JSON 內容，例如串流的 SSE
+}
+
+// body 重播時的響應內容
+func (r *Response) body() []byte {
+	if len(r.Body) > 0 {
+		return r.Body
+	}
+	return []byte(r.BodyText)
+}
+
+// Key 以請求方法、路徑與正規化後的內容計算 fixture 鍵；不含主機，不同 base URL 可共用 fixture
+func Key(method, path string, normalized []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, path)
+	h.Write(normalized)
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// normalize 正規化請求內容：JSON 重新序列化（物件鍵排序、去除空白），以 sha256 摘要取代圖片 data URI
+// （相同圖片得到相同結果）並遮蔽每次請求都不同的內容；非 JSON 內容只處理字串部分
+func normalize(body []byte) ([]byte, bool) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, false
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return []byte(normalizeString(string(body))), false
+	}
+	out, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return []byte(normalizeString(string(body))), false
+	}
+	return out, true
+}
+
+// redactValue 遞迴正規化 JSON 值中的字串
+func redactValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, val := range x {
+			x[k] = redactValue(val)
+		}
+		return x
+	case []interface{}:
+		for i, val := range x {
+			x[i] = redactValue(val)
+		}
+		return x
+	case string:
+		return normalizeString(x)
+	default:
+		return v
+	}
+}
+
+// normalizeString 遮蔽字串中的圖片資料與每次請求都不同的內容
+func normalizeString(s string) string {
+	s = redactString(s)
+	for _, p := range volatilePatterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	return s
+}
+
+// redactString 以 sha256 摘要取代字串中的圖片 data URI
+func redactString(s string) string {
+	return dataURIPattern.ReplaceAllStringFunc(s, func(m string) string {
+		sub := dataURIPattern.FindStringSubmatch(m)
+		sum := sha256.Sum256([]byte(sub[2]))
+		return "data:" + sub[1] + ";sha256," + hex.EncodeToString(sum[:8])
+	})
+}
+
+// newFixture 建立 fixture，遮蔽敏感標頭與圖片資料
+func newFixture(key string, req *http.Request, normalized []byte, isJSON bool, resp *http.Response, body []byte) *Fixture {
+	f := &Fixture{
+		Key: key,
+		Request: Request{
+			Method: req.Method,
+			URL:    req.URL.Scheme + "://" + req.URL.Host + req.URL.Path,
+			Header: req.Header.Clone(),
+		},
+		Response: Response{
+			Status: resp.StatusCode,
+			Header: http.Header{},
+		},
+	}
+	for _, name := range sensitiveHeaders {
+		if f.Request.Header.Get(name) != "" {
+			f.Request.Header.Set(name, redacted)
+		}
+	}
+	if isJSON {
+		f.Request.Body = normalized
+	} else {
+		f.Request.BodyText = string(normalized)
+	}
+
+	for _, name := range responseHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			f.Response.Header.Set(name, value)
+		}
+	}
+	body = []byte(redactString(string(body)))
+	if json.Valid(body) {
+		f.Response.Body = body
+	} else {
+		f.Response.BodyText = string(body)
+	}
+	return f
+}
+
+// fixturePath fixture 檔案路徑
+func fixturePath(dir, key string) string {
+	return filepath.Join(dir, key+".json")
+}
+
+// save 寫入 fixture，先寫暫存檔再改名，同時錄製的請求不會讀到寫到一半的檔案
+func (f *Fixture) save(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode fixture: %w", err)
+	}
+
+	target := fixturePath(dir, f.Key)
+	tmp, err := os.CreateTemp(dir, f.Key+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to write fixture: %w", err)
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write fixture: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write fixture: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return target, nil
+}
+
+// load 讀取 fixture，不存在時回傳的錯誤符合 os.ErrNotExist
+func load(dir, key string) (*Fixture, error) {
+	data, err := os.ReadFile(fixturePath(dir, key))
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", key, err)
+	}
+	return &f, nil
+}
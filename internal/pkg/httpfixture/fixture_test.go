@@ -0,0 +1,183 @@
+package httpfixture
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	common.Logger = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// testImage 假的 base64 圖片資料
+var testImage = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff, 0xd8, 0xff, 0xe0}, 64))
+
+func TestNormalizeKey(t *testing.T) {
+	key := func(body string) string {
+		t.Helper()
+		normalized, _ := normalize([]byte(body))
+		return Key(http.MethodPost, "/v1/chat/completions", normalized)
+	}
+
+	base := key(`{"model":"m","messages":[{"role":"user","content":"hi"}],"temperature":0}`)
+	// 物件鍵順序與空白不影響鍵
+	if got := key(`{ "temperature": 0, "messages": [ {"content": "hi", "role": "user"} ], "model": "m" }`); got != base {
+		t.Fatal("key order changed the fixture key")
+	}
+	// 數字維持原本的表示，不會因浮點轉換而與其他值相同
+	if got := key(`{"model":"m","messages":[{"role":"user","content":"hi"}],"temperature":0.0}`); got == base {
+		t.Fatal("distinct number literal produced the same key")
+	}
+	if got := key(`{"model":"m","messages":[{"role":"user","content":"bye"}],"temperature":0}`); got == base {
+		t.Fatal("different content produced the same key")
+	}
+	if got := Key(http.MethodPost, "/v1/other", nil); got == Key(http.MethodPost, "/v1/chat/completions", nil) {
+		t.Fatal("path not part of the key")
+	}
+
+	// 推薦食譜提示詞中避免快取的識別碼不影響鍵
+	token := func(n string) string {
+		return key(`{"messages":[{"role":"user","content":"請忽略識別碼 SessionToken:` + n + `，該識別碼僅用於避免快取"}]}`)
+	}
+	if token("1729000000000000001") != token("1729000000000000002") {
+		t.Fatal("SessionToken changed the fixture key")
+	}
+
+	// 相同圖片得到相同鍵，不同圖片得到不同鍵
+	image := func(data string) string {
+		return key(`{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/jpeg;base64,` + data + `"}}]}]}`)
+	}
+	if image(testImage) != image(testImage) || image(testImage) == image("AAAA") {
+		t.Fatal("image data not reflected in the key")
+	}
+}
+
+func TestNormalizeRedactsDataURIs(t *testing.T) {
+	prompt := `參考圖片 data:image/png;base64,` + testImage + ` 與 SessionToken:123`
+	normalized, isJSON := normalize([]byte(`{"prompt":"` + prompt + `"}`))
+	if !isJSON {
+		t.Fatal("JSON body not detected")
+	}
+	sum := sha256.Sum256([]byte(testImage))
+	want := `{"prompt":"參考圖片 data:image/png;sha256,` + hex.EncodeToString(sum[:8]) + ` 與 SessionToken:*"}`
+	if string(normalized) != want {
+		t.Fatalf("normalized:\n%s\nwant:\n%s", normalized, want)
+	}
+
+	// 非 JSON 內容同樣遮蔽圖片與識別碼
+	text, isJSON := normalize([]byte(prompt))
+	if isJSON || strings.Contains(string(text), testImage) || !strings.Contains(string(text), "SessionToken:*") {
+		t.Fatalf("normalized text: %s", text)
+	}
+	if out, _ := normalize([]byte("  ")); out != nil {
+		t.Fatalf("empty body normalized to %q", out)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "upstream-id")
+		w.Write([]byte(`{"choices":[{"message":{"content":"番茄炒蛋"}}]}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	send := func(mode, token string) *http.Response {
+		t.Helper()
+		body := `{"model":"m","messages":[{"role":"user","content":[{"type":"text","text":"SessionToken:` + token + `"},{"type":"image_url","image_url":{"url":"data:image/jpeg;base64,` + testImage + `"}}]}]}`
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/chat/completions?debug=1", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer sk-secret")
+		req.Header.Set("X-Api-Key", "key-secret")
+		resp, err := (&http.Client{Transport: Wrap(mode, dir, nil)}).Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := send(ModeRecord, "1")
+	recorded, _ := io.ReadAll(resp.Body)
+	if calls != 1 || !strings.Contains(string(recorded), "番茄炒蛋") {
+		t.Fatalf("record: calls=%d body=%s", calls, recorded)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("fixture files: %v", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"sk-secret", "key-secret", testImage, "debug=1", "upstream-id"} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("fixture contains %q:\n%s", secret, data)
+		}
+	}
+	f, err := load(dir, strings.TrimSuffix(filepath.Base(files[0]), ".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Request.Header.Get("Authorization") != redacted || f.Request.Header.Get("X-Api-Key") != redacted {
+		t.Fatalf("sensitive headers not redacted: %v", f.Request.Header)
+	}
+	if f.Request.URL != server.URL+"/v1/chat/completions" || len(f.Request.Body) == 0 {
+		t.Fatalf("recorded request: %+v", f.Request)
+	}
+
+	// 重播時不連線，識別碼不同的相同請求仍命中
+	resp = send(ModeReplay, "2")
+	replayed, _ := io.ReadAll(resp.Body)
+	// fixture 以縮排格式保存，響應內容只比對 JSON 值
+	var want, got bytes.Buffer
+	json.Compact(&want, recorded)
+	json.Compact(&got, replayed)
+	if calls != 1 || resp.StatusCode != http.StatusOK || got.Len() == 0 || got.String() != want.String() {
+		t.Fatalf("replay: calls=%d status=%d body=%s", calls, resp.StatusCode, replayed)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("replayed headers: %v", resp.Header)
+	}
+
+	// 沒有對應 fixture 時回傳 404
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/chat/completions", strings.NewReader(`{"model":"other"}`))
+	resp, err = (&http.Client{Transport: Wrap(ModeReplay, dir, nil)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusNotFound || !strings.Contains(string(body), "fixture_not_found") || calls != 1 {
+		t.Fatalf("missing fixture: status=%d body=%s calls=%d", resp.StatusCode, body, calls)
+	}
+}
+
+func TestWrapOff(t *testing.T) {
+	next := http.DefaultTransport
+	for _, mode := range []string{"", ModeOff, "unknown"} {
+		if Wrap(mode, t.TempDir(), next) != next {
+			t.Fatalf("Wrap(%q) wrapped the transport", mode)
+		}
+	}
+}
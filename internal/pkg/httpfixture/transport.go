@@ -0,0 +1,120 @@
+// Package httpfixture 錄製與重播 HTTP 請求的 RoundTripper：錄製時把請求與響應寫成 fixture 檔，
+// 重播時以正規化後的請求內容比對 fixture，不連線外部服務；API 金鑰與圖片資料不會寫入檔案
+package httpfixture
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"recipe-generator/internal/pkg/common"
+
+	"go.uber.org/zap"
+)
+
+// 錄製模式
+const (
+	ModeOff    = "off"    // 直接送出請求
+	ModeRecord = "record" // 送出請求並寫入 fixture，已存在的 fixture 會被覆寫
+	ModeReplay = "replay" // 只從 fixture 回應，找不到時回傳 404
+)
+
+// Transport 錄製或重播請求的 RoundTripper
+type Transport struct {
+	mode string
+	dir  string
+	next http.RoundTripper
+}
+
+// Wrap 依模式包裝 next，off 或空字串時原樣回傳 next；next 為 nil 時錄製使用 http.DefaultTransport
+func Wrap(mode, dir string, next http.RoundTripper) http.RoundTripper {
+	if mode != ModeRecord && mode != ModeReplay {
+		return next
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{mode: mode, dir: dir, next: next}
+}
+
+// RoundTrip 實作 http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		body = data
+	}
+	normalized, isJSON := normalize(body)
+	key := Key(req.Method, req.URL.Path, normalized)
+
+	if t.mode == ModeReplay {
+		return t.replay(req, key)
+	}
+	return t.record(req, body, normalized, isJSON, key)
+}
+
+// record 送出請求，把請求與響應寫入 fixture；寫入失敗只記錄警告，不影響請求
+func (t *Transport) record(req *http.Request, body, normalized []byte, isJSON bool, key string) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+
+	resp, err := t.next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	f := newFixture(key, req, normalized, isJSON, resp, data)
+	path, err := f.save(t.dir)
+	if err != nil {
+		common.LogWarn("寫入 HTTP fixture 失敗", zap.String("key", key), zap.Error(err))
+	} else {
+		common.LogDebug("已錄製 HTTP fixture", zap.String("path", path), zap.Int("status", resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// replay 從 fixture 建立響應，找不到時回傳 404 與 fixture 鍵，客戶端會當成不重試的錯誤處理
+func (t *Transport) replay(req *http.Request, key string) (*http.Response, error) {
+	f, err := load(t.dir, key)
+	if errors.Is(err, os.ErrNotExist) {
+		common.LogWarn("找不到符合請求的 HTTP fixture", zap.String("key", key), zap.String("path", req.URL.Path))
+		body := fmt.Sprintf(`{"error":{"message":"no fixture for request %s","type":"fixture_not_found"}}`, key)
+		header := http.Header{"Content-Type": []string{"application/json"}}
+		return newResponse(req, http.StatusNotFound, header, []byte(body)), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(req, f.Response.Status, f.Response.Header, f.Response.body()), nil
+}
+
+func newResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
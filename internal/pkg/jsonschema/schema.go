@@ -0,0 +1,237 @@
+// Package jsonschema 從 Go 結構自動推導 JSON Schema，並驗證 AI 回傳的 JSON 是否符合
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Schema JSON Schema 的子集合，只涵蓋 AI 結構化輸出用得到的關鍵字
+type Schema struct {
+	Types      []string           // 允許的型別，多個時代表聯合型別（例如 string 或 null）
+	Properties map[string]*Schema // 物件欄位
+	Required   []string           // 必填欄位，依結構欄位順序
+	Items      *Schema            // 陣列元素
+	Enum       []string           // 字串列舉值
+	Minimum    *float64           // 數值下限
+	Maximum    *float64           // 數值上限
+
+	order []string // 欄位宣告順序，用於產生穩定的錯誤訊息
+}
+
+// Enumer 由型別自行提供列舉值，例如 common.ARtype
+type Enumer interface {
+	SchemaEnum() []string
+}
+
+// Describer 由型別自行提供結構描述，例如自訂 JSON 編解碼的 common.NullableFloat64
+type Describer interface {
+	JSONSchema() *Schema
+}
+
+var (
+	enumerType    = reflect.TypeOf((*Enumer)(nil)).Elem()
+	describerType = reflect.TypeOf((*Describer)(nil)).Elem()
+
+	cacheMu sync.Mutex
+	cached  = map[reflect.Type]*Schema{}
+)
+
+// For 從值的型別推導結構描述：
+//   - json 標籤決定欄位名稱，沒有 omitempty 的欄位為必填
+//   - 指標欄位允許 null
+//   - 欄位可用 jsonschema 標籤補充限制，例如 `jsonschema:"nullable,minimum=0,maximum=1"`
+//
+// 推導結果會依型別快取
+func For(v interface{}) *Schema {
+	t := reflect.TypeOf(v)
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if s, ok := cached[t]; ok {
+		return s
+	}
+	s := derive(t, map[reflect.Type]bool{})
+	cached[t] = s
+	return s
+}
+
+// derive 依型別遞迴推導；seen 防止自我參照的型別無限遞迴
+func derive(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	if t.Kind() == reflect.Ptr {
+		s := derive(t.Elem(), seen).clone()
+		s.addNull()
+		return s
+	}
+
+	if t.Implements(describerType) {
+		return reflect.Zero(t).Interface().(Describer).JSONSchema()
+	}
+
+	if t.Implements(enumerType) {
+		return &Schema{Types: []string{"string"}, Enum: reflect.Zero(t).Interface().(Enumer).SchemaEnum()}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Types: []string{"string"}}
+	case reflect.Bool:
+		return &Schema{Types: []string{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Types: []string{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Types: []string{"number"}}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Types: []string{"array"}, Items: derive(t.Elem(), seen)}
+	case reflect.Map:
+		return &Schema{Types: []string{"object"}}
+	case reflect.Struct:
+		if seen[t] {
+			return &Schema{Types: []string{"object"}}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		s := &Schema{Types: []string{"object"}, Properties: map[string]*Schema{}}
+		addFields(s, t, seen)
+		return s
+	default:
+		// interface{} 等無法推導的型別不加限制
+		return &Schema{}
+	}
+}
+
+// addFields 將結構欄位加入物件描述，匿名嵌入的結構會攤平
+func addFields(s *Schema, t reflect.Type, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			addFields(s, f.Type, seen)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := derive(f.Type, seen).clone()
+		applyTag(prop, f.Tag.Get("jsonschema"))
+
+		if _, exists := s.Properties[name]; !exists {
+			s.order = append(s.order, name)
+		}
+		s.Properties[name] = prop
+		if !hasOption(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// applyTag 套用 jsonschema 標籤：nullable、minimum=、maximum=、enum=a|b
+func applyTag(s *Schema, tag string) {
+	if tag == "" {
+		return
+	}
+	for _, opt := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		switch key {
+		case "nullable":
+			s.addNull()
+		case "minimum":
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				s.Minimum = &v
+			}
+		case "maximum":
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				s.Maximum = &v
+			}
+		case "enum":
+			s.Enum = strings.Split(value, "|")
+		}
+	}
+}
+
+func hasOption(opts, want string) bool {
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == want {
+			return true
+		}
+	}
+	return false
+}
+
+// clone 淺層複製，避免欄位標籤修改到快取中共用的描述
+func (s *Schema) clone() *Schema {
+	c := *s
+	c.Types = append([]string(nil), s.Types...)
+	return &c
+}
+
+// addNull 允許 null
+func (s *Schema) addNull() {
+	if len(s.Types) == 0 || s.allows("null") {
+		return
+	}
+	s.Types = append(s.Types, "null")
+}
+
+// allows 判斷是否允許指定型別；未限制型別時全部允許
+func (s *Schema) allows(typ string) bool {
+	if len(s.Types) == 0 {
+		return true
+	}
+	for _, t := range s.Types {
+		if t == typ || (typ == "integer" && t == "number") {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON 輸出標準 JSON Schema，可直接放進 response_format
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	out := map[string]interface{}{}
+	switch len(s.Types) {
+	case 0:
+	case 1:
+		out["type"] = s.Types[0]
+	default:
+		out["type"] = s.Types
+	}
+	if s.Properties != nil {
+		out["properties"] = s.Properties
+		if len(s.Required) > 0 {
+			out["required"] = s.Required
+		}
+	}
+	if s.Items != nil {
+		out["items"] = s.Items
+	}
+	if len(s.Enum) > 0 {
+		enum := make([]interface{}, 0, len(s.Enum)+1)
+		for _, v := range s.Enum {
+			enum = append(enum, v)
+		}
+		if s.allows("null") {
+			enum = append(enum, nil)
+		}
+		out["enum"] = enum
+	}
+	if s.Minimum != nil {
+		out["minimum"] = *s.Minimum
+	}
+	if s.Maximum != nil {
+		out["maximum"] = *s.Maximum
+	}
+	return json.Marshal(out)
+}
@@ -0,0 +1,124 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type tagged struct {
+	Name     string   `json:"name"`
+	Note     string   `json:"note,omitempty"`
+	Score    *float64 `json:"score" jsonschema:"minimum=0,maximum=1"`
+	Level    string   `json:"level" jsonschema:"nullable,enum=low|high"`
+	Count    int      `json:"count"`
+	Tags     []string `json:"tags,omitempty"`
+	Skipped  string   `json:"-"`
+	internal string
+	Embedded
+}
+
+type Embedded struct {
+	Source string `json:"source"`
+}
+
+type selfRef struct {
+	Name     string     `json:"name"`
+	Children []*selfRef `json:"children,omitempty"`
+}
+
+func TestForDerivesFields(t *testing.T) {
+	s := For(tagged{})
+
+	if want := []string{"name", "score", "level", "count", "source"}; !reflect.DeepEqual(s.Required, want) {
+		t.Fatalf("required = %v, want %v", s.Required, want)
+	}
+	if want := []string{"name", "note", "score", "level", "count", "tags", "source"}; !reflect.DeepEqual(s.order, want) {
+		t.Fatalf("properties = %v, want %v", s.order, want)
+	}
+
+	tests := []struct {
+		field string
+		types []string
+	}{
+		{"name", []string{"string"}},
+		{"note", []string{"string"}},
+		{"score", []string{"number", "null"}},
+		{"level", []string{"string", "null"}},
+		{"count", []string{"integer"}},
+		{"tags", []string{"array"}},
+		{"source", []string{"string"}},
+	}
+	for _, tt := range tests {
+		prop := s.Properties[tt.field]
+		if prop == nil || !reflect.DeepEqual(prop.Types, tt.types) {
+			t.Errorf("%s: %+v, want types %v", tt.field, prop, tt.types)
+		}
+	}
+
+	score := s.Properties["score"]
+	if score.Minimum == nil || *score.Minimum != 0 || score.Maximum == nil || *score.Maximum != 1 {
+		t.Errorf("score bounds: %+v", score)
+	}
+	if level := s.Properties["level"]; !reflect.DeepEqual(level.Enum, []string{"low", "high"}) {
+		t.Errorf("level enum: %v", level.Enum)
+	}
+	if _, ok := s.Properties["Skipped"]; ok {
+		t.Error(`json:"-" field included`)
+	}
+	if _, ok := s.Properties["internal"]; ok {
+		t.Error("unexported field included")
+	}
+}
+
+func TestForCachesAndDoesNotShareTaggedCopies(t *testing.T) {
+	a := For(tagged{})
+	if For(tagged{}) != a {
+		t.Fatal("schema not cached")
+	}
+
+	// 同一個型別在不同欄位套用不同標籤時，不可互相影響
+	type twoScores struct {
+		A float64 `json:"a" jsonschema:"minimum=1"`
+		B float64 `json:"b"`
+	}
+	s := For(twoScores{})
+	if s.Properties["b"].Minimum != nil {
+		t.Fatal("tag leaked into a sibling field")
+	}
+}
+
+func TestForSelfReference(t *testing.T) {
+	s := For(selfRef{})
+	child := s.Properties["children"].Items
+	if child == nil || !reflect.DeepEqual(child.Types, []string{"object", "null"}) {
+		t.Fatalf("children items: %+v", child)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	data, err := json.Marshal(For(tagged{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	props := got["properties"].(map[string]interface{})
+
+	level := props["level"].(map[string]interface{})
+	if want := []interface{}{"low", "high", nil}; !reflect.DeepEqual(level["enum"], want) {
+		t.Errorf("nullable enum = %v, want %v", level["enum"], want)
+	}
+	if want := []interface{}{"string", "null"}; !reflect.DeepEqual(level["type"], want) {
+		t.Errorf("nullable type = %v, want %v", level["type"], want)
+	}
+	score := props["score"].(map[string]interface{})
+	if score["minimum"] != 0.0 || score["maximum"] != 1.0 {
+		t.Errorf("score = %v", score)
+	}
+	if props["name"].(map[string]interface{})["type"] != "string" {
+		t.Errorf("name = %v", props["name"])
+	}
+}
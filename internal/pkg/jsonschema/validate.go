@@ -0,0 +1,148 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxErrors 單次驗證最多回報的錯誤數，避免修正提示過長
+const maxErrors = 20
+
+// FieldError 單一欄位的驗證錯誤，Path 以 $ 表示根節點，例如 $.recipe[2].time_minutes
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// Errors 驗證錯誤列表
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Error()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Strings 回傳每個錯誤的文字，方便記錄與放進修正提示
+func (e Errors) Strings() []string {
+	out := make([]string, len(e))
+	for i, fe := range e {
+		out[i] = fe.Error()
+	}
+	return out
+}
+
+// Validate 驗證 JSON 是否符合結構描述，符合時回傳 nil；
+// 物件中多出的欄位不視為錯誤
+func (s *Schema) Validate(data []byte) Errors {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return Errors{{Path: "$", Message: "invalid JSON: " + err.Error()}}
+	}
+	if dec.More() {
+		return Errors{{Path: "$", Message: "unexpected content after the JSON value"}}
+	}
+
+	var errs Errors
+	s.validate("$", v, &errs)
+	return errs
+}
+
+func (s *Schema) validate(path string, v interface{}, errs *Errors) {
+	if len(*errs) >= maxErrors {
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	typ := typeOf(v)
+	if !s.allows(typ) {
+		fail("expected %s, got %s", strings.Join(s.Types, " or "), typ)
+		return
+	}
+
+	switch val := v.(type) {
+	case string:
+		if len(s.Enum) > 0 && !contains(s.Enum, val) {
+			fail("%q is not one of %s", val, strings.Join(s.Enum, ", "))
+		}
+	case json.Number:
+		f, err := val.Float64()
+		if err != nil {
+			fail("invalid number %s", val)
+			return
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("%s is less than the minimum %s", val, formatFloat(*s.Minimum))
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("%s is greater than the maximum %s", val, formatFloat(*s.Maximum))
+		}
+	case []interface{}:
+		if s.Items == nil {
+			return
+		}
+		for i, item := range val {
+			s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+		}
+	case map[string]interface{}:
+		for _, name := range s.order {
+			field, ok := val[name]
+			switch {
+			case ok:
+				s.Properties[name].validate(path+"."+name, field, errs)
+			case contains(s.Required, name) && len(*errs) < maxErrors:
+				*errs = append(*errs, FieldError{Path: path + "." + name, Message: "required field is missing"})
+			}
+		}
+	}
+}
+
+// typeOf 回傳值的 JSON Schema 型別，整數值回傳 integer
+func typeOf(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		// 與 encoding/json 一致：3.0 或 1e2 不能解析成 int，視為一般數值
+		if _, err := val.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,134 @@
+package jsonschema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	s := For(tagged{})
+	tests := []struct {
+		name string
+		json string
+		want []string
+	}{
+		{
+			name: "valid",
+			json: `{"name":"a","score":0.5,"level":"low","count":3,"source":"x"}`,
+		},
+		{
+			name: "null for pointer and nullable fields",
+			json: `{"name":"a","score":null,"level":null,"count":3,"source":"x"}`,
+		},
+		{
+			name: "optional fields may be missing, extra fields are ignored",
+			json: `{"name":"a","score":1,"level":"high","count":0,"source":"x","extra":true}`,
+		},
+		{
+			name: "missing required fields",
+			json: `{"name":"a","level":"low","count":1}`,
+			want: []string{"$.score: required field is missing", "$.source: required field is missing"},
+		},
+		{
+			name: "null for a non-nullable field",
+			json: `{"name":null,"score":0,"level":"low","count":1,"source":"x"}`,
+			want: []string{"$.name: expected string, got null"},
+		},
+		{
+			name: "integer accepted for a number",
+			json: `{"name":"a","score":1,"level":"low","count":1,"source":"x"}`,
+		},
+		{
+			name: "3.0 is not an integer",
+			json: `{"name":"a","score":0,"level":"low","count":3.0,"source":"x"}`,
+			want: []string{"$.count: expected integer, got number"},
+		},
+		{
+			name: "exponent is not an integer",
+			json: `{"name":"a","score":0,"level":"low","count":1e2,"source":"x"}`,
+			want: []string{"$.count: expected integer, got number"},
+		},
+		{
+			name: "out of range",
+			json: `{"name":"a","score":1.5,"level":"low","count":1,"source":"x"}`,
+			want: []string{"$.score: 1.5 is greater than the maximum 1"},
+		},
+		{
+			name: "below minimum",
+			json: `{"name":"a","score":-0.1,"level":"low","count":1,"source":"x"}`,
+			want: []string{"$.score: -0.1 is less than the minimum 0"},
+		},
+		{
+			name: "not in enum",
+			json: `{"name":"a","score":0,"level":"medium","count":1,"source":"x"}`,
+			want: []string{`$.level: "medium" is not one of low, high`},
+		},
+		{
+			name: "array items",
+			json: `{"name":"a","score":0,"level":"low","count":1,"source":"x","tags":["ok",2]}`,
+			want: []string{"$.tags[1]: expected string, got integer"},
+		},
+		{
+			name: "wrong root type",
+			json: `[]`,
+			want: []string{"$: expected object, got array"},
+		},
+		{
+			name: "invalid JSON",
+			json: `{"name":`,
+			want: []string{"$: invalid JSON: unexpected EOF"},
+		},
+		{
+			name: "trailing content",
+			json: `{"name":"a","score":0,"level":"low","count":1,"source":"x"} {"name":"b"}`,
+			want: []string{"$: unexpected content after the JSON value"},
+		},
+		{
+			name: "trailing whitespace is fine",
+			json: "{\"name\":\"a\",\"score\":0,\"level\":\"low\",\"count\":1,\"source\":\"x\"}\n  ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.Validate([]byte(tt.json)).Strings()
+			if len(got) == 0 {
+				got = nil
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("errors = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCapsErrors(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+	}
+	type list struct {
+		Items []item `json:"items"`
+	}
+
+	items := make([]string, maxErrors+10)
+	for i := range items {
+		items[i] = `{"name":` + fmt.Sprint(i) + `}`
+	}
+	errs := For(list{}).Validate([]byte(`{"items":[` + strings.Join(items, ",") + `]}`))
+	if len(errs) != maxErrors {
+		t.Fatalf("got %d errors, want %d", len(errs), maxErrors)
+	}
+	if last := errs[len(errs)-1].Path; last != fmt.Sprintf("$.items[%d].name", maxErrors-1) {
+		t.Fatalf("last error path %s", last)
+	}
+
+	// 缺少必填欄位同樣受上限約束
+	empty := make([]string, maxErrors+5)
+	for i := range empty {
+		empty[i] = "{}"
+	}
+	if errs := For(list{}).Validate([]byte(`{"items":[` + strings.Join(empty, ",") + `]}`)); len(errs) != maxErrors {
+		t.Fatalf("got %d missing-field errors, want %d", len(errs), maxErrors)
+	}
+}